@@ -1,11 +1,21 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"net/url"
+	"time"
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
+	"git.containerum.net/ch/resource-service/pkg/util/certs"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
 	"github.com/gin-gonic/gin"
 	"github.com/globalsign/mgo"
 	"github.com/go-playground/locales/en"
@@ -55,6 +65,12 @@ var flags = []cli.Flag{
 		Name:   "cors",
 		Usage:  "enable CORS",
 	},
+	cli.StringFlag{
+		EnvVar: "DB",
+		Name:   "db",
+		Value:  "mongo",
+		Usage:  "database type (mongo or memory)",
+	},
 	cli.StringFlag{
 		EnvVar: "MONGO_DB",
 		Name:   "mongo_db",
@@ -87,11 +103,115 @@ var flags = []cli.Flag{
 		Name:   "mongo_addr",
 		Usage:  "MongoDB address",
 	},
+	cli.StringFlag{
+		EnvVar: "DOMAIN_POLICY",
+		Name:   "domain_policy",
+		Value:  string(domain.Weighted),
+		Usage:  "domain selection policy for external services (weighted|least_used)",
+	},
 	cli.StringFlag{
 		EnvVar: "INGRESS_SUFFIX",
 		Name:   "ingress_suffix",
 		Usage:  "suffix to add to all ingress hostnames",
 	},
+	cli.DurationFlag{
+		EnvVar: "OUTBOX_PERIOD",
+		Name:   "outbox_period",
+		Value:  10 * time.Second,
+		Usage:  "period of applying pending kube-api operations",
+	},
+	cli.DurationFlag{
+		EnvVar: "WEBHOOK_PERIOD",
+		Name:   "webhook_period",
+		Value:  5 * time.Second,
+		Usage:  "period of sending pending webhook deliveries",
+	},
+	cli.StringSliceFlag{
+		EnvVar: "WEBHOOK_BLOCKED_CIDRS",
+		Name:   "webhook_blocked_cidrs",
+		Usage:  "networks webhooks can't be sent to besides loopback, private and link-local ones, e.g. pod and service CIDRs",
+	},
+	cli.DurationFlag{
+		EnvVar: "IDEMPOTENCY_WINDOW",
+		Name:   "idempotency_window",
+		Value:  24 * time.Hour,
+		Usage:  "period of replaying responses of POST requests with Idempotency-Key header",
+	},
+	cli.DurationFlag{
+		EnvVar: "ROLLBACK_DEADLINE",
+		Name:   "rollback_deadline",
+		Usage:  "rollback new deployment version if its replicas are not ready in time, 0 disables automatic rollback",
+	},
+	cli.DurationFlag{
+		EnvVar: "RECONCILE_PERIOD",
+		Name:   "reconcile_period",
+		Value:  10 * time.Minute,
+		Usage:  "period of checking drift between db and kube-api, 0 disables periodic reconciliation",
+	},
+	cli.StringFlag{
+		EnvVar: "SECRET_KEY",
+		Name:   "secret_key",
+		Usage:  "base64-encoded 32 bytes key used to encrypt secret values in db, required",
+	},
+	cli.StringFlag{
+		EnvVar: "RECONCILE_DIRECTION",
+		Name:   "reconcile_direction",
+		Usage:  "default drift repair direction (cluster|db), if empty drift is only reported. Resources are deleted only by requests with prune flag",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_CA_CERT",
+		Name:   "tls_ca_cert",
+		Usage:  "PEM file with CA certificate used to issue ingress certificates",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_CA_KEY",
+		Name:   "tls_ca_key",
+		Usage:  "PEM file with private key of CA certificate",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_ACME_DIRECTORY",
+		Name:   "tls_acme_directory",
+		Usage:  "ACME directory URL used to issue ingress certificates instead of CA",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_ACME_EMAIL",
+		Name:   "tls_acme_email",
+		Usage:  "contact email of ACME account",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_ACME_ACCOUNT_KEY",
+		Name:   "tls_acme_account_key",
+		Usage:  "PEM file with RSA or ECDSA key of ACME account, required with ACME directory",
+	},
+	cli.StringFlag{
+		EnvVar: "TLS_ACME_CA_BUNDLE",
+		Name:   "tls_acme_ca_bundle",
+		Usage:  "PEM file with CA certificates trusted in requests to ACME server, e.g. of local test server",
+	},
+	cli.DurationFlag{
+		EnvVar: "TLS_CERT_VALIDITY",
+		Name:   "tls_cert_validity",
+		Value:  90 * 24 * time.Hour,
+		Usage:  "validity period of certificates issued by CA",
+	},
+	cli.DurationFlag{
+		EnvVar: "TLS_RENEW_BEFORE",
+		Name:   "tls_renew_before",
+		Value:  30 * 24 * time.Hour,
+		Usage:  "renew ingress certificates this long before expiration",
+	},
+	cli.DurationFlag{
+		EnvVar: "TLS_RENEW_PERIOD",
+		Name:   "tls_renew_period",
+		Value:  time.Hour,
+		Usage:  "period of checking ingress certificates for renewal",
+	},
+	cli.DurationFlag{
+		EnvVar: "INSTANCE_LOCK_TTL",
+		Name:   "instance_lock_ttl",
+		Value:  30 * time.Second,
+		Usage:  "expiration of lock held by running instance, only one instance can run at a time",
+	},
 }
 
 func setupLogs(c *cli.Context) {
@@ -114,6 +234,17 @@ func setupTranslator() *ut.UniversalTranslator {
 	return ut.New(en.New(), en.New(), en_US.New())
 }
 
+func setupStorage(c *cli.Context) (db.Storage, error) {
+	switch c.String("db") {
+	case "mongo":
+		return setupMongo(c)
+	case "memory":
+		return db.NewMemory(logrus.WithField("component", "memory")), nil
+	default:
+		return nil, errors.New("invalid database type")
+	}
+}
+
 func setupMongo(c *cli.Context) (*db.MongoStorage, error) {
 	dialInfo := mgo.DialInfo{
 		Username:  c.String("mongo_login"),
@@ -151,3 +282,96 @@ func setupPermissions(c *cli.Context) *clients.Permissions {
 	client := clients.NewPermissionsHTTP(c.String("permissions_addr"))
 	return &client
 }
+
+func setupReconcileDirection(c *cli.Context) (reconcile.Direction, error) {
+	switch direction := reconcile.Direction(c.String("reconcile_direction")); direction {
+	case "", reconcile.ToCluster, reconcile.ToDB:
+		return direction, nil
+	default:
+		return "", errors.New("invalid reconcile direction")
+	}
+}
+
+func setupDomainPolicy(c *cli.Context) (domain.SelectionPolicy, error) {
+	switch policy := domain.SelectionPolicy(c.String("domain_policy")); policy {
+	case domain.Weighted, domain.LeastUsed:
+		return policy, nil
+	default:
+		return "", errors.New("invalid domain selection policy")
+	}
+}
+
+func setupWebhookBlockedNetworks(c *cli.Context) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range c.StringSlice("webhook_blocked_cidrs") {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// setupSecretBox returns box encrypting secret values, service doesn't start without key
+func setupSecretBox(c *cli.Context) (*secretbox.Box, error) {
+	if c.String("secret_key") == "" {
+		return nil, errors.New("SECRET_KEY is not set, generate it with `openssl rand -base64 32`")
+	}
+	return secretbox.New(c.String("secret_key"))
+}
+
+// setupCertIssuer returns issuer of ingress certificates and handler of ACME challenges, issuer is nil if it's not configured
+func setupCertIssuer(c *cli.Context) (certs.Issuer, http.Handler, error) {
+	caCert, acmeDirectory := c.String("tls_ca_cert"), c.String("tls_acme_directory")
+	switch {
+	case caCert != "" && acmeDirectory != "":
+		return nil, nil, errors.New("only one of CA and ACME certificate issuers can be configured")
+	case caCert != "":
+		certPEM, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyPEM, err := ioutil.ReadFile(c.String("tls_ca_key"))
+		if err != nil {
+			return nil, nil, err
+		}
+		issuer, err := certs.NewCAIssuer(certPEM, keyPEM, c.Duration("tls_cert_validity"))
+		return issuer, nil, err
+	case acmeDirectory != "":
+		cfg := certs.ACMEConfig{
+			DirectoryURL: acmeDirectory,
+			Email:        c.String("tls_acme_email"),
+		}
+		keyFile := c.String("tls_acme_account_key")
+		if keyFile == "" {
+			return nil, nil, errors.New("ACME account key is required")
+		}
+		keyPEM, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.AccountKeyPEM = keyPEM
+		if bundle := c.String("tls_acme_ca_bundle"); bundle != "" {
+			bundlePEM, err := ioutil.ReadFile(bundle)
+			if err != nil {
+				return nil, nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(bundlePEM) {
+				return nil, nil, errors.New("no certificates in ACME CA bundle")
+			}
+			cfg.HTTPClient = &http.Client{
+				Timeout:   30 * time.Second,
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+			}
+		}
+		issuer, err := certs.NewACMEIssuer(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return issuer, issuer.ChallengeHandler(), nil
+	default:
+		return nil, nil, nil
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"fmt"
 	"text/tabwriter"
 
+	"git.containerum.net/ch/resource-service/pkg/db"
 	"git.containerum.net/ch/resource-service/pkg/router"
 	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server/impl"
 	"git.containerum.net/ch/resource-service/pkg/util/validation"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/sirupsen/logrus"
@@ -34,7 +36,7 @@ func initServer(c *cli.Context) error {
 
 	tv := &m.TranslateValidate{UniversalTranslator: translate, Validate: validate}
 
-	mongo, err := setupMongo(c)
+	mongo, err := setupStorage(c)
 	exitOnError(err)
 	defer mongo.Close()
 
@@ -52,7 +54,72 @@ func initServer(c *cli.Context) error {
 		StatusOK: true,
 	}
 
-	app := router.CreateRouter(mongo, permissions, kube, &status, tv, c.Bool("cors"), c.String("ingress_suffix"), c.Uint("min_port"), c.Uint("max_port"))
+	direction, err := setupReconcileDirection(c)
+	exitOnError(err)
+
+	box, err := setupSecretBox(c)
+	exitOnError(err)
+	mongo = db.WithSealedConfigMaps(mongo, box)
+
+	domainPolicy, err := setupDomainPolicy(c)
+	exitOnError(err)
+
+	issuer, acmeChallenges, err := setupCertIssuer(c)
+	exitOnError(err)
+
+	// watch history, rollout watchers and workers are not shared between instances
+	instanceLock := impl.NewInstanceLockImpl(mongo, c.Duration("instance_lock_ttl"))
+	lockCtx, cancelLock := context.WithTimeout(context.Background(), 2*c.Duration("instance_lock_ttl"))
+	err = instanceLock.Acquire(lockCtx)
+	cancelLock()
+	exitOnError(err)
+	// released after workers are stopped
+	defer instanceLock.Release()
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go instanceLock.Hold(workersCtx, func(err error) {
+		logrus.WithError(err).Fatalf("instance lock is lost, another instance may be running")
+	})
+
+	watcher := impl.NewWatchImpl()
+
+	outbox := impl.NewOutboxImpl(mongo, kube, box, watcher)
+	go outbox.Run(workersCtx, c.Duration("outbox_period"))
+
+	webhookBlocked, err := setupWebhookBlockedNetworks(c)
+	exitOnError(err)
+
+	webhooks := impl.NewWebhookImpl(mongo, box, webhookBlocked)
+	go webhooks.Run(workersCtx, c.Duration("webhook_period"))
+
+	reconciler := impl.NewReconcileActionsImpl(mongo, kube, direction, watcher)
+	if period := c.Duration("reconcile_period"); period > 0 {
+		go reconciler.Run(workersCtx, period)
+	}
+
+	certificates := impl.NewCertificatesImpl(mongo, kube, box, issuer, c.Duration("tls_renew_before"), watcher)
+	if certificates.Enabled() {
+		go certificates.Run(workersCtx, c.Duration("tls_renew_period"))
+	}
+
+	app := router.CreateRouter(mongo, permissions, kube, box, &status, tv, router.Workers{
+		Outbox:         outbox,
+		Webhooks:       webhooks,
+		Reconciler:     reconciler,
+		Certificates:   certificates,
+		Watch:          watcher,
+		ACMEChallenges: acmeChallenges,
+	}, router.Config{
+		EnableCORS:        c.Bool("cors"),
+		IngressSuffix:     c.String("ingress_suffix"),
+		DomainPolicy:      domainPolicy,
+		MinPort:           c.Uint("min_port"),
+		MaxPort:           c.Uint("max_port"),
+		RollbackDeadline:  c.Duration("rollback_deadline"),
+		IdempotencyWindow: c.Duration("idempotency_window"),
+	})
 
 	srv := &http.Server{
 		Addr:    ":" + c.String("port"),
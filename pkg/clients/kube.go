@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
 	"github.com/containerum/cherry"
@@ -18,31 +20,81 @@ import (
 
 // Kube is an interface to kube-api service
 type Kube interface {
+	GetNamespaceList(ctx context.Context) ([]kubtypes.Namespace, error)
+
 	GetDeployment(ctx context.Context, nsID, deployName string) (*kubtypes.Deployment, error)
-	CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error
-	UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error
+	GetDeploymentList(ctx context.Context, nsID string) ([]KubeDeployment, error)
+	CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error
+	UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error
 	SetDeploymentReplicas(ctx context.Context, nsID, deplName string, replicas int) error
 	SetContainerImage(ctx context.Context, nsID, deplName string, container kubtypes.UpdateImage) error
 	DeleteSolutionDeployments(ctx context.Context, nsID, solutionName string) error
 	DeleteDeployment(ctx context.Context, nsID, deplName string) error
 
-	CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error
-	UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error
+	GetIngressList(ctx context.Context, nsID string) ([]KubeIngress, error)
+	CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error
+	UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error
 	DeleteIngress(ctx context.Context, nsID, ingressName string) error
 
 	CreateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error
+	UpdateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error
 	DeleteSecret(ctx context.Context, nsID, secretName string) error
 
 	GetService(ctx context.Context, nsID, svcName string) (*kubtypes.Service, error)
-	CreateService(ctx context.Context, nsID string, service kubtypes.Service) error
-	UpdateService(ctx context.Context, nsID string, service kubtypes.Service) error
+	GetServiceList(ctx context.Context, nsID string) ([]KubeService, error)
+	CreateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error
+	UpdateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error
 	DeleteService(ctx context.Context, nsID, serviceName string) error
 	DeleteSolutionServices(ctx context.Context, nsID, solutionName string) error
 
-	CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) error
+	GetConfigMapList(ctx context.Context, nsID string) ([]KubeConfigMap, error)
+	CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error
+	UpdateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error
 	DeleteConfigMap(ctx context.Context, nsID, cmName string) error
 }
 
+// request bodies of resources with user labels and annotations
+type (
+	deploymentBody struct {
+		kubtypes.Deployment
+		labels.Metadata
+		Strategy       *deployment.KubeStrategy `json:"strategy,omitempty"`
+		PodAnnotations map[string]string        `json:"pod_annotations,omitempty"`
+	}
+	ingressBody struct {
+		kubtypes.Ingress
+		labels.Metadata
+	}
+	serviceBody struct {
+		kubtypes.Service
+		labels.Metadata
+	}
+	configMapBody struct {
+		kubtypes.ConfigMap
+		labels.Metadata
+	}
+)
+
+// resources listed in kube-api with their labels and annotations
+type (
+	KubeDeployment struct {
+		kubtypes.Deployment
+		labels.Metadata
+	}
+	KubeIngress struct {
+		kubtypes.Ingress
+		labels.Metadata
+	}
+	KubeService struct {
+		kubtypes.Service
+		labels.Metadata
+	}
+	KubeConfigMap struct {
+		kubtypes.ConfigMap
+		labels.Metadata
+	}
+)
+
 type kube struct {
 	client *resty.Client
 	log    *cherrylog.LogrusAdapter
@@ -90,12 +142,55 @@ func (kub kube) GetDeployment(ctx context.Context, nsID, deployName string) (*ku
 	return &ret, nil
 }
 
-func (kub kube) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error {
+func (kub kube) GetNamespaceList(ctx context.Context) ([]kubtypes.Namespace, error) {
+	kub.log.Debugf("get namespaces list")
+
+	var ret kubtypes.NamespacesList
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		Get("/namespaces")
+	if err != nil {
+		return nil, rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret.Namespaces, nil
+}
+
+func (kub kube) GetDeploymentList(ctx context.Context, nsID string) ([]KubeDeployment, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debugf("get deployments list")
+
+	var ret struct {
+		Deployments []KubeDeployment `json:"deployments"`
+	}
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+		}).
+		Get("/namespaces/{namespace}/deployments")
+	if err != nil {
+		return nil, rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret.Deployments, nil
+}
+
+func (kub kube) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
 	kub.log.WithField("ns_id", nsID).Debugf("create deployment %v", deploy.Name)
 	coblog.Std.Struct(deploy)
 
 	resp, err := kub.client.R().
-		SetBody(deploy).
+		SetBody(deploymentBody{Deployment: deploy, Metadata: meta.Managed(), Strategy: strategy.Kube(), PodAnnotations: deployment.PodAnnotations(configHashes)}).
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
 		SetPathParams(map[string]string{
@@ -157,7 +252,7 @@ func (kub kube) DeleteSolutionDeployments(ctx context.Context, nsID, solutionNam
 	return nil
 }
 
-func (kub kube) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error {
+func (kub kube) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("update deployment %v", deploy.Name)
@@ -166,7 +261,7 @@ func (kub kube) UpdateDeployment(ctx context.Context, nsID string, deploy kubtyp
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(deploy).
+		SetBody(deploymentBody{Deployment: deploy, Metadata: meta.Managed(), Strategy: strategy.Kube(), PodAnnotations: deployment.PodAnnotations(configHashes)}).
 		SetPathParams(map[string]string{
 			"namespace":  nsID,
 			"deployment": deploy.Name,
@@ -232,7 +327,32 @@ func (kub kube) SetContainerImage(ctx context.Context, nsID, deplName string, co
 	return nil
 }
 
-func (kub kube) CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error {
+func (kub kube) GetIngressList(ctx context.Context, nsID string) ([]KubeIngress, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debugf("get ingresses list")
+
+	var ret struct {
+		Ingress []KubeIngress `json:"ingresses"`
+	}
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+		}).
+		Get("/namespaces/{namespace}/ingresses")
+	if err != nil {
+		return nil, rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret.Ingress, nil
+}
+
+func (kub kube) CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("create ingress %v", ingress.Name)
@@ -241,7 +361,7 @@ func (kub kube) CreateIngress(ctx context.Context, nsID string, ingress kubtypes
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(ingress).
+		SetBody(ingressBody{Ingress: ingress, Metadata: meta.Managed()}).
 		SetPathParams(map[string]string{
 			"namespace": nsID,
 		}).
@@ -255,7 +375,7 @@ func (kub kube) CreateIngress(ctx context.Context, nsID string, ingress kubtypes
 	return nil
 }
 
-func (kub kube) UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error {
+func (kub kube) UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":        nsID,
 		"ingress_name": ingress.Name,
@@ -265,7 +385,7 @@ func (kub kube) UpdateIngress(ctx context.Context, nsID string, ingress kubtypes
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(ingress).
+		SetBody(ingressBody{Ingress: ingress, Metadata: meta.Managed()}).
 		SetPathParams(map[string]string{
 			"namespace": nsID,
 			"ingress":   ingress.Name,
@@ -307,7 +427,6 @@ func (kub kube) CreateSecret(ctx context.Context, nsID string, secret kubtypes.S
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("create secret %v", secret.Name)
-	coblog.Std.Struct(secret)
 
 	resp, err := kub.client.R().
 		SetContext(ctx).
@@ -326,6 +445,30 @@ func (kub kube) CreateSecret(ctx context.Context, nsID string, secret kubtypes.S
 	return nil
 }
 
+func (kub kube) UpdateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id":       nsID,
+		"secret_name": secret.Name,
+	}).Debug("update secret")
+
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetBody(secret).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+			"secret":    secret.Name,
+		}).
+		Put("/namespaces/{namespace}/secrets/{secret}")
+	if err != nil {
+		return rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return resp.Error().(*cherry.Err)
+	}
+	return nil
+}
+
 func (kub kube) DeleteSecret(ctx context.Context, nsID, secretName string) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":       nsID,
@@ -374,14 +517,39 @@ func (kub kube) GetService(ctx context.Context, nsID, svcName string) (*kubtypes
 	return &ret, nil
 }
 
-func (kub kube) CreateService(ctx context.Context, nsID string, service kubtypes.Service) error {
+func (kub kube) GetServiceList(ctx context.Context, nsID string) ([]KubeService, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debugf("get services list")
+
+	var ret struct {
+		Services []KubeService `json:"services"`
+	}
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+		}).
+		Get("/namespaces/{namespace}/services")
+	if err != nil {
+		return nil, rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret.Services, nil
+}
+
+func (kub kube) CreateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error {
 	kub.log.WithField("ns_id", nsID).Debugf("create service %v", service)
 	coblog.Std.Struct(service)
 
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(service).
+		SetBody(serviceBody{Service: service, Metadata: meta.Managed()}).
 		SetPathParams(map[string]string{
 			"namespace": nsID,
 		}).
@@ -397,7 +565,7 @@ func (kub kube) CreateService(ctx context.Context, nsID string, service kubtypes
 	return nil
 }
 
-func (kub kube) UpdateService(ctx context.Context, nsID string, service kubtypes.Service) error {
+func (kub kube) UpdateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":        nsID,
 		"service_name": service.Name,
@@ -407,7 +575,7 @@ func (kub kube) UpdateService(ctx context.Context, nsID string, service kubtypes
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(service).
+		SetBody(serviceBody{Service: service, Metadata: meta.Managed()}).
 		SetPathParams(map[string]string{
 			"namespace": nsID,
 			"service":   service.Name,
@@ -472,7 +640,32 @@ func (kub kube) DeleteSolutionServices(ctx context.Context, nsID, solutionName s
 	return nil
 }
 
-func (kub kube) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) error {
+func (kub kube) GetConfigMapList(ctx context.Context, nsID string) ([]KubeConfigMap, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debugf("get configmaps list")
+
+	var ret struct {
+		ConfigMaps []KubeConfigMap `json:"configmaps"`
+	}
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetResult(&ret).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+		}).
+		Get("/namespaces/{namespace}/configmaps")
+	if err != nil {
+		return nil, rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return nil, resp.Error().(*cherry.Err)
+	}
+	return ret.ConfigMaps, nil
+}
+
+func (kub kube) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("create configmap %v", cm.Name)
@@ -481,7 +674,7 @@ func (kub kube) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.Co
 	resp, err := kub.client.R().
 		SetContext(ctx).
 		SetHeaders(httputil.RequestXHeadersMap(ctx)).
-		SetBody(cm).
+		SetBody(configMapBody{ConfigMap: cm, Metadata: meta.Managed()}).
 		SetPathParams(map[string]string{
 			"namespace": nsID,
 		}).
@@ -495,6 +688,31 @@ func (kub kube) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.Co
 	return nil
 }
 
+func (kub kube) UpdateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"cm_name": cm.Name,
+	}).Debug("update configmap")
+	coblog.Std.Struct(cm)
+
+	resp, err := kub.client.R().
+		SetContext(ctx).
+		SetHeaders(httputil.RequestXHeadersMap(ctx)).
+		SetBody(configMapBody{ConfigMap: cm, Metadata: meta.Managed()}).
+		SetPathParams(map[string]string{
+			"namespace": nsID,
+			"configmap": cm.Name,
+		}).
+		Put("/namespaces/{namespace}/configmaps/{configmap}")
+	if err != nil {
+		return rserrors.ErrInternal().Log(err, kub.log)
+	}
+	if resp.Error() != nil {
+		return resp.Error().(*cherry.Err)
+	}
+	return nil
+}
+
 func (kub kube) DeleteConfigMap(ctx context.Context, nsID, cmName string) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":   nsID,
@@ -546,13 +764,27 @@ func (kub kubeDummy) GetDeployment(ctx context.Context, nsID, deployName string)
 	return nil, nil
 }
 
-func (kub kubeDummy) CreateDeployment(_ context.Context, nsID string, deploy kubtypes.Deployment) error {
-	kub.log.WithField("ns_id", nsID).Debug("create deployment %+v", deploy)
+func (kub kubeDummy) GetNamespaceList(ctx context.Context) ([]kubtypes.Namespace, error) {
+	kub.log.Debug("get namespaces list")
+
+	return []kubtypes.Namespace{}, nil
+}
+
+func (kub kubeDummy) GetDeploymentList(ctx context.Context, nsID string) ([]KubeDeployment, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debug("get deployments list")
+
+	return []KubeDeployment{}, nil
+}
+
+func (kub kubeDummy) CreateDeployment(_ context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	kub.log.WithField("ns_id", nsID).Debugf("create deployment %+v", deploy)
 
 	return nil
 }
 
-func (kub kubeDummy) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error {
+func (kub kubeDummy) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("update deployment %+v", deploy)
@@ -608,7 +840,15 @@ func (kub kubeDummy) SetContainerImage(ctx context.Context, nsID, deplName strin
 	return nil
 }
 
-func (kub kubeDummy) CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error {
+func (kub kubeDummy) GetIngressList(ctx context.Context, nsID string) ([]KubeIngress, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debug("get ingresses list")
+
+	return []KubeIngress{}, nil
+}
+
+func (kub kubeDummy) CreateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("create ingress %+v", ingress)
@@ -616,7 +856,7 @@ func (kub kubeDummy) CreateIngress(ctx context.Context, nsID string, ingress kub
 	return nil
 }
 
-func (kub kubeDummy) UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress) error {
+func (kub kubeDummy) UpdateIngress(ctx context.Context, nsID string, ingress kubtypes.Ingress, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":        nsID,
 		"ingress_name": ingress.Name,
@@ -637,7 +877,16 @@ func (kub kubeDummy) DeleteIngress(ctx context.Context, nsID, ingressName string
 func (kub kubeDummy) CreateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
-	}).Debugf("create secret %+v", secret)
+	}).Debugf("create secret %v", secret.Name)
+
+	return nil
+}
+
+func (kub kubeDummy) UpdateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id":       nsID,
+		"secret_name": secret.Name,
+	}).Debug("update secret")
 
 	return nil
 }
@@ -659,13 +908,21 @@ func (kub kubeDummy) GetService(ctx context.Context, nsID, svcName string) (*kub
 	return nil, nil
 }
 
-func (kub kubeDummy) CreateService(ctx context.Context, nsID string, service kubtypes.Service) error {
+func (kub kubeDummy) GetServiceList(ctx context.Context, nsID string) ([]KubeService, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debug("get services list")
+
+	return []KubeService{}, nil
+}
+
+func (kub kubeDummy) CreateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error {
 	kub.log.WithField("ns_id", nsID).Debugf("create service %+v", service)
 
 	return nil
 }
 
-func (kub kubeDummy) UpdateService(ctx context.Context, nsID string, service kubtypes.Service) error {
+func (kub kubeDummy) UpdateService(ctx context.Context, nsID string, service kubtypes.Service, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":        nsID,
 		"service_name": service.Name,
@@ -692,7 +949,15 @@ func (kub kubeDummy) DeleteSolutionServices(ctx context.Context, nsID, solutionN
 	return nil
 }
 
-func (kub kubeDummy) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) error {
+func (kub kubeDummy) GetConfigMapList(ctx context.Context, nsID string) ([]KubeConfigMap, error) {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Debug("get configmaps list")
+
+	return []KubeConfigMap{}, nil
+}
+
+func (kub kubeDummy) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Debugf("create configmap %v", cm.Name)
@@ -700,6 +965,15 @@ func (kub kubeDummy) CreateConfigMap(ctx context.Context, nsID string, cm kubtyp
 	return nil
 }
 
+func (kub kubeDummy) UpdateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
+	kub.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"cm_name": cm.Name,
+	}).Debug("update configmap")
+
+	return nil
+}
+
 func (kub kubeDummy) DeleteConfigMap(ctx context.Context, nsID, cmName string) error {
 	kub.log.WithFields(logrus.Fields{
 		"ns_id":   nsID,
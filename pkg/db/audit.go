@@ -0,0 +1,27 @@
+package db
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+)
+
+func (mongo *MongoStorage) CreateAuditEntry(entry audit.Entry) error {
+	mongo.logger.Debugf("creating audit entry")
+	var collection = mongo.db.C(CollectionAudit)
+	if err := collection.Insert(entry); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create audit entry")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetAuditEntries(query audit.Query, list *ListQuery) ([]audit.Entry, int, error) {
+	mongo.logger.Debugf("getting audit entries")
+	var collection = mongo.db.C(CollectionAudit)
+	var entries = make([]audit.Entry, 0)
+	total, err := findList(collection, query.SelectQuery(), list, &entries, "-time")
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get audit entries")
+		return nil, 0, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return entries, total, nil
+}
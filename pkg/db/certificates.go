@@ -0,0 +1,66 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+func (mongo *MongoStorage) SetCertificate(cert ingress.Certificate) error {
+	mongo.logger.Debugf("setting certificate")
+	var collection = mongo.db.C(CollectionCertificate)
+	if _, err := collection.Upsert(bson.M{
+		"namespaceid": cert.NamespaceID,
+		"ingress":     cert.Ingress,
+	}, cert); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to set certificate")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetCertificate(namespaceID, ingressName string) (ingress.Certificate, error) {
+	mongo.logger.Debugf("getting certificate")
+	var collection = mongo.db.C(CollectionCertificate)
+	var result ingress.Certificate
+	if err := collection.Find(bson.M{
+		"namespaceid": namespaceID,
+		"ingress":     ingressName,
+	}).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get certificate")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("certificate of ingress %v", ingressName)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetCertificatesExpiringBefore(t time.Time) ([]ingress.Certificate, error) {
+	mongo.logger.Debugf("getting expiring certificates")
+	var collection = mongo.db.C(CollectionCertificate)
+	result := make([]ingress.Certificate, 0)
+	if err := collection.Find(bson.M{
+		"notafter": bson.M{"$lt": t},
+	}).Sort("notafter").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get expiring certificates")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) DeleteCertificate(namespaceID, ingressName string) error {
+	mongo.logger.Debugf("deleting certificate")
+	var collection = mongo.db.C(CollectionCertificate)
+	if err := collection.Remove(bson.M{
+		"namespaceid": namespaceID,
+		"ingress":     ingressName,
+	}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete certificate")
+		return PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return nil
+}
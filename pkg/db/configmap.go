@@ -5,6 +5,7 @@ import (
 
 	"git.containerum.net/ch/resource-service/pkg/models/configmap"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/blang/semver"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -26,37 +27,78 @@ func (mongo *MongoStorage) GetConfigMap(namespaceID, cmName string) (configmap.R
 	return result, nil
 }
 
-func (mongo *MongoStorage) GetConfigMapList(namespaceID string) (configmap.ListConfigMaps, error) {
+func (mongo *MongoStorage) GetConfigMapVersion(namespaceID, cmName string, version semver.Version) (configmap.ResourceConfigMap, error) {
+	mongo.logger.Debugf("getting configmap version")
+	var collection = mongo.db.C(CollectionCM)
+	var result configmap.ResourceConfigMap
+	var err error
+	if err = collection.Find(configmap.ResourceConfigMap{
+		ConfigMap: model.ConfigMap{
+			Name: cmName,
+		},
+		NamespaceID: namespaceID,
+		Version:     version,
+	}.OneAnyVersionSelectQuery()).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get configmap version")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("%v %v", cmName, version.String())
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetConfigMapVersionsList(namespaceID, cmName string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	mongo.logger.Debugf("getting configmap versions list")
+	var collection = mongo.db.C(CollectionCM)
+	result := make(configmap.ListConfigMaps, 0)
+	total, err := findList(collection, bson.M{
+		"namespaceid":    namespaceID,
+		"deleted":        false,
+		"configmap.name": cmName,
+	}, query, &result, "-version")
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get configmap versions list")
+		return result, 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, total, nil
+}
+
+func (mongo *MongoStorage) GetConfigMapList(namespaceID string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
 	mongo.logger.Debugf("getting configmaps list")
 	var collection = mongo.db.C(CollectionCM)
 	result := make(configmap.ListConfigMaps, 0)
-	if err := collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid": namespaceID,
 		"deleted":     false,
-	}).All(&result); err != nil {
+		"active":      true,
+	}, query, &result)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get configmaps list")
-		return result, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+		return result, 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
 	}
-	return result, nil
+	return result, total, nil
 }
 
-func (mongo *MongoStorage) GetSelectedConfigMaps(namespaceID []string) (configmap.ListConfigMaps, error) {
+func (mongo *MongoStorage) GetSelectedConfigMaps(namespaceID []string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
 	mongo.logger.Debugf("getting selected configmaps")
 	var collection = mongo.db.C(CollectionCM)
 	list := make(configmap.ListConfigMaps, 0)
-	if err := collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid": bson.M{
 			"$in": namespaceID,
 		},
 		"deleted": false,
-	}).All(&list); err != nil {
+		"active":  true,
+	}, query, &list)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get configmaps")
 		if err == mgo.ErrNotFound {
-			return list, rserrors.ErrResourceNotExists()
+			return list, 0, rserrors.ErrResourceNotExists()
 		}
-		return list, PipErr{error: err}.ToMongerr().Extract()
+		return list, 0, PipErr{error: err}.ToMongerr().Extract()
 	}
-	return list, nil
+	return list, total, nil
 }
 
 // If ID is empty, then generates UUID4 and uses it
@@ -66,8 +108,12 @@ func (mongo *MongoStorage) CreateConfigMap(cm configmap.ResourceConfigMap) (conf
 	if cm.ID == "" {
 		cm.ID = uuid.New().String()
 	}
-	cm.Data = nil
 	cm.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return cm, err
+	}
+	cm.ResourceVersion = version
 	if err := collection.Insert(cm); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to create configmap")
 		if mgo.IsDup(err) {
@@ -81,20 +127,94 @@ func (mongo *MongoStorage) CreateConfigMap(cm configmap.ResourceConfigMap) (conf
 func (mongo *MongoStorage) DeleteConfigMap(namespaceID, name string) error {
 	mongo.logger.Debugf("deleting configmap")
 	var collection = mongo.db.C(CollectionCM)
-	err := collection.Update(configmap.ResourceConfigMap{
+	info, err := collection.UpdateAll(bson.M{
+		"namespaceid":    namespaceID,
+		"deleted":        false,
+		"configmap.name": name,
+	},
+		bson.M{
+			"$set": bson.M{"deleted": true,
+				"configmap.deletedat": time.Now().UTC().Format(time.RFC3339)},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete configmap")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	if info.Updated == 0 {
+		mongo.logger.Errorf("unable to delete configmap")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ActivateConfigMap(namespaceID, name string, version semver.Version) error {
+	mongo.logger.Debugf("activating configmap")
+	var collection = mongo.db.C(CollectionCM)
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(configmap.ResourceConfigMap{
 		ConfigMap: model.ConfigMap{
 			Name: name,
 		},
 		NamespaceID: namespaceID,
-	}.OneSelectQuery(),
+		Version:     version,
+	}.OneAnyVersionSelectQuery(),
+		bson.M{
+			"$set": bson.M{"resourceversion": resourceVersion, "active": true},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to activate configmap")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+// DeactivateConfigMap deactivates active configmap version if it wasn't changed since it was read.
+func (mongo *MongoStorage) DeactivateConfigMap(namespaceID, name string, resourceVersion int64) error {
+	mongo.logger.Debugf("deactivating configmap")
+	var collection = mongo.db.C(CollectionCM)
+	newVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(casSelectQuery(configmap.OneSelectQuery(namespaceID, name), resourceVersion),
+		bson.M{
+			"$set": bson.M{"active": false, "resourceversion": newVersion},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to deactivate configmap")
+		if err == mgo.ErrNotFound {
+			return versionConflict(collection, configmap.OneSelectQuery(namespaceID, name), name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+// DeleteConfigMapVersion deletes inactive configmap version
+func (mongo *MongoStorage) DeleteConfigMapVersion(namespaceID, name string, version semver.Version) error {
+	mongo.logger.Debugf("deleting configmap version")
+	var collection = mongo.db.C(CollectionCM)
+	err := collection.Update(bson.M{
+		"namespaceid":    namespaceID,
+		"deleted":        false,
+		"active":         false,
+		"configmap.name": name,
+		"version":        version,
+	},
 		bson.M{
 			"$set": bson.M{"deleted": true,
 				"configmap.deletedat": time.Now().UTC().Format(time.RFC3339)},
 		})
 	if err != nil {
-		mongo.logger.WithError(err).Errorf("unable to delete configmap")
+		mongo.logger.WithError(err).Errorf("unable to delete configmap version")
 		if err == mgo.ErrNotFound {
-			return rserrors.ErrResourceNotExists().AddDetails(name)
+			return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
 		}
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
@@ -135,19 +255,17 @@ func (mongo *MongoStorage) DeleteAllConfigMapsByOwner(owner string) error {
 	return nil
 }
 
+// RestoreConfigMap restores last deleted configmap with all versions deleted together with it
 func (mongo *MongoStorage) RestoreConfigMap(namespaceID, name string) error {
 	mongo.logger.Debugf("restoring configmap")
 	var collection = mongo.db.C(CollectionCM)
-	err := collection.Update(configmap.ResourceConfigMap{
+	var deleted configmap.ResourceConfigMap
+	err := collection.Find(configmap.ResourceConfigMap{
 		ConfigMap: model.ConfigMap{
 			Name: name,
 		},
 		NamespaceID: namespaceID,
-	}.OneSelectDeletedQuery(),
-		bson.M{
-			"$set": bson.M{"deleted": false,
-				"configmap.deletedat": ""},
-		})
+	}.OneSelectDeletedQuery()).Sort("-configmap.deletedat").One(&deleted)
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to restore configmap")
 		if err == mgo.ErrNotFound {
@@ -155,6 +273,32 @@ func (mongo *MongoStorage) RestoreConfigMap(namespaceID, name string) error {
 		}
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
+	// active version goes first, so unique index stops restore before other versions are touched
+	err = collection.UpdateId(deleted.ID, bson.M{
+		"$set": bson.M{"deleted": false,
+			"configmap.deletedat": ""},
+	})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to restore configmap")
+		if mgo.IsDup(err) {
+			return rserrors.ErrResourceAlreadyExists().AddDetails(name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	_, err = collection.UpdateAll(bson.M{
+		"namespaceid":         namespaceID,
+		"deleted":             true,
+		"configmap.name":      name,
+		"configmap.deletedat": deleted.DeletedAt,
+	},
+		bson.M{
+			"$set": bson.M{"deleted": false,
+				"configmap.deletedat": ""},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to restore configmap versions")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
 	return nil
 }
 
@@ -164,6 +308,7 @@ func (mongo *MongoStorage) CountConfigMaps(owner string) (int, error) {
 	n, err := collection.Find(bson.M{
 		"configmap.owner": owner,
 		"deleted":         false,
+		"active":          true,
 	}).Count()
 	if err != nil {
 		return 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
@@ -176,6 +321,7 @@ func (mongo *MongoStorage) CountAllConfigMaps() (int, error) {
 	var collection = mongo.db.C(CollectionCM)
 	n, err := collection.Find(bson.M{
 		"deleted": false,
+		"active":  true,
 	}).Count()
 	if err != nil {
 		return 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
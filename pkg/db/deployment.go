@@ -66,34 +66,35 @@ func (mongo *MongoStorage) GetDeploymentLatestVersion(namespaceID, deploymentNam
 	return depl, err
 }
 
-func (mongo *MongoStorage) GetDeploymentVersionsList(namespaceID, deploymentName string) (deployment.ListDeploy, error) {
+func (mongo *MongoStorage) GetDeploymentVersionsList(namespaceID, deploymentName string, query *ListQuery) (deployment.ListDeploy, int, error) {
 	mongo.logger.Debugf("getting deployment versions list")
 	var collection = mongo.db.C(CollectionDeployment)
 	depl := make(deployment.ListDeploy, 0)
-	var err error
-	if err = collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid":     namespaceID,
 		"deleted":         false,
 		"deployment.name": deploymentName,
-	}).Sort("-deployment.version").All(&depl); err != nil {
+	}, query, &depl, "-deployment.version")
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get deployment %v", deploymentName)
 	}
-	return depl, PipErr{error: err}.ToMongerr().Extract()
+	return depl, total, PipErr{error: err}.ToMongerr().Extract()
 }
 
-func (mongo *MongoStorage) GetDeploymentList(namespaceID string) (deployment.ListDeploy, error) {
+func (mongo *MongoStorage) GetDeploymentList(namespaceID string, query *ListQuery) (deployment.ListDeploy, int, error) {
 	mongo.logger.Debugf("getting deployments list")
 	var collection = mongo.db.C(CollectionDeployment)
 	depl := make(deployment.ListDeploy, 0)
-	var err error
-	if err = collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid":       namespaceID,
 		"deleted":           false,
 		"deployment.active": true,
-	}).All(&depl); err != nil {
+		"canary":            false,
+	}, query, &depl)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get deployment")
 	}
-	return depl, PipErr{error: err}.ToMongerr().Extract()
+	return depl, total, PipErr{error: err}.ToMongerr().Extract()
 }
 
 // If ID is empty when use UUID4 to generate one
@@ -104,6 +105,11 @@ func (mongo *MongoStorage) CreateDeployment(deployment deployment.ResourceDeploy
 		deployment.ID = uuid.New().String()
 	}
 	deployment.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return deployment, err
+	}
+	deployment.ResourceVersion = version
 	if err := collection.Insert(deployment); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to create deployment")
 		if mgo.IsDup(err) {
@@ -117,9 +123,55 @@ func (mongo *MongoStorage) CreateDeployment(deployment deployment.ResourceDeploy
 func (mongo *MongoStorage) UpdateActiveDeployment(upd deployment.ResourceDeploy) error {
 	mongo.logger.Debugf("updating active deployment")
 	var collection = mongo.db.C(CollectionDeployment)
-	err := collection.Update(upd.OneSelectQuery(), upd.UpdateQuery())
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(casSelectQuery(upd.OneSelectQuery(), upd.ResourceVersion), withResourceVersion(upd.UpdateQuery(), version))
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to update deployment")
+		if err == mgo.ErrNotFound {
+			return versionConflict(collection, upd.OneSelectQuery(), upd.Name)
+		}
+	}
+	return PipErr{error: err}.ToMongerr().Extract()
+}
+
+// GetCanaryDeployment returns active canary version of deployment
+func (mongo *MongoStorage) GetCanaryDeployment(namespaceID, deploymentName string) (deployment.ResourceDeploy, error) {
+	mongo.logger.Debugf("getting canary deployment")
+	var collection = mongo.db.C(CollectionDeployment)
+	var depl deployment.ResourceDeploy
+	var err error
+	if err = collection.Find(deployment.ResourceDeploy{
+		Deployment: model.Deployment{
+			Name: deploymentName,
+		},
+		NamespaceID: namespaceID,
+	}.OneCanarySelectQuery()).One(&depl); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get canary deployment")
+		if err == mgo.ErrNotFound {
+			return depl, rserrors.ErrResourceNotExists().AddDetailF("canary of %v", deploymentName)
+		}
+		return depl, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return depl, err
+}
+
+// UpdateCanaryDeployment updates active canary version of deployment
+func (mongo *MongoStorage) UpdateCanaryDeployment(upd deployment.ResourceDeploy) error {
+	mongo.logger.Debugf("updating canary deployment")
+	var collection = mongo.db.C(CollectionDeployment)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(casSelectQuery(upd.OneCanarySelectQuery(), upd.ResourceVersion), withResourceVersion(upd.CanaryUpdateQuery(), version))
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update canary deployment")
+		if err == mgo.ErrNotFound {
+			return versionConflict(collection, upd.OneCanarySelectQuery(), "canary of "+upd.Name)
+		}
 	}
 	return PipErr{error: err}.ToMongerr().Extract()
 }
@@ -127,13 +179,17 @@ func (mongo *MongoStorage) UpdateActiveDeployment(upd deployment.ResourceDeploy)
 func (mongo *MongoStorage) UpdateDeploymentVersion(namespace, name string, oldversion, newversion semver.Version) error {
 	mongo.logger.Debugf("updating deployment version")
 	var collection = mongo.db.C(CollectionDeployment)
-	err := collection.Update(bson.M{
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(bson.M{
 		"namespaceid":        namespace,
 		"deleted":            false,
 		"deployment.name":    name,
 		"deployment.version": oldversion,
 	}, bson.M{
-		"$set": bson.M{"deployment.version": newversion},
+		"$set": bson.M{"resourceversion": resourceVersion, "deployment.version": newversion},
 	})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to update deployment version")
@@ -169,7 +225,11 @@ func (mongo *MongoStorage) DeleteDeployment(namespace, name string) error {
 func (mongo *MongoStorage) ActivateDeployment(namespace, name string, version semver.Version) error {
 	mongo.logger.Debugf("activating deployment")
 	var collection = mongo.db.C(CollectionDeployment)
-	err := collection.Update(deployment.ResourceDeploy{
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(deployment.ResourceDeploy{
 		Deployment: model.Deployment{
 			Name:    name,
 			Version: version,
@@ -177,7 +237,7 @@ func (mongo *MongoStorage) ActivateDeployment(namespace, name string, version se
 		NamespaceID: namespace,
 	}.OneAnyVersionSelectQuery(),
 		bson.M{
-			"$set": bson.M{"deployment.active": true},
+			"$set": bson.M{"resourceversion": resourceVersion, "deployment.active": true},
 		})
 
 	if err != nil {
@@ -193,14 +253,18 @@ func (mongo *MongoStorage) ActivateDeployment(namespace, name string, version se
 func (mongo *MongoStorage) ActivateDeploymentWOVersion(namespace, name string) error {
 	mongo.logger.Debugf("activating deployment without version")
 	var collection = mongo.db.C(CollectionDeployment)
-	err := collection.Update(bson.M{
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(bson.M{
 		"namespaceid":        namespace,
 		"deleted":            false,
 		"deployment.version": nil,
 		"deployment.name":    name,
 	},
 		bson.M{
-			"$set": bson.M{"deployment.active": true},
+			"$set": bson.M{"resourceversion": resourceVersion, "deployment.active": true},
 		})
 
 	if err != nil {
@@ -213,17 +277,24 @@ func (mongo *MongoStorage) ActivateDeploymentWOVersion(namespace, name string) e
 	return nil
 }
 
-func (mongo *MongoStorage) DeactivateDeployment(namespace, name string) error {
+// DeactivateDeployment deactivates active version of deployment if it wasn't changed since it was read.
+// Zero resource version deactivates any active version.
+func (mongo *MongoStorage) DeactivateDeployment(namespace, name string, resourceVersion int64) error {
 	mongo.logger.Debugf("deactivating deployment")
 	var collection = mongo.db.C(CollectionDeployment)
-	_, err := collection.UpdateAll(deployment.ResourceDeploy{
+	newVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	query := deployment.ResourceDeploy{
 		Deployment: model.Deployment{
 			Name: name,
 		},
 		NamespaceID: namespace,
-	}.OneSelectQuery(),
+	}.OneSelectQuery()
+	info, err := collection.UpdateAll(casSelectQuery(query, resourceVersion),
 		bson.M{
-			"$set": bson.M{"deployment.active": false},
+			"$set": bson.M{"deployment.active": false, "resourceversion": newVersion},
 		})
 
 	if err != nil {
@@ -233,6 +304,67 @@ func (mongo *MongoStorage) DeactivateDeployment(namespace, name string) error {
 		}
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
+	if resourceVersion != 0 && info.Matched == 0 {
+		return versionConflict(collection, query, name)
+	}
+	return nil
+}
+
+// SetDeploymentPreviousVersion saves version which was active before provided deployment version
+func (mongo *MongoStorage) SetDeploymentPreviousVersion(namespace, name string, version, previous semver.Version) error {
+	mongo.logger.Debugf("setting deployment previous version")
+	var collection = mongo.db.C(CollectionDeployment)
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(deployment.ResourceDeploy{
+		Deployment: model.Deployment{
+			Name:    name,
+			Version: version,
+		},
+		NamespaceID: namespace,
+	}.OneAnyVersionSelectQuery(),
+		bson.M{
+			"$set": bson.M{"resourceversion": resourceVersion, "previousversion": previous},
+		})
+
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to set deployment previous version")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+// SetDeploymentVersionFailed marks deployment version as failed, failed versions are skipped on rollback
+func (mongo *MongoStorage) SetDeploymentVersionFailed(namespace, name string, version semver.Version, failed bool) error {
+	mongo.logger.Debugf("setting deployment version failed")
+	var collection = mongo.db.C(CollectionDeployment)
+	resourceVersion, err := mongo.nextResourceVersion()
+	if err != nil {
+		return err
+	}
+	err = collection.Update(deployment.ResourceDeploy{
+		Deployment: model.Deployment{
+			Name:    name,
+			Version: version,
+		},
+		NamespaceID: namespace,
+	}.OneAnyVersionSelectQuery(),
+		bson.M{
+			"$set": bson.M{"resourceversion": resourceVersion, "failed": failed},
+		})
+
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to set deployment version failed")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
 	return nil
 }
 
@@ -340,6 +472,7 @@ func (mongo *MongoStorage) CountDeployments(owner string) (int, error) {
 	n, err := collection.Find(bson.M{
 		"deployment.owner":  owner,
 		"deployment.active": true,
+		"canary":            false,
 		"deleted":           false,
 	}).Count()
 	if err != nil {
@@ -353,6 +486,7 @@ func (mongo *MongoStorage) CountAllDeployments() (int, error) {
 	var collection = mongo.db.C(CollectionDeployment)
 	n, err := collection.Find(bson.M{
 		"deployment.active": true,
+		"canary":            false,
 		"deleted":           false,
 	}).Count()
 	if err != nil {
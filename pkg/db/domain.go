@@ -22,15 +22,19 @@ func (mongo *MongoStorage) GetDomain(domainName string) (*domain.Domain, error)
 	return &result, nil
 }
 
-func (mongo *MongoStorage) GetRandomDomain() (*domain.Domain, error) {
-	mongo.logger.Debugf("getting random domain")
+func (mongo *MongoStorage) GetSchedulableDomains(group string) ([]domain.Domain, error) {
+	mongo.logger.Debugf("getting schedulable domains")
 	var collection = mongo.db.C(CollectionDomain)
-	result := domain.Domain{}
-	if err := collection.Pipe([]bson.M{{"$sample": bson.M{"size": 1}}}).One(&result); err != nil {
-		mongo.logger.WithError(err).Errorf("unable to get random domain")
-		return nil, PipErr{error: err}.ToMongerr().Extract()
+	var query = bson.M{"state": bson.M{"$in": []interface{}{nil, "", domain.Active}}}
+	if group != "" {
+		query["domaingroup"] = group
 	}
-	return &result, nil
+	result := make([]domain.Domain, 0)
+	if err := collection.Find(query).Sort("domain").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get schedulable domains")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
 }
 
 // GetDomainsList supports pagination
@@ -79,3 +83,51 @@ func (mongo *MongoStorage) DeleteDomain(domainName string) error {
 	}
 	return nil
 }
+
+func (mongo *MongoStorage) GetDomainPin(kind domain.PinKind, id string) (domain.Pin, error) {
+	mongo.logger.Debugf("getting domain pin")
+	var collection = mongo.db.C(CollectionDomainPin)
+	var result domain.Pin
+	if err := collection.Find(bson.M{"kind": kind, "id": id}).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get domain pin")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("domain pin of %v %v", kind, id)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetDomainPinsList() ([]domain.Pin, error) {
+	mongo.logger.Debugf("getting domain pins list")
+	var collection = mongo.db.C(CollectionDomainPin)
+	result := make([]domain.Pin, 0)
+	if err := collection.Find(nil).Sort("kind", "id").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get domain pins list")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) SetDomainPin(pin domain.Pin) (domain.Pin, error) {
+	mongo.logger.Debugf("setting domain pin")
+	var collection = mongo.db.C(CollectionDomainPin)
+	if _, err := collection.Upsert(bson.M{"kind": pin.Kind, "id": pin.ID}, pin); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to set domain pin")
+		return pin, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return pin, nil
+}
+
+func (mongo *MongoStorage) DeleteDomainPin(kind domain.PinKind, id string) error {
+	mongo.logger.Debugf("deleting domain pin")
+	var collection = mongo.db.C(CollectionDomainPin)
+	if err := collection.Remove(bson.M{"kind": kind, "id": id}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete domain pin")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("domain pin of %v %v", kind, id)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"regexp"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+func hostConflict(claim host.Claim) error {
+	return rserrors.ErrIngressHostConflict().AddDetailF("path '%v' is used by another ingress", claim.Key())
+}
+
+func hostOwnerConflict(claim host.Claim) error {
+	return rserrors.ErrIngressHostConflict().AddDetailF("host '%v' is used by another user", claim.Host)
+}
+
+// subtreeQuery matches hosts which are subdomains of domain
+func subtreeQuery(domain string) bson.M {
+	return bson.M{"$regex": `\.` + regexp.QuoteMeta(domain) + "$"}
+}
+
+func (mongo *MongoStorage) ClaimHost(claim host.Claim) error {
+	mongo.logger.Debugf("claiming host")
+	var collection = mongo.db.C(CollectionIngressHost)
+	claim.CreatedAt = time.Now().UTC()
+	err := collection.Insert(claim)
+	if err == nil {
+		return mongo.checkHostOwner(claim)
+	}
+	if !mgo.IsDup(err) {
+		mongo.logger.WithError(err).Errorf("unable to claim host")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	var existing host.Claim
+	if err := collection.Find(bson.M{"host": claim.Host, "path": claim.Path}).One(&existing); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get host claim")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	if !existing.SameIngress(claim) {
+		mongo.logger.Errorf("host is used by another ingress")
+		return hostConflict(claim)
+	}
+	return nil
+}
+
+// checkHostOwner releases just inserted claim if host has paths of another owner in other ingresses.
+// Claims of different owners inserted concurrently both see each other, so at most one of them is kept.
+func (mongo *MongoStorage) checkHostOwner(claim host.Claim) error {
+	var collection = mongo.db.C(CollectionIngressHost)
+	n, err := collection.Find(bson.M{
+		"host":  claim.Host,
+		"owner": bson.M{"$ne": claim.Owner},
+		"$or": []bson.M{
+			{"namespaceid": bson.M{"$ne": claim.NamespaceID}},
+			{"ingress": bson.M{"$ne": claim.Ingress}},
+		},
+	}).Count()
+	if err == nil && n == 0 {
+		return nil
+	}
+	if releaseErr := mongo.ReleaseHost(claim); releaseErr != nil {
+		mongo.logger.WithError(releaseErr).Errorf("unable to release host")
+	}
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get host claims")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	mongo.logger.Errorf("host is used by another user")
+	return hostOwnerConflict(claim)
+}
+
+func (mongo *MongoStorage) GetHostClaims(hostName string, subtree bool) ([]host.Claim, error) {
+	mongo.logger.Debugf("getting host claims")
+	var collection = mongo.db.C(CollectionIngressHost)
+	var query = bson.M{"host": hostName}
+	if subtree {
+		query["host"] = subtreeQuery(hostName)
+	}
+	result := make([]host.Claim, 0)
+	if err := collection.Find(query).Sort("host", "path").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get host claims")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) ReleaseHost(claim host.Claim) error {
+	mongo.logger.Debugf("releasing host")
+	var collection = mongo.db.C(CollectionIngressHost)
+	if err := collection.Remove(bson.M{
+		"host":        claim.Host,
+		"path":        claim.Path,
+		"namespaceid": claim.NamespaceID,
+		"ingress":     claim.Ingress,
+	}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to release host")
+		return PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ReleaseIngressHosts(namespaceID, ingressName string) error {
+	mongo.logger.Debugf("releasing ingress hosts")
+	return mongo.releaseHosts(bson.M{"namespaceid": namespaceID, "ingress": ingressName})
+}
+
+func (mongo *MongoStorage) releaseHosts(query bson.M) error {
+	var collection = mongo.db.C(CollectionIngressHost)
+	if _, err := collection.RemoveAll(query); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to release hosts")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ReserveHost(res host.Reservation) error {
+	mongo.logger.Debugf("reserving host")
+	var collection = mongo.db.C(CollectionReservation)
+	res.CreatedAt = time.Now().UTC()
+	if err := collection.Insert(res); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to reserve host")
+		if mgo.IsDup(err) {
+			return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved already", res.Host)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetHostReservation(hostName string) (host.Reservation, error) {
+	mongo.logger.Debugf("getting host reservation")
+	var collection = mongo.db.C(CollectionReservation)
+	var result host.Reservation
+	if err := collection.Find(bson.M{"host": hostName}).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get host reservation")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("host reservation %v", hostName)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetHostReservations(owner string) ([]host.Reservation, error) {
+	mongo.logger.Debugf("getting host reservations")
+	var query = bson.M{}
+	if owner != "" {
+		query["owner"] = owner
+	}
+	return mongo.findReservations(query)
+}
+
+func (mongo *MongoStorage) GetCoveringReservations(hostName string) ([]host.Reservation, error) {
+	mongo.logger.Debugf("getting covering host reservations")
+	return mongo.findReservations(bson.M{"host": bson.M{"$in": host.Covering(hostName)}})
+}
+
+func (mongo *MongoStorage) GetReservationsInSubtree(domain string) ([]host.Reservation, error) {
+	mongo.logger.Debugf("getting host reservations in subtree")
+	return mongo.findReservations(bson.M{"host": subtreeQuery(domain)})
+}
+
+func (mongo *MongoStorage) findReservations(query bson.M) ([]host.Reservation, error) {
+	var collection = mongo.db.C(CollectionReservation)
+	result := make([]host.Reservation, 0)
+	if err := collection.Find(query).Sort("host").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get host reservations")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) DeleteHostReservation(hostName string) error {
+	mongo.logger.Debugf("deleting host reservation")
+	var collection = mongo.db.C(CollectionReservation)
+	if err := collection.Remove(bson.M{"host": hostName}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete host reservation")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("host reservation %v", hostName)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+func (mongo *MongoStorage) CreateIdempotencyRecord(rec idempotency.Record) error {
+	mongo.logger.Debugf("creating idempotency record")
+	var collection = mongo.db.C(CollectionIdempotency)
+	// expired records are removed by TTL index with delay, so they are replaced here
+	if _, err := collection.RemoveAll(bson.M{
+		"userid":    rec.UserID,
+		"key":       rec.Key,
+		"expiresat": bson.M{"$lte": rec.CreatedAt},
+	}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to remove expired idempotency record")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	if err := collection.Insert(rec); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create idempotency record")
+		if mgo.IsDup(err) {
+			return rserrors.ErrResourceAlreadyExists().AddDetailF("idempotency key %v", rec.Key)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetIdempotencyRecord(userID, key string) (idempotency.Record, error) {
+	mongo.logger.Debugf("getting idempotency record")
+	var collection = mongo.db.C(CollectionIdempotency)
+	var result idempotency.Record
+	if err := collection.Find(bson.M{"userid": userID, "key": key}).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get idempotency record")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("idempotency key %v", key)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) UpdateIdempotencyRecord(rec idempotency.Record) error {
+	mongo.logger.Debugf("updating idempotency record")
+	var collection = mongo.db.C(CollectionIdempotency)
+	if err := collection.Update(bson.M{"userid": rec.UserID, "key": rec.Key}, rec); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update idempotency record")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("idempotency key %v", rec.Key)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) DeleteIdempotencyRecord(userID, key string) error {
+	mongo.logger.Debugf("deleting idempotency record")
+	var collection = mongo.db.C(CollectionIdempotency)
+	if err := collection.Remove(bson.M{"userid": userID, "key": key}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete idempotency record")
+		return PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return nil
+}
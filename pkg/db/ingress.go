@@ -3,6 +3,7 @@ package db
 import (
 	"time"
 
+	"git.containerum.net/ch/resource-service/pkg/models/host"
 	"git.containerum.net/ch/resource-service/pkg/models/ingress"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"github.com/containerum/kube-client/pkg/model"
@@ -18,6 +19,11 @@ func (mongo *MongoStorage) CreateIngress(ingress ingress.ResourceIngress) (ingre
 		ingress.ID = uuid.New().String()
 	}
 	ingress.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return ingress, err
+	}
+	ingress.ResourceVersion = version
 	if err := collection.Insert(ingress); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to create ingress")
 		if mgo.IsDup(err) {
@@ -60,43 +66,53 @@ func (mongo *MongoStorage) GetIngressByService(namespaceID, serviceName string)
 	return ingr, nil
 }
 
-func (mongo *MongoStorage) GetSelectedIngresses(namespaceID []string) (ingress.ListIngress, error) {
+func (mongo *MongoStorage) GetSelectedIngresses(namespaceID []string, query *ListQuery) (ingress.ListIngress, int, error) {
 	mongo.logger.Debugf("getting selected ingresses")
 	var collection = mongo.db.C(CollectionIngress)
 	list := make(ingress.ListIngress, 0)
-	if err := collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid": bson.M{
 			"$in": namespaceID,
 		},
 		"deleted": false,
-	}).All(&list); err != nil {
+	}, query, &list)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get ingress")
 		if err == mgo.ErrNotFound {
-			return list, rserrors.ErrResourceNotExists()
+			return list, 0, rserrors.ErrResourceNotExists()
 		}
-		return list, PipErr{error: err}.ToMongerr().Extract()
+		return list, 0, PipErr{error: err}.ToMongerr().Extract()
 	}
-	return list, nil
+	return list, total, nil
 }
 
-func (mongo *MongoStorage) GetIngressList(namespaceID string) (ingress.ListIngress, error) {
+func (mongo *MongoStorage) GetIngressList(namespaceID string, query *ListQuery) (ingress.ListIngress, int, error) {
 	mongo.logger.Debugf("getting ingress")
 	var collection = mongo.db.C(CollectionIngress)
 	list := make(ingress.ListIngress, 0)
-	if err := collection.Find(ingress.ListSelectQuery(namespaceID)).All(&list); err != nil {
+	total, err := findList(collection, ingress.ListSelectQuery(namespaceID).(bson.M), query, &list)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get ingress list")
-		return list, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+		return list, 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
 	}
-	return list, nil
+	return list, total, nil
 }
 
 func (mongo *MongoStorage) UpdateIngress(upd ingress.ResourceIngress) (ingress.ResourceIngress, error) {
 	mongo.logger.Debugf("updating ingress")
 	var collection = mongo.db.C(CollectionIngress)
-	if err := collection.Update(upd.OneSelectQuery(), upd.UpdateQuery()); err != nil {
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return upd, err
+	}
+	if err := collection.Update(casSelectQuery(upd.OneSelectQuery(), upd.ResourceVersion), withResourceVersion(upd.UpdateQuery(), version)); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to update ingress")
+		if err == mgo.ErrNotFound {
+			return upd, versionConflict(collection, upd.OneSelectQuery(), upd.Name)
+		}
 		return upd, PipErr{error: err}.ToMongerr().Extract()
 	}
+	upd.ResourceVersion = version
 	return upd, nil
 }
 
@@ -120,24 +136,38 @@ func (mongo *MongoStorage) DeleteIngress(namespaceID, name string) error {
 		}
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return nil
+	return mongo.ReleaseIngressHosts(namespaceID, name)
 }
 
 func (mongo *MongoStorage) RestoreIngress(namespaceID, name string) error {
 	mongo.logger.Debugf("restoring ingress")
 	var collection = mongo.db.C(CollectionIngress)
-	err := collection.Update(ingress.ResourceIngress{
+	var query = ingress.ResourceIngress{
 		Ingress: model.Ingress{
 			Name: name,
 		},
 		NamespaceID: namespaceID,
-	}.OneSelectDeletedQuery(),
+	}.OneSelectDeletedQuery()
+	var deleted ingress.ResourceIngress
+	if err := collection.Find(query).One(&deleted); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to restore ingress")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	// hosts could be claimed by another ingress after deletion
+	if err := mongo.claimIngressHosts(deleted); err != nil {
+		return err
+	}
+	err := collection.Update(bson.M{"_id": deleted.ID},
 		bson.M{
 			"$set": bson.M{"deleted": false,
 				"ingress.deletedat": ""},
 		})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to restore ingress")
+		mongo.ReleaseIngressHosts(namespaceID, name)
 		if err == mgo.ErrNotFound {
 			return rserrors.ErrResourceNotExists().AddDetails(name)
 		}
@@ -146,6 +176,20 @@ func (mongo *MongoStorage) RestoreIngress(namespaceID, name string) error {
 	return nil
 }
 
+// claimIngressHosts claims all hosts and paths of ingress or none of them
+func (mongo *MongoStorage) claimIngressHosts(ingr ingress.ResourceIngress) error {
+	var claims = host.Claims(ingr.NamespaceID, ingr.Ingress)
+	for i, claim := range claims {
+		if err := mongo.ClaimHost(claim); err != nil {
+			for _, claimed := range claims[:i] {
+				mongo.ReleaseHost(claimed)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func (mongo *MongoStorage) DeleteAllIngressesInNamespace(namespace string) error {
 	mongo.logger.Debugf("deleting all ingresses in namespace")
 	var collection = mongo.db.C(CollectionIngress)
@@ -158,8 +202,9 @@ func (mongo *MongoStorage) DeleteAllIngressesInNamespace(namespace string) error
 		})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to delete deployment")
+		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return PipErr{error: err}.ToMongerr().Extract()
+	return mongo.releaseHosts(bson.M{"namespaceid": namespace})
 }
 
 func (mongo *MongoStorage) DeleteAllIngressesByOwner(owner string) error {
@@ -174,8 +219,9 @@ func (mongo *MongoStorage) DeleteAllIngressesByOwner(owner string) error {
 		})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to delete deployments")
+		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return PipErr{error: err}.ToMongerr().Extract()
+	return mongo.releaseHosts(bson.M{"owner": owner})
 }
 
 func (mongo *MongoStorage) CountIngresses(owner string) (int, error) {
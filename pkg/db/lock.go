@@ -0,0 +1,48 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// lock -- named lock document, expired lock can be taken by any holder
+type lock struct {
+	Name      string    `bson:"_id"`
+	Holder    string    `bson:"holder"`
+	ExpiresAt time.Time `bson:"expiresat"`
+}
+
+func (mongo *MongoStorage) AcquireLock(name, holder string, expiresAt time.Time) error {
+	mongo.logger.Debugf("acquiring lock")
+	var collection = mongo.db.C(CollectionLock)
+	// lock held by another holder doesn't match query, so upsert fails on unique _id
+	_, err := collection.Upsert(bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"holder": holder},
+			{"expiresat": bson.M{"$lt": time.Now().UTC()}},
+		},
+	}, lock{Name: name, Holder: holder, ExpiresAt: expiresAt.UTC()})
+	if err != nil {
+		if mgo.IsDup(err) {
+			mongo.logger.Errorf("lock is held by another holder")
+			return rserrors.ErrResourceAlreadyExists().AddDetailF("lock %v is held by another holder", name)
+		}
+		mongo.logger.WithError(err).Errorf("unable to acquire lock")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ReleaseLock(name, holder string) error {
+	mongo.logger.Debugf("releasing lock")
+	var collection = mongo.db.C(CollectionLock)
+	if err := collection.Remove(bson.M{"_id": name, "holder": holder}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to release lock")
+		return PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/sirupsen/logrus"
+
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+)
+
+// MemoryStorage is an in-memory Storage implementation.
+// It mimics MongoStorage behaviour (soft delete, active versions, unique indexes and errors)
+// and is intended for tests and development.
+type MemoryStorage struct {
+	logger logrus.FieldLogger
+	mu     sync.RWMutex
+	closed bool
+
+	deployments []deployment.ResourceDeploy
+	services    []service.ResourceService
+	ingresses   []ingress.ResourceIngress
+	configmaps  []configmap.ResourceConfigMap
+	secrets     []secret.ResourceSecret
+	domains     []domain.Domain
+	domainPins  []domain.Pin
+	operations  []outbox.Operation
+	audit       []audit.Entry
+	webhooks    []webhook.Webhook
+	deliveries  []webhook.Delivery
+	idempotency []idempotency.Record
+	ports       []port.Allocation
+	hosts       []host.Claim
+	reserved    []host.Reservation
+	certs       []ingress.Certificate
+	locks       map[string]lock
+
+	resourceVersion int64
+}
+
+func NewMemory(logger logrus.FieldLogger) *MemoryStorage {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &MemoryStorage{
+		logger: logger.WithField("app", "resource-service"),
+	}
+}
+
+func (mem *MemoryStorage) Init() error {
+	return nil
+}
+
+func (mem *MemoryStorage) Close() error {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if mem.closed {
+		return fmt.Errorf("memory storage already closed")
+	}
+	mem.closed = true
+	return nil
+}
+
+func (mem *MemoryStorage) IsClosed() bool {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return mem.closed
+}
+
+// clone makes deep copy of src into dst using bson encoding, so stored objects look exactly like objects read from mongo
+func clone(src, dst interface{}) {
+	data, err := bson.Marshal(src)
+	if err != nil {
+		panic(err)
+	}
+	if err := bson.Unmarshal(data, dst); err != nil {
+		panic(err)
+	}
+}
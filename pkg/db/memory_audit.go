@@ -0,0 +1,39 @@
+package db
+
+import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+)
+
+func cloneAuditEntry(entry audit.Entry) audit.Entry {
+	var cp audit.Entry
+	clone(entry, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) CreateAuditEntry(entry audit.Entry) error {
+	mem.logger.Debugf("creating audit entry")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.audit = append(mem.audit, cloneAuditEntry(entry))
+	return nil
+}
+
+func (mem *MemoryStorage) GetAuditEntries(query audit.Query, list *ListQuery) ([]audit.Entry, int, error) {
+	mem.logger.Debugf("getting audit entries")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var entries = make([]audit.Entry, 0)
+	// entries are appended in time order, so entries written in the same millisecond stay newest first
+	for i := len(mem.audit) - 1; i >= 0; i-- {
+		if query.Match(mem.audit[i]) {
+			entries = append(entries, cloneAuditEntry(mem.audit[i]))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	total := applyListQuery(&entries, list)
+	return entries, total, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func (mem *MemoryStorage) findCertificate(namespaceID, ingressName string) int {
+	for i, cert := range mem.certs {
+		if cert.NamespaceID == namespaceID && cert.Ingress == ingressName {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneCertificate(cert ingress.Certificate) ingress.Certificate {
+	cert.Hosts = append([]string(nil), cert.Hosts...)
+	return cert
+}
+
+func (mem *MemoryStorage) SetCertificate(cert ingress.Certificate) error {
+	mem.logger.Debugf("setting certificate")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if i := mem.findCertificate(cert.NamespaceID, cert.Ingress); i >= 0 {
+		mem.certs[i] = cloneCertificate(cert)
+		return nil
+	}
+	mem.certs = append(mem.certs, cloneCertificate(cert))
+	return nil
+}
+
+func (mem *MemoryStorage) GetCertificate(namespaceID, ingressName string) (ingress.Certificate, error) {
+	mem.logger.Debugf("getting certificate")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	i := mem.findCertificate(namespaceID, ingressName)
+	if i < 0 {
+		mem.logger.Errorf("unable to get certificate")
+		return ingress.Certificate{}, rserrors.ErrResourceNotExists().AddDetailF("certificate of ingress %v", ingressName)
+	}
+	return cloneCertificate(mem.certs[i]), nil
+}
+
+func (mem *MemoryStorage) GetCertificatesExpiringBefore(t time.Time) ([]ingress.Certificate, error) {
+	mem.logger.Debugf("getting expiring certificates")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make([]ingress.Certificate, 0)
+	for _, cert := range mem.certs {
+		if cert.NotAfter.Before(t) {
+			result = append(result, cloneCertificate(cert))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NotAfter.Before(result[j].NotAfter)
+	})
+	return result, nil
+}
+
+func (mem *MemoryStorage) DeleteCertificate(namespaceID, ingressName string) error {
+	mem.logger.Debugf("deleting certificate")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if i := mem.findCertificate(namespaceID, ingressName); i >= 0 {
+		mem.certs = append(mem.certs[:i], mem.certs[i+1:]...)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/strset"
+	"github.com/blang/semver"
+	"github.com/google/uuid"
+)
+
+func cloneConfigMap(cm configmap.ResourceConfigMap) configmap.ResourceConfigMap {
+	var cp configmap.ResourceConfigMap
+	clone(cm, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findConfigMaps(pred func(configmap.ResourceConfigMap) bool) []int {
+	var found []int
+	for i, cm := range mem.configmaps {
+		if pred(cm) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func (mem *MemoryStorage) listConfigMaps(pred func(configmap.ResourceConfigMap) bool) configmap.ListConfigMaps {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	list := make(configmap.ListConfigMaps, 0)
+	for _, cm := range mem.configmaps {
+		if !cm.Deleted && cm.Active && pred(cm) {
+			list = append(list, cloneConfigMap(cm))
+		}
+	}
+	return list
+}
+
+func (mem *MemoryStorage) GetConfigMap(namespaceID, cmName string) (configmap.ResourceConfigMap, error) {
+	mem.logger.Debugf("getting configmap")
+	var list = mem.listConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && cm.Name == cmName
+	})
+	if len(list) == 0 {
+		mem.logger.Errorf("unable to get configmap")
+		return configmap.ResourceConfigMap{}, rserrors.ErrResourceNotExists().AddDetails(cmName)
+	}
+	return list[0], nil
+}
+
+func (mem *MemoryStorage) GetConfigMapVersion(namespaceID, cmName string, version semver.Version) (configmap.ResourceConfigMap, error) {
+	mem.logger.Debugf("getting configmap version")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && cm.Name == cmName && cm.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to get configmap version")
+		return configmap.ResourceConfigMap{}, rserrors.ErrResourceNotExists().AddDetailF("%v %v", cmName, version.String())
+	}
+	return cloneConfigMap(mem.configmaps[found[0]]), nil
+}
+
+func (mem *MemoryStorage) GetConfigMapVersionsList(namespaceID, cmName string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	mem.logger.Debugf("getting configmap versions list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	list := make(configmap.ListConfigMaps, 0)
+	for _, i := range mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && cm.Name == cmName
+	}) {
+		list = append(list, cloneConfigMap(mem.configmaps[i]))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Version.GT(list[j].Version)
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+func (mem *MemoryStorage) GetConfigMapList(namespaceID string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	mem.logger.Debugf("getting configmaps list")
+	list := mem.listConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+func (mem *MemoryStorage) GetSelectedConfigMaps(namespaceID []string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	mem.logger.Debugf("getting selected configmaps")
+	var namespaces = strset.FromSlice(namespaceID)
+	list := mem.listConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return namespaces.In(cm.NamespaceID)
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+// If ID is empty, then generates UUID4 and uses it
+func (mem *MemoryStorage) CreateConfigMap(cm configmap.ResourceConfigMap) (configmap.ResourceConfigMap, error) {
+	mem.logger.Debugf("creating configmap")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if cm.ID == "" {
+		cm.ID = uuid.New().String()
+	}
+	cm.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	for _, existing := range mem.configmaps {
+		// "alive_configmap" index is not namespaced
+		if existing.ID == cm.ID || (!existing.Deleted && existing.Active && cm.Active && existing.Name == cm.Name) {
+			mem.logger.Errorf("unable to create configmap")
+			return cm, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	cm.ResourceVersion = mem.nextResourceVersion()
+	mem.configmaps = append(mem.configmaps, cloneConfigMap(cm))
+	return cm, nil
+}
+
+func (mem *MemoryStorage) DeleteConfigMap(namespaceID, name string) error {
+	mem.logger.Debugf("deleting configmap")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && cm.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete configmap")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	for _, i := range found {
+		mem.configmaps[i].Deleted = true
+		mem.configmaps[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) ActivateConfigMap(namespaceID, name string, version semver.Version) error {
+	mem.logger.Debugf("activating configmap")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && cm.Name == name && cm.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to activate configmap")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.configmaps[found[0]].Active = true
+	mem.configmaps[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) DeactivateConfigMap(namespaceID, name string, resourceVersion int64) error {
+	mem.logger.Debugf("deactivating configmap")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && cm.Active && cm.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to deactivate configmap")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	if err := checkResourceVersion(mem.configmaps[found[0]].ResourceVersion, resourceVersion, name); err != nil {
+		return err
+	}
+	mem.configmaps[found[0]].Active = false
+	mem.configmaps[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+// DeleteConfigMapVersion deletes inactive configmap version
+func (mem *MemoryStorage) DeleteConfigMapVersion(namespaceID, name string, version semver.Version) error {
+	mem.logger.Debugf("deleting configmap version")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted && !cm.Active && cm.Name == name && cm.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete configmap version")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.configmaps[found[0]].Deleted = true
+	mem.configmaps[found[0]].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (mem *MemoryStorage) deleteConfigMaps(pred func(configmap.ResourceConfigMap) bool) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findConfigMaps(pred) {
+		mem.configmaps[i].Deleted = true
+		mem.configmaps[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (mem *MemoryStorage) DeleteAllConfigMapsInNamespace(namespaceID string) error {
+	mem.logger.Debugf("deleting all configmaps in namespace")
+	mem.deleteConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && !cm.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllConfigMapsByOwner(owner string) error {
+	mem.logger.Debugf("deleting all configmaps in namespace")
+	mem.deleteConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.Owner == owner && !cm.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) RestoreConfigMap(namespaceID, name string) error {
+	mem.logger.Debugf("restoring configmap")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && cm.Deleted && cm.Active && cm.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to restore configmap")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	var last = found[0]
+	for _, i := range found {
+		if mem.configmaps[i].DeletedAt > mem.configmaps[last].DeletedAt {
+			last = i
+		}
+	}
+	var deletedAt = mem.configmaps[last].DeletedAt
+	for _, i := range mem.findConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.NamespaceID == namespaceID && cm.Deleted && cm.Name == name && cm.DeletedAt == deletedAt
+	}) {
+		mem.configmaps[i].Deleted = false
+		mem.configmaps[i].DeletedAt = ""
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) CountConfigMaps(owner string) (int, error) {
+	mem.logger.Debugf("counting configmaps")
+	return len(mem.listConfigMaps(func(cm configmap.ResourceConfigMap) bool {
+		return cm.Owner == owner
+	})), nil
+}
+
+func (mem *MemoryStorage) CountAllConfigMaps() (int, error) {
+	mem.logger.Debugf("counting all configmaps")
+	return len(mem.listConfigMaps(func(configmap.ResourceConfigMap) bool {
+		return true
+	})), nil
+}
@@ -0,0 +1,426 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/blang/semver"
+	"github.com/google/uuid"
+)
+
+func cloneDeploy(depl deployment.ResourceDeploy) deployment.ResourceDeploy {
+	var cp deployment.ResourceDeploy
+	clone(depl, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findDeployments(pred func(deployment.ResourceDeploy) bool) []int {
+	var found []int
+	for i, depl := range mem.deployments {
+		if pred(depl) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func (mem *MemoryStorage) GetDeployment(namespaceID, deploymentName string) (deployment.ResourceDeploy, error) {
+	mem.logger.Debugf("getting deployment by name")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, depl := range mem.deployments {
+		if depl.NamespaceID == namespaceID && !depl.Deleted && depl.Active && !depl.Canary && depl.Name == deploymentName {
+			return cloneDeploy(depl), nil
+		}
+	}
+	return deployment.ResourceDeploy{}, rserrors.ErrResourceNotExists().AddDetails(deploymentName)
+}
+
+// GetCanaryDeployment returns active canary version of deployment
+func (mem *MemoryStorage) GetCanaryDeployment(namespaceID, deploymentName string) (deployment.ResourceDeploy, error) {
+	mem.logger.Debugf("getting canary deployment")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, depl := range mem.deployments {
+		if depl.NamespaceID == namespaceID && !depl.Deleted && depl.Active && depl.Canary && depl.Name == deploymentName {
+			return cloneDeploy(depl), nil
+		}
+	}
+	return deployment.ResourceDeploy{}, rserrors.ErrResourceNotExists().AddDetailF("canary of %v", deploymentName)
+}
+
+func (mem *MemoryStorage) GetDeploymentVersion(namespaceID, deploymentName string, version semver.Version) (deployment.ResourceDeploy, error) {
+	mem.logger.Debugf("getting deployment version by name")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, depl := range mem.deployments {
+		if depl.NamespaceID == namespaceID && !depl.Deleted && depl.Name == deploymentName && depl.Version.EQ(version) {
+			return cloneDeploy(depl), nil
+		}
+	}
+	return deployment.ResourceDeploy{}, rserrors.ErrResourceNotExists().AddDetailF("%v %v", deploymentName, version.String())
+}
+
+func (mem *MemoryStorage) GetDeploymentLatestVersion(namespaceID, deploymentName string) (deployment.ResourceDeploy, error) {
+	mem.logger.Debugf("getting deployment latest version")
+	var versions, _, _ = mem.GetDeploymentVersionsList(namespaceID, deploymentName, nil)
+	if versions.Len() == 0 {
+		return deployment.ResourceDeploy{}, rserrors.ErrResourceNotExists().AddDetails(deploymentName)
+	}
+	return versions[0], nil
+}
+
+func (mem *MemoryStorage) GetDeploymentVersionsList(namespaceID, deploymentName string, query *ListQuery) (deployment.ListDeploy, int, error) {
+	mem.logger.Debugf("getting deployment versions list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	depl := make(deployment.ListDeploy, 0)
+	for _, d := range mem.deployments {
+		if d.NamespaceID == namespaceID && !d.Deleted && d.Name == deploymentName {
+			depl = append(depl, cloneDeploy(d))
+		}
+	}
+	sort.SliceStable(depl, func(i, j int) bool {
+		return depl[i].Version.GT(depl[j].Version)
+	})
+	total := applyListQuery(&depl, query)
+	return depl, total, nil
+}
+
+func (mem *MemoryStorage) GetDeploymentList(namespaceID string, query *ListQuery) (deployment.ListDeploy, int, error) {
+	mem.logger.Debugf("getting deployments list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	depl := make(deployment.ListDeploy, 0)
+	for _, d := range mem.deployments {
+		if d.NamespaceID == namespaceID && !d.Deleted && d.Active && !d.Canary {
+			depl = append(depl, cloneDeploy(d))
+		}
+	}
+	total := applyListQuery(&depl, query)
+	return depl, total, nil
+}
+
+// If ID is empty when use UUID4 to generate one
+func (mem *MemoryStorage) CreateDeployment(deployment deployment.ResourceDeploy) (deployment.ResourceDeploy, error) {
+	mem.logger.Debugf("creating deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if deployment.ID == "" {
+		deployment.ID = uuid.New().String()
+	}
+	deployment.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	for _, depl := range mem.deployments {
+		var dup = depl.ID == deployment.ID
+		if depl.NamespaceID == deployment.NamespaceID && !depl.Deleted && depl.Name == deployment.Name {
+			// "alive_deployment" and "unique_version_deployment" indexes
+			dup = dup || (depl.Active && deployment.Active && depl.Canary == deployment.Canary) || depl.Version.EQ(deployment.Version)
+		}
+		if dup {
+			mem.logger.Errorf("unable to create deployment")
+			return deployment, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	deployment.ResourceVersion = mem.nextResourceVersion()
+	mem.deployments = append(mem.deployments, cloneDeploy(deployment))
+	return deployment, nil
+}
+
+func (mem *MemoryStorage) UpdateActiveDeployment(upd deployment.ResourceDeploy) error {
+	mem.logger.Debugf("updating active deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == upd.NamespaceID && !depl.Deleted && depl.Active && !depl.Canary && depl.Name == upd.Name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update deployment")
+		return rserrors.ErrResourceNotExists().AddDetails(upd.Name)
+	}
+	if err := checkResourceVersion(mem.deployments[found[0]].ResourceVersion, upd.ResourceVersion, upd.Name); err != nil {
+		mem.logger.WithError(err).Errorf("unable to update deployment")
+		return err
+	}
+	var updated = cloneDeploy(upd)
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	mem.deployments[found[0]].Deployment = updated.Deployment
+	mem.deployments[found[0]].Strategy = updated.Strategy
+	mem.deployments[found[0]].ReloadOnConfigChange = updated.ReloadOnConfigChange
+	mem.deployments[found[0]].ConfigHashes = updated.ConfigHashes
+	return nil
+}
+
+// UpdateCanaryDeployment updates active canary version of deployment
+func (mem *MemoryStorage) UpdateCanaryDeployment(upd deployment.ResourceDeploy) error {
+	mem.logger.Debugf("updating canary deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == upd.NamespaceID && !depl.Deleted && depl.Active && depl.Canary && depl.Name == upd.Name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update canary deployment")
+		return rserrors.ErrResourceNotExists().AddDetailF("canary of %v", upd.Name)
+	}
+	if err := checkResourceVersion(mem.deployments[found[0]].ResourceVersion, upd.ResourceVersion, "canary of "+upd.Name); err != nil {
+		mem.logger.WithError(err).Errorf("unable to update canary deployment")
+		return err
+	}
+	var updated = cloneDeploy(upd)
+	if !updated.Canary && updated.Active {
+		// "alive_deployment" index
+		for _, i := range mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+			return depl.NamespaceID == upd.NamespaceID && !depl.Deleted && depl.Active && !depl.Canary && depl.Name == upd.Name
+		}) {
+			if i != found[0] {
+				mem.logger.Errorf("unable to update canary deployment")
+				return rserrors.ErrResourceAlreadyExists()
+			}
+		}
+	}
+	mem.deployments[found[0]].Deployment = updated.Deployment
+	mem.deployments[found[0]].Strategy = updated.Strategy
+	mem.deployments[found[0]].ReloadOnConfigChange = updated.ReloadOnConfigChange
+	mem.deployments[found[0]].ConfigHashes = updated.ConfigHashes
+	mem.deployments[found[0]].Canary = updated.Canary
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) UpdateDeploymentVersion(namespace, name string, oldversion, newversion semver.Version) error {
+	mem.logger.Debugf("updating deployment version")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(oldversion)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update deployment version")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, oldversion.String())
+	}
+	var dups = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(newversion)
+	})
+	if len(dups) > 0 && dups[0] != found[0] {
+		mem.logger.Errorf("unable to update deployment version")
+		return rserrors.ErrResourceAlreadyExists()
+	}
+	mem.deployments[found[0]].Version = newversion
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteDeployment(namespace, name string) error {
+	mem.logger.Debugf("deleting deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name
+	}) {
+		mem.deployments[i].Deleted = true
+		mem.deployments[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) ActivateDeployment(namespace, name string, version semver.Version) error {
+	mem.logger.Debugf("activating deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to activate deployment")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.deployments[found[0]].Active = true
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) ActivateDeploymentWOVersion(namespace, name string) error {
+	mem.logger.Debugf("activating deployment without version")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(semver.Version{})
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to activate deployment w/o version")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v", name)
+	}
+	mem.deployments[found[0]].Active = true
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) DeactivateDeployment(namespace, name string, resourceVersion int64) error {
+	mem.logger.Debugf("deactivating deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Active && !depl.Canary && depl.Name == name
+	})
+	if resourceVersion != 0 {
+		if len(found) == 0 {
+			return rserrors.ErrResourceNotExists().AddDetails(name)
+		}
+		if err := checkResourceVersion(mem.deployments[found[0]].ResourceVersion, resourceVersion, name); err != nil {
+			return err
+		}
+	}
+	for _, i := range found {
+		mem.deployments[i].Active = false
+		mem.deployments[i].ResourceVersion = mem.nextResourceVersion()
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) SetDeploymentPreviousVersion(namespace, name string, version, previous semver.Version) error {
+	mem.logger.Debugf("setting deployment previous version")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to set deployment previous version")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.deployments[found[0]].PreviousVersion = &previous
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) SetDeploymentVersionFailed(namespace, name string, version semver.Version, failed bool) error {
+	mem.logger.Debugf("setting deployment version failed")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && depl.Name == name && depl.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to set deployment version failed")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.deployments[found[0]].Failed = failed
+	mem.deployments[found[0]].ResourceVersion = mem.nextResourceVersion()
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteDeploymentVersion(namespace, name string, version semver.Version) error {
+	mem.logger.Debugf("deleting deployment version")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted && !depl.Active && depl.Name == name && depl.Version.EQ(version)
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete deployment version")
+		return rserrors.ErrResourceNotExists().AddDetailF("%v %v", name, version.String())
+	}
+	mem.deployments[found[0]].Deleted = true
+	mem.deployments[found[0]].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (mem *MemoryStorage) RestoreDeployment(namespace, name string) error {
+	mem.logger.Debugf("restoring deployment")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && depl.Deleted && depl.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to restore deployment")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	var last = found[len(found)-1]
+	mem.deployments[last].Deleted = false
+	mem.deployments[last].DeletedAt = ""
+	return nil
+}
+
+func (mem *MemoryStorage) deleteDeployments(pred func(deployment.ResourceDeploy) bool) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findDeployments(pred) {
+		mem.deployments[i].Deleted = true
+		mem.deployments[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (mem *MemoryStorage) DeleteAllDeploymentsInNamespace(namespace string) error {
+	mem.logger.Debugf("deleting all deployments in namespace")
+	mem.deleteDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == namespace && !depl.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllDeploymentsByOwner(owner string) error {
+	mem.logger.Debugf("deleting all user deployments")
+	mem.deleteDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.Owner == owner && !depl.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllDeploymentsBySolutionName(nsID, solution string) error {
+	mem.logger.Debugf("deleting all solution deployments")
+	mem.deleteDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.NamespaceID == nsID && depl.SolutionID == solution
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) activeDeployments(pred func(deployment.ResourceDeploy) bool) deployment.ListDeploy {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var list deployment.ListDeploy
+	for _, depl := range mem.deployments {
+		if !depl.Deleted && depl.Active && pred(depl) {
+			list = append(list, depl)
+		}
+	}
+	return list
+}
+
+func (mem *MemoryStorage) CountDeployments(owner string) (int, error) {
+	mem.logger.Debugf("counting user deployment")
+	return mem.activeDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.Owner == owner && !depl.Canary
+	}).Len(), nil
+}
+
+func (mem *MemoryStorage) CountAllDeployments() (int, error) {
+	mem.logger.Debugf("counting user deployment")
+	return mem.activeDeployments(func(depl deployment.ResourceDeploy) bool {
+		return !depl.Canary
+	}).Len(), nil
+}
+
+func (mem *MemoryStorage) CountReplicas(owner string) (int, error) {
+	mem.logger.Debugf("counting deployments replicas")
+	var count int
+	for _, depl := range mem.activeDeployments(func(depl deployment.ResourceDeploy) bool {
+		return depl.Owner == owner
+	}) {
+		count += depl.Replicas
+	}
+	return count, nil
+}
+
+func (mem *MemoryStorage) CountAllReplicas() (int, error) {
+	mem.logger.Debugf("counting deployments replicas")
+	var count int
+	for _, depl := range mem.activeDeployments(func(deployment.ResourceDeploy) bool {
+		return true
+	}) {
+		count += depl.Replicas
+	}
+	return count, nil
+}
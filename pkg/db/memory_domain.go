@@ -0,0 +1,182 @@
+package db
+
+import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/google/uuid"
+)
+
+func cloneDomain(dom domain.Domain) domain.Domain {
+	var cp domain.Domain
+	clone(dom, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findDomain(domainName string) int {
+	for i, dom := range mem.domains {
+		if dom.Domain == domainName {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mem *MemoryStorage) GetDomain(domainName string) (*domain.Domain, error) {
+	mem.logger.Debugf("getting domain")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var i = mem.findDomain(domainName)
+	if i < 0 {
+		mem.logger.Errorf("unable to get domain")
+		return &domain.Domain{}, rserrors.ErrResourceNotExists().AddDetails(domainName)
+	}
+	var result = cloneDomain(mem.domains[i])
+	return &result, nil
+}
+
+func (mem *MemoryStorage) GetSchedulableDomains(group string) ([]domain.Domain, error) {
+	mem.logger.Debugf("getting schedulable domains")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make([]domain.Domain, 0)
+	for _, dom := range mem.domains {
+		if dom.Schedulable() && (group == "" || dom.DomainGroup == group) {
+			result = append(result, cloneDomain(dom))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Domain < result[j].Domain
+	})
+	return result, nil
+}
+
+// GetDomainsList supports pagination
+func (mem *MemoryStorage) GetDomainsList(pages *PageInfo) ([]domain.Domain, error) {
+	mem.logger.Debugf("getting domain list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var domains = mem.domains
+	if pages != nil {
+		var limit, offset = pages.Init()
+		if offset > len(domains) {
+			offset = len(domains)
+		}
+		domains = domains[offset:]
+		if limit < len(domains) {
+			domains = domains[:limit]
+		}
+	}
+	result := make(domain.ListDomain, 0, len(domains))
+	for _, dom := range domains {
+		result = append(result, cloneDomain(dom))
+	}
+	return result, nil
+}
+
+func (mem *MemoryStorage) CreateDomain(domain domain.Domain) (*domain.Domain, error) {
+	mem.logger.Debugf("creating domain")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if domain.ID == "" {
+		domain.ID = uuid.New().String()
+	}
+	for _, dom := range mem.domains {
+		if dom.ID == domain.ID || dom.Domain == domain.Domain {
+			mem.logger.Errorf("unable to create domain")
+			return nil, rserrors.ErrResourceAlreadyExists().AddDetails(domain.Domain)
+		}
+	}
+	mem.domains = append(mem.domains, cloneDomain(domain))
+	return &domain, nil
+}
+
+func (mem *MemoryStorage) UpdateDomain(domain domain.Domain) (*domain.Domain, error) {
+	mem.logger.Debugf("updating domain")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findDomain(domain.Domain)
+	if i < 0 {
+		mem.logger.WithError(mgo.ErrNotFound).Errorf("unable to update domain")
+		return nil, mgo.ErrNotFound
+	}
+	var updated = cloneDomain(domain)
+	updated.ID = mem.domains[i].ID
+	mem.domains[i] = updated
+	return &domain, nil
+}
+
+func (mem *MemoryStorage) DeleteDomain(domainName string) error {
+	mem.logger.Debugf("deleting domain")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findDomain(domainName)
+	if i < 0 {
+		mem.logger.WithError(mgo.ErrNotFound).Errorf("unable to delete domain")
+		return mgo.ErrNotFound
+	}
+	mem.domains = append(mem.domains[:i], mem.domains[i+1:]...)
+	return nil
+}
+
+func (mem *MemoryStorage) findDomainPin(kind domain.PinKind, id string) int {
+	for i, pin := range mem.domainPins {
+		if pin.Kind == kind && pin.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mem *MemoryStorage) GetDomainPin(kind domain.PinKind, id string) (domain.Pin, error) {
+	mem.logger.Debugf("getting domain pin")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var i = mem.findDomainPin(kind, id)
+	if i < 0 {
+		mem.logger.Errorf("unable to get domain pin")
+		return domain.Pin{}, rserrors.ErrResourceNotExists().AddDetailF("domain pin of %v %v", kind, id)
+	}
+	return mem.domainPins[i], nil
+}
+
+func (mem *MemoryStorage) GetDomainPinsList() ([]domain.Pin, error) {
+	mem.logger.Debugf("getting domain pins list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := append(make([]domain.Pin, 0, len(mem.domainPins)), mem.domainPins...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (mem *MemoryStorage) SetDomainPin(pin domain.Pin) (domain.Pin, error) {
+	mem.logger.Debugf("setting domain pin")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if i := mem.findDomainPin(pin.Kind, pin.ID); i >= 0 {
+		mem.domainPins[i] = pin
+		return pin, nil
+	}
+	mem.domainPins = append(mem.domainPins, pin)
+	return pin, nil
+}
+
+func (mem *MemoryStorage) DeleteDomainPin(kind domain.PinKind, id string) error {
+	mem.logger.Debugf("deleting domain pin")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findDomainPin(kind, id)
+	if i < 0 {
+		mem.logger.Errorf("unable to delete domain pin")
+		return rserrors.ErrResourceNotExists().AddDetailF("domain pin of %v %v", kind, id)
+	}
+	mem.domainPins = append(mem.domainPins[:i], mem.domainPins[i+1:]...)
+	return nil
+}
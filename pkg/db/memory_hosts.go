@@ -0,0 +1,179 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func (mem *MemoryStorage) findHost(claim host.Claim) int {
+	for i, existing := range mem.hosts {
+		if existing.Host == claim.Host && existing.Path == claim.Path {
+			return i
+		}
+	}
+	return -1
+}
+
+// releaseHosts removes claims matching predicate, must be called with write lock
+func (mem *MemoryStorage) releaseHosts(pred func(host.Claim) bool) {
+	var kept = mem.hosts[:0]
+	for _, claim := range mem.hosts {
+		if !pred(claim) {
+			kept = append(kept, claim)
+		}
+	}
+	mem.hosts = kept
+}
+
+func (mem *MemoryStorage) releaseIngressHosts(namespaceID, ingressName string) {
+	mem.releaseHosts(func(claim host.Claim) bool {
+		return claim.NamespaceID == namespaceID && claim.Ingress == ingressName
+	})
+}
+
+// claimHost checks unique index on host and path and owner of host, must be called with write lock
+func (mem *MemoryStorage) claimHost(claim host.Claim) error {
+	if i := mem.findHost(claim); i >= 0 {
+		if mem.hosts[i].SameIngress(claim) {
+			return nil
+		}
+		return hostConflict(claim)
+	}
+	for _, existing := range mem.hosts {
+		if existing.Host == claim.Host && existing.Owner != claim.Owner && !existing.SameIngress(claim) {
+			return hostOwnerConflict(claim)
+		}
+	}
+	claim.CreatedAt = time.Now().UTC()
+	mem.hosts = append(mem.hosts, claim)
+	return nil
+}
+
+func (mem *MemoryStorage) ClaimHost(claim host.Claim) error {
+	mem.logger.Debugf("claiming host")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if err := mem.claimHost(claim); err != nil {
+		mem.logger.WithError(err).Errorf("unable to claim host")
+		return err
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) GetHostClaims(hostName string, subtree bool) ([]host.Claim, error) {
+	mem.logger.Debugf("getting host claims")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make([]host.Claim, 0)
+	for _, claim := range mem.hosts {
+		if (!subtree && claim.Host == hostName) || (subtree && host.InSubtree(claim.Host, hostName)) {
+			result = append(result, claim)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Host != result[j].Host {
+			return result[i].Host < result[j].Host
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+func (mem *MemoryStorage) ReleaseHost(claim host.Claim) error {
+	mem.logger.Debugf("releasing host")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.releaseHosts(func(existing host.Claim) bool {
+		return existing.Host == claim.Host && existing.Path == claim.Path && existing.SameIngress(claim)
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) ReleaseIngressHosts(namespaceID, ingressName string) error {
+	mem.logger.Debugf("releasing ingress hosts")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.releaseIngressHosts(namespaceID, ingressName)
+	return nil
+}
+
+func (mem *MemoryStorage) ReserveHost(res host.Reservation) error {
+	mem.logger.Debugf("reserving host")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, existing := range mem.reserved {
+		if existing.Host == res.Host {
+			mem.logger.Errorf("unable to reserve host")
+			return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved already", res.Host)
+		}
+	}
+	res.CreatedAt = time.Now().UTC()
+	mem.reserved = append(mem.reserved, res)
+	return nil
+}
+
+func (mem *MemoryStorage) GetHostReservation(hostName string) (host.Reservation, error) {
+	mem.logger.Debugf("getting host reservation")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, res := range mem.reserved {
+		if res.Host == hostName {
+			return res, nil
+		}
+	}
+	mem.logger.Errorf("unable to get host reservation")
+	return host.Reservation{}, rserrors.ErrResourceNotExists().AddDetailF("host reservation %v", hostName)
+}
+
+func (mem *MemoryStorage) listReservations(pred func(host.Reservation) bool) []host.Reservation {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make([]host.Reservation, 0)
+	for _, res := range mem.reserved {
+		if pred(res) {
+			result = append(result, res)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Host < result[j].Host
+	})
+	return result
+}
+
+func (mem *MemoryStorage) GetHostReservations(owner string) ([]host.Reservation, error) {
+	mem.logger.Debugf("getting host reservations")
+	return mem.listReservations(func(res host.Reservation) bool {
+		return owner == "" || res.Owner == owner
+	}), nil
+}
+
+func (mem *MemoryStorage) GetCoveringReservations(hostName string) ([]host.Reservation, error) {
+	mem.logger.Debugf("getting covering host reservations")
+	return mem.listReservations(func(res host.Reservation) bool {
+		return res.Covers(hostName)
+	}), nil
+}
+
+func (mem *MemoryStorage) GetReservationsInSubtree(domain string) ([]host.Reservation, error) {
+	mem.logger.Debugf("getting host reservations in subtree")
+	return mem.listReservations(func(res host.Reservation) bool {
+		return host.InSubtree(res.Host, domain)
+	}), nil
+}
+
+func (mem *MemoryStorage) DeleteHostReservation(hostName string) error {
+	mem.logger.Debugf("deleting host reservation")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for i, res := range mem.reserved {
+		if res.Host == hostName {
+			mem.reserved = append(mem.reserved[:i], mem.reserved[i+1:]...)
+			return nil
+		}
+	}
+	mem.logger.Errorf("unable to delete host reservation")
+	return rserrors.ErrResourceNotExists().AddDetailF("host reservation %v", hostName)
+}
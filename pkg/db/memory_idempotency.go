@@ -0,0 +1,73 @@
+package db
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func cloneIdempotencyRecord(rec idempotency.Record) idempotency.Record {
+	var cp idempotency.Record
+	clone(rec, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findIdempotencyRecord(userID, key string) int {
+	for i, rec := range mem.idempotency {
+		if rec.UserID == userID && rec.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mem *MemoryStorage) CreateIdempotencyRecord(rec idempotency.Record) error {
+	mem.logger.Debugf("creating idempotency record")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findIdempotencyRecord(rec.UserID, rec.Key)
+	if i >= 0 {
+		if !mem.idempotency[i].Expired(rec.CreatedAt) {
+			mem.logger.Errorf("unable to create idempotency record")
+			return rserrors.ErrResourceAlreadyExists().AddDetailF("idempotency key %v", rec.Key)
+		}
+		mem.idempotency[i] = cloneIdempotencyRecord(rec)
+		return nil
+	}
+	mem.idempotency = append(mem.idempotency, cloneIdempotencyRecord(rec))
+	return nil
+}
+
+func (mem *MemoryStorage) GetIdempotencyRecord(userID, key string) (idempotency.Record, error) {
+	mem.logger.Debugf("getting idempotency record")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var i = mem.findIdempotencyRecord(userID, key)
+	if i < 0 {
+		mem.logger.Errorf("unable to get idempotency record")
+		return idempotency.Record{}, rserrors.ErrResourceNotExists().AddDetailF("idempotency key %v", key)
+	}
+	return cloneIdempotencyRecord(mem.idempotency[i]), nil
+}
+
+func (mem *MemoryStorage) UpdateIdempotencyRecord(rec idempotency.Record) error {
+	mem.logger.Debugf("updating idempotency record")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findIdempotencyRecord(rec.UserID, rec.Key)
+	if i < 0 {
+		mem.logger.Errorf("unable to update idempotency record")
+		return rserrors.ErrResourceNotExists().AddDetailF("idempotency key %v", rec.Key)
+	}
+	mem.idempotency[i] = cloneIdempotencyRecord(rec)
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteIdempotencyRecord(userID, key string) error {
+	mem.logger.Debugf("deleting idempotency record")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if i := mem.findIdempotencyRecord(userID, key); i >= 0 {
+		mem.idempotency = append(mem.idempotency[:i], mem.idempotency[i+1:]...)
+	}
+	return nil
+}
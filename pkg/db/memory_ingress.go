@@ -0,0 +1,219 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/strset"
+	"github.com/google/uuid"
+)
+
+func cloneIngress(ingr ingress.ResourceIngress) ingress.ResourceIngress {
+	var cp ingress.ResourceIngress
+	clone(ingr, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findIngresses(pred func(ingress.ResourceIngress) bool) []int {
+	var found []int
+	for i, ingr := range mem.ingresses {
+		if pred(ingr) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func (mem *MemoryStorage) listIngresses(pred func(ingress.ResourceIngress) bool) ingress.ListIngress {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	list := make(ingress.ListIngress, 0)
+	for _, ingr := range mem.ingresses {
+		if !ingr.Deleted && pred(ingr) {
+			list = append(list, cloneIngress(ingr))
+		}
+	}
+	return list
+}
+
+func (mem *MemoryStorage) CreateIngress(ingress ingress.ResourceIngress) (ingress.ResourceIngress, error) {
+	mem.logger.Debugf("creating ingress")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if ingress.ID == "" {
+		ingress.ID = uuid.New().String()
+	}
+	ingress.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	for _, ingr := range mem.ingresses {
+		// "alive_ingress" index is not namespaced
+		if ingr.ID == ingress.ID || (!ingr.Deleted && ingr.Name == ingress.Name) {
+			mem.logger.Errorf("unable to create ingress")
+			return ingress, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	ingress.ResourceVersion = mem.nextResourceVersion()
+	mem.ingresses = append(mem.ingresses, cloneIngress(ingress))
+	return ingress, nil
+}
+
+func (mem *MemoryStorage) GetIngress(namespaceID, name string) (ingress.ResourceIngress, error) {
+	mem.logger.Debugf("getting ingress")
+	var list = mem.listIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == namespaceID && ingr.Name == name
+	})
+	if len(list) == 0 {
+		mem.logger.Errorf("unable to get ingress")
+		return ingress.ResourceIngress{}, rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	return list[0], nil
+}
+
+func (mem *MemoryStorage) GetIngressByService(namespaceID, serviceName string) (ingress.ResourceIngress, error) {
+	mem.logger.Debugf("getting ingress by service")
+	var list = mem.listIngresses(func(ingr ingress.ResourceIngress) bool {
+		if ingr.NamespaceID != namespaceID {
+			return false
+		}
+		for _, path := range ingr.Paths() {
+			if path.ServiceName == serviceName {
+				return true
+			}
+		}
+		return false
+	})
+	if len(list) == 0 {
+		mem.logger.Errorf("unable to get ingress")
+		return ingress.ResourceIngress{}, rserrors.ErrResourceNotExists().AddDetails(serviceName)
+	}
+	return list[0], nil
+}
+
+func (mem *MemoryStorage) GetSelectedIngresses(namespaceID []string, query *ListQuery) (ingress.ListIngress, int, error) {
+	mem.logger.Debugf("getting selected ingresses")
+	var namespaces = strset.FromSlice(namespaceID)
+	list := mem.listIngresses(func(ingr ingress.ResourceIngress) bool {
+		return namespaces.In(ingr.NamespaceID)
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+func (mem *MemoryStorage) GetIngressList(namespaceID string, query *ListQuery) (ingress.ListIngress, int, error) {
+	mem.logger.Debugf("getting ingress")
+	list := mem.listIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == namespaceID
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+func (mem *MemoryStorage) UpdateIngress(upd ingress.ResourceIngress) (ingress.ResourceIngress, error) {
+	mem.logger.Debugf("updating ingress")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == upd.NamespaceID && !ingr.Deleted && ingr.Name == upd.Name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update ingress")
+		return upd, rserrors.ErrResourceNotExists().AddDetails(upd.Name)
+	}
+	if err := checkResourceVersion(mem.ingresses[found[0]].ResourceVersion, upd.ResourceVersion, upd.Name); err != nil {
+		mem.logger.WithError(err).Errorf("unable to update ingress")
+		return upd, err
+	}
+	mem.ingresses[found[0]].Ingress = cloneIngress(upd).Ingress
+	mem.ingresses[found[0]].TLS = upd.TLS
+	mem.ingresses[found[0]].ResourceVersion = mem.nextResourceVersion()
+	upd.ResourceVersion = mem.ingresses[found[0]].ResourceVersion
+	return upd, nil
+}
+
+func (mem *MemoryStorage) DeleteIngress(namespaceID, name string) error {
+	mem.logger.Debugf("deleting ingress")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == namespaceID && !ingr.Deleted && ingr.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete ingress")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	mem.ingresses[found[0]].Deleted = true
+	mem.ingresses[found[0]].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	mem.releaseIngressHosts(namespaceID, name)
+	return nil
+}
+
+func (mem *MemoryStorage) RestoreIngress(namespaceID, name string) error {
+	mem.logger.Debugf("restoring ingress")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == namespaceID && ingr.Deleted && ingr.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to restore ingress")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	var last = found[len(found)-1]
+	var claims = host.Claims(namespaceID, mem.ingresses[last].Ingress)
+	for _, claim := range claims {
+		if i := mem.findHost(claim); i >= 0 && !mem.hosts[i].SameIngress(claim) {
+			var err = hostConflict(claim)
+			mem.logger.WithError(err).Errorf("unable to restore ingress")
+			return err
+		}
+	}
+	for _, claim := range claims {
+		mem.claimHost(claim)
+	}
+	mem.ingresses[last].Deleted = false
+	mem.ingresses[last].DeletedAt = ""
+	return nil
+}
+
+func (mem *MemoryStorage) deleteIngresses(pred func(ingress.ResourceIngress) bool) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findIngresses(pred) {
+		if !mem.ingresses[i].Deleted {
+			mem.releaseIngressHosts(mem.ingresses[i].NamespaceID, mem.ingresses[i].Name)
+		}
+		mem.ingresses[i].Deleted = true
+		mem.ingresses[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (mem *MemoryStorage) DeleteAllIngressesInNamespace(namespace string) error {
+	mem.logger.Debugf("deleting all ingresses in namespace")
+	mem.deleteIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.NamespaceID == namespace && !ingr.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllIngressesByOwner(owner string) error {
+	mem.logger.Debugf("deleting all user ingresses")
+	mem.deleteIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.Owner == owner && !ingr.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) CountIngresses(owner string) (int, error) {
+	mem.logger.Debugf("counting ingresses")
+	return len(mem.listIngresses(func(ingr ingress.ResourceIngress) bool {
+		return ingr.Owner == owner
+	})), nil
+}
+
+func (mem *MemoryStorage) CountAllIngresses() (int, error) {
+	mem.logger.Debugf("counting all ingresses")
+	return len(mem.listIngresses(func(ingress.ResourceIngress) bool {
+		return true
+	})), nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func (mem *MemoryStorage) AcquireLock(name, holder string, expiresAt time.Time) error {
+	mem.logger.Debugf("acquiring lock")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if held, ok := mem.locks[name]; ok && held.Holder != holder && !held.ExpiresAt.Before(time.Now()) {
+		mem.logger.Errorf("lock is held by another holder")
+		return rserrors.ErrResourceAlreadyExists().AddDetailF("lock %v is held by another holder", name)
+	}
+	if mem.locks == nil {
+		mem.locks = make(map[string]lock)
+	}
+	mem.locks[name] = lock{Name: name, Holder: holder, ExpiresAt: expiresAt.UTC()}
+	return nil
+}
+
+func (mem *MemoryStorage) ReleaseLock(name, holder string) error {
+	mem.logger.Debugf("releasing lock")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if held, ok := mem.locks[name]; ok && held.Holder == holder {
+		delete(mem.locks, name)
+	}
+	return nil
+}
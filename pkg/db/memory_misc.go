@@ -0,0 +1,53 @@
+package db
+
+import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/util/strset"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+)
+
+func (mem *MemoryStorage) GetNamespaceResourcesLimits(namespaceID string) (kubtypes.Resource, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var res kubtypes.Resource
+	for _, deploy := range mem.deployments {
+		if deploy.Deleted || !deploy.Active || deploy.NamespaceID != namespaceID {
+			continue
+		}
+		for _, container := range deploy.Containers {
+			res.CPU += container.Limits.CPU * uint(deploy.Replicas)
+			res.Memory += container.Limits.Memory * uint(deploy.Replicas)
+		}
+	}
+	return res, nil
+}
+
+func (mem *MemoryStorage) GetNamespaces() ([]string, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var namespaces = strset.Set{}
+	for _, deploy := range mem.deployments {
+		if !deploy.Deleted {
+			namespaces = namespaces.Put(deploy.NamespaceID)
+		}
+	}
+	for _, svc := range mem.services {
+		if !svc.Deleted {
+			namespaces = namespaces.Put(svc.NamespaceID)
+		}
+	}
+	for _, ingr := range mem.ingresses {
+		if !ingr.Deleted {
+			namespaces = namespaces.Put(ingr.NamespaceID)
+		}
+	}
+	for _, cm := range mem.configmaps {
+		if !cm.Deleted {
+			namespaces = namespaces.Put(cm.NamespaceID)
+		}
+	}
+	var list = namespaces.Items()
+	sort.Strings(list)
+	return list, nil
+}
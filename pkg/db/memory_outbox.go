@@ -0,0 +1,82 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func cloneOperation(op outbox.Operation) outbox.Operation {
+	var cp outbox.Operation
+	clone(op, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) CreateOperation(op outbox.Operation) (outbox.Operation, error) {
+	mem.logger.Debugf("creating outbox operation")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, stored := range mem.operations {
+		if stored.ID == op.ID {
+			mem.logger.Errorf("unable to create outbox operation")
+			return op, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	mem.operations = append(mem.operations, cloneOperation(op))
+	return op, nil
+}
+
+func (mem *MemoryStorage) ClaimOperation(now time.Time, lease time.Duration) (outbox.Operation, error) {
+	mem.logger.Debugf("claiming outbox operation")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var ready []int
+	for i, op := range mem.operations {
+		if op.Status == outbox.Pending && !op.NextAttemptAt.After(now) {
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) == 0 {
+		return outbox.Operation{}, rserrors.ErrResourceNotExists().AddDetails("no pending operations")
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return mem.operations[ready[i]].NextAttemptAt.Before(mem.operations[ready[j]].NextAttemptAt)
+	})
+	var op = &mem.operations[ready[0]]
+	op.NextAttemptAt = now.Add(lease).UTC()
+	op.Attempts++
+	return cloneOperation(*op), nil
+}
+
+func (mem *MemoryStorage) UpdateOperation(op outbox.Operation) error {
+	mem.logger.Debugf("updating outbox operation")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for i, stored := range mem.operations {
+		if stored.ID == op.ID {
+			stored.Status = op.Status
+			stored.LastError = op.LastError
+			stored.NextAttemptAt = op.NextAttemptAt
+			stored.DoneAt = op.DoneAt
+			mem.operations[i] = cloneOperation(stored)
+			return nil
+		}
+	}
+	mem.logger.Errorf("unable to update outbox operation")
+	return rserrors.ErrResourceNotExists().AddDetails(op.ID)
+}
+
+func (mem *MemoryStorage) GetPendingOperations(nsID string) ([]outbox.Operation, error) {
+	mem.logger.Debugf("getting pending outbox operations")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var ops []outbox.Operation
+	for _, op := range mem.operations {
+		if op.Status == outbox.Pending && op.NamespaceID == nsID {
+			ops = append(ops, cloneOperation(op))
+		}
+	}
+	return ops, nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+func (mem *MemoryStorage) findPort(alloc port.Allocation) int {
+	for i, existing := range mem.ports {
+		if existing.Domain == alloc.Domain && existing.Protocol == alloc.Protocol && existing.Port == alloc.Port {
+			return i
+		}
+	}
+	return -1
+}
+
+// releasePorts removes allocations matching predicate, must be called with write lock
+func (mem *MemoryStorage) releasePorts(pred func(port.Allocation) bool) {
+	var kept = mem.ports[:0]
+	for _, alloc := range mem.ports {
+		if !pred(alloc) {
+			kept = append(kept, alloc)
+		}
+	}
+	mem.ports = kept
+}
+
+func (mem *MemoryStorage) releaseServicePorts(namespaceID, serviceName string) {
+	mem.releasePorts(func(alloc port.Allocation) bool {
+		return alloc.NamespaceID == namespaceID && alloc.Service == serviceName
+	})
+}
+
+// claimPort checks unique index on domain, protocol and port, must be called with write lock
+func (mem *MemoryStorage) claimPort(alloc port.Allocation) error {
+	if i := mem.findPort(alloc); i >= 0 {
+		if mem.ports[i].SameService(alloc) {
+			return nil
+		}
+		return rserrors.ErrPortReserved().AddDetailF("port %v/%v of domain %v", alloc.Port, alloc.Protocol, alloc.Domain)
+	}
+	alloc.CreatedAt = time.Now().UTC()
+	mem.ports = append(mem.ports, alloc)
+	return nil
+}
+
+func (mem *MemoryStorage) ReservePort(alloc port.Allocation, minPort, maxPort int) (port.Allocation, error) {
+	mem.logger.Debugf("reserving port")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var taken []int
+	for _, existing := range mem.ports {
+		if existing.Domain == alloc.Domain && existing.Protocol == alloc.Protocol &&
+			existing.Port >= minPort && existing.Port < maxPort {
+			taken = append(taken, existing.Port)
+		}
+	}
+	sort.Ints(taken)
+	if maxPort <= minPort || len(taken) >= maxPort-minPort {
+		mem.logger.Errorf("no free ports left")
+		return alloc, portsExhausted(alloc)
+	}
+	alloc.CreatedAt = time.Now().UTC()
+	for i := 0; i < randomPortAttempts; i++ {
+		alloc.Port = rnd.Intn(maxPort-minPort) + minPort
+		if mem.findPort(alloc) < 0 {
+			mem.ports = append(mem.ports, alloc)
+			return alloc, nil
+		}
+	}
+	alloc.Port = firstFreePort(taken, minPort, maxPort)
+	mem.ports = append(mem.ports, alloc)
+	return alloc, nil
+}
+
+func (mem *MemoryStorage) ClaimPort(alloc port.Allocation) error {
+	mem.logger.Debugf("claiming port")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if err := mem.claimPort(alloc); err != nil {
+		mem.logger.WithError(err).Errorf("unable to claim port")
+		return err
+	}
+	return nil
+}
+
+func (mem *MemoryStorage) ReleasePort(alloc port.Allocation) error {
+	mem.logger.Debugf("releasing port")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.releasePorts(func(existing port.Allocation) bool {
+		return existing.Domain == alloc.Domain && existing.Protocol == alloc.Protocol && existing.Port == alloc.Port &&
+			existing.SameService(alloc)
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) ReleaseServicePorts(namespaceID, serviceName string) error {
+	mem.logger.Debugf("releasing service ports")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.releaseServicePorts(namespaceID, serviceName)
+	return nil
+}
+
+func (mem *MemoryStorage) GetPortsUsage(minPort, maxPort int) ([]port.Usage, error) {
+	mem.logger.Debugf("getting ports usage")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var usage []port.Usage
+	var index = make(map[port.Key]int)
+	for _, alloc := range mem.ports {
+		if alloc.Port < minPort || alloc.Port >= maxPort {
+			continue
+		}
+		i, ok := index[alloc.Key()]
+		if !ok {
+			i = len(usage)
+			index[alloc.Key()] = i
+			usage = append(usage, port.Usage{Domain: alloc.Domain, Protocol: alloc.Protocol, Total: maxPort - minPort})
+		}
+		usage[i].Used++
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Domain != usage[j].Domain {
+			return usage[i].Domain < usage[j].Domain
+		}
+		return usage[i].Protocol < usage[j].Protocol
+	})
+	return usage, nil
+}
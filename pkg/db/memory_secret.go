@@ -0,0 +1,183 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/strset"
+	"github.com/google/uuid"
+)
+
+func cloneSecret(s secret.ResourceSecret) secret.ResourceSecret {
+	var cp secret.ResourceSecret
+	clone(s, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findSecrets(pred func(secret.ResourceSecret) bool) []int {
+	var found []int
+	for i, s := range mem.secrets {
+		if pred(s) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func (mem *MemoryStorage) listSecrets(pred func(secret.ResourceSecret) bool) secret.ListSecrets {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	list := make(secret.ListSecrets, 0)
+	for _, s := range mem.secrets {
+		if !s.Deleted && pred(s) {
+			list = append(list, cloneSecret(s))
+		}
+	}
+	return list
+}
+
+func (mem *MemoryStorage) GetSecret(namespaceID, secretName string) (secret.ResourceSecret, error) {
+	mem.logger.Debugf("getting secret")
+	var list = mem.listSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == namespaceID && s.Name == secretName
+	})
+	if len(list) == 0 {
+		mem.logger.Errorf("unable to get secret")
+		return secret.ResourceSecret{}, rserrors.ErrResourceNotExists().AddDetails(secretName)
+	}
+	return list[0], nil
+}
+
+func (mem *MemoryStorage) GetSecretList(namespaceID string, query *ListQuery) (secret.ListSecrets, int, error) {
+	mem.logger.Debugf("getting secrets list")
+	list := mem.listSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == namespaceID
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+func (mem *MemoryStorage) GetSelectedSecrets(namespaceID []string, query *ListQuery) (secret.ListSecrets, int, error) {
+	mem.logger.Debugf("getting selected secrets")
+	var namespaces = strset.FromSlice(namespaceID)
+	list := mem.listSecrets(func(s secret.ResourceSecret) bool {
+		return namespaces.In(s.NamespaceID)
+	})
+	total := applyListQuery(&list, query)
+	return list, total, nil
+}
+
+// If ID is empty, then generates UUID4 and uses it
+func (mem *MemoryStorage) CreateSecret(s secret.ResourceSecret) (secret.ResourceSecret, error) {
+	mem.logger.Debugf("creating secret")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	s.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	for _, existing := range mem.secrets {
+		if existing.ID == s.ID ||
+			(!existing.Deleted && existing.NamespaceID == s.NamespaceID && existing.Name == s.Name) {
+			mem.logger.Errorf("unable to create secret")
+			return s, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	s.ResourceVersion = mem.nextResourceVersion()
+	mem.secrets = append(mem.secrets, cloneSecret(s))
+	return s, nil
+}
+
+func (mem *MemoryStorage) UpdateSecret(upd secret.ResourceSecret) (secret.ResourceSecret, error) {
+	mem.logger.Debugf("updating secret")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == upd.NamespaceID && !s.Deleted && s.Name == upd.Name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update secret")
+		return upd, rserrors.ErrResourceNotExists().AddDetails(upd.Name)
+	}
+	if err := checkResourceVersion(mem.secrets[found[0]].ResourceVersion, upd.ResourceVersion, upd.Name); err != nil {
+		mem.logger.WithError(err).Errorf("unable to update secret")
+		return upd, err
+	}
+	mem.secrets[found[0]].Secret = cloneSecret(upd).Secret
+	mem.secrets[found[0]].ResourceVersion = mem.nextResourceVersion()
+	upd.ResourceVersion = mem.secrets[found[0]].ResourceVersion
+	return upd, nil
+}
+
+func (mem *MemoryStorage) DeleteSecret(namespaceID, name string) error {
+	mem.logger.Debugf("deleting secret")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == namespaceID && !s.Deleted && s.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete secret")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	mem.secrets[found[0]].Deleted = true
+	mem.secrets[found[0]].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (mem *MemoryStorage) deleteSecrets(pred func(secret.ResourceSecret) bool) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findSecrets(pred) {
+		mem.secrets[i].Deleted = true
+		mem.secrets[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (mem *MemoryStorage) DeleteAllSecretsInNamespace(namespaceID string) error {
+	mem.logger.Debugf("deleting all secrets in namespace")
+	mem.deleteSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == namespaceID && !s.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllSecretsByOwner(owner string) error {
+	mem.logger.Debugf("deleting all user secrets")
+	mem.deleteSecrets(func(s secret.ResourceSecret) bool {
+		return s.Owner == owner && !s.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) RestoreSecret(namespaceID, name string) error {
+	mem.logger.Debugf("restoring secret")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findSecrets(func(s secret.ResourceSecret) bool {
+		return s.NamespaceID == namespaceID && s.Deleted && s.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to restore secret")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	var last = found[len(found)-1]
+	mem.secrets[last].Deleted = false
+	mem.secrets[last].DeletedAt = ""
+	return nil
+}
+
+func (mem *MemoryStorage) CountSecrets(owner string) (int, error) {
+	mem.logger.Debugf("counting secrets")
+	return len(mem.listSecrets(func(s secret.ResourceSecret) bool {
+		return s.Owner == owner
+	})), nil
+}
+
+func (mem *MemoryStorage) CountAllSecrets() (int, error) {
+	mem.logger.Debugf("counting all secrets")
+	return len(mem.listSecrets(func(secret.ResourceSecret) bool {
+		return true
+	})), nil
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/stats"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/google/uuid"
+)
+
+func cloneService(svc service.ResourceService) service.ResourceService {
+	var cp service.ResourceService
+	clone(svc, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findServices(pred func(service.ResourceService) bool) []int {
+	var found []int
+	for i, svc := range mem.services {
+		if pred(svc) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// servicePortsConflict checks "alive_service_with_ports" index constraint
+func (mem *MemoryStorage) servicePortsConflict(svc service.ResourceService) bool {
+	if svc.Type != service.External {
+		return false
+	}
+	for _, existing := range mem.services {
+		if existing.Deleted || existing.Type != service.External || existing.ID == svc.ID || existing.Domain != svc.Domain {
+			continue
+		}
+		for _, port := range svc.Ports {
+			for _, existingPort := range existing.Ports {
+				if port.Port != nil && existingPort.Port != nil &&
+					*port.Port == *existingPort.Port && port.Protocol == existingPort.Protocol {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (mem *MemoryStorage) GetService(namespaceID, serviceName string) (service.ResourceService, error) {
+	mem.logger.Debugf("getting service")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, svc := range mem.services {
+		if svc.NamespaceID == namespaceID && !svc.Deleted && svc.Name == serviceName {
+			return cloneService(svc), nil
+		}
+	}
+	return service.ResourceService{}, rserrors.ErrResourceNotExists().AddDetails(serviceName)
+}
+
+func (mem *MemoryStorage) GetServiceList(namespaceID string, query *ListQuery) (service.ListService, int, error) {
+	mem.logger.Debugf("getting services list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make(service.ListService, 0)
+	for _, svc := range mem.services {
+		if svc.NamespaceID == namespaceID && !svc.Deleted {
+			result = append(result, cloneService(svc))
+		}
+	}
+	total := applyListQuery(&result, query)
+	return result, total, nil
+}
+
+// If ID is empty, then generates UUID4 and uses it
+func (mem *MemoryStorage) CreateService(service service.ResourceService) (service.ResourceService, error) {
+	mem.logger.Debugf("creating service")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if service.ID == "" {
+		service.ID = uuid.New().String()
+	}
+	service.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	for _, svc := range mem.services {
+		if svc.ID == service.ID || (svc.NamespaceID == service.NamespaceID && !svc.Deleted && svc.Name == service.Name) {
+			mem.logger.Errorf("unable to create service")
+			return service, rserrors.ErrResourceAlreadyExists()
+		}
+	}
+	if mem.servicePortsConflict(service) {
+		mem.logger.Errorf("unable to create service")
+		return service, rserrors.ErrResourceAlreadyExists()
+	}
+	service.ResourceVersion = mem.nextResourceVersion()
+	mem.services = append(mem.services, cloneService(service))
+	return service, nil
+}
+
+func (mem *MemoryStorage) UpdateService(upd service.ResourceService) (service.ResourceService, error) {
+	mem.logger.Debugf("updating service")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == upd.NamespaceID && !svc.Deleted && svc.Name == upd.Name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to update service")
+		return upd, rserrors.ErrResourceNotExists().AddDetails(upd.Name)
+	}
+	var updated = mem.services[found[0]]
+	if err := checkResourceVersion(updated.ResourceVersion, upd.ResourceVersion, upd.Name); err != nil {
+		mem.logger.WithError(err).Errorf("unable to update service")
+		return upd, err
+	}
+	updated.Service = cloneService(upd).Service
+	if mem.servicePortsConflict(updated) {
+		mem.logger.Errorf("unable to update service")
+		return upd, rserrors.ErrResourceAlreadyExists()
+	}
+	updated.ResourceVersion = mem.nextResourceVersion()
+	mem.services[found[0]] = updated
+	upd.ResourceVersion = updated.ResourceVersion
+	return upd, nil
+}
+
+func (mem *MemoryStorage) DeleteService(namespaceID, name string) error {
+	mem.logger.Debugf("deleting service")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == namespaceID && !svc.Deleted && svc.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to delete service")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	mem.services[found[0]].Deleted = true
+	mem.services[found[0]].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	mem.releaseServicePorts(namespaceID, name)
+	return nil
+}
+
+func (mem *MemoryStorage) RestoreService(namespaceID, name string) error {
+	mem.logger.Debugf("restoring service")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var found = mem.findServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == namespaceID && svc.Deleted && svc.Name == name
+	})
+	if len(found) == 0 {
+		mem.logger.Errorf("unable to restore service")
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	var last = found[len(found)-1]
+	var allocs = mem.services[last].PortAllocations()
+	for _, alloc := range allocs {
+		if i := mem.findPort(alloc); i >= 0 && !mem.ports[i].SameService(alloc) {
+			var err = rserrors.ErrPortReserved().AddDetailF("port %v/%v of domain %v", alloc.Port, alloc.Protocol, alloc.Domain)
+			mem.logger.WithError(err).Errorf("unable to restore service")
+			return err
+		}
+	}
+	for _, alloc := range allocs {
+		mem.claimPort(alloc)
+	}
+	mem.services[last].Deleted = false
+	mem.services[last].DeletedAt = ""
+	return nil
+}
+
+func (mem *MemoryStorage) deleteServices(pred func(service.ResourceService) bool) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for _, i := range mem.findServices(pred) {
+		if !mem.services[i].Deleted {
+			mem.releaseServicePorts(mem.services[i].NamespaceID, mem.services[i].Name)
+		}
+		mem.services[i].Deleted = true
+		mem.services[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (mem *MemoryStorage) DeleteAllServicesInNamespace(namespaceID string) error {
+	mem.logger.Debugf("deleting all services in namespace")
+	mem.deleteServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == namespaceID && !svc.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllServicesByOwner(owner string) error {
+	mem.logger.Debugf("deleting all services in namespace")
+	mem.deleteServices(func(svc service.ResourceService) bool {
+		return svc.Owner == owner && !svc.Deleted
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) DeleteAllServicesBySolutionName(nsID, solution string) error {
+	mem.logger.Debugf("deleting all solutions services")
+	mem.deleteServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == nsID && svc.SolutionID == solution
+	})
+	return nil
+}
+
+func (mem *MemoryStorage) GetServicesByDomain(domainName string) (service.ListService, error) {
+	mem.logger.Debugf("getting services by domain")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	result := make(service.ListService, 0)
+	for _, svc := range mem.services {
+		if !svc.Deleted && svc.Domain == domainName {
+			result = append(result, cloneService(svc))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].NamespaceID != result[j].NamespaceID {
+			return result[i].NamespaceID < result[j].NamespaceID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// countServices groups services the same way as mongo pipelines in CountServices* methods
+func (mem *MemoryStorage) countServices(pred func(service.ResourceService) bool) stats.Service {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var serviceStats stats.Service
+	for _, svc := range mem.services {
+		if svc.Deleted || !pred(svc) {
+			continue
+		}
+		if svc.Domain == "" {
+			serviceStats.External++
+		} else {
+			serviceStats.Internal++
+		}
+	}
+	return serviceStats
+}
+
+func (mem *MemoryStorage) CountServices(owner string) (stats.Service, error) {
+	mem.logger.Debugf("counting services")
+	return mem.countServices(func(svc service.ResourceService) bool {
+		return svc.Owner == owner
+	}), nil
+}
+
+func (mem *MemoryStorage) CountAllServices() (stats.Service, error) {
+	mem.logger.Debugf("counting services")
+	return mem.countServices(func(service.ResourceService) bool {
+		return true
+	}), nil
+}
+
+func (mem *MemoryStorage) CountServicesInNamespace(namespaceID string) (stats.Service, error) {
+	mem.logger.Debugf("counting services in namespace")
+	return mem.countServices(func(svc service.ResourceService) bool {
+		return svc.NamespaceID == namespaceID
+	}), nil
+}
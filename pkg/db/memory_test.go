@@ -0,0 +1,258 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/blang/semver"
+	"github.com/containerum/cherry"
+	"github.com/containerum/kube-client/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDeploymentVersions(t *testing.T) {
+	var mem = NewMemory(nil)
+	var newDeploy = func(version string, active bool) deployment.ResourceDeploy {
+		return deployment.ResourceDeploy{
+			Deployment: model.Deployment{
+				Name:       "test",
+				Version:    semver.MustParse(version),
+				Active:     active,
+				Replicas:   2,
+				Containers: []model.Container{{Name: "c", Limits: model.Resource{CPU: 100, Memory: 128}}},
+			},
+			NamespaceID: "ns",
+		}
+	}
+
+	_, err := mem.CreateDeployment(newDeploy("1.0.0", true))
+	assert.NoError(t, err)
+	_, err = mem.CreateDeployment(newDeploy("1.0.0", false))
+	assert.Error(t, err, "version duplicate")
+	_, err = mem.CreateDeployment(newDeploy("1.1.0", false))
+	assert.NoError(t, err)
+
+	active, err := mem.GetDeployment("ns", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", active.Version.String())
+
+	latest, err := mem.GetDeploymentLatestVersion("ns", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", latest.Version.String())
+
+	assert.NoError(t, mem.DeactivateDeployment("ns", "test", 0))
+	assert.NoError(t, mem.ActivateDeployment("ns", "test", semver.MustParse("1.1.0")))
+	active, err = mem.GetDeployment("ns", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", active.Version.String())
+
+	limits, err := mem.GetNamespaceResourcesLimits("ns")
+	assert.NoError(t, err)
+	assert.Equal(t, model.Resource{CPU: 200, Memory: 256}, limits)
+
+	assert.NoError(t, mem.DeleteDeployment("ns", "test"))
+	_, err = mem.GetDeployment("ns", "test")
+	assert.Error(t, err)
+	assert.NoError(t, mem.RestoreDeployment("ns", "test"))
+}
+
+func TestMemoryConfigMapVersions(t *testing.T) {
+	box, err := secretbox.New("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	assert.NoError(t, err)
+	var mem = NewMemory(nil)
+	var storage = WithSealedConfigMaps(mem, box)
+	var newCM = func(version string, active bool) configmap.ResourceConfigMap {
+		return configmap.ResourceConfigMap{
+			ConfigMap:   model.ConfigMap{Name: "cfg", Data: model.ConfigMapData{"a": version}},
+			NamespaceID: "ns",
+			Version:     semver.MustParse(version),
+			Active:      active,
+		}
+	}
+
+	first, err := storage.CreateConfigMap(newCM("1.0.0", true))
+	assert.NoError(t, err)
+	assert.Equal(t, model.ConfigMapData{"a": "1.0.0"}, first.Data)
+	stored, err := mem.GetConfigMap("ns", "cfg")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "1.0.0", stored.Data["a"], "data must be sealed in storage")
+
+	assert.NoError(t, storage.DeactivateConfigMap("ns", "cfg", first.ResourceVersion))
+	second, err := storage.CreateConfigMap(newCM("1.1.0", true))
+	assert.NoError(t, err)
+	err = storage.DeactivateConfigMap("ns", "cfg", first.ResourceVersion)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+
+	active, err := storage.GetConfigMap("ns", "cfg")
+	assert.NoError(t, err)
+	assert.Equal(t, second.Version.String(), active.Version.String())
+	assert.Equal(t, model.ConfigMapData{"a": "1.1.0"}, active.Data)
+
+	assert.NoError(t, storage.DeleteConfigMap("ns", "cfg"))
+	assert.NoError(t, storage.RestoreConfigMap("ns", "cfg"))
+	versions, _, err := storage.GetConfigMapVersionsList("ns", "cfg", nil)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2, "all versions must be restored")
+}
+
+func TestMemoryPorts(t *testing.T) {
+	var mem = NewMemory(nil)
+	var alloc = port.Allocation{Domain: "example.com", Protocol: model.TCP, NamespaceID: "ns", Service: "svc"}
+
+	var reserved = make(map[int]bool)
+	for i := 0; i < 3; i++ {
+		res, err := mem.ReservePort(alloc, 1000, 1003)
+		assert.NoError(t, err)
+		assert.True(t, res.Port >= 1000 && res.Port < 1003)
+		reserved[res.Port] = true
+	}
+	assert.Len(t, reserved, 3, "ports are reserved once")
+	_, err := mem.ReservePort(alloc, 1000, 1003)
+	assert.True(t, cherry.Equals(err, rserrors.ErrPortsExhausted()))
+
+	var other = alloc
+	other.Service = "other"
+	other.Port = 1001
+	assert.True(t, cherry.Equals(mem.ClaimPort(other), rserrors.ErrPortReserved()))
+	other.Protocol = model.UDP
+	assert.NoError(t, mem.ClaimPort(other), "ports of protocols are independent")
+
+	usage, err := mem.GetPortsUsage(1000, 1003)
+	assert.NoError(t, err)
+	assert.Equal(t, []port.Usage{
+		{Domain: "example.com", Protocol: model.TCP, Used: 3, Total: 3},
+		{Domain: "example.com", Protocol: model.UDP, Used: 1, Total: 3},
+	}, usage)
+
+	alloc.Port = 1001
+	assert.NoError(t, mem.ReleasePort(alloc))
+	res, err := mem.ReservePort(alloc, 1000, 1003)
+	assert.NoError(t, err)
+	assert.Equal(t, 1001, res.Port, "released port is reused")
+}
+
+func TestMemoryServicePorts(t *testing.T) {
+	var mem = NewMemory(nil)
+	var externalPort = 30000
+	var svc = service.ResourceService{
+		Service: model.Service{
+			Name:   "svc",
+			Domain: "example.com",
+			Ports:  []model.ServicePort{{Name: "http", Port: &externalPort, TargetPort: 80, Protocol: model.TCP}},
+		},
+		NamespaceID: "ns",
+		Type:        service.External,
+	}
+	for _, alloc := range svc.PortAllocations() {
+		assert.NoError(t, mem.ClaimPort(alloc))
+	}
+	_, err := mem.CreateService(svc)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mem.DeleteService("ns", "svc"))
+	usage, err := mem.GetPortsUsage(30000, 30100)
+	assert.NoError(t, err)
+	assert.Empty(t, usage, "ports are released with service")
+
+	assert.NoError(t, mem.ClaimPort(port.Allocation{Domain: "example.com", Protocol: model.TCP, Port: 30000, NamespaceID: "ns", Service: "another"}))
+	assert.True(t, cherry.Equals(mem.RestoreService("ns", "svc"), rserrors.ErrPortReserved()))
+	assert.NoError(t, mem.ReleaseServicePorts("ns", "another"))
+	assert.NoError(t, mem.RestoreService("ns", "svc"))
+	usage, err = mem.GetPortsUsage(30000, 30100)
+	assert.NoError(t, err)
+	assert.Equal(t, []port.Usage{{Domain: "example.com", Protocol: model.TCP, Used: 1, Total: 100}}, usage)
+}
+
+func TestMemoryHosts(t *testing.T) {
+	var mem = NewMemory(nil)
+	var ingr = ingress.ResourceIngress{
+		Ingress: model.Ingress{
+			Name:  "ingr",
+			Owner: "user",
+			Rules: []model.Rule{{Host: "app.example.com", Path: []model.Path{{Path: "/", ServiceName: "svc", ServicePort: 80}}}},
+		},
+		NamespaceID: "ns",
+	}
+	_, err := mem.CreateIngress(ingr)
+	assert.NoError(t, err)
+	for _, claim := range host.Claims("ns", ingr.Ingress) {
+		assert.NoError(t, mem.ClaimHost(claim))
+		assert.NoError(t, mem.ClaimHost(claim), "claim of the same ingress is not a conflict")
+	}
+
+	var other = host.Claim{Host: "app.example.com", Path: "/", NamespaceID: "other", Ingress: "ingr"}
+	assert.True(t, cherry.Equals(mem.ClaimHost(other), rserrors.ErrIngressHostConflict()))
+	var nested = host.Claim{Host: "app.example.com", Path: "/admin", NamespaceID: "other", Ingress: "admin", Owner: "other"}
+	assert.True(t, cherry.Equals(mem.ClaimHost(nested), rserrors.ErrIngressHostConflict()), "paths of host used by another owner can't be claimed")
+
+	claims, err := mem.GetHostClaims("example.com", true)
+	assert.NoError(t, err)
+	assert.Len(t, claims, 1)
+
+	assert.NoError(t, mem.DeleteIngress("ns", "ingr"))
+	assert.NoError(t, mem.ClaimHost(other), "hosts are released on delete")
+	assert.True(t, cherry.Equals(mem.RestoreIngress("ns", "ingr"), rserrors.ErrIngressHostConflict()))
+	assert.NoError(t, mem.ReleaseHost(other))
+	assert.NoError(t, mem.RestoreIngress("ns", "ingr"))
+	claims, err = mem.GetHostClaims("app.example.com", false)
+	assert.NoError(t, err)
+	if assert.Len(t, claims, 1) {
+		assert.Equal(t, "ns", claims[0].NamespaceID, "hosts are claimed on restore")
+	}
+
+	assert.NoError(t, mem.ReserveHost(host.Reservation{Host: "*.example.com", Owner: "user"}))
+	assert.NoError(t, mem.ReserveHost(host.Reservation{Host: "a.b.example.com", Owner: "user"}))
+	assert.True(t, cherry.Equals(mem.ReserveHost(host.Reservation{Host: "*.example.com", Owner: "other"}), rserrors.ErrHostReserved()))
+
+	covering, err := mem.GetCoveringReservations("c.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com"}, reservationHosts(covering))
+	inSubtree, err := mem.GetReservationsInSubtree("example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com", "a.b.example.com"}, reservationHosts(inSubtree))
+
+	assert.NoError(t, mem.DeleteHostReservation("*.example.com"))
+	covering, err = mem.GetCoveringReservations("c.example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, covering)
+}
+
+func reservationHosts(reservations []host.Reservation) []string {
+	var hosts []string
+	for _, res := range reservations {
+		hosts = append(hosts, res.Host)
+	}
+	return hosts
+}
+
+func TestMemoryCertificates(t *testing.T) {
+	var mem = NewMemory(nil)
+	var now = time.Now()
+	var cert = ingress.Certificate{NamespaceID: "ns", Ingress: "app", Secret: "app-tls", Hosts: []string{"app.test"}, NotAfter: now.Add(time.Hour)}
+	assert.NoError(t, mem.SetCertificate(cert))
+	assert.NoError(t, mem.SetCertificate(ingress.Certificate{NamespaceID: "ns", Ingress: "web", NotAfter: now.Add(48 * time.Hour)}))
+
+	cert.Hosts = append(cert.Hosts, "www.test")
+	assert.NoError(t, mem.SetCertificate(cert), "certificate of ingress is replaced")
+	stored, err := mem.GetCertificate("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"app.test", "www.test"}, stored.Hosts)
+	}
+
+	expiring, err := mem.GetCertificatesExpiringBefore(now.Add(24 * time.Hour))
+	if assert.NoError(t, err) && assert.Len(t, expiring, 1) {
+		assert.Equal(t, "app", expiring[0].Ingress)
+	}
+
+	assert.NoError(t, mem.DeleteCertificate("ns", "app"))
+	_, err = mem.GetCertificate("ns", "app")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/google/uuid"
+)
+
+func cloneWebhook(hook webhook.Webhook) webhook.Webhook {
+	var cp webhook.Webhook
+	clone(hook, &cp)
+	return cp
+}
+
+func cloneDelivery(delivery webhook.Delivery) webhook.Delivery {
+	var cp webhook.Delivery
+	clone(delivery, &cp)
+	return cp
+}
+
+func (mem *MemoryStorage) findWebhook(nsID, name string) int {
+	for i, hook := range mem.webhooks {
+		if hook.NamespaceID == nsID && hook.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (mem *MemoryStorage) GetWebhook(nsID, name string) (webhook.Webhook, error) {
+	mem.logger.Debugf("getting webhook")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var i = mem.findWebhook(nsID, name)
+	if i < 0 {
+		mem.logger.Errorf("unable to get webhook")
+		return webhook.Webhook{}, rserrors.ErrResourceNotExists().AddDetailF("webhook %v", name)
+	}
+	return cloneWebhook(mem.webhooks[i]), nil
+}
+
+func (mem *MemoryStorage) GetWebhookList(nsID string) ([]webhook.Webhook, error) {
+	mem.logger.Debugf("getting webhook list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var result = make([]webhook.Webhook, 0)
+	for _, hook := range mem.webhooks {
+		if hook.NamespaceID == nsID {
+			result = append(result, cloneWebhook(hook))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (mem *MemoryStorage) CreateWebhook(hook webhook.Webhook) (webhook.Webhook, error) {
+	mem.logger.Debugf("creating webhook")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if hook.ID == "" {
+		hook.ID = uuid.New().String()
+	}
+	if mem.findWebhook(hook.NamespaceID, hook.Name) >= 0 {
+		mem.logger.Errorf("unable to create webhook")
+		return hook, rserrors.ErrResourceAlreadyExists().AddDetailF("webhook %v", hook.Name)
+	}
+	mem.webhooks = append(mem.webhooks, cloneWebhook(hook))
+	return hook, nil
+}
+
+func (mem *MemoryStorage) UpdateWebhook(hook webhook.Webhook) (webhook.Webhook, error) {
+	mem.logger.Debugf("updating webhook")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findWebhook(hook.NamespaceID, hook.Name)
+	if i < 0 {
+		mem.logger.Errorf("unable to update webhook")
+		return hook, rserrors.ErrResourceNotExists().AddDetailF("webhook %v", hook.Name)
+	}
+	mem.webhooks[i] = cloneWebhook(hook)
+	return hook, nil
+}
+
+func (mem *MemoryStorage) DeleteWebhook(nsID, name string) error {
+	mem.logger.Debugf("deleting webhook")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var i = mem.findWebhook(nsID, name)
+	if i < 0 {
+		mem.logger.Errorf("unable to delete webhook")
+		return rserrors.ErrResourceNotExists().AddDetailF("webhook %v", name)
+	}
+	mem.webhooks = append(mem.webhooks[:i], mem.webhooks[i+1:]...)
+	return nil
+}
+
+func (mem *MemoryStorage) CreateDelivery(delivery webhook.Delivery) error {
+	mem.logger.Debugf("creating webhook delivery")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.deliveries = append(mem.deliveries, cloneDelivery(delivery))
+	return nil
+}
+
+func (mem *MemoryStorage) ClaimDelivery(now time.Time, lease time.Duration) (webhook.Delivery, error) {
+	mem.logger.Debugf("claiming webhook delivery")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	var ready []int
+	for i, delivery := range mem.deliveries {
+		if delivery.Status == webhook.Pending && !delivery.NextAttemptAt.After(now) {
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) == 0 {
+		return webhook.Delivery{}, rserrors.ErrResourceNotExists().AddDetails("no pending deliveries")
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return mem.deliveries[ready[i]].NextAttemptAt.Before(mem.deliveries[ready[j]].NextAttemptAt)
+	})
+	var delivery = &mem.deliveries[ready[0]]
+	delivery.NextAttemptAt = now.Add(lease).UTC()
+	delivery.Attempts++
+	return cloneDelivery(*delivery), nil
+}
+
+func (mem *MemoryStorage) UpdateDelivery(delivery webhook.Delivery) error {
+	mem.logger.Debugf("updating webhook delivery")
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	for i, stored := range mem.deliveries {
+		if stored.ID == delivery.ID {
+			stored.Status = delivery.Status
+			stored.Log = delivery.Log
+			stored.NextAttemptAt = delivery.NextAttemptAt
+			stored.DoneAt = delivery.DoneAt
+			mem.deliveries[i] = cloneDelivery(stored)
+			return nil
+		}
+	}
+	mem.logger.Errorf("unable to update webhook delivery")
+	return rserrors.ErrResourceNotExists().AddDetails(delivery.ID)
+}
+
+func (mem *MemoryStorage) GetDeliveryList(nsID, webhookID string) ([]webhook.Delivery, error) {
+	mem.logger.Debugf("getting webhook delivery list")
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	var result = make([]webhook.Delivery, 0)
+	// deliveries are appended in creation order
+	for i := len(mem.deliveries) - 1; i >= 0 && len(result) < webhook.MaxDeliveries; i-- {
+		if mem.deliveries[i].NamespaceID == nsID && mem.deliveries[i].WebhookID == webhookID {
+			result = append(result, cloneDelivery(mem.deliveries[i]))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		if err := db.C("secret").Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		var collection = db.C("secret")
+		if err := collection.EnsureIndex(mgo.Index{
+			Key: []string{"secret.owner"},
+		}); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndexKey("namespaceid"); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndexKey("deleted"); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndex(mgo.Index{
+			Name: "alive_secret",
+			Key:  []string{"namespaceid", "secret.name"},
+			PartialFilter: bson.M{
+				"deleted": false,
+			},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		return db.C("secret").DropCollection()
+	})
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"github.com/blang/semver"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("configmap")
+		if _, err := collection.UpdateAll(bson.M{
+			"active": bson.M{"$exists": false},
+		}, bson.M{
+			"$set": bson.M{
+				"active":  true,
+				"version": semver.MustParse("1.0.0"),
+			},
+		}); err != nil {
+			return err
+		}
+		if err := collection.DropIndexName("alive_configmap"); err != nil {
+			return err
+		}
+		// inactive versions are kept as history
+		if err := collection.EnsureIndex(mgo.Index{
+			Name: "alive_configmap",
+			Key:  []string{"configmap.name"},
+			PartialFilter: bson.M{
+				"deleted": false,
+				"active":  true,
+			},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		return collection.EnsureIndexKey("namespaceid", "configmap.name", "version")
+	}, func(db *mgo.Database) error {
+		var collection = db.C("configmap")
+		if err := collection.DropIndex("namespaceid", "configmap.name", "version"); err != nil {
+			return err
+		}
+		if err := collection.DropIndexName("alive_configmap"); err != nil {
+			return err
+		}
+		// old index doesn't allow configmap history
+		if _, err := collection.RemoveAll(bson.M{"active": false}); err != nil {
+			return err
+		}
+		return collection.EnsureIndex(mgo.Index{
+			Name: "alive_configmap",
+			Key:  []string{"configmap.name"},
+			PartialFilter: bson.M{
+				"deleted": false,
+			},
+			Unique: true,
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		if err := db.C("audit").Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		var collection = db.C("audit")
+		for _, key := range [][]string{
+			{"namespaceid", "-time"},
+			{"userid", "-time"},
+			{"-time"},
+		} {
+			if err := collection.EnsureIndexKey(key...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		return db.C("audit").DropCollection()
+	})
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		for _, name := range []string{"webhook", "webhook_delivery"} {
+			if err := db.C(name).Create(&mgo.CollectionInfo{
+				ForceIdIndex: true,
+			}); err != nil {
+				return err
+			}
+		}
+		if err := db.C("webhook").EnsureIndex(mgo.Index{
+			Key:    []string{"namespaceid", "name"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		var deliveries = db.C("webhook_delivery")
+		if err := deliveries.EnsureIndexKey("status", "nextattemptat"); err != nil {
+			return err
+		}
+		if err := deliveries.EnsureIndexKey("namespaceid", "webhookid", "-createdat"); err != nil {
+			return err
+		}
+		// finished deliveries are kept for a week
+		if err := deliveries.EnsureIndex(mgo.Index{
+			Key:         []string{"doneat"},
+			ExpireAfter: 7 * 24 * time.Hour,
+		}); err != nil {
+			return err
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		if err := db.C("webhook_delivery").DropCollection(); err != nil {
+			return err
+		}
+		return db.C("webhook").DropCollection()
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+var versionedCollections = []string{"deployment", "service", "ingress", "configmap", "secret"}
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		for _, name := range versionedCollections {
+			if _, err := db.C(name).UpdateAll(bson.M{
+				"resourceversion": bson.M{"$exists": false},
+			}, bson.M{
+				"$set": bson.M{"resourceversion": 1},
+			}); err != nil {
+				return err
+			}
+		}
+		// existing resources have version 1, so counter must start after it
+		return db.C("counter").Insert(bson.M{"_id": "resourceversion", "value": 1})
+	}, func(db *mgo.Database) error {
+		for _, name := range versionedCollections {
+			if _, err := db.C(name).UpdateAll(nil, bson.M{
+				"$unset": bson.M{"resourceversion": ""},
+			}); err != nil {
+				return err
+			}
+		}
+		return db.C("counter").DropCollection()
+	})
+}
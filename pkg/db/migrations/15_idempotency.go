@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("idempotency")
+		if err := collection.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndex(mgo.Index{
+			Key:    []string{"userid", "key"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		// records are removed when replay window is over
+		return collection.EnsureIndex(mgo.Index{
+			Key:         []string{"expiresat"},
+			ExpireAfter: time.Second,
+		})
+	}, func(db *mgo.Database) error {
+		return db.C("idempotency").DropCollection()
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+var labeledCollections = []string{"deployment", "service", "ingress", "configmap"}
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		for _, name := range labeledCollections {
+			// labels are stored as list of key/value documents, so selectors use multikey index
+			if err := db.C(name).EnsureIndex(mgo.Index{
+				Name: "labels",
+				Key:  []string{"namespaceid", "labels.key", "labels.value"},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		for _, name := range labeledCollections {
+			if err := db.C(name).DropIndexName("labels"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("port")
+		if err := collection.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		// port is reserved atomically by insert
+		if err := collection.EnsureIndex(mgo.Index{
+			Name:   "reserved_port",
+			Key:    []string{"domain", "protocol", "port"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndexKey("namespaceid", "service"); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndexKey("owner"); err != nil {
+			return err
+		}
+
+		// reserve ports of existing external services
+		var services []struct {
+			NamespaceID string `bson:"namespaceid"`
+			Service     struct {
+				Name   string `bson:"name"`
+				Owner  string `bson:"owner"`
+				Domain string `bson:"domain"`
+				Ports  []struct {
+					Port     *int   `bson:"port"`
+					Protocol string `bson:"protocol"`
+				} `bson:"ports"`
+			} `bson:"service"`
+		}
+		if err := db.C("service").Find(bson.M{
+			"deleted":        false,
+			"type":           "external",
+			"service.domain": bson.M{"$nin": []interface{}{"", nil}},
+		}).All(&services); err != nil {
+			return err
+		}
+		var now = time.Now().UTC()
+		for _, svc := range services {
+			for _, p := range svc.Service.Ports {
+				if p.Port == nil {
+					continue
+				}
+				if err := collection.Insert(bson.M{
+					"domain":      svc.Service.Domain,
+					"protocol":    p.Protocol,
+					"port":        *p.Port,
+					"namespaceid": svc.NamespaceID,
+					"service":     svc.Service.Name,
+					"owner":       svc.Service.Owner,
+					"createdat":   now,
+				}); err != nil && !mgo.IsDup(err) {
+					return err
+				}
+			}
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		return db.C("port").DropCollection()
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("domain_pin")
+		if err := collection.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndex(mgo.Index{
+			Key:    []string{"kind", "id"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		return db.C("domain").EnsureIndexKey("domaingroup", "state")
+	}, func(db *mgo.Database) error {
+		if err := db.C("domain").DropIndex("domaingroup", "state"); err != nil {
+			return err
+		}
+		return db.C("domain_pin").DropCollection()
+	})
+}
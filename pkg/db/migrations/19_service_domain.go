@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		// services of domain are moved on drain and checked before domain deletion
+		return db.C("service").EnsureIndexKey("service.domain", "deleted")
+	}, func(db *mgo.Database) error {
+		return db.C("service").DropIndex("service.domain", "deleted")
+	})
+}
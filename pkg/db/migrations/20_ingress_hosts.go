@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var hosts = db.C("ingress_host")
+		if err := hosts.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		// host and path are claimed atomically by insert
+		if err := hosts.EnsureIndex(mgo.Index{
+			Name:   "claimed_host",
+			Key:    []string{"host", "path"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		if err := hosts.EnsureIndexKey("namespaceid", "ingress"); err != nil {
+			return err
+		}
+		if err := hosts.EnsureIndexKey("owner"); err != nil {
+			return err
+		}
+
+		var reservations = db.C("host_reservation")
+		if err := reservations.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		if err := reservations.EnsureIndex(mgo.Index{
+			Name:   "reserved_host",
+			Key:    []string{"host"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		if err := reservations.EnsureIndexKey("owner"); err != nil {
+			return err
+		}
+
+		// claim hosts of existing ingresses, first created ingress keeps host if several use it
+		var ingresses []struct {
+			NamespaceID string `bson:"namespaceid"`
+			Ingress     struct {
+				Name  string `bson:"name"`
+				Owner string `bson:"owner"`
+				Rules []struct {
+					Host string `bson:"host"`
+					Path []struct {
+						Path string `bson:"path"`
+					} `bson:"path"`
+				} `bson:"rules"`
+			} `bson:"ingress"`
+		}
+		if err := db.C("ingress").Find(bson.M{
+			"deleted": false,
+		}).Sort("ingress.createdat").All(&ingresses); err != nil {
+			return err
+		}
+		var now = time.Now().UTC()
+		for _, ingr := range ingresses {
+			for _, rule := range ingr.Ingress.Rules {
+				for _, path := range rule.Path {
+					if err := hosts.Insert(bson.M{
+						"host":        rule.Host,
+						"path":        path.Path,
+						"namespaceid": ingr.NamespaceID,
+						"ingress":     ingr.Ingress.Name,
+						"owner":       ingr.Ingress.Owner,
+						"createdat":   now,
+					}); err != nil && !mgo.IsDup(err) {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		if err := db.C("host_reservation").DropCollection(); err != nil {
+			return err
+		}
+		return db.C("ingress_host").DropCollection()
+	})
+}
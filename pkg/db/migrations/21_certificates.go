@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var certs = db.C("certificate")
+		if err := certs.Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		// ingress has one certificate
+		if err := certs.EnsureIndex(mgo.Index{
+			Name:   "ingress_certificate",
+			Key:    []string{"namespaceid", "ingress"},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		// expiring certificates are selected for renewal
+		return certs.EnsureIndexKey("notafter")
+	}, func(db *mgo.Database) error {
+		return db.C("certificate").DropCollection()
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("outbox")
+		// only user and role of request are kept instead of all X-headers
+		if _, err := collection.UpdateAll(bson.M{
+			"headers": bson.M{"$exists": true},
+		}, bson.M{
+			"$rename": bson.M{
+				"headers.X-User-Id":   "userid",
+				"headers.X-User-Role": "userrole",
+			},
+		}); err != nil {
+			return err
+		}
+		if _, err := collection.UpdateAll(bson.M{
+			"headers": bson.M{"$exists": true},
+		}, bson.M{
+			"$unset": bson.M{"headers": ""},
+		}); err != nil {
+			return err
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		_, err := db.C("outbox").UpdateAll(bson.M{
+			"userid": bson.M{"$exists": true},
+		}, bson.M{
+			"$rename": bson.M{
+				"userid":   "headers.X-User-Id",
+				"userrole": "headers.X-User-Role",
+			},
+		})
+		return err
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+// configHashEnv -- container env which was used to restart pods on configmap change before pod template annotation
+const configHashEnv = "CONFIGMAPS_HASH"
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("deployment")
+		type deployContainers struct {
+			ID         interface{} `bson:"_id"`
+			Deployment struct {
+				Containers []bson.M `bson:"containers"`
+			} `bson:"deployment"`
+		}
+		iter := collection.Find(bson.M{"deployment.containers.env.name": configHashEnv}).Iter()
+		for depl := (deployContainers{}); iter.Next(&depl); depl = (deployContainers{}) {
+			for _, container := range depl.Deployment.Containers {
+				envs, _ := container["env"].([]interface{})
+				var kept []interface{}
+				for _, env := range envs {
+					if e, ok := env.(bson.M); ok && e["name"] == configHashEnv {
+						continue
+					}
+					kept = append(kept, env)
+				}
+				container["env"] = kept
+			}
+			if err := collection.UpdateId(depl.ID, bson.M{
+				"$set": bson.M{"deployment.containers": depl.Deployment.Containers},
+			}); err != nil {
+				return err
+			}
+		}
+		return iter.Close()
+	}, func(db *mgo.Database) error {
+		// hash env is added back by next rollout of deployment, if needed
+		return nil
+	})
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		// configmap operations read active version from db, so sealed copies of data are not needed anymore
+		_, err := db.C("outbox").UpdateAll(bson.M{
+			"configmap": bson.M{"$exists": true},
+		}, bson.M{
+			"$unset": bson.M{"configmap": ""},
+		})
+		return err
+	}, func(db *mgo.Database) error {
+		return nil
+	})
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		return db.C("lock").Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		})
+	}, func(db *mgo.Database) error {
+		return db.C("lock").DropCollection()
+	})
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		var collection = db.C("deployment")
+		if _, err := collection.UpdateAll(bson.M{
+			"canary": bson.M{"$exists": false},
+		}, bson.M{
+			"$set": bson.M{"canary": false},
+		}); err != nil {
+			return err
+		}
+		if err := collection.DropIndexName("alive_deployment"); err != nil {
+			return err
+		}
+		// canary version is active together with stable one
+		if err := collection.EnsureIndex(mgo.Index{
+			Name: "alive_deployment",
+			Key:  []string{"deployment.name", "namespaceid"},
+			PartialFilter: bson.M{
+				"deleted":           false,
+				"deployment.active": true,
+				"canary":            false,
+			},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndex(mgo.Index{
+			Name: "alive_canary_deployment",
+			Key:  []string{"namespaceid", "deployment.name"},
+			PartialFilter: bson.M{
+				"deleted":           false,
+				"deployment.active": true,
+				"canary":            true,
+			},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		var collection = db.C("deployment")
+		if err := collection.DropIndexName("alive_canary_deployment"); err != nil {
+			return err
+		}
+		if err := collection.DropIndexName("alive_deployment"); err != nil {
+			return err
+		}
+		if err := collection.EnsureIndex(mgo.Index{
+			Name: "alive_deployment",
+			Key:  []string{"deployment.name", "namespaceid"},
+			PartialFilter: bson.M{
+				"deleted":           false,
+				"deployment.active": true,
+			},
+			Unique: true,
+		}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
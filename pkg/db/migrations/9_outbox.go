@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	migrate.Register(func(db *mgo.Database) error {
+		if err := db.C("outbox").Create(&mgo.CollectionInfo{
+			ForceIdIndex: true,
+		}); err != nil {
+			return err
+		}
+		var collection = db.C("outbox")
+		if err := collection.EnsureIndexKey("status", "nextattemptat"); err != nil {
+			return err
+		}
+		// finished operations are kept for a week
+		if err := collection.EnsureIndex(mgo.Index{
+			Key:         []string{"doneat"},
+			ExpireAfter: 7 * 24 * time.Hour,
+		}); err != nil {
+			return err
+		}
+		return nil
+	}, func(db *mgo.Database) error {
+		return db.C("outbox").DropCollection()
+	})
+}
@@ -15,11 +15,24 @@ import (
 const (
 	localURL = "localhost:27017"
 
-	CollectionDeployment = "deployment"
-	CollectionService    = "service"
-	CollectionDomain     = "domain"
-	CollectionIngress    = "ingress"
-	CollectionCM         = "configmap"
+	CollectionDeployment  = "deployment"
+	CollectionService     = "service"
+	CollectionDomain      = "domain"
+	CollectionIngress     = "ingress"
+	CollectionCM          = "configmap"
+	CollectionOutbox      = "outbox"
+	CollectionSecret      = "secret"
+	CollectionAudit       = "audit"
+	CollectionWebhook     = "webhook"
+	CollectionDelivery    = "webhook_delivery"
+	CollectionCounter     = "counter"
+	CollectionIdempotency = "idempotency"
+	CollectionPort        = "port"
+	CollectionDomainPin   = "domain_pin"
+	CollectionIngressHost = "ingress_host"
+	CollectionReservation = "host_reservation"
+	CollectionCertificate = "certificate"
+	CollectionLock        = "lock"
 )
 
 type MongoStorage struct {
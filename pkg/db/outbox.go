@@ -0,0 +1,72 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+func (mongo *MongoStorage) CreateOperation(op outbox.Operation) (outbox.Operation, error) {
+	mongo.logger.Debugf("creating outbox operation")
+	var collection = mongo.db.C(CollectionOutbox)
+	if err := collection.Insert(op); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create outbox operation")
+		if mgo.IsDup(err) {
+			return op, rserrors.ErrResourceAlreadyExists()
+		}
+		return op, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return op, nil
+}
+
+// ClaimOperation returns pending operation ready to be applied.
+// Next attempt of claimed operation is postponed by lease, so other workers don't pick it.
+func (mongo *MongoStorage) ClaimOperation(now time.Time, lease time.Duration) (outbox.Operation, error) {
+	mongo.logger.Debugf("claiming outbox operation")
+	var collection = mongo.db.C(CollectionOutbox)
+	var op outbox.Operation
+	_, err := collection.Find(outbox.ClaimSelectQuery(now)).
+		Sort("nextattemptat").
+		Apply(mgo.Change{
+			Update: bson.M{
+				"$set": bson.M{"nextattemptat": now.Add(lease).UTC()},
+				"$inc": bson.M{"attempts": 1},
+			},
+			ReturnNew: true,
+		}, &op)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return op, rserrors.ErrResourceNotExists().AddDetails("no pending operations")
+		}
+		mongo.logger.WithError(err).Errorf("unable to claim outbox operation")
+		return op, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return op, nil
+}
+
+func (mongo *MongoStorage) UpdateOperation(op outbox.Operation) error {
+	mongo.logger.Debugf("updating outbox operation")
+	var collection = mongo.db.C(CollectionOutbox)
+	if err := collection.UpdateId(op.ID, op.UpdateQuery()); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update outbox operation")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(op.ID)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetPendingOperations(nsID string) ([]outbox.Operation, error) {
+	mongo.logger.Debugf("getting pending outbox operations")
+	var collection = mongo.db.C(CollectionOutbox)
+	var ops []outbox.Operation
+	if err := collection.Find(outbox.PendingSelectQuery(nsID)).All(&ops); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get pending outbox operations")
+		return nil, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return ops, nil
+}
@@ -2,32 +2,199 @@ package db
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 
-	"github.com/containerum/kube-client/pkg/model"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 )
 
+// randomPortAttempts -- number of random ports tried before searching for gaps in reserved ports
+const randomPortAttempts = 16
+
 var (
-	rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rnd = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
 )
 
-func (mongo *MongoStorage) GetFreePort(domain string, protocol model.Protocol, minPort, maxPort int) (int, error) {
-	var collection = mongo.db.C(CollectionService)
+// lockedSource makes rand.Source safe for concurrent requests
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (source *lockedSource) Int63() int64 {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	return source.src.Int63()
+}
+
+func (source *lockedSource) Seed(seed int64) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.src.Seed(seed)
+}
+
+// firstFreePort returns least port in range [minPort, maxPort) which is not in sorted taken ports, -1 if range is full
+func firstFreePort(taken []int, minPort, maxPort int) int {
+	var candidate = minPort
+	for _, p := range taken {
+		if p < candidate {
+			continue
+		}
+		if p > candidate {
+			break
+		}
+		candidate++
+	}
+	if candidate >= maxPort {
+		return -1
+	}
+	return candidate
+}
+
+func portsExhausted(alloc port.Allocation) error {
+	return rserrors.ErrPortsExhausted().AddDetailF("domain %v, protocol %v", alloc.Domain, alloc.Protocol)
+}
+
+func portSelectQuery(alloc port.Allocation) bson.M {
+	return bson.M{
+		"domain":   alloc.Domain,
+		"protocol": alloc.Protocol,
+		"port":     alloc.Port,
+	}
+}
+
+// ReservePort tries random ports first, so concurrent reservations rarely collide,
+// then falls back to search of gaps, so reservation terminates when range is full.
+// Unique index on domain, protocol and port guarantees that port is reserved once.
+func (mongo *MongoStorage) ReservePort(alloc port.Allocation, minPort, maxPort int) (port.Allocation, error) {
+	mongo.logger.Debugf("reserving port")
+	var collection = mongo.db.C(CollectionPort)
+	if maxPort <= minPort {
+		return alloc, portsExhausted(alloc)
+	}
+	alloc.CreatedAt = time.Now().UTC()
+	for i := 0; i < randomPortAttempts; i++ {
+		alloc.Port = rnd.Intn(maxPort-minPort) + minPort
+		err := collection.Insert(alloc)
+		if err == nil {
+			return alloc, nil
+		}
+		if !mgo.IsDup(err) {
+			mongo.logger.WithError(err).Errorf("unable to reserve port")
+			return alloc, PipErr{error: err}.ToMongerr().Extract()
+		}
+	}
 	for {
-		var port = rnd.Intn(maxPort-minPort) + minPort
-		// TODO: benchmark and optimize!
-		n, err := collection.Find(bson.M{
-			"service.domain":         domain,
-			"service.ports.port":     port,
-			"deleted":                false,
-			"service.ports.protocol": protocol,
-		}).Count()
-		if err != nil {
-			return -1, err
+		var reserved []struct {
+			Port int `bson:"port"`
 		}
-		if n == 0 {
-			return port, nil
+		if err := collection.Find(bson.M{
+			"domain":   alloc.Domain,
+			"protocol": alloc.Protocol,
+			"port":     bson.M{"$gte": minPort, "$lt": maxPort},
+		}).Select(bson.M{"port": 1}).Sort("port").All(&reserved); err != nil {
+			mongo.logger.WithError(err).Errorf("unable to get reserved ports")
+			return alloc, PipErr{error: err}.ToMongerr().Extract()
 		}
+		var taken = make([]int, 0, len(reserved))
+		for _, r := range reserved {
+			taken = append(taken, r.Port)
+		}
+		alloc.Port = firstFreePort(taken, minPort, maxPort)
+		if alloc.Port < 0 {
+			mongo.logger.Errorf("no free ports left")
+			return alloc, portsExhausted(alloc)
+		}
+		err := collection.Insert(alloc)
+		if err == nil {
+			return alloc, nil
+		}
+		if !mgo.IsDup(err) {
+			mongo.logger.WithError(err).Errorf("unable to reserve port")
+			return alloc, PipErr{error: err}.ToMongerr().Extract()
+		}
+		// port was reserved by concurrent request, search again
+	}
+}
+
+func (mongo *MongoStorage) ClaimPort(alloc port.Allocation) error {
+	mongo.logger.Debugf("claiming port")
+	var collection = mongo.db.C(CollectionPort)
+	alloc.CreatedAt = time.Now().UTC()
+	err := collection.Insert(alloc)
+	if err == nil {
+		return nil
+	}
+	if !mgo.IsDup(err) {
+		mongo.logger.WithError(err).Errorf("unable to claim port")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	var existing port.Allocation
+	if err := collection.Find(portSelectQuery(alloc)).One(&existing); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get port allocation")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	if !existing.SameService(alloc) {
+		mongo.logger.Errorf("port is reserved by another service")
+		return rserrors.ErrPortReserved().AddDetailF("port %v/%v of domain %v", alloc.Port, alloc.Protocol, alloc.Domain)
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ReleasePort(alloc port.Allocation) error {
+	mongo.logger.Debugf("releasing port")
+	var collection = mongo.db.C(CollectionPort)
+	var query = portSelectQuery(alloc)
+	query["namespaceid"] = alloc.NamespaceID
+	query["service"] = alloc.Service
+	if err := collection.Remove(query); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to release port")
+		return PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) ReleaseServicePorts(namespaceID, serviceName string) error {
+	mongo.logger.Debugf("releasing service ports")
+	return mongo.releasePorts(bson.M{"namespaceid": namespaceID, "service": serviceName})
+}
+
+func (mongo *MongoStorage) releasePorts(query bson.M) error {
+	var collection = mongo.db.C(CollectionPort)
+	if _, err := collection.RemoveAll(query); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to release ports")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetPortsUsage(minPort, maxPort int) ([]port.Usage, error) {
+	mongo.logger.Debugf("getting ports usage")
+	var collection = mongo.db.C(CollectionPort)
+	var usage []port.Usage
+	if err := collection.Pipe([]bson.M{
+		{"$match": bson.M{
+			"port": bson.M{"$gte": minPort, "$lt": maxPort},
+		}},
+		{"$group": bson.M{
+			"_id":  bson.M{"domain": "$domain", "protocol": "$protocol"},
+			"used": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"domain":   "$_id.domain",
+			"protocol": "$_id.protocol",
+			"used":     1,
+		}},
+		{"$sort": bson.M{"domain": 1, "protocol": 1}},
+	}).All(&usage); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get ports usage")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	for i := range usage {
+		usage[i].Total = maxPort - minPort
 	}
+	return usage, nil
 }
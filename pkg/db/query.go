@@ -0,0 +1,467 @@
+package db
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	PageQuery    = "page"
+	PerPageQuery = "per_page"
+	// SortQuery -- comma separated field names, "-" prefix means descending order
+	SortQuery = "sort"
+	// SelectorQuery -- label selector, e.g. "team=payments,env!=dev"
+	SelectorQuery = "selector"
+
+	MaxPerPage = 1000
+)
+
+// ListField -- resource field which can be used in list query parameters
+type ListField struct {
+	// Path -- field path in db document
+	Path   string
+	Filter bool
+	Sort   bool
+	// Bool -- filter value is parsed as boolean
+	Bool bool
+	// Selector -- value is parsed as label selector on labels stored at Path
+	Selector bool
+}
+
+// ListFields -- list query fields of resource by query parameter name
+type ListFields map[string]ListField
+
+var (
+	DeploymentListFields = ListFields{
+		"name":        {Path: "deployment.name", Filter: true, Sort: true},
+		"namespace":   {Path: "namespaceid", Filter: true, Sort: true},
+		"owner":       {Path: "deployment.owner", Filter: true, Sort: true},
+		"solution_id": {Path: "deployment.solutionid", Filter: true},
+		"image":       {Path: "deployment.containers.image", Filter: true},
+		"replicas":    {Path: "deployment.replicas", Sort: true},
+		"created_at":  {Path: "deployment.createdat", Sort: true},
+		"selector":    {Path: "labels", Selector: true},
+	}
+	DeploymentVersionListFields = ListFields{
+		"owner":      {Path: "deployment.owner", Filter: true},
+		"image":      {Path: "deployment.containers.image", Filter: true},
+		"active":     {Path: "deployment.active", Filter: true, Bool: true},
+		"failed":     {Path: "failed", Filter: true, Bool: true},
+		"version":    {Path: "deployment.version", Sort: true},
+		"created_at": {Path: "deployment.createdat", Sort: true},
+		"selector":   {Path: "labels", Selector: true},
+	}
+	ServiceListFields = ListFields{
+		"name":        {Path: "service.name", Filter: true, Sort: true},
+		"namespace":   {Path: "namespaceid", Filter: true, Sort: true},
+		"owner":       {Path: "service.owner", Filter: true, Sort: true},
+		"solution_id": {Path: "service.solutionid", Filter: true},
+		"type":        {Path: "type", Filter: true, Sort: true},
+		"deploy":      {Path: "service.deploy", Filter: true},
+		"created_at":  {Path: "service.createdat", Sort: true},
+		"selector":    {Path: "labels", Selector: true},
+	}
+	IngressListFields = ListFields{
+		"name":       {Path: "ingress.name", Filter: true, Sort: true},
+		"namespace":  {Path: "namespaceid", Filter: true, Sort: true},
+		"owner":      {Path: "ingress.owner", Filter: true, Sort: true},
+		"host":       {Path: "ingress.rules.host", Filter: true},
+		"created_at": {Path: "ingress.createdat", Sort: true},
+		"selector":   {Path: "labels", Selector: true},
+	}
+	ConfigMapListFields = ListFields{
+		"name":       {Path: "configmap.name", Filter: true, Sort: true},
+		"namespace":  {Path: "namespaceid", Filter: true, Sort: true},
+		"owner":      {Path: "configmap.owner", Filter: true, Sort: true},
+		"created_at": {Path: "configmap.createdat", Sort: true},
+		"selector":   {Path: "labels", Selector: true},
+	}
+	ConfigMapVersionListFields = ListFields{
+		"owner":      {Path: "configmap.owner", Filter: true},
+		"active":     {Path: "active", Filter: true, Bool: true},
+		"version":    {Path: "version", Sort: true},
+		"created_at": {Path: "configmap.createdat", Sort: true},
+		"selector":   {Path: "labels", Selector: true},
+	}
+	AuditListFields = ListFields{
+		"kind":    {Path: "kind", Filter: true, Sort: true},
+		"name":    {Path: "name", Filter: true, Sort: true},
+		"action":  {Path: "action", Filter: true},
+		"outcome": {Path: "outcome", Filter: true},
+		"time":    {Path: "time", Sort: true},
+	}
+	SecretListFields = ListFields{
+		"name":       {Path: "secret.name", Filter: true, Sort: true},
+		"namespace":  {Path: "namespaceid", Filter: true, Sort: true},
+		"owner":      {Path: "secret.owner", Filter: true, Sort: true},
+		"created_at": {Path: "secret.createdat", Sort: true},
+	}
+)
+
+// ListQuery -- filters, sort keys and page of list request.
+// Nil query selects all documents in default order.
+type ListQuery struct {
+	// Filters -- required values by document path, value matches array field if any element is equal to it
+	Filters bson.M
+	// Sort -- document paths, "-" prefix means descending order
+	Sort []string
+	// Page -- nil means all documents
+	Page *PageInfo
+	// Selector -- label selector on labels stored at SelectorPath
+	Selector     labels.Selector
+	SelectorPath string
+}
+
+// ParseListQuery makes list query of request query parameters.
+// Pagination is enabled by "page" or "per_page" parameter, other parameters not described by fields are ignored.
+func ParseListQuery(values url.Values, fields ListFields) (*ListQuery, error) {
+	var query = &ListQuery{Filters: bson.M{}}
+
+	for name, field := range fields {
+		value, ok := values[name]
+		if !ok || len(value) == 0 {
+			continue
+		}
+		if field.Selector {
+			selector, err := labels.ParseSelector(value[0])
+			if err != nil {
+				return nil, rserrors.ErrValidation().AddDetailF("invalid %v: %v", name, err)
+			}
+			query.Selector, query.SelectorPath = selector, field.Path
+			continue
+		}
+		if !field.Filter {
+			continue
+		}
+		if !field.Bool {
+			query.Filters[field.Path] = value[0]
+			continue
+		}
+		b, err := strconv.ParseBool(value[0])
+		if err != nil {
+			return nil, rserrors.ErrValidation().AddDetailF("invalid %v value: %v", name, value[0])
+		}
+		query.Filters[field.Path] = b
+	}
+
+	if sortKeys := values.Get(SortQuery); sortKeys != "" {
+		for _, key := range strings.Split(sortKeys, ",") {
+			var desc = strings.HasPrefix(key, "-")
+			field, ok := fields[strings.TrimPrefix(key, "-")]
+			if !ok || !field.Sort {
+				return nil, rserrors.ErrValidation().AddDetailF("unable to sort by %v", key)
+			}
+			if desc {
+				query.Sort = append(query.Sort, "-"+field.Path)
+			} else {
+				query.Sort = append(query.Sort, field.Path)
+			}
+		}
+	}
+
+	page, hasPage := values[PageQuery]
+	perPage, hasPerPage := values[PerPageQuery]
+	if hasPage || hasPerPage {
+		query.Page = &PageInfo{Page: 1, DefaultPerPage: 100}
+		var err error
+		if hasPage {
+			if query.Page.Page, err = strconv.Atoi(page[0]); err != nil || query.Page.Page < 1 {
+				return nil, rserrors.ErrValidation().AddDetailF("invalid %v value: %v", PageQuery, page[0])
+			}
+		}
+		if hasPerPage {
+			if query.Page.PerPage, err = strconv.Atoi(perPage[0]); err != nil || query.Page.PerPage < 1 || query.Page.PerPage > MaxPerPage {
+				return nil, rserrors.ErrValidation().AddDetailF("%v must be between 1 and %v", PerPageQuery, MaxPerPage)
+			}
+		}
+	}
+	return query, nil
+}
+
+// findList runs list query with selector, result must be a pointer to slice.
+// Returns number of documents matched before pagination.
+func findList(collection *mgo.Collection, selector bson.M, query *ListQuery, result interface{}, defaultSort ...string) (int, error) {
+	var sortKeys = defaultSort
+	if query != nil {
+		for path, value := range query.Filters {
+			selector[path] = value
+		}
+		if len(query.Selector) > 0 {
+			selector["$and"] = selectorConditions(query.SelectorPath, query.Selector)
+		}
+		if len(query.Sort) > 0 {
+			sortKeys = query.Sort
+		}
+	}
+	var find = collection.Find(selector)
+	if query == nil || query.Page == nil {
+		if len(sortKeys) > 0 {
+			find = find.Sort(sortKeys...)
+		}
+		if err := find.All(result); err != nil {
+			return 0, err
+		}
+		return reflect.ValueOf(result).Elem().Len(), nil
+	}
+
+	total, err := find.Count()
+	if err != nil {
+		return 0, err
+	}
+	// _id makes order of pages stable if sort keys are equal
+	find = find.Sort(append(append([]string{}, sortKeys...), "_id")...)
+	return total, Paginate(find, query.Page).All(result)
+}
+
+// applyListQuery filters, sorts and paginates list in the same way as findList does, list must be a pointer to slice.
+// Items are compared by their bson representation, so memory storage uses the same paths as mongo.
+func applyListQuery(list interface{}, query *ListQuery) int {
+	var items = reflect.ValueOf(list).Elem()
+	if query == nil {
+		return items.Len()
+	}
+
+	var docs = make([]bson.D, 0, items.Len())
+	var matched = reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		var doc bson.D
+		clone(items.Index(i).Interface(), &doc)
+		if matchFilters(doc, query.Filters) && matchSelector(doc, query.SelectorPath, query.Selector) {
+			docs = append(docs, doc)
+			matched = reflect.Append(matched, items.Index(i))
+		}
+	}
+
+	if len(query.Sort) > 0 {
+		var order = make([]int, len(docs))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			for _, key := range query.Sort {
+				var desc = strings.HasPrefix(key, "-")
+				var path = strings.Split(strings.TrimPrefix(key, "-"), ".")
+				c := compareValues(firstValue(lookupPath(docs[order[i]], path)), firstValue(lookupPath(docs[order[j]], path)))
+				if c != 0 {
+					return (c < 0) != desc
+				}
+			}
+			return false
+		})
+		var sorted = reflect.MakeSlice(items.Type(), 0, len(order))
+		for _, i := range order {
+			sorted = reflect.Append(sorted, matched.Index(i))
+		}
+		matched = sorted
+	}
+
+	var total = matched.Len()
+	if query.Page != nil {
+		var limit, offset = query.Page.Init()
+		if offset > total {
+			offset = total
+		}
+		var end = offset + limit
+		if end > total {
+			end = total
+		}
+		matched = matched.Slice(offset, end)
+	}
+	items.Set(matched)
+	return total
+}
+
+func matchFilters(doc bson.D, filters bson.M) bool {
+	for path, expected := range filters {
+		var found bool
+		for _, value := range lookupPath(doc, strings.Split(path, ".")) {
+			if compareValues(value, expected) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorConditions makes conditions on labels stored as list of key/value documents, indexed by key and value.
+// Negative requirements match documents without the label, like in kubernetes.
+func selectorConditions(path string, selector labels.Selector) []bson.M {
+	var conditions = make([]bson.M, 0, len(selector))
+	for _, req := range selector {
+		var elem = bson.M{"key": req.Key}
+		switch req.Operator {
+		case labels.Equals, labels.NotEquals:
+			elem["value"] = req.Values[0]
+		case labels.In, labels.NotIn:
+			elem["value"] = bson.M{"$in": req.Values}
+		}
+		var match = bson.M{"$elemMatch": elem}
+		switch req.Operator {
+		case labels.NotEquals, labels.NotIn, labels.DoesNotExist:
+			conditions = append(conditions, bson.M{path: bson.M{"$not": match}})
+		default:
+			conditions = append(conditions, bson.M{path: match})
+		}
+	}
+	return conditions
+}
+
+func matchSelector(doc bson.D, path string, selector labels.Selector) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	var set = labels.Labels{}
+	for _, value := range lookupPath(doc, strings.Split(path, ".")) {
+		if elem, ok := value.(bson.D); ok {
+			var p struct {
+				Key   string `bson:"key"`
+				Value string `bson:"value"`
+			}
+			clone(elem, &p)
+			set[p.Key] = p.Value
+		}
+	}
+	return selector.Matches(set)
+}
+
+// lookupPath returns values of document at dotted path, arrays on the path are expanded like mongo does
+func lookupPath(value interface{}, path []string) []interface{} {
+	if arr, ok := value.([]interface{}); ok {
+		var result []interface{}
+		for _, elem := range arr {
+			result = append(result, lookupPath(elem, path)...)
+		}
+		if len(path) == 0 {
+			return append([]interface{}{value}, result...)
+		}
+		return result
+	}
+	if len(path) == 0 {
+		return []interface{}{value}
+	}
+	doc, ok := value.(bson.D)
+	if !ok {
+		return nil
+	}
+	for _, elem := range doc {
+		if elem.Name == path[0] {
+			return lookupPath(elem.Value, path[1:])
+		}
+	}
+	return nil
+}
+
+func firstValue(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// compareValues compares bson values, values of different types are ordered by type like in mongo
+func compareValues(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case !a:
+			return -1
+		default:
+			return 1
+		}
+	case time.Time:
+		switch {
+		case a.Before(b.(time.Time)):
+			return -1
+		case a.After(b.(time.Time)):
+			return 1
+		default:
+			return 0
+		}
+	case bson.D:
+		var db = b.(bson.D)
+		for i := 0; i < len(a) && i < len(db); i++ {
+			if c := strings.Compare(a[i].Name, db[i].Name); c != 0 {
+				return c
+			}
+			if c := compareValues(a[i].Value, db[i].Value); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(db)
+	case []interface{}:
+		var arrB = b.([]interface{})
+		for i := 0; i < len(a) && i < len(arrB); i++ {
+			if c := compareValues(a[i], arrB[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(arrB)
+	}
+	if fa, ok := toFloat(a); ok {
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+	}
+	return 0
+}
+
+func typeOrder(value interface{}) int {
+	if _, ok := toFloat(value); ok {
+		return 1
+	}
+	switch value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 2
+	case bson.D:
+		return 3
+	case []interface{}:
+		return 4
+	case bool:
+		return 5
+	case time.Time:
+		return 6
+	default:
+		return 7
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/blang/semver"
+	"github.com/containerum/cherry"
+	"github.com/containerum/kube-client/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListQuery(t *testing.T) {
+	query, err := ParseListQuery(url.Values{
+		"owner":    {"user"},
+		"active":   {"true"},
+		"sort":     {"-version,created_at"},
+		"per_page": {"10"},
+		"dry_run":  {"true"},
+	}, DeploymentVersionListFields)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", query.Filters["deployment.owner"])
+	assert.Equal(t, true, query.Filters["deployment.active"])
+	assert.Equal(t, []string{"-deployment.version", "deployment.createdat"}, query.Sort)
+	if assert.NotNil(t, query.Page) {
+		limit, offset := query.Page.Init()
+		assert.Equal(t, 10, limit)
+		assert.Equal(t, 0, offset)
+	}
+
+	query, err = ParseListQuery(nil, DeploymentListFields)
+	assert.NoError(t, err)
+	assert.Nil(t, query.Page)
+
+	for _, values := range []url.Values{
+		{"active": {"yes please"}},
+		{"sort": {"image"}},
+		{"sort": {"unknown"}},
+		{"page": {"0"}},
+		{"per_page": {"100000"}},
+	} {
+		_, err = ParseListQuery(values, DeploymentVersionListFields)
+		assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "%v: %v", values, err)
+	}
+}
+
+func TestMemoryListQuery(t *testing.T) {
+	var mem = NewMemory(nil)
+	for i, version := range []string{"1.0.0", "1.10.0", "1.2.0", "2.0.0"} {
+		var image = "nginx"
+		if i%2 == 1 {
+			image = "redis"
+		}
+		_, err := mem.CreateDeployment(deployment.ResourceDeploy{
+			Deployment: model.Deployment{
+				Name:       "app",
+				Version:    semver.MustParse(version),
+				Active:     version == "2.0.0",
+				Replicas:   1,
+				Containers: []model.Container{{Name: "c", Image: image}},
+			},
+			NamespaceID: "ns",
+		})
+		assert.NoError(t, err)
+	}
+	versions := func(list deployment.ListDeploy) []string {
+		var result []string
+		for _, depl := range list {
+			result = append(result, depl.Version.String())
+		}
+		return result
+	}
+
+	list, total, err := mem.GetDeploymentVersionsList("ns", "app", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"2.0.0", "1.10.0", "1.2.0", "1.0.0"}, versions(list))
+
+	query, err := ParseListQuery(url.Values{"sort": {"version"}, "page": {"2"}, "per_page": {"3"}}, DeploymentVersionListFields)
+	assert.NoError(t, err)
+	list, total, err = mem.GetDeploymentVersionsList("ns", "app", query)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"2.0.0"}, versions(list))
+
+	// filter matches any container of deployment
+	query, err = ParseListQuery(url.Values{"image": {"redis"}, "active": {"false"}}, DeploymentVersionListFields)
+	assert.NoError(t, err)
+	list, total, err = mem.GetDeploymentVersionsList("ns", "app", query)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"1.10.0"}, versions(list))
+
+	query, err = ParseListQuery(url.Values{"page": {"5"}}, DeploymentListFields)
+	assert.NoError(t, err)
+	list, total, err = mem.GetDeploymentList("ns", query)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Empty(t, list)
+}
+
+func TestLabelSelector(t *testing.T) {
+	var mem = NewMemory(nil)
+	for name, lbls := range map[string]labels.Labels{
+		"payments-api": {"team": "payments", "env": "prod", "example.com/tier": "api"},
+		"payments-dev": {"team": "payments", "env": "dev"},
+		"search":       {"team": "search", "env": "prod", "canary": ""},
+		"unlabeled":    nil,
+	} {
+		_, err := mem.CreateService(service.ResourceService{
+			Service:     model.Service{Name: name},
+			Metadata:    labels.Metadata{Labels: lbls},
+			NamespaceID: "ns",
+		})
+		assert.NoError(t, err)
+	}
+
+	svc, err := mem.GetService("ns", "payments-api")
+	assert.NoError(t, err)
+	assert.Equal(t, "api", svc.Labels["example.com/tier"], "label keys with dots must be stored")
+
+	for selector, expected := range map[string][]string{
+		"team=payments":                 {"payments-api", "payments-dev"},
+		"team==payments,env!=dev":       {"payments-api"},
+		"env!=dev":                      {"payments-api", "search", "unlabeled"},
+		"team in (payments, search)":    {"payments-api", "payments-dev", "search"},
+		"team notin (payments),!canary": {"unlabeled"},
+		"canary":                        {"search"},
+		"example.com/tier":              {"payments-api"},
+	} {
+		query, err := ParseListQuery(url.Values{"selector": {selector}, "sort": {"name"}}, ServiceListFields)
+		if !assert.NoError(t, err, selector) {
+			continue
+		}
+		list, total, err := mem.GetServiceList("ns", query)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), total, selector)
+		var names []string
+		for _, svc := range list {
+			names = append(names, svc.Name)
+		}
+		assert.Equal(t, expected, names, selector)
+	}
+
+	for _, selector := range []string{"=payments", "team in ()", "team in (a,", "Team/x=y", "team=-bad-", ","} {
+		_, err := ParseListQuery(url.Values{"selector": {selector}}, ServiceListFields)
+		assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "%v: %v", selector, err)
+	}
+	// secrets have no labels
+	query, err := ParseListQuery(url.Values{"selector": {"team=payments"}}, SecretListFields)
+	assert.NoError(t, err)
+	assert.Empty(t, query.Selector)
+
+	assert.Empty(t, labels.Metadata{Labels: labels.Labels{"example.com/team": "payments", "env": ""}}.Validate())
+	assert.Len(t, labels.Metadata{
+		Labels:      labels.Labels{"-team": "payments", "env": "not valid"},
+		Annotations: labels.Labels{"note": "any text is allowed"},
+	}.Validate(), 2)
+}
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/util/strset"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo/bson"
 )
@@ -31,3 +34,22 @@ func (mongo *MongoStorage) GetNamespaceResourcesLimits(namespaceID string) (kubt
 	}).One(&res)
 	return res, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
 }
+
+// GetNamespaces returns IDs of namespaces which have any alive resources
+func (mongo *MongoStorage) GetNamespaces() ([]string, error) {
+	mongo.logger.Debugf("getting namespaces")
+	var namespaces = strset.Set{}
+	for _, collectionName := range []string{CollectionDeployment, CollectionService, CollectionIngress, CollectionCM} {
+		var list []string
+		if err := mongo.db.C(collectionName).Find(bson.M{
+			"deleted": false,
+		}).Distinct("namespaceid", &list); err != nil {
+			mongo.logger.WithError(err).Errorf("unable to get namespaces")
+			return nil, PipErr{error: err}.ToMongerr().Extract()
+		}
+		namespaces = namespaces.AddSlice(list)
+	}
+	var list = namespaces.Items()
+	sort.Strings(list)
+	return list, nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const resourceVersionCounter = "resourceversion"
+
+// nextResourceVersion increments counter shared by all resources.
+// Resource gets new version on every change, so versions of one resource only grow, even if other version of deployment or configmap becomes active.
+func (mongo *MongoStorage) nextResourceVersion() (int64, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	_, err := mongo.db.C(CollectionCounter).FindId(resourceVersionCounter).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"value": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get next resource version")
+		return 0, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return counter.Value, nil
+}
+
+// casSelectQuery adds resource version to select query, so update is applied only if resource wasn't changed since it was read.
+// Zero version selects resource of any version.
+func casSelectQuery(query interface{}, version int64) interface{} {
+	sel := bson.M{}
+	for k, v := range query.(bson.M) {
+		sel[k] = v
+	}
+	if version != 0 {
+		sel["resourceversion"] = version
+	}
+	return sel
+}
+
+// withResourceVersion adds new resource version to "$set" of update query
+func withResourceVersion(update interface{}, version int64) interface{} {
+	upd := update.(bson.M)
+	set, _ := upd["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+		upd["$set"] = set
+	}
+	set["resourceversion"] = version
+	return upd
+}
+
+// versionConflict is called when compare-and-set update selected nothing.
+// It returns conflict error if resource exists, so its version was changed by other request.
+func versionConflict(collection *mgo.Collection, query interface{}, name string) error {
+	n, err := collection.Find(query).Count()
+	if err != nil {
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	if n == 0 {
+		return rserrors.ErrResourceNotExists().AddDetails(name)
+	}
+	return rserrors.ErrResourceVersionConflict().AddDetails(name)
+}
+
+// nextResourceVersion returns next value of resource version counter, mem.mu must be locked
+func (mem *MemoryStorage) nextResourceVersion() int64 {
+	mem.resourceVersion++
+	return mem.resourceVersion
+}
+
+// checkResourceVersion returns conflict error if stored resource has other version than expected one.
+// Zero expected version matches any resource version.
+func checkResourceVersion(stored, expected int64, name string) error {
+	if expected != 0 && stored != expected {
+		return rserrors.ErrResourceVersionConflict().AddDetails(name)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/blang/semver"
+)
+
+// sealedStorage seals configmap data before it is written to storage and opens it after it is read.
+// Callers always work with plaintext data.
+type sealedStorage struct {
+	Storage
+	box *secretbox.Box
+}
+
+// WithSealedConfigMaps wraps storage, so configmap data is stored sealed with box.
+// Nil box keeps data in plaintext with marker, it is used in tests.
+func WithSealedConfigMaps(storage Storage, box *secretbox.Box) Storage {
+	return &sealedStorage{Storage: storage, box: box}
+}
+
+func (s *sealedStorage) open(cm configmap.ResourceConfigMap) (configmap.ResourceConfigMap, error) {
+	data, err := s.box.Open(cm.Data)
+	if err != nil {
+		return cm, err
+	}
+	cm.Data = data
+	return cm, nil
+}
+
+func (s *sealedStorage) openList(list configmap.ListConfigMaps, total int, err error) (configmap.ListConfigMaps, int, error) {
+	if err != nil {
+		return list, total, err
+	}
+	for i := range list {
+		if list[i], err = s.open(list[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return list, total, nil
+}
+
+func (s *sealedStorage) GetConfigMap(namespaceID, cmName string) (configmap.ResourceConfigMap, error) {
+	cm, err := s.Storage.GetConfigMap(namespaceID, cmName)
+	if err != nil {
+		return cm, err
+	}
+	return s.open(cm)
+}
+
+func (s *sealedStorage) GetConfigMapVersion(namespaceID, cmName string, version semver.Version) (configmap.ResourceConfigMap, error) {
+	cm, err := s.Storage.GetConfigMapVersion(namespaceID, cmName, version)
+	if err != nil {
+		return cm, err
+	}
+	return s.open(cm)
+}
+
+func (s *sealedStorage) GetConfigMapVersionsList(namespaceID, cmName string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	return s.openList(s.Storage.GetConfigMapVersionsList(namespaceID, cmName, query))
+}
+
+func (s *sealedStorage) GetConfigMapList(namespaceID string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	return s.openList(s.Storage.GetConfigMapList(namespaceID, query))
+}
+
+func (s *sealedStorage) GetSelectedConfigMaps(namespaceID []string, query *ListQuery) (configmap.ListConfigMaps, int, error) {
+	return s.openList(s.Storage.GetSelectedConfigMaps(namespaceID, query))
+}
+
+func (s *sealedStorage) CreateConfigMap(cm configmap.ResourceConfigMap) (configmap.ResourceConfigMap, error) {
+	var data = cm.Data
+	sealed, err := s.box.Seal(data)
+	if err != nil {
+		return cm, err
+	}
+	cm.Data = sealed
+	created, err := s.Storage.CreateConfigMap(cm)
+	created.Data = data
+	return created, err
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/kube-client/pkg/model"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+func (mongo *MongoStorage) GetSecret(namespaceID, secretName string) (secret.ResourceSecret, error) {
+	mongo.logger.Debugf("getting secret")
+	var collection = mongo.db.C(CollectionSecret)
+	var result secret.ResourceSecret
+	var err error
+	if err = collection.Find(secret.OneSelectQuery(namespaceID, secretName)).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get secret")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetails(secretName)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetSecretList(namespaceID string, query *ListQuery) (secret.ListSecrets, int, error) {
+	mongo.logger.Debugf("getting secrets list")
+	var collection = mongo.db.C(CollectionSecret)
+	result := make(secret.ListSecrets, 0)
+	total, err := findList(collection, bson.M{
+		"namespaceid": namespaceID,
+		"deleted":     false,
+	}, query, &result)
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get secrets list")
+		return result, 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, total, nil
+}
+
+func (mongo *MongoStorage) GetSelectedSecrets(namespaceID []string, query *ListQuery) (secret.ListSecrets, int, error) {
+	mongo.logger.Debugf("getting selected secrets")
+	var collection = mongo.db.C(CollectionSecret)
+	list := make(secret.ListSecrets, 0)
+	total, err := findList(collection, bson.M{
+		"namespaceid": bson.M{
+			"$in": namespaceID,
+		},
+		"deleted": false,
+	}, query, &list)
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get secrets")
+		if err == mgo.ErrNotFound {
+			return list, 0, rserrors.ErrResourceNotExists()
+		}
+		return list, 0, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return list, total, nil
+}
+
+// If ID is empty, then generates UUID4 and uses it
+func (mongo *MongoStorage) CreateSecret(secret secret.ResourceSecret) (secret.ResourceSecret, error) {
+	mongo.logger.Debugf("creating secret")
+	var collection = mongo.db.C(CollectionSecret)
+	if secret.ID == "" {
+		secret.ID = uuid.New().String()
+	}
+	secret.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return secret, err
+	}
+	secret.ResourceVersion = version
+	if err := collection.Insert(secret); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create secret")
+		if mgo.IsDup(err) {
+			return secret, rserrors.ErrResourceAlreadyExists()
+		}
+		return secret, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return secret, nil
+}
+
+func (mongo *MongoStorage) UpdateSecret(upd secret.ResourceSecret) (secret.ResourceSecret, error) {
+	mongo.logger.Debugf("updating secret")
+	var collection = mongo.db.C(CollectionSecret)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return upd, err
+	}
+	if err := collection.Update(casSelectQuery(upd.OneSelectQuery(), upd.ResourceVersion), withResourceVersion(upd.UpdateQuery(), version)); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update secret")
+		if err == mgo.ErrNotFound {
+			return upd, versionConflict(collection, upd.OneSelectQuery(), upd.Name)
+		}
+		return upd, PipErr{error: err}.ToMongerr().Extract()
+	}
+	upd.ResourceVersion = version
+	return upd, nil
+}
+
+func (mongo *MongoStorage) DeleteSecret(namespaceID, name string) error {
+	mongo.logger.Debugf("deleting secret")
+	var collection = mongo.db.C(CollectionSecret)
+	err := collection.Update(secret.ResourceSecret{
+		Secret: model.Secret{
+			Name: name,
+		},
+		NamespaceID: namespaceID,
+	}.OneSelectQuery(),
+		bson.M{
+			"$set": bson.M{"deleted": true,
+				"secret.deletedat": time.Now().UTC().Format(time.RFC3339)},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete secret")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) DeleteAllSecretsInNamespace(namespaceID string) error {
+	mongo.logger.Debugf("deleting all secrets in namespace")
+	var collection = mongo.db.C(CollectionSecret)
+	_, err := collection.UpdateAll(secret.ResourceSecret{
+		NamespaceID: namespaceID,
+	}.AllSelectQuery(),
+		bson.M{
+			"$set": bson.M{"deleted": true,
+				"secret.deletedat": time.Now().UTC().Format(time.RFC3339)},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete secrets")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) DeleteAllSecretsByOwner(owner string) error {
+	mongo.logger.Debugf("deleting all user secrets")
+	var collection = mongo.db.C(CollectionSecret)
+	_, err := collection.UpdateAll(secret.ResourceSecret{
+		Secret: model.Secret{Owner: owner},
+	}.AllSelectOwnerQuery(),
+		bson.M{
+			"$set": bson.M{"deleted": true,
+				"secret.deletedat": time.Now().UTC().Format(time.RFC3339)},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete secrets")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) RestoreSecret(namespaceID, name string) error {
+	mongo.logger.Debugf("restoring secret")
+	var collection = mongo.db.C(CollectionSecret)
+	err := collection.Update(secret.ResourceSecret{
+		Secret: model.Secret{
+			Name: name,
+		},
+		NamespaceID: namespaceID,
+	}.OneSelectDeletedQuery(),
+		bson.M{
+			"$set": bson.M{"deleted": false,
+				"secret.deletedat": ""},
+		})
+	if err != nil {
+		mongo.logger.WithError(err).Errorf("unable to restore secret")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) CountSecrets(owner string) (int, error) {
+	mongo.logger.Debugf("counting secrets")
+	var collection = mongo.db.C(CollectionSecret)
+	n, err := collection.Find(bson.M{
+		"secret.owner": owner,
+		"deleted":      false,
+	}).Count()
+	if err != nil {
+		return 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return n, nil
+}
+
+func (mongo *MongoStorage) CountAllSecrets() (int, error) {
+	mongo.logger.Debugf("counting all secrets")
+	var collection = mongo.db.C(CollectionSecret)
+	n, err := collection.Find(bson.M{
+		"deleted": false,
+	}).Count()
+	if err != nil {
+		return 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return n, nil
+}
@@ -27,18 +27,19 @@ func (mongo *MongoStorage) GetService(namespaceID, serviceName string) (service.
 	return result, nil
 }
 
-func (mongo *MongoStorage) GetServiceList(namespaceID string) (service.ListService, error) {
+func (mongo *MongoStorage) GetServiceList(namespaceID string, query *ListQuery) (service.ListService, int, error) {
 	mongo.logger.Debugf("getting services list")
 	var collection = mongo.db.C(CollectionService)
 	result := make(service.ListService, 0)
-	if err := collection.Find(bson.M{
+	total, err := findList(collection, bson.M{
 		"namespaceid": namespaceID,
 		"deleted":     false,
-	}).All(&result); err != nil {
+	}, query, &result)
+	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to get service list")
-		return result, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+		return result, 0, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
 	}
-	return result, nil
+	return result, total, nil
 }
 
 // If ID is empty, then generates UUID4 and uses it
@@ -49,6 +50,11 @@ func (mongo *MongoStorage) CreateService(service service.ResourceService) (servi
 		service.ID = uuid.New().String()
 	}
 	service.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return service, err
+	}
+	service.ResourceVersion = version
 	if err := collection.Insert(service); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to create service")
 		if mgo.IsDup(err) {
@@ -62,10 +68,18 @@ func (mongo *MongoStorage) CreateService(service service.ResourceService) (servi
 func (mongo *MongoStorage) UpdateService(upd service.ResourceService) (service.ResourceService, error) {
 	mongo.logger.Debugf("updating service")
 	var collection = mongo.db.C(CollectionService)
-	if err := collection.Update(upd.OneSelectQuery(), upd.UpdateQuery()); err != nil {
+	version, err := mongo.nextResourceVersion()
+	if err != nil {
+		return upd, err
+	}
+	if err := collection.Update(casSelectQuery(upd.OneSelectQuery(), upd.ResourceVersion), withResourceVersion(upd.UpdateQuery(), version)); err != nil {
 		mongo.logger.WithError(err).Errorf("unable to update service")
+		if err == mgo.ErrNotFound {
+			return upd, versionConflict(collection, upd.OneSelectQuery(), upd.Name)
+		}
 		return upd, PipErr{error: err}.ToMongerr().Extract()
 	}
+	upd.ResourceVersion = version
 	return upd, nil
 }
 
@@ -89,24 +103,38 @@ func (mongo *MongoStorage) DeleteService(namespaceID, name string) error {
 		}
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return nil
+	return mongo.ReleaseServicePorts(namespaceID, name)
 }
 
 func (mongo *MongoStorage) RestoreService(namespaceID, name string) error {
 	mongo.logger.Debugf("restoring service")
 	var collection = mongo.db.C(CollectionService)
-	err := collection.Update(service.ResourceService{
+	var query = service.ResourceService{
 		Service: model.Service{
 			Name: name,
 		},
 		NamespaceID: namespaceID,
-	}.OneSelectDeletedQuery(),
+	}.OneSelectDeletedQuery()
+	var deleted service.ResourceService
+	if err := collection.Find(query).One(&deleted); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to restore service")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	// ports could be reserved by another service after deletion
+	if err := mongo.claimServicePorts(deleted); err != nil {
+		return err
+	}
+	err := collection.Update(bson.M{"_id": deleted.ID},
 		bson.M{
-			"$set": bson.M{"deleted": true,
+			"$set": bson.M{"deleted": false,
 				"service.deletedat": ""},
 		})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to restore service")
+		mongo.ReleaseServicePorts(namespaceID, name)
 		if err == mgo.ErrNotFound {
 			return rserrors.ErrResourceNotExists().AddDetails(name)
 		}
@@ -115,6 +143,20 @@ func (mongo *MongoStorage) RestoreService(namespaceID, name string) error {
 	return nil
 }
 
+// claimServicePorts claims all external ports of service or none of them
+func (mongo *MongoStorage) claimServicePorts(svc service.ResourceService) error {
+	var allocs = svc.PortAllocations()
+	for i, alloc := range allocs {
+		if err := mongo.ClaimPort(alloc); err != nil {
+			for _, claimed := range allocs[:i] {
+				mongo.ReleasePort(claimed)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func (mongo *MongoStorage) DeleteAllServicesInNamespace(namespaceID string) error {
 	mongo.logger.Debugf("deleting all services in namespace")
 	var collection = mongo.db.C(CollectionService)
@@ -129,7 +171,7 @@ func (mongo *MongoStorage) DeleteAllServicesInNamespace(namespaceID string) erro
 		mongo.logger.WithError(err).Errorf("unable to delete service")
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return nil
+	return mongo.releasePorts(bson.M{"namespaceid": namespaceID})
 }
 
 func (mongo *MongoStorage) DeleteAllServicesByOwner(owner string) error {
@@ -146,12 +188,21 @@ func (mongo *MongoStorage) DeleteAllServicesByOwner(owner string) error {
 		mongo.logger.WithError(err).Errorf("unable to delete services")
 		return PipErr{error: err}.ToMongerr().Extract()
 	}
-	return nil
+	return mongo.releasePorts(bson.M{"owner": owner})
 }
 
 func (mongo *MongoStorage) DeleteAllServicesBySolutionName(nsID, solution string) error {
 	mongo.logger.Debugf("deleting all solutions services")
 	var collection = mongo.db.C(CollectionService)
+	var alive []service.ResourceService
+	if err := collection.Find(bson.M{
+		"namespaceid":        nsID,
+		"service.solutionid": solution,
+		"deleted":            false,
+	}).Select(bson.M{"service.name": 1}).All(&alive); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get solution services")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
 	_, err := collection.UpdateAll(bson.M{
 		"namespaceid":        nsID,
 		"service.solutionid": solution,
@@ -162,8 +213,27 @@ func (mongo *MongoStorage) DeleteAllServicesBySolutionName(nsID, solution string
 		})
 	if err != nil {
 		mongo.logger.WithError(err).Errorf("unable to delete solution services")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	var names = make([]string, 0, len(alive))
+	for _, svc := range alive {
+		names = append(names, svc.Name)
+	}
+	return mongo.releasePorts(bson.M{"namespaceid": nsID, "service": bson.M{"$in": names}})
+}
+
+func (mongo *MongoStorage) GetServicesByDomain(domainName string) (service.ListService, error) {
+	mongo.logger.Debugf("getting services by domain")
+	var collection = mongo.db.C(CollectionService)
+	result := make(service.ListService, 0)
+	if err := collection.Find(bson.M{
+		"service.domain": domainName,
+		"deleted":        false,
+	}).Sort("namespaceid", "service.name").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get services by domain")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
 	}
-	return PipErr{error: err}.ToMongerr().Extract()
+	return result, nil
 }
 
 func (mongo *MongoStorage) CountServices(owner string) (stats.Service, error) {
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/stats"
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	"github.com/blang/semver"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+)
+
+// Storage is an interface to resource-service database
+type Storage interface {
+	Init() error
+	Close() error
+	IsClosed() bool
+
+	DeploymentStorage
+	ServiceStorage
+	IngressStorage
+	ConfigMapStorage
+	SecretStorage
+	DomainStorage
+	OutboxStorage
+	AuditStorage
+	WebhookStorage
+	IdempotencyStorage
+	PortStorage
+	HostStorage
+	CertificateStorage
+	LockStorage
+
+	GetNamespaceResourcesLimits(namespaceID string) (kubtypes.Resource, error)
+	GetNamespaces() ([]string, error)
+}
+
+type DeploymentStorage interface {
+	GetDeployment(namespaceID, deploymentName string) (deployment.ResourceDeploy, error)
+	GetDeploymentVersion(namespaceID, deploymentName string, version semver.Version) (deployment.ResourceDeploy, error)
+	GetDeploymentLatestVersion(namespaceID, deploymentName string) (deployment.ResourceDeploy, error)
+	GetDeploymentVersionsList(namespaceID, deploymentName string, query *ListQuery) (deployment.ListDeploy, int, error)
+	GetDeploymentList(namespaceID string, query *ListQuery) (deployment.ListDeploy, int, error)
+	CreateDeployment(deployment deployment.ResourceDeploy) (deployment.ResourceDeploy, error)
+	UpdateActiveDeployment(upd deployment.ResourceDeploy) error
+	GetCanaryDeployment(namespaceID, deploymentName string) (deployment.ResourceDeploy, error)
+	UpdateCanaryDeployment(upd deployment.ResourceDeploy) error
+	UpdateDeploymentVersion(namespace, name string, oldversion, newversion semver.Version) error
+	DeleteDeployment(namespace, name string) error
+	ActivateDeployment(namespace, name string, version semver.Version) error
+	ActivateDeploymentWOVersion(namespace, name string) error
+	// DeactivateDeployment deactivates active version of deployment if it has provided resource version
+	DeactivateDeployment(namespace, name string, resourceVersion int64) error
+	SetDeploymentPreviousVersion(namespace, name string, version, previous semver.Version) error
+	SetDeploymentVersionFailed(namespace, name string, version semver.Version, failed bool) error
+	DeleteDeploymentVersion(namespace, name string, version semver.Version) error
+	RestoreDeployment(namespace, name string) error
+	DeleteAllDeploymentsInNamespace(namespace string) error
+	DeleteAllDeploymentsByOwner(owner string) error
+	DeleteAllDeploymentsBySolutionName(nsID, solution string) error
+	CountDeployments(owner string) (int, error)
+	CountAllDeployments() (int, error)
+	CountReplicas(owner string) (int, error)
+	CountAllReplicas() (int, error)
+}
+
+type ServiceStorage interface {
+	GetService(namespaceID, serviceName string) (service.ResourceService, error)
+	GetServiceList(namespaceID string, query *ListQuery) (service.ListService, int, error)
+	CreateService(service service.ResourceService) (service.ResourceService, error)
+	UpdateService(upd service.ResourceService) (service.ResourceService, error)
+	DeleteService(namespaceID, name string) error
+	RestoreService(namespaceID, name string) error
+	DeleteAllServicesInNamespace(namespaceID string) error
+	DeleteAllServicesByOwner(owner string) error
+	DeleteAllServicesBySolutionName(nsID, solution string) error
+	// GetServicesByDomain returns external services placed on domain in all namespaces
+	GetServicesByDomain(domainName string) (service.ListService, error)
+	CountServices(owner string) (stats.Service, error)
+	CountAllServices() (stats.Service, error)
+	CountServicesInNamespace(namespaceID string) (stats.Service, error)
+}
+
+type IngressStorage interface {
+	CreateIngress(ingress ingress.ResourceIngress) (ingress.ResourceIngress, error)
+	GetIngress(namespaceID, name string) (ingress.ResourceIngress, error)
+	GetIngressByService(namespaceID, serviceName string) (ingress.ResourceIngress, error)
+	GetSelectedIngresses(namespaceID []string, query *ListQuery) (ingress.ListIngress, int, error)
+	GetIngressList(namespaceID string, query *ListQuery) (ingress.ListIngress, int, error)
+	UpdateIngress(upd ingress.ResourceIngress) (ingress.ResourceIngress, error)
+	DeleteIngress(namespaceID, name string) error
+	RestoreIngress(namespaceID, name string) error
+	DeleteAllIngressesInNamespace(namespace string) error
+	DeleteAllIngressesByOwner(owner string) error
+	CountIngresses(owner string) (int, error)
+	CountAllIngresses() (int, error)
+}
+
+type ConfigMapStorage interface {
+	GetConfigMap(namespaceID, cmName string) (configmap.ResourceConfigMap, error)
+	GetConfigMapVersion(namespaceID, cmName string, version semver.Version) (configmap.ResourceConfigMap, error)
+	GetConfigMapVersionsList(namespaceID, cmName string, query *ListQuery) (configmap.ListConfigMaps, int, error)
+	GetConfigMapList(namespaceID string, query *ListQuery) (configmap.ListConfigMaps, int, error)
+	GetSelectedConfigMaps(namespaceID []string, query *ListQuery) (configmap.ListConfigMaps, int, error)
+	CreateConfigMap(cm configmap.ResourceConfigMap) (configmap.ResourceConfigMap, error)
+	ActivateConfigMap(namespaceID, name string, version semver.Version) error
+	DeactivateConfigMap(namespaceID, name string, resourceVersion int64) error
+	DeleteConfigMap(namespaceID, name string) error
+	DeleteConfigMapVersion(namespaceID, name string, version semver.Version) error
+	DeleteAllConfigMapsInNamespace(namespaceID string) error
+	DeleteAllConfigMapsByOwner(owner string) error
+	RestoreConfigMap(namespaceID, name string) error
+	CountConfigMaps(owner string) (int, error)
+	CountAllConfigMaps() (int, error)
+}
+
+type SecretStorage interface {
+	GetSecret(namespaceID, secretName string) (secret.ResourceSecret, error)
+	GetSecretList(namespaceID string, query *ListQuery) (secret.ListSecrets, int, error)
+	GetSelectedSecrets(namespaceID []string, query *ListQuery) (secret.ListSecrets, int, error)
+	CreateSecret(secret secret.ResourceSecret) (secret.ResourceSecret, error)
+	UpdateSecret(upd secret.ResourceSecret) (secret.ResourceSecret, error)
+	DeleteSecret(namespaceID, name string) error
+	DeleteAllSecretsInNamespace(namespaceID string) error
+	DeleteAllSecretsByOwner(owner string) error
+	RestoreSecret(namespaceID, name string) error
+	CountSecrets(owner string) (int, error)
+	CountAllSecrets() (int, error)
+}
+
+type DomainStorage interface {
+	GetDomain(domainName string) (*domain.Domain, error)
+	// GetSchedulableDomains returns domains where new external services can be placed, empty group means all groups
+	GetSchedulableDomains(group string) ([]domain.Domain, error)
+	GetDomainsList(pages *PageInfo) ([]domain.Domain, error)
+	CreateDomain(domain domain.Domain) (*domain.Domain, error)
+	UpdateDomain(domain domain.Domain) (*domain.Domain, error)
+	DeleteDomain(domainName string) error
+
+	GetDomainPin(kind domain.PinKind, id string) (domain.Pin, error)
+	GetDomainPinsList() ([]domain.Pin, error)
+	// SetDomainPin creates or replaces pin of namespace or user
+	SetDomainPin(pin domain.Pin) (domain.Pin, error)
+	DeleteDomainPin(kind domain.PinKind, id string) error
+}
+
+type OutboxStorage interface {
+	CreateOperation(op outbox.Operation) (outbox.Operation, error)
+	ClaimOperation(now time.Time, lease time.Duration) (outbox.Operation, error)
+	UpdateOperation(op outbox.Operation) error
+	// GetPendingOperations returns operations of namespace which are not applied yet
+	GetPendingOperations(nsID string) ([]outbox.Operation, error)
+}
+
+// AuditStorage is append-only: entries can't be updated or deleted
+type AuditStorage interface {
+	CreateAuditEntry(entry audit.Entry) error
+	// GetAuditEntries returns entries matching query and list query, newest first by default.
+	// Returns number of entries matched before pagination.
+	GetAuditEntries(query audit.Query, list *ListQuery) ([]audit.Entry, int, error)
+}
+
+type WebhookStorage interface {
+	GetWebhook(nsID, name string) (webhook.Webhook, error)
+	GetWebhookList(nsID string) ([]webhook.Webhook, error)
+	CreateWebhook(hook webhook.Webhook) (webhook.Webhook, error)
+	UpdateWebhook(hook webhook.Webhook) (webhook.Webhook, error)
+	DeleteWebhook(nsID, name string) error
+
+	CreateDelivery(delivery webhook.Delivery) error
+	ClaimDelivery(now time.Time, lease time.Duration) (webhook.Delivery, error)
+	UpdateDelivery(delivery webhook.Delivery) error
+	// GetDeliveryList returns newest deliveries of webhook, at most webhook.MaxDeliveries
+	GetDeliveryList(nsID, webhookID string) ([]webhook.Delivery, error)
+}
+
+type IdempotencyStorage interface {
+	// CreateIdempotencyRecord returns ErrResourceAlreadyExists if user has not expired record with same key
+	CreateIdempotencyRecord(rec idempotency.Record) error
+	GetIdempotencyRecord(userID, key string) (idempotency.Record, error)
+	UpdateIdempotencyRecord(rec idempotency.Record) error
+	DeleteIdempotencyRecord(userID, key string) error
+}
+
+// PortStorage keeps external ports reserved by services, port is unique for domain and protocol.
+// Ports of deleted services are released by ServiceStorage.
+type PortStorage interface {
+	// ReservePort reserves free port in range [minPort, maxPort), returns ErrPortsExhausted if range is full
+	ReservePort(alloc port.Allocation, minPort, maxPort int) (port.Allocation, error)
+	// ClaimPort reserves allocation port, returns ErrPortReserved if it's reserved by another service
+	ClaimPort(alloc port.Allocation) error
+	ReleasePort(alloc port.Allocation) error
+	ReleaseServicePorts(namespaceID, serviceName string) error
+	// GetPortsUsage returns number of reserved ports in range [minPort, maxPort) for domains and protocols having reservations
+	GetPortsUsage(minPort, maxPort int) ([]port.Usage, error)
+}
+
+type HostStorage interface {
+	// ClaimHost claims host and path for ingress, returns ErrIngressHostConflict if they are claimed by another ingress
+	// or host has paths of another owner
+	ClaimHost(claim host.Claim) error
+	// GetHostClaims returns claims of host, or claims of its subdomains if subtree is set
+	GetHostClaims(hostName string, subtree bool) ([]host.Claim, error)
+	ReleaseHost(claim host.Claim) error
+	ReleaseIngressHosts(namespaceID, ingressName string) error
+	// ReserveHost reserves host or wildcard subtree for user, returns ErrHostReserved if host is reserved already
+	ReserveHost(res host.Reservation) error
+	GetHostReservation(hostName string) (host.Reservation, error)
+	// GetHostReservations returns reservations of user, or all reservations if owner is empty
+	GetHostReservations(owner string) ([]host.Reservation, error)
+	// GetCoveringReservations returns reservations of host and wildcards of its parent domains
+	GetCoveringReservations(hostName string) ([]host.Reservation, error)
+	// GetReservationsInSubtree returns reservations of subdomains of domain
+	GetReservationsInSubtree(domain string) ([]host.Reservation, error)
+	DeleteHostReservation(hostName string) error
+}
+
+// LockStorage keeps named locks held by service instances until they expire
+type LockStorage interface {
+	// AcquireLock takes lock or extends lock of the same holder until expiresAt,
+	// returns ErrResourceAlreadyExists if lock is held by another holder and not expired yet
+	AcquireLock(name, holder string, expiresAt time.Time) error
+	// ReleaseLock releases lock if it is held by holder
+	ReleaseLock(name, holder string) error
+}
+
+var (
+	_ Storage = &MongoStorage{}
+	_ Storage = &MemoryStorage{}
+)
+
+// CertificateStorage keeps certificates issued for ingresses, one per ingress.
+// Certificate and key are stored in ingress tls secret.
+type CertificateStorage interface {
+	// SetCertificate creates or replaces certificate of ingress
+	SetCertificate(cert ingress.Certificate) error
+	GetCertificate(namespaceID, ingressName string) (ingress.Certificate, error)
+	// GetCertificatesExpiringBefore returns certificates which expire before t, they should be renewed
+	GetCertificatesExpiringBefore(t time.Time) ([]ingress.Certificate, error)
+	DeleteCertificate(namespaceID, ingressName string) error
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+func (mongo *MongoStorage) GetWebhook(nsID, name string) (webhook.Webhook, error) {
+	mongo.logger.Debugf("getting webhook")
+	var collection = mongo.db.C(CollectionWebhook)
+	var result webhook.Webhook
+	if err := collection.Find(bson.M{"namespaceid": nsID, "name": name}).One(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get webhook")
+		if err == mgo.ErrNotFound {
+			return result, rserrors.ErrResourceNotExists().AddDetailF("webhook %v", name)
+		}
+		return result, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) GetWebhookList(nsID string) ([]webhook.Webhook, error) {
+	mongo.logger.Debugf("getting webhook list")
+	var collection = mongo.db.C(CollectionWebhook)
+	var result = make([]webhook.Webhook, 0)
+	if err := collection.Find(bson.M{"namespaceid": nsID}).Sort("name").All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get webhook list")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
+
+func (mongo *MongoStorage) CreateWebhook(hook webhook.Webhook) (webhook.Webhook, error) {
+	mongo.logger.Debugf("creating webhook")
+	if hook.ID == "" {
+		hook.ID = uuid.New().String()
+	}
+	var collection = mongo.db.C(CollectionWebhook)
+	if err := collection.Insert(hook); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create webhook")
+		if mgo.IsDup(err) {
+			return hook, rserrors.ErrResourceAlreadyExists().AddDetailF("webhook %v", hook.Name)
+		}
+		return hook, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return hook, nil
+}
+
+func (mongo *MongoStorage) UpdateWebhook(hook webhook.Webhook) (webhook.Webhook, error) {
+	mongo.logger.Debugf("updating webhook")
+	var collection = mongo.db.C(CollectionWebhook)
+	if err := collection.Update(bson.M{"namespaceid": hook.NamespaceID, "name": hook.Name}, hook); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update webhook")
+		if err == mgo.ErrNotFound {
+			return hook, rserrors.ErrResourceNotExists().AddDetailF("webhook %v", hook.Name)
+		}
+		return hook, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return hook, nil
+}
+
+func (mongo *MongoStorage) DeleteWebhook(nsID, name string) error {
+	mongo.logger.Debugf("deleting webhook")
+	var collection = mongo.db.C(CollectionWebhook)
+	if err := collection.Remove(bson.M{"namespaceid": nsID, "name": name}); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to delete webhook")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetailF("webhook %v", name)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) CreateDelivery(delivery webhook.Delivery) error {
+	mongo.logger.Debugf("creating webhook delivery")
+	var collection = mongo.db.C(CollectionDelivery)
+	if err := collection.Insert(delivery); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to create webhook delivery")
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+// ClaimDelivery returns pending delivery ready to be sent.
+// Next attempt of claimed delivery is postponed by lease, so other workers don't pick it.
+func (mongo *MongoStorage) ClaimDelivery(now time.Time, lease time.Duration) (webhook.Delivery, error) {
+	mongo.logger.Debugf("claiming webhook delivery")
+	var collection = mongo.db.C(CollectionDelivery)
+	var delivery webhook.Delivery
+	_, err := collection.Find(webhook.ClaimSelectQuery(now)).
+		Sort("nextattemptat").
+		Apply(mgo.Change{
+			Update: bson.M{
+				"$set": bson.M{"nextattemptat": now.Add(lease).UTC()},
+				"$inc": bson.M{"attempts": 1},
+			},
+			ReturnNew: true,
+		}, &delivery)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return delivery, rserrors.ErrResourceNotExists().AddDetails("no pending deliveries")
+		}
+		mongo.logger.WithError(err).Errorf("unable to claim webhook delivery")
+		return delivery, PipErr{error: err}.ToMongerr().Extract()
+	}
+	return delivery, nil
+}
+
+func (mongo *MongoStorage) UpdateDelivery(delivery webhook.Delivery) error {
+	mongo.logger.Debugf("updating webhook delivery")
+	var collection = mongo.db.C(CollectionDelivery)
+	if err := collection.UpdateId(delivery.ID, delivery.UpdateQuery()); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to update webhook delivery")
+		if err == mgo.ErrNotFound {
+			return rserrors.ErrResourceNotExists().AddDetails(delivery.ID)
+		}
+		return PipErr{error: err}.ToMongerr().Extract()
+	}
+	return nil
+}
+
+func (mongo *MongoStorage) GetDeliveryList(nsID, webhookID string) ([]webhook.Delivery, error) {
+	mongo.logger.Debugf("getting webhook delivery list")
+	var collection = mongo.db.C(CollectionDelivery)
+	var result = make([]webhook.Delivery, 0)
+	if err := collection.Find(bson.M{"namespaceid": nsID, "webhookid": webhookID}).
+		Sort("-createdat").
+		Limit(webhook.MaxDeliveries).
+		All(&result); err != nil {
+		mongo.logger.WithError(err).Errorf("unable to get webhook delivery list")
+		return nil, PipErr{error: err}.ToMongerr().NotFoundToNil().Extract()
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+// Kind -- kind of changed resource
+type Kind string
+
+const (
+	Deployment Kind = "deployment"
+	Service    Kind = "service"
+	Ingress    Kind = "ingress"
+	ConfigMap  Kind = "configmap"
+	Secret     Kind = "secret"
+	Domain     Kind = "domain"
+	DomainPin  Kind = "domain_pin"
+	// host reserved by user
+	HostReservation Kind = "host_reservation"
+	// all resources of namespace or user
+	Resources Kind = "resources"
+)
+
+// Action -- type of resource mutation
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+	Import Action = "import"
+	// active version of deployment was changed
+	Activate Action = "activate"
+	// deployment version was renamed
+	Rename Action = "rename"
+)
+
+// Outcome -- result of resource mutation
+type Outcome string
+
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+)
+
+// Entry -- record of resource mutation. Entries are never changed after they are written.
+//
+// swagger:model AuditEntry
+type Entry struct {
+	ID          string    `json:"_id" bson:"_id"`
+	Time        time.Time `json:"time" bson:"time"`
+	UserID      string    `json:"user_id" bson:"userid"`
+	Role        string    `json:"role,omitempty" bson:"role,omitempty"`
+	NamespaceID string    `json:"namespace,omitempty" bson:"namespaceid,omitempty"`
+	Kind        Kind      `json:"kind" bson:"kind"`
+	Name        string    `json:"name,omitempty" bson:"name,omitempty"`
+	Action      Action    `json:"action" bson:"action"`
+	//resource before mutation, in the same format as in API responses
+	Before json.RawMessage `json:"before,omitempty" bson:"before,omitempty"`
+	//resource after mutation, in the same format as in API responses
+	After   json.RawMessage `json:"after,omitempty" bson:"after,omitempty"`
+	Outcome Outcome         `json:"outcome" bson:"outcome"`
+	Error   string          `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// EntriesResponse -- audit entries list
+//
+// swagger:model AuditEntriesResponse
+type EntriesResponse struct {
+	Entries []Entry `json:"entries"`
+	//number of entries matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
+}
+
+// Query -- filter of audit entries, empty fields match any entry
+type Query struct {
+	NamespaceID string
+	UserID      string
+	From        time.Time
+	To          time.Time
+}
+
+// NewEntry creates entry of mutation with provided error, nil error means success
+func NewEntry(userID, role, nsID string, kind Kind, name string, action Action, err error) Entry {
+	var entry = Entry{
+		ID:          uuid.New().String(),
+		Time:        time.Now().UTC(),
+		UserID:      userID,
+		Role:        role,
+		NamespaceID: nsID,
+		Kind:        kind,
+		Name:        name,
+		Action:      action,
+		Outcome:     Success,
+	}
+	if err != nil {
+		entry.Outcome = Failure
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// Snapshot encodes resource state, nil resource has empty snapshot
+func Snapshot(resource interface{}) (json.RawMessage, error) {
+	if resource == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Match returns true if entry matches query
+func (query Query) Match(entry Entry) bool {
+	switch {
+	case query.NamespaceID != "" && entry.NamespaceID != query.NamespaceID:
+		return false
+	case query.UserID != "" && entry.UserID != query.UserID:
+		return false
+	case !query.From.IsZero() && entry.Time.Before(query.From):
+		return false
+	case !query.To.IsZero() && entry.Time.After(query.To):
+		return false
+	}
+	return true
+}
+
+func (query Query) SelectQuery() bson.M {
+	var sel = bson.M{}
+	if query.NamespaceID != "" {
+		sel["namespaceid"] = query.NamespaceID
+	}
+	if query.UserID != "" {
+		sel["userid"] = query.UserID
+	}
+	var timeRange = bson.M{}
+	if !query.From.IsZero() {
+		timeRange["$gte"] = query.From.UTC()
+	}
+	if !query.To.IsZero() {
+		timeRange["$lte"] = query.To.UTC()
+	}
+	if len(timeRange) > 0 {
+		sel["time"] = timeRange
+	}
+	return sel
+}
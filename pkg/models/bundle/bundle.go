@@ -0,0 +1,33 @@
+package bundle
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+)
+
+// Bundle -- resources applied to namespace at once
+//
+// swagger:model
+type Bundle struct {
+	ConfigMaps  []configmap.ConfigMapRequest   `json:"configmaps,omitempty" yaml:"configmaps,omitempty" binding:"dive"`
+	Deployments []deployment.DeploymentRequest `json:"deployments,omitempty" yaml:"deployments,omitempty" binding:"dive"`
+	Services    []service.ServiceRequest       `json:"services,omitempty" yaml:"services,omitempty" binding:"dive"`
+	Ingresses   []ingress.IngressRequest       `json:"ingresses,omitempty" yaml:"ingresses,omitempty" binding:"dive"`
+}
+
+// BundleResponse -- resources created from bundle
+//
+// swagger:model
+type BundleResponse struct {
+	ConfigMaps  configmap.ListConfigMaps `json:"configmaps"`
+	Deployments deployment.ListDeploy    `json:"deployments"`
+	Services    service.ListService      `json:"services"`
+	Ingresses   ingress.ListIngress      `json:"ingresses"`
+}
+
+// Len returns number of resources in bundle
+func (bundle Bundle) Len() int {
+	return len(bundle.ConfigMaps) + len(bundle.Deployments) + len(bundle.Services) + len(bundle.Ingresses)
+}
@@ -1,6 +1,8 @@
 package configmap
 
 import (
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"github.com/blang/semver"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
@@ -11,9 +13,28 @@ import (
 // swagger:model
 type ResourceConfigMap struct {
 	model.ConfigMap
-	ID          string `json:"_id" bson:"_id,omitempty"`
-	Deleted     bool   `json:"deleted"`
-	NamespaceID string `json:"namespaceid"`
+	labels.Metadata `bson:",inline"`
+
+	ID          string         `json:"_id" bson:"_id,omitempty"`
+	Deleted     bool           `json:"deleted"`
+	NamespaceID string         `json:"namespaceid"`
+	Active      bool           `json:"active" bson:"active"`
+	Version     semver.Version `json:"version" bson:"version"`
+
+	//incremented on every change of resource, returned in ETag header and checked against If-Match header
+	ResourceVersion int64 `json:"resource_version,omitempty" bson:"resourceversion"`
+	//deployments rolled out after data change, returned only by update requests
+	Reloads []Reload `json:"reloads,omitempty" bson:"-"`
+}
+
+// Reload -- result of rollout of deployment which mounts changed configmap
+//
+// swagger:model
+type Reload struct {
+	Deployment string `json:"deployment"`
+	// new version of deployment, empty if rollout failed
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
 }
 
 // ListConfigMaps -- ConfigMaps list
@@ -26,6 +47,31 @@ type ListConfigMaps []ResourceConfigMap
 // swagger:model
 type ConfigMapsResponse struct {
 	ConfigMaps ListConfigMaps `json:"config_maps"`
+	//number of resources matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
+}
+
+// ConfigMapRequest -- configmap with labels and annotations
+//
+// swagger:model
+type ConfigMapRequest struct {
+	model.ConfigMap `yaml:",inline"`
+	labels.Metadata `yaml:",inline"`
+}
+
+// ConfigMapPatch -- keys to set and to remove
+//
+// swagger:model
+type ConfigMapPatch struct {
+	Set    model.ConfigMapData `json:"set,omitempty"`
+	Remove []string            `json:"remove,omitempty"`
+}
+
+// ConfigMapDiff -- unified diff between configmap versions
+//
+// swagger:model
+type ConfigMapDiff struct {
+	Diff string `json:"diff"`
 }
 
 func FromKube(nsID, owner string, ConfigMap model.ConfigMap) ResourceConfigMap {
@@ -38,19 +84,45 @@ func FromKube(nsID, owner string, ConfigMap model.ConfigMap) ResourceConfigMap {
 		ConfigMap:   ConfigMap,
 		NamespaceID: nsID,
 		ID:          uuid.New().String(),
+		Active:      true,
+		Version:     semver.MustParse("1.0.0"),
 	}
 }
 
 func (cm ResourceConfigMap) Copy() ResourceConfigMap {
 	var cp = cm
+	if cm.Data != nil {
+		cp.Data = make(model.ConfigMapData, len(cm.Data))
+		for k, v := range cm.Data {
+			cp.Data[k] = v
+		}
+	}
+	cp.Metadata = cm.Metadata.Copy()
+	return cp
+}
+
+// WithoutData returns copy of configmap without data
+func (cm ResourceConfigMap) WithoutData() ResourceConfigMap {
+	var cp = cm
+	cp.Data = nil
 	return cp
 }
 
 func (cm ResourceConfigMap) OneSelectQuery() interface{} {
+	return bson.M{
+		"namespaceid":    cm.NamespaceID,
+		"deleted":        false,
+		"active":         true,
+		"configmap.name": cm.Name,
+	}
+}
+
+func (cm ResourceConfigMap) OneAnyVersionSelectQuery() interface{} {
 	return bson.M{
 		"namespaceid":    cm.NamespaceID,
 		"deleted":        false,
 		"configmap.name": cm.Name,
+		"version":        cm.Version,
 	}
 }
 
@@ -58,6 +130,7 @@ func (cm ResourceConfigMap) OneSelectDeletedQuery() interface{} {
 	return bson.M{
 		"namespaceid":    cm.NamespaceID,
 		"deleted":        true,
+		"active":         true,
 		"configmap.name": cm.Name,
 	}
 }
@@ -79,7 +152,9 @@ func (cm ResourceConfigMap) AllSelectOwnerQuery() interface{} {
 func (cm ResourceConfigMap) UpdateQuery() interface{} {
 	return bson.M{
 		"$set": bson.M{
-			"configmap": cm.ConfigMap,
+			"configmap":   cm.ConfigMap,
+			"labels":      cm.Labels,
+			"annotations": cm.Annotations,
 		},
 	}
 }
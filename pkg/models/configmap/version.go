@@ -0,0 +1,99 @@
+package configmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+	"github.com/containerum/kube-client/pkg/model"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// NewVersion returns version of configmap with new data.
+// Removed keys bump major version, added keys bump minor version and changed values bump patch version.
+// If data is not changed, version is returned as is.
+func NewVersion(version semver.Version, oldData, newData model.ConfigMapData) semver.Version {
+	var added, changed bool
+	for k, oldValue := range oldData {
+		newValue, ok := newData[k]
+		if !ok {
+			version.Major++
+			version.Minor = 0
+			version.Patch = 0
+			return version
+		}
+		if newValue != oldValue {
+			changed = true
+		}
+	}
+	for k := range newData {
+		if _, ok := oldData[k]; !ok {
+			added = true
+		}
+	}
+	switch {
+	case added:
+		version.Minor++
+		version.Patch = 0
+	case changed:
+		version.Patch++
+	}
+	return version
+}
+
+// Patch returns copy of data with patch applied
+func Patch(data model.ConfigMapData, patch ConfigMapPatch) model.ConfigMapData {
+	var patched = make(model.ConfigMapData, len(data)+len(patch.Set))
+	for k, v := range data {
+		patched[k] = v
+	}
+	for _, k := range patch.Remove {
+		delete(patched, k)
+	}
+	for k, v := range patch.Set {
+		patched[k] = v
+	}
+	return patched
+}
+
+// Diff returns unified diff between data of configmap versions
+func Diff(oldCM, newCM ResourceConfigMap) string {
+	var diff = difflib.UnifiedDiff{
+		A:        dataLines(oldCM.Data),
+		B:        dataLines(newCM.Data),
+		FromFile: oldCM.Version.String(),
+		FromDate: oldCM.CreatedAt,
+		ToFile:   newCM.Version.String(),
+		ToDate:   newCM.CreatedAt,
+		Context:  3,
+	}
+	var diffString, err = difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return err.Error()
+	}
+	return diffString
+}
+
+// Hash returns hash of configmap data, it doesn't depend on keys order
+func Hash(data model.ConfigMapData) string {
+	var h = sha256.New()
+	for _, line := range dataLines(data) {
+		h.Write([]byte(line))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func dataLines(data model.ConfigMapData) []string {
+	var keys = make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var lines = make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %q\n", k, data[k]))
+	}
+	return lines
+}
@@ -1,6 +1,8 @@
 package deployment
 
 import (
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"github.com/blang/semver"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
@@ -11,9 +13,26 @@ import (
 // swagger:model
 type ResourceDeploy struct {
 	model.Deployment
-	ID          string `json:"_id,omitempty" bson:"_id,omitempty"`
-	Deleted     bool   `json:"deleted"`
-	NamespaceID string `json:"namespaceid"`
+	labels.Metadata `bson:",inline"`
+
+	ID          string    `json:"_id,omitempty" bson:"_id,omitempty"`
+	Deleted     bool      `json:"deleted"`
+	NamespaceID string    `json:"namespaceid"`
+	Strategy    *Strategy `json:"strategy,omitempty" bson:"strategy,omitempty"`
+	//true if version is active canary of deployment
+	Canary bool `json:"canary,omitempty" bson:"canary"`
+	//version which was active before this one
+	PreviousVersion *semver.Version `json:"previous_version,omitempty" bson:"previousversion,omitempty"`
+	//true if version was rolled back
+	Failed bool `json:"failed,omitempty" bson:"failed"`
+	//true if deployment is redeployed when mounted configmaps change
+	ReloadOnConfigChange bool `json:"reload_on_config_change,omitempty" bson:"reloadonconfigchange"`
+	//hashes of mounted configmaps data by configmap name, set if reload_on_config_change is enabled
+	ConfigHashes map[string]string `json:"config_hashes,omitempty" bson:"confighashes,omitempty"`
+	//changes against active version, returned only by dry run requests
+	Diff *model.DeploymentDiff `json:"diff,omitempty" bson:"-"`
+	//incremented on every change of resource, returned in ETag header and checked against If-Match header
+	ResourceVersion int64 `json:"resource_version,omitempty" bson:"resourceversion"`
 }
 
 // Deployment -- deployments list
@@ -26,12 +45,33 @@ type ListDeploy []ResourceDeploy
 // swagger:model
 type DeploymentsResponse struct {
 	Deployments ListDeploy `json:"deployments"`
+	//number of resources matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
 }
 
 func (depl ResourceDeploy) UpdateQuery() interface{} {
 	return bson.M{
 		"$set": bson.M{
-			"deployment": depl.Deployment,
+			"deployment":           depl.Deployment,
+			"strategy":             depl.Strategy,
+			"reloadonconfigchange": depl.ReloadOnConfigChange,
+			"confighashes":         depl.ConfigHashes,
+			"labels":               depl.Labels,
+			"annotations":          depl.Annotations,
+		},
+	}
+}
+
+func (depl ResourceDeploy) CanaryUpdateQuery() interface{} {
+	return bson.M{
+		"$set": bson.M{
+			"deployment":           depl.Deployment,
+			"strategy":             depl.Strategy,
+			"canary":               depl.Canary,
+			"reloadonconfigchange": depl.ReloadOnConfigChange,
+			"confighashes":         depl.ConfigHashes,
+			"labels":               depl.Labels,
+			"annotations":          depl.Annotations,
 		},
 	}
 }
@@ -41,6 +81,17 @@ func (depl ResourceDeploy) OneSelectQuery() interface{} {
 		"namespaceid":       depl.NamespaceID,
 		"deleted":           false,
 		"deployment.active": true,
+		"canary":            false,
+		"deployment.name":   depl.Name,
+	}
+}
+
+func (depl ResourceDeploy) OneCanarySelectQuery() interface{} {
+	return bson.M{
+		"namespaceid":       depl.NamespaceID,
+		"deleted":           false,
+		"deployment.active": true,
+		"canary":            true,
 		"deployment.name":   depl.Name,
 	}
 }
@@ -104,8 +155,24 @@ func (depl ResourceDeploy) Copy() ResourceDeploy {
 		var status = *cp.Status
 		cp.Status = &status
 	}
-	for i, container := range depl.Containers {
-		depl.Containers[i] = copyContainer(container)
+	if cp.PreviousVersion != nil {
+		var version = *cp.PreviousVersion
+		cp.PreviousVersion = &version
+	}
+	cp.Metadata = depl.Metadata.Copy()
+	if cp.Strategy != nil {
+		var strategy = *cp.Strategy
+		cp.Strategy = &strategy
+	}
+	if cp.ConfigHashes != nil {
+		cp.ConfigHashes = make(map[string]string, len(depl.ConfigHashes))
+		for name, hash := range depl.ConfigHashes {
+			cp.ConfigHashes[name] = hash
+		}
+	}
+	cp.Containers = make([]model.Container, 0, len(depl.Containers))
+	for _, container := range depl.Containers {
+		cp.Containers = append(cp.Containers, copyContainer(container))
 	}
 	return cp
 }
@@ -0,0 +1,61 @@
+package deployment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// ConfigHashAnnotation -- pod template annotation with hash of mounted configmaps.
+// Its change makes kube-api restart pods when configmap data changes.
+const ConfigHashAnnotation = "resource-service/configmaps-hash"
+
+// ConfigMapNames returns sorted names of configmaps mounted by deployment containers
+func ConfigMapNames(deploy model.Deployment) []string {
+	var set = make(map[string]struct{})
+	for _, container := range deploy.Containers {
+		for _, cm := range container.ConfigMaps {
+			set[cm.Name] = struct{}{}
+		}
+	}
+	var names = make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MountsConfigMap returns true if any deployment container mounts configmap
+func (depl ResourceDeploy) MountsConfigMap(name string) bool {
+	for _, container := range depl.Containers {
+		for _, cm := range container.ConfigMaps {
+			if cm.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PodAnnotations returns pod template annotations with hash of mounted configmaps.
+// Nil is returned if no configmaps are hashed.
+func PodAnnotations(hashes map[string]string) map[string]string {
+	if len(hashes) == 0 {
+		return nil
+	}
+	var names = make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var h = sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name + "=" + hashes[name] + "\n"))
+	}
+	return map[string]string{
+		ConfigHashAnnotation: hex.EncodeToString(h.Sum(nil))[:16],
+	}
+}
@@ -0,0 +1,108 @@
+package deployment
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// StrategyType -- deployment rollout strategy type
+type StrategyType string
+
+const (
+	// StrategyRecreate -- all old pods are stopped before new version starts
+	StrategyRecreate StrategyType = "recreate"
+	// StrategyRolling -- pods are replaced one by one
+	StrategyRolling StrategyType = "rolling"
+	// StrategyCanary -- new version receives part of replicas until it is promoted
+	StrategyCanary StrategyType = "canary"
+)
+
+// Strategy -- deployment rollout strategy
+//
+// swagger:model
+type Strategy struct {
+	// required: true
+	Type StrategyType `json:"type" yaml:"type" binding:"required,eq=recreate|eq=rolling|eq=canary"`
+	//rolling: max number of replicas created over desired number of replicas
+	MaxSurge int `json:"max_surge,omitempty" yaml:"max_surge,omitempty" binding:"min=0"`
+	//rolling: max number of replicas that can be unavailable during update
+	MaxUnavailable int `json:"max_unavailable,omitempty" yaml:"max_unavailable,omitempty" binding:"min=0"`
+	//canary: percentage of replicas running new version
+	CanaryPercent int `json:"canary_percent,omitempty" yaml:"canary_percent,omitempty" binding:"omitempty,min=1,max=100"`
+	//canary: percentage added to canary on each promotion, if empty canary is promoted at once
+	PromotionStep int `json:"promotion_step,omitempty" yaml:"promotion_step,omitempty" binding:"omitempty,min=1,max=100"`
+}
+
+// DeploymentRequest -- deployment with rollout strategy
+//
+// swagger:model
+type DeploymentRequest struct {
+	model.Deployment `yaml:",inline"`
+	labels.Metadata  `yaml:",inline"`
+	Strategy         *Strategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	//redeploy deployment when mounted configmaps change, if empty current value is kept on update
+	ReloadOnConfigChange *bool `json:"reload_on_config_change,omitempty" yaml:"reload_on_config_change,omitempty"`
+}
+
+func (strategy *Strategy) IsCanary() bool {
+	return strategy != nil && strategy.Type == StrategyCanary
+}
+
+func (strategy *Strategy) IsRecreate() bool {
+	return strategy != nil && strategy.Type == StrategyRecreate
+}
+
+// Surge returns number of extra replicas which can be started during rollout
+func (strategy *Strategy) Surge() int {
+	if strategy == nil || strategy.Type != StrategyRolling {
+		return 0
+	}
+	return strategy.MaxSurge
+}
+
+// KubeStrategy -- rollout strategy of kubernetes deployment
+type KubeStrategy struct {
+	Type           string `json:"type"`
+	MaxSurge       int    `json:"max_surge,omitempty"`
+	MaxUnavailable int    `json:"max_unavailable,omitempty"`
+}
+
+const (
+	kubeStrategyRecreate = "Recreate"
+	kubeStrategyRolling  = "RollingUpdate"
+)
+
+// Kube returns strategy sent to kube-api. Canary versions are separate kubernetes deployments,
+// so both of them use default rolling update. Nil means kubernetes default.
+func (strategy *Strategy) Kube() *KubeStrategy {
+	switch {
+	case strategy.IsRecreate():
+		return &KubeStrategy{Type: kubeStrategyRecreate}
+	case strategy != nil && strategy.Type == StrategyRolling:
+		return &KubeStrategy{
+			Type:           kubeStrategyRolling,
+			MaxSurge:       strategy.MaxSurge,
+			MaxUnavailable: strategy.MaxUnavailable,
+		}
+	default:
+		return nil
+	}
+}
+
+// CanaryReplicas returns number of replicas of canary version for given canary percentage.
+// Canary always has at least one replica.
+func CanaryReplicas(total, percent int) int {
+	var replicas = (total*percent + 99) / 100
+	if replicas < 1 {
+		replicas = 1
+	}
+	if replicas > total {
+		replicas = total
+	}
+	return replicas
+}
+
+// CanaryName returns name of kubernetes deployment running canary version
+func CanaryName(deploymentName string) string {
+	return deploymentName + "-canary"
+}
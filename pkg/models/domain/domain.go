@@ -1,5 +1,22 @@
 package domain
 
+import "fmt"
+
+// State -- scheduling state of domain
+type State string
+
+const (
+	// Active -- external services are placed on domain
+	Active State = "active"
+	// Disabled -- new external services are not placed on domain
+	Disabled State = "disabled"
+	// Draining -- domain is retired, new external services are not placed on it
+	Draining State = "draining"
+)
+
+// DefaultWeight -- selection weight of domain without weight
+const DefaultWeight = 1
+
 // Domain -- model for available service domain for resource-service db
 //
 // swagger:model
@@ -14,6 +31,23 @@ type Domain struct {
 	//Domain ip addresses
 	// required: true
 	IP []string `json:"ip"`
+	//Scheduling state, empty state is active
+	State State `json:"state,omitempty" bson:"state,omitempty"`
+	//Relative weight in weighted domain selection, 0 means default weight 1
+	Weight uint `json:"weight,omitempty" bson:"weight,omitempty"`
+}
+
+// Schedulable returns true if new external services can be placed on domain
+func (dom Domain) Schedulable() bool {
+	return dom.State == "" || dom.State == Active
+}
+
+// SelectionWeight returns weight of domain in weighted selection
+func (dom Domain) SelectionWeight() uint {
+	if dom.Weight == 0 {
+		return DefaultWeight
+	}
+	return dom.Weight
 }
 
 // ListDomain -- domains list
@@ -27,3 +61,79 @@ type ListDomain []Domain
 type DomainsResponse struct {
 	Domains ListDomain `json:"domains"`
 }
+
+// UpdateRequest -- new domain addresses and group, omitted fields are not changed
+//
+// swagger:model DomainUpdateRequest
+type UpdateRequest struct {
+	//Domain ip addresses, services of domain get new addresses
+	IP []string `json:"ip,omitempty"`
+	//Group for domain
+	DomainGroup string `json:"domain_group,omitempty"`
+}
+
+// Apply returns updated domain
+func (req UpdateRequest) Apply(dom Domain) Domain {
+	if len(req.IP) > 0 {
+		dom.IP = append([]string(nil), req.IP...)
+	}
+	if req.DomainGroup != "" {
+		dom.DomainGroup = req.DomainGroup
+	}
+	return dom
+}
+
+// DrainResponse -- result of moving external services off domain
+//
+// swagger:model DomainDrainResponse
+type DrainResponse struct {
+	Domain string `json:"domain"`
+	//true if all services are moved off domain, otherwise drain is stopped on failed service and can be repeated
+	Complete bool `json:"complete"`
+	//services placed on new domains, they are not moved back if drain is not complete
+	Moved []MovedService `json:"moved"`
+	//service which can't be moved, every service which can't be moved on dry run
+	Failed []MovedService `json:"failed,omitempty"`
+	//services left on domain without move attempt because drain was stopped
+	Remaining []MovedService `json:"remaining,omitempty"`
+}
+
+// MovedService -- external service moved off drained domain
+type MovedService struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	//new domain of service
+	Domain string `json:"domain,omitempty"`
+	//reason of failed move
+	Error string `json:"error,omitempty"`
+}
+
+// PoolSettings -- domain settings used in domain selection, omitted fields are not changed
+//
+// swagger:model DomainPoolSettings
+type PoolSettings struct {
+	Weight *uint `json:"weight,omitempty"`
+	//active, disabled or draining
+	State State `json:"state,omitempty"`
+}
+
+// Validate checks pool settings
+func (settings PoolSettings) Validate() []string {
+	switch settings.State {
+	case "", Active, Disabled, Draining:
+		return nil
+	default:
+		return []string{fmt.Sprintf("state must be one of %v, %v, %v", Active, Disabled, Draining)}
+	}
+}
+
+// Apply returns domain with changed settings
+func (settings PoolSettings) Apply(dom Domain) Domain {
+	if settings.Weight != nil {
+		dom.Weight = *settings.Weight
+	}
+	if settings.State != "" {
+		dom.State = settings.State
+	}
+	return dom
+}
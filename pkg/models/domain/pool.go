@@ -0,0 +1,44 @@
+package domain
+
+// SelectionPolicy -- how domain for external service is selected from eligible ones
+type SelectionPolicy string
+
+const (
+	// Weighted -- random domain, probability is proportional to domain weight
+	Weighted SelectionPolicy = "weighted"
+	// LeastUsed -- domain with least number of reserved ports
+	LeastUsed SelectionPolicy = "least_used"
+)
+
+// PinKind -- kind of pinned object
+type PinKind string
+
+const (
+	NamespacePin PinKind = "namespace"
+	UserPin      PinKind = "user"
+)
+
+// Pin -- external services of namespace or user are placed only on domains of pinned group.
+// Namespace pin has priority over pin of user.
+//
+// swagger:model DomainPin
+type Pin struct {
+	//namespace or user
+	Kind PinKind `json:"kind" bson:"kind"`
+	//namespace or user id
+	ID string `json:"id" bson:"id"`
+	// required: true
+	DomainGroup string `json:"domain_group" bson:"domain_group" binding:"required"`
+}
+
+// PinsResponse -- domain pins list
+//
+// swagger:model DomainPinsResponse
+type PinsResponse struct {
+	Pins []Pin `json:"pins"`
+}
+
+// ValidPinKind returns true if objects of kind can be pinned
+func ValidPinKind(kind PinKind) bool {
+	return kind == NamespacePin || kind == UserPin
+}
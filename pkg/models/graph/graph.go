@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+)
+
+// Kind -- kind of resource in graph
+type Kind string
+
+const (
+	Deployment Kind = "deployment"
+	Service    Kind = "service"
+	Ingress    Kind = "ingress"
+	ConfigMap  Kind = "configmap"
+	Secret     Kind = "secret"
+)
+
+// Node -- resource in graph
+//
+// swagger:model GraphNode
+type Node struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (node Node) String() string {
+	return string(node.Kind) + "/" + node.Name
+}
+
+// Edge -- dependency between resources, resource From can't work without resource To
+//
+// swagger:model GraphEdge
+type Edge struct {
+	From Node `json:"from"`
+	To   Node `json:"to"`
+}
+
+// Graph -- namespace resources and dependencies between them
+//
+// swagger:model
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Resources -- namespace resources graph is built from
+type Resources struct {
+	Deployments deployment.ListDeploy
+	Services    service.ListService
+	Ingresses   ingress.ListIngress
+	ConfigMaps  configmap.ListConfigMaps
+	Secrets     secret.ListSecrets
+}
+
+// Build returns graph of namespace resources.
+// Edges to resources which don't exist are included, so dangling references are visible.
+func Build(res Resources) Graph {
+	var graph = Graph{
+		Nodes: []Node{},
+		Edges: []Edge{},
+	}
+	for _, depl := range res.Deployments {
+		var from = Node{Kind: Deployment, Name: depl.Name}
+		graph.Nodes = append(graph.Nodes, from)
+		for _, cm := range deployment.ConfigMapNames(depl.Deployment) {
+			graph.Edges = append(graph.Edges, Edge{From: from, To: Node{Kind: ConfigMap, Name: cm}})
+		}
+		for _, s := range depl.ImagePullSecrets {
+			graph.Edges = append(graph.Edges, Edge{From: from, To: Node{Kind: Secret, Name: s}})
+		}
+	}
+	for _, svc := range res.Services {
+		var from = Node{Kind: Service, Name: svc.Name}
+		graph.Nodes = append(graph.Nodes, from)
+		if svc.Deploy != "" {
+			graph.Edges = append(graph.Edges, Edge{From: from, To: Node{Kind: Deployment, Name: svc.Deploy}})
+		}
+	}
+	for _, ingr := range res.Ingresses {
+		var from = Node{Kind: Ingress, Name: ingr.Name}
+		graph.Nodes = append(graph.Nodes, from)
+		for _, rule := range ingr.Rules {
+			for _, path := range rule.Path {
+				graph.Edges = append(graph.Edges, Edge{From: from, To: Node{Kind: Service, Name: path.ServiceName}})
+			}
+			if rule.TLSSecret != nil && *rule.TLSSecret != "" {
+				graph.Edges = append(graph.Edges, Edge{From: from, To: Node{Kind: Secret, Name: *rule.TLSSecret}})
+			}
+		}
+	}
+	for _, cm := range res.ConfigMaps {
+		graph.Nodes = append(graph.Nodes, Node{Kind: ConfigMap, Name: cm.Name})
+	}
+	for _, s := range res.Secrets {
+		graph.Nodes = append(graph.Nodes, Node{Kind: Secret, Name: s.Name})
+	}
+	graph.Edges = uniqueEdges(graph.Edges)
+	return graph
+}
+
+// Dependents returns edges to node from resources which depend on it directly or transitively.
+// Edges are ordered so that each resource goes before resources it depends on, i.e. it's a safe deletion order.
+func (graph Graph) Dependents(node Node) []Edge {
+	var visited = map[Node]bool{node: true}
+	var ordered []Edge
+	var visit func(to Node)
+	visit = func(to Node) {
+		for _, edge := range graph.Edges {
+			if edge.To != to || visited[edge.From] {
+				continue
+			}
+			visited[edge.From] = true
+			visit(edge.From)
+			ordered = append(ordered, edge)
+		}
+	}
+	visit(node)
+	return ordered
+}
+
+// Has returns true if node is in graph
+func (graph Graph) Has(node Node) bool {
+	for _, n := range graph.Nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// Dangling returns edges to resources of provided kinds which are not in graph
+func (graph Graph) Dangling(kinds ...Kind) []Edge {
+	var nodes = make(map[Node]bool, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node] = true
+	}
+	var dangling []Edge
+	for _, edge := range graph.Edges {
+		if nodes[edge.To] {
+			continue
+		}
+		for _, kind := range kinds {
+			if edge.To.Kind == kind {
+				dangling = append(dangling, edge)
+				break
+			}
+		}
+	}
+	return dangling
+}
+
+func uniqueEdges(edges []Edge) []Edge {
+	var seen = make(map[Edge]bool, len(edges))
+	var unique = make([]Edge, 0, len(edges))
+	for _, edge := range edges {
+		if !seen[edge] {
+			seen[edge] = true
+			unique = append(unique, edge)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].From != unique[j].From {
+			return unique[i].From.String() < unique[j].From.String()
+		}
+		return unique[i].To.String() < unique[j].To.String()
+	})
+	return unique
+}
@@ -0,0 +1,121 @@
+package host
+
+import (
+	"strings"
+	"time"
+
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// WildcardPrefix marks reservation of all subdomains of domain
+const WildcardPrefix = "*."
+
+// Claim -- host and path prefix used by ingress.
+// Host and path pair is unique in cluster.
+type Claim struct {
+	Host        string    `json:"host" bson:"host"`
+	Path        string    `json:"path" bson:"path"`
+	NamespaceID string    `json:"namespace" bson:"namespaceid"`
+	Ingress     string    `json:"ingress" bson:"ingress"`
+	Owner       string    `json:"owner,omitempty" bson:"owner"`
+	CreatedAt   time.Time `json:"created_at" bson:"createdat"`
+}
+
+// SameIngress returns true if claims belong to the same ingress
+func (claim Claim) SameIngress(other Claim) bool {
+	return claim.NamespaceID == other.NamespaceID && claim.Ingress == other.Ingress
+}
+
+// Key returns host and path pair of claim
+func (claim Claim) Key() string {
+	return claim.Host + claim.Path
+}
+
+// Claims returns claims of all ingress paths
+func Claims(nsID string, ingr model.Ingress) []Claim {
+	var claims []Claim
+	for _, rule := range ingr.Rules {
+		for _, path := range rule.Path {
+			claims = append(claims, Claim{
+				Host:        rule.Host,
+				Path:        path.Path,
+				NamespaceID: nsID,
+				Ingress:     ingr.Name,
+				Owner:       ingr.Owner,
+			})
+		}
+	}
+	return claims
+}
+
+// Diff returns claims which are in a but not in b
+func Diff(a, b []Claim) []Claim {
+	var inB = make(map[string]struct{}, len(b))
+	for _, claim := range b {
+		inB[claim.Key()] = struct{}{}
+	}
+	var diff []Claim
+	for _, claim := range a {
+		if _, ok := inB[claim.Key()]; !ok {
+			diff = append(diff, claim)
+		}
+	}
+	return diff
+}
+
+// Reservation -- host or wildcard subtree (*.example.com) reserved by user.
+// Ingresses of other users can't use reserved hosts.
+//
+// swagger:model HostReservation
+type Reservation struct {
+	Host      string    `json:"host" bson:"host"`
+	Owner     string    `json:"owner" bson:"owner"`
+	CreatedAt time.Time `json:"created_at" bson:"createdat"`
+}
+
+// Wildcard returns true if reservation covers subdomains of domain
+func (res Reservation) Wildcard() bool {
+	return strings.HasPrefix(res.Host, WildcardPrefix)
+}
+
+// Domain returns reserved host without wildcard prefix
+func (res Reservation) Domain() string {
+	return strings.TrimPrefix(res.Host, WildcardPrefix)
+}
+
+// Covers returns true if host is reserved by reservation
+func (res Reservation) Covers(host string) bool {
+	if res.Wildcard() {
+		return InSubtree(host, res.Domain())
+	}
+	return res.Host == host
+}
+
+// ReservationRequest -- host or wildcard subtree to reserve
+//
+// swagger:model HostReservationRequest
+type ReservationRequest struct {
+	Host string `json:"host" binding:"required"`
+}
+
+// ReservationsResponse -- hosts reserved by user
+//
+// swagger:model HostReservationsResponse
+type ReservationsResponse struct {
+	Reservations []Reservation `json:"reservations"`
+}
+
+// InSubtree returns true if host is subdomain of domain
+func InSubtree(host, domain string) bool {
+	return strings.HasSuffix(host, "."+domain)
+}
+
+// Covering returns reservation hosts which cover host: host itself and wildcards of its parent domains
+func Covering(host string) []string {
+	var hosts = []string{host}
+	for rest := host; strings.Contains(rest, "."); {
+		rest = rest[strings.Index(rest, ".")+1:]
+		hosts = append(hosts, WildcardPrefix+rest)
+	}
+	return hosts
+}
@@ -0,0 +1,35 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Record -- stored result of request sent with Idempotency-Key header
+type Record struct {
+	UserID string `bson:"userid"`
+	Key    string `bson:"key"`
+	// hash of request method, URL and body, other request with same key is rejected
+	RequestHash string `bson:"requesthash"`
+	// false while first request is processed
+	Done      bool              `bson:"done"`
+	Status    int               `bson:"status,omitempty"`
+	Header    map[string]string `bson:"header,omitempty"`
+	Body      []byte            `bson:"body,omitempty"`
+	CreatedAt time.Time         `bson:"createdat"`
+	ExpiresAt time.Time         `bson:"expiresat"`
+}
+
+// RequestHash returns hex encoded SHA-256 of request method, URL and body
+func RequestHash(method, url string, body []byte) string {
+	var hash = sha256.New()
+	hash.Write([]byte(method + " " + url + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Expired returns true if record can't be replayed anymore
+func (rec Record) Expired(now time.Time) bool {
+	return !now.Before(rec.ExpiresAt)
+}
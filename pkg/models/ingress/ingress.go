@@ -1,6 +1,7 @@
 package ingress
 
 import (
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
@@ -11,9 +12,15 @@ import (
 // swagger:model
 type ResourceIngress struct {
 	model.Ingress
-	ID          string `json:"_id" bson:"_id,omitempty"`
-	Deleted     bool   `json:"deleted"`
-	NamespaceID string `json:"namespaceid"`
+	labels.Metadata `bson:",inline"`
+
+	ID          string  `json:"_id" bson:"_id,omitempty"`
+	Deleted     bool    `json:"deleted"`
+	NamespaceID string  `json:"namespaceid"`
+	TLS         TLSMode `json:"tls,omitempty" bson:"tls"`
+
+	//incremented on every change of resource, returned in ETag header and checked against If-Match header
+	ResourceVersion int64 `json:"resource_version,omitempty" bson:"resourceversion"`
 }
 
 // ListIngress -- ingresses list
@@ -26,6 +33,18 @@ type ListIngress []ResourceIngress
 // swagger:model
 type IngressesResponse struct {
 	Ingresses ListIngress `json:"ingresses"`
+	//number of resources matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
+}
+
+// IngressRequest -- ingress with labels and annotations
+//
+// swagger:model
+type IngressRequest struct {
+	model.Ingress   `yaml:",inline"`
+	labels.Metadata `yaml:",inline"`
+	//"auto" to issue certificate for ingress hosts, "manual" to use own tls secrets. Empty value keeps mode on update.
+	TLS TLSMode `json:"tls,omitempty" yaml:"tls,omitempty"`
 }
 
 func (ingr ResourceIngress) Copy() ResourceIngress {
@@ -35,6 +54,7 @@ func (ingr ResourceIngress) Copy() ResourceIngress {
 		rule.Path = append(make([]model.Path, 0, len(rule.Path)), rule.Path...)
 		cp.Rules[i] = rule
 	}
+	cp.Metadata = ingr.Metadata.Copy()
 	return cp
 }
 
@@ -107,7 +127,10 @@ func OneSelectQuery(namespaceID, name string) interface{} {
 func (ingr ResourceIngress) UpdateQuery() interface{} {
 	return bson.M{
 		"$set": bson.M{
-			"ingress": ingr.Ingress,
+			"ingress":     ingr.Ingress,
+			"tls":         ingr.TLS,
+			"labels":      ingr.Labels,
+			"annotations": ingr.Annotations,
 		},
 	}
 }
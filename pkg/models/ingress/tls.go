@@ -0,0 +1,61 @@
+package ingress
+
+import (
+	"time"
+)
+
+// TLSMode -- how tls secrets of ingress are managed
+type TLSMode string
+
+const (
+	// TLSManual -- tls secrets are set by user
+	TLSManual TLSMode = "manual"
+	// TLSAuto -- certificate for ingress hosts is issued and renewed by resource-service
+	TLSAuto TLSMode = "auto"
+)
+
+// Certificate -- certificate issued for ingress hosts.
+// Certificate and key are stored in ingress tls secret.
+//
+// swagger:model IngressCertificate
+type Certificate struct {
+	NamespaceID string   `json:"namespace" bson:"namespaceid"`
+	Ingress     string   `json:"ingress" bson:"ingress"`
+	Secret      string   `json:"secret" bson:"secret"`
+	Owner       string   `json:"owner,omitempty" bson:"owner"`
+	Hosts       []string `json:"hosts" bson:"hosts"`
+	Issuer      string   `json:"issuer" bson:"issuer"`
+	// zero if certificate is requested, so it is issued on next renewal run
+	NotAfter  time.Time `json:"not_after" bson:"notafter"`
+	RenewedAt time.Time `json:"renewed_at" bson:"renewedat"`
+	//last issue error, certificate is renewed again on next renewal run
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Covers returns true if certificate is issued for all hosts
+func (cert Certificate) Covers(hosts []string) bool {
+	var issued = make(map[string]struct{}, len(cert.Hosts))
+	for _, host := range cert.Hosts {
+		issued[host] = struct{}{}
+	}
+	for _, host := range hosts {
+		if _, ok := issued[host]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Hosts returns unique hosts of ingress rules in order of rules
+func (ingr ResourceIngress) Hosts() []string {
+	var hosts []string
+	var seen = make(map[string]struct{}, len(ingr.Rules))
+	for _, rule := range ingr.Rules {
+		if _, ok := seen[rule.Host]; ok {
+			continue
+		}
+		seen[rule.Host] = struct{}{}
+		hosts = append(hosts, rule.Host)
+	}
+	return hosts
+}
@@ -0,0 +1,197 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	maxNameLength   = 63
+	maxPrefixLength = 253
+	maxValueLength  = 63
+	// MaxAnnotationsSize -- total size of annotation keys and values in bytes, same as in kubernetes
+	MaxAnnotationsSize = 256 * 1024
+
+	// ManagedByLabel -- label of resources created in kube-api by resource-service
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue -- value of ManagedByLabel
+	ManagedByValue = "resource-service"
+)
+
+var (
+	nameRegexp   = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	prefixRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// Labels -- string key/value pairs of resource.
+// Keys may contain dots, which are not allowed in db field names, so labels are stored as list of key/value documents.
+type Labels map[string]string
+
+type pair struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
+func (labels Labels) GetBSON() (interface{}, error) {
+	var pairs = make([]pair, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, pair{Key: key, Value: value})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+	return pairs, nil
+}
+
+func (labels *Labels) SetBSON(raw bson.Raw) error {
+	var pairs []pair
+	if err := raw.Unmarshal(&pairs); err != nil {
+		return err
+	}
+	*labels = make(Labels, len(pairs))
+	for _, p := range pairs {
+		(*labels)[p.Key] = p.Value
+	}
+	return nil
+}
+
+// Copy returns independent copy of labels
+func (labels Labels) Copy() Labels {
+	if labels == nil {
+		return nil
+	}
+	var cp = make(Labels, len(labels))
+	for key, value := range labels {
+		cp[key] = value
+	}
+	return cp
+}
+
+// Equal returns true if labels have the same pairs, nil labels are equal to empty ones
+func (labels Labels) Equal(other Labels) bool {
+	if len(labels) != len(other) {
+		return false
+	}
+	for key, value := range labels {
+		if otherValue, ok := other[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Metadata -- user defined labels and annotations of resource
+//
+// swagger:model
+type Metadata struct {
+	//labels used in selectors, keys and values have kubernetes syntax
+	Labels Labels `json:"labels,omitempty" yaml:"labels,omitempty" bson:"labels,omitempty"`
+	//free-form data which can't be used in selectors
+	Annotations Labels `json:"annotations,omitempty" yaml:"annotations,omitempty" bson:"annotations,omitempty"`
+}
+
+// Copy returns independent copy of metadata
+func (meta Metadata) Copy() Metadata {
+	return Metadata{
+		Labels:      meta.Labels.Copy(),
+		Annotations: meta.Annotations.Copy(),
+	}
+}
+
+// Equal returns true if metadata have the same labels and annotations
+func (meta Metadata) Equal(other Metadata) bool {
+	return meta.Labels.Equal(other.Labels) && meta.Annotations.Equal(other.Annotations)
+}
+
+// Managed returns copy of metadata with label marking resource as created by resource-service
+func (meta Metadata) Managed() Metadata {
+	var managed = meta.Copy()
+	if managed.Labels == nil {
+		managed.Labels = make(Labels, 1)
+	}
+	managed.Labels[ManagedByLabel] = ManagedByValue
+	return managed
+}
+
+// IsManaged returns true if resource is labelled as created by resource-service
+func (meta Metadata) IsManaged() bool {
+	return meta.Labels[ManagedByLabel] == ManagedByValue
+}
+
+// Merge returns metadata of update request. Nil labels or annotations of request keep current ones, empty ones remove them.
+func (meta Metadata) Merge(current Metadata) Metadata {
+	var merged = meta.Copy()
+	if merged.Labels == nil {
+		merged.Labels = current.Labels.Copy()
+	}
+	if merged.Annotations == nil {
+		merged.Annotations = current.Annotations.Copy()
+	}
+	return merged
+}
+
+// Validate checks labels and annotations against kubernetes syntax
+func (meta Metadata) Validate() []string {
+	var errs []string
+	for _, key := range sortedKeys(meta.Labels) {
+		if err := ValidateKey(key); err != nil {
+			errs = append(errs, "label "+err.Error())
+		}
+		if err := ValidateValue(meta.Labels[key]); err != nil {
+			errs = append(errs, fmt.Sprintf("label %v %v", key, err))
+		}
+	}
+	var size int
+	for _, key := range sortedKeys(meta.Annotations) {
+		if err := ValidateKey(key); err != nil {
+			errs = append(errs, "annotation "+err.Error())
+		}
+		size += len(key) + len(meta.Annotations[key])
+	}
+	if size > MaxAnnotationsSize {
+		errs = append(errs, fmt.Sprintf("annotations size must be no more than %v bytes", MaxAnnotationsSize))
+	}
+	return errs
+}
+
+// ValidateKey checks that key is qualified name with optional DNS subdomain prefix, e.g. "example.com/team"
+func ValidateKey(key string) error {
+	var name = key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		var prefix = key[:i]
+		name = key[i+1:]
+		if len(prefix) > maxPrefixLength || !prefixRegexp.MatchString(prefix) {
+			return fmt.Errorf("key %q: prefix must be DNS subdomain of no more than %v characters", key, maxPrefixLength)
+		}
+	}
+	if len(name) > maxNameLength || !nameRegexp.MatchString(name) {
+		return fmt.Errorf("key %q: name must consist of alphanumeric characters, '-', '_' or '.', "+
+			"start and end with alphanumeric character and be no more than %v characters", key, maxNameLength)
+	}
+	return nil
+}
+
+// ValidateValue checks that label value is empty or has qualified name syntax
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxValueLength || !nameRegexp.MatchString(value) {
+		return fmt.Errorf("value %q must consist of alphanumeric characters, '-', '_' or '.', "+
+			"start and end with alphanumeric character and be no more than %v characters", value, maxValueLength)
+	}
+	return nil
+}
+
+func sortedKeys(labels Labels) []string {
+	var keys = make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operator -- label selector requirement operator
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+var setRequirementRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// Requirement -- single expression of label selector
+type Requirement struct {
+	Key      string
+	Operator Operator
+	// Values -- single value for equality operators, one or more for set operators, empty for existence operators
+	Values []string
+}
+
+// Selector -- label selector, resource matches it if all requirements are satisfied
+type Selector []Requirement
+
+// ParseSelector parses selector in kubernetes syntax, e.g. "team=payments,env!=dev,tier in (web,api),!canary".
+// Inequality and "notin" are satisfied by resources without the label.
+func ParseSelector(selector string) (Selector, error) {
+	var result Selector
+	for _, expr := range splitSelector(selector) {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return nil, fmt.Errorf("empty requirement in selector %q", selector)
+		}
+		req, err := parseRequirement(expr)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, req)
+	}
+	return result, nil
+}
+
+// splitSelector splits selector by commas which are not inside of value sets
+func splitSelector(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var exprs []string
+	var depth, start int
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				exprs = append(exprs, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(exprs, selector[start:])
+}
+
+func parseRequirement(expr string) (Requirement, error) {
+	var req Requirement
+	switch {
+	case setRequirementRegexp.MatchString(expr):
+		var groups = setRequirementRegexp.FindStringSubmatch(expr)
+		req = Requirement{Key: groups[1], Operator: Operator(groups[2])}
+		for _, value := range strings.Split(groups[3], ",") {
+			req.Values = append(req.Values, strings.TrimSpace(value))
+		}
+	case strings.Contains(expr, "!="):
+		var parts = strings.SplitN(expr, "!=", 2)
+		req = Requirement{Key: strings.TrimSpace(parts[0]), Operator: NotEquals, Values: []string{strings.TrimSpace(parts[1])}}
+	case strings.Contains(expr, "="):
+		var parts = strings.SplitN(expr, "=", 2)
+		var value = strings.TrimPrefix(parts[1], "=")
+		req = Requirement{Key: strings.TrimSpace(parts[0]), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	case strings.HasPrefix(expr, "!"):
+		req = Requirement{Key: strings.TrimSpace(strings.TrimPrefix(expr, "!")), Operator: DoesNotExist}
+	default:
+		req = Requirement{Key: expr, Operator: Exists}
+	}
+
+	if err := ValidateKey(req.Key); err != nil {
+		return req, fmt.Errorf("invalid requirement %q: %v", expr, err)
+	}
+	for _, value := range req.Values {
+		if err := ValidateValue(value); err != nil {
+			return req, fmt.Errorf("invalid requirement %q: %v", expr, err)
+		}
+	}
+	if (req.Operator == In || req.Operator == NotIn) && len(req.Values) == 1 && req.Values[0] == "" {
+		return req, fmt.Errorf("invalid requirement %q: empty value set", expr)
+	}
+	return req, nil
+}
+
+// Matches returns true if labels satisfy requirement
+func (req Requirement) Matches(labels Labels) bool {
+	value, ok := labels[req.Key]
+	switch req.Operator {
+	case Equals, In:
+		return ok && contains(req.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !contains(req.Values, value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// Matches returns true if labels satisfy all requirements
+func (selector Selector) Matches(labels Labels) bool {
+	for _, req := range selector {
+		if !req.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+// Kind -- type of kube-api operation
+type Kind string
+
+const (
+	CreateDeployment Kind = "create_deployment"
+	CreateService    Kind = "create_service"
+	CreateIngress    Kind = "create_ingress"
+	CreateConfigMap  Kind = "create_configmap"
+	CreateSecret     Kind = "create_secret"
+)
+
+// Status -- outbox operation status
+type Status string
+
+const (
+	// Pending -- operation is waiting to be applied
+	Pending Status = "pending"
+	// Done -- operation was applied to kube-api
+	Done Status = "done"
+	// Cancelled -- resource was removed from db before operation was applied
+	Cancelled Status = "cancelled"
+	// Failed -- kube-api rejected operation
+	Failed Status = "failed"
+)
+
+// Operation -- kube-api operation which must be applied after resource was written to db.
+// Operation is written before resource, so every resource in db has an operation. Operation of resource
+// which was not written is cancelled by worker. Resource itself is read from db when operation is applied,
+// so db stays the source of truth.
+//
+// swagger:model
+type Operation struct {
+	ID          string `json:"_id" bson:"_id"`
+	Kind        Kind   `json:"kind" bson:"kind"`
+	NamespaceID string `json:"namespaceid" bson:"namespaceid"`
+	Name        string `json:"name" bson:"name"`
+	Status      Status `json:"status" bson:"status"`
+	//user and role of original request, passed to kube-api
+	UserID        string     `json:"user_id,omitempty" bson:"userid,omitempty"`
+	UserRole      string     `json:"user_role,omitempty" bson:"userrole,omitempty"`
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	LastError     string     `json:"last_error,omitempty" bson:"lasterror,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" bson:"createdat"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" bson:"nextattemptat"`
+	DoneAt        *time.Time `json:"done_at,omitempty" bson:"doneat,omitempty"`
+}
+
+// NewOperation creates pending operation. Operation is not picked by worker until notBefore.
+func NewOperation(kind Kind, nsID, name string, notBefore time.Time) Operation {
+	return Operation{
+		ID:            uuid.New().String(),
+		Kind:          kind,
+		NamespaceID:   nsID,
+		Name:          name,
+		Status:        Pending,
+		CreatedAt:     time.Now().UTC(),
+		NextAttemptAt: notBefore.UTC(),
+	}
+}
+
+// Finish sets final status of operation
+func (op *Operation) Finish(status Status, lastError string) {
+	var now = time.Now().UTC()
+	op.Status = status
+	op.LastError = lastError
+	op.DoneAt = &now
+}
+
+// Retry keeps operation pending until next attempt
+func (op *Operation) Retry(nextAttempt time.Time, lastError string) {
+	op.Status = Pending
+	op.LastError = lastError
+	op.NextAttemptAt = nextAttempt.UTC()
+}
+
+func (op Operation) UpdateQuery() interface{} {
+	return bson.M{
+		"$set": bson.M{
+			"status":        op.Status,
+			"lasterror":     op.LastError,
+			"nextattemptat": op.NextAttemptAt,
+			"doneat":        op.DoneAt,
+		},
+	}
+}
+
+// PendingSelectQuery selects operations of namespace which are not applied yet
+func PendingSelectQuery(nsID string) interface{} {
+	return bson.M{
+		"status":      Pending,
+		"namespaceid": nsID,
+	}
+}
+
+// ClaimSelectQuery selects pending operations which are ready to be applied
+func ClaimSelectQuery(now time.Time) interface{} {
+	return bson.M{
+		"status":        Pending,
+		"nextattemptat": bson.M{"$lte": now.UTC()},
+	}
+}
@@ -0,0 +1,89 @@
+package port
+
+import (
+	"time"
+
+	"github.com/containerum/kube-client/pkg/model"
+)
+
+// Allocation -- external port of domain reserved by service.
+// Port is unique for domain and protocol.
+type Allocation struct {
+	Domain      string         `json:"domain" bson:"domain"`
+	Protocol    model.Protocol `json:"protocol" bson:"protocol"`
+	Port        int            `json:"port" bson:"port"`
+	NamespaceID string         `json:"namespace" bson:"namespaceid"`
+	Service     string         `json:"service" bson:"service"`
+	Owner       string         `json:"owner,omitempty" bson:"owner"`
+	CreatedAt   time.Time      `json:"created_at" bson:"createdat"`
+}
+
+// Key returns domain and protocol of allocation
+func (alloc Allocation) Key() Key {
+	return Key{Domain: alloc.Domain, Protocol: alloc.Protocol}
+}
+
+// Key -- domain and protocol, ports of every pair are allocated independently
+type Key struct {
+	Domain   string
+	Protocol model.Protocol
+}
+
+// SameService returns true if allocations belong to the same service
+func (alloc Allocation) SameService(other Allocation) bool {
+	return alloc.NamespaceID == other.NamespaceID && alloc.Service == other.Service
+}
+
+// Usage -- external ports utilisation of domain
+//
+// swagger:model PortUsage
+type Usage struct {
+	Domain   string         `json:"domain" bson:"domain"`
+	Protocol model.Protocol `json:"protocol" bson:"protocol"`
+	//number of reserved ports in allocation range
+	Used int `json:"used" bson:"used"`
+	//size of allocation range
+	Total int `json:"total" bson:"-"`
+}
+
+// Key returns domain and protocol of usage
+func (usage Usage) Key() Key {
+	return Key{Domain: usage.Domain, Protocol: usage.Protocol}
+}
+
+// Free returns number of ports which can be reserved
+func (usage Usage) Free() int {
+	if usage.Used > usage.Total {
+		return 0
+	}
+	return usage.Total - usage.Used
+}
+
+// UsageResponse -- external ports utilisation of all domains
+//
+// swagger:model PortsUsageResponse
+type UsageResponse struct {
+	//allocation range is [min_port, max_port)
+	MinPort int     `json:"min_port"`
+	MaxPort int     `json:"max_port"`
+	Usage   []Usage `json:"usage"`
+}
+
+// Allocations returns allocations of external service ports
+func Allocations(nsID, serviceName, owner, domain string, ports []model.ServicePort) []Allocation {
+	var allocs = make([]Allocation, 0, len(ports))
+	for _, p := range ports {
+		if p.Port == nil {
+			continue
+		}
+		allocs = append(allocs, Allocation{
+			Domain:      domain,
+			Protocol:    p.Protocol,
+			Port:        *p.Port,
+			NamespaceID: nsID,
+			Service:     serviceName,
+			Owner:       owner,
+		})
+	}
+	return allocs
+}
@@ -0,0 +1,86 @@
+package reconcile
+
+// Kind -- kind of reconciled resource
+type Kind string
+
+const (
+	Deployment Kind = "deployment"
+	Service    Kind = "service"
+	Ingress    Kind = "ingress"
+	ConfigMap  Kind = "configmap"
+)
+
+// DriftType -- type of difference between db and kube-api
+type DriftType string
+
+const (
+	// MissingInCluster -- resource is active in db but not exists in kube-api
+	MissingInCluster DriftType = "missing_in_cluster"
+	// MissingInDB -- resource exists in kube-api but not in db
+	MissingInDB DriftType = "missing_in_db"
+	// Changed -- resource exists in both, but differs
+	Changed DriftType = "changed"
+)
+
+// Direction -- direction of drift repair
+type Direction string
+
+const (
+	// ToCluster -- db state is pushed to kube-api
+	ToCluster Direction = "cluster"
+	// ToDB -- kube-api state is imported to db
+	ToDB Direction = "db"
+)
+
+// Drift -- difference between resource in db and kube-api
+//
+// swagger:model
+type Drift struct {
+	Kind      Kind      `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Type      DriftType `json:"type"`
+	//changed fields
+	Fields   []string `json:"fields,omitempty"`
+	Repaired bool     `json:"repaired"`
+	//repair error
+	Error string `json:"error,omitempty"`
+}
+
+// DriftResponse -- drift report
+//
+// swagger:model
+type DriftResponse struct {
+	//check date in RFC3339 format
+	CheckedAt string    `json:"checked_at"`
+	Direction Direction `json:"direction,omitempty"`
+	Drifts    []Drift   `json:"drifts"`
+	//namespaces which can't be checked
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ReconcileRequest -- reconciliation request, all fields are optional
+//
+// swagger:model
+type ReconcileRequest struct {
+	//if empty, default direction is used, drift is only detected if there is no default direction
+	Direction Direction `json:"direction,omitempty" binding:"omitempty,eq=cluster|eq=db"`
+	Namespace string    `json:"namespace,omitempty"`
+	Kind      Kind      `json:"kind,omitempty" binding:"omitempty,eq=deployment|eq=service|eq=ingress|eq=configmap"`
+	Name      string    `json:"name,omitempty"`
+	//drifts which are repaired by deleting resource (from cluster in cluster direction, from db in db direction) are repaired only if set
+	Prune bool `json:"prune,omitempty"`
+}
+
+// Destructive returns true if drift is repaired in direction by deleting resource
+func (drift Drift) Destructive(direction Direction) bool {
+	return direction == ToCluster && drift.Type == MissingInDB ||
+		direction == ToDB && drift.Type == MissingInCluster
+}
+
+// Match returns true if drift is selected by request
+func (req ReconcileRequest) Match(drift Drift) bool {
+	return (req.Namespace == "" || req.Namespace == drift.Namespace) &&
+		(req.Kind == "" || req.Kind == drift.Kind) &&
+		(req.Name == "" || req.Name == drift.Name)
+}
@@ -10,4 +10,5 @@ type GetResourcesCountResponse struct {
 	Ingresses   int `json:"ingresses"`
 	Pods        int `json:"pods"`
 	ConfigMaps  int `json:"configmaps"`
+	Secrets     int `json:"secrets"`
 }
@@ -0,0 +1,152 @@
+package secret
+
+import (
+	"github.com/containerum/kube-client/pkg/model"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+// ResourceSecret --  model for Secret for resource-service db.
+// Data values are stored encrypted.
+//
+// swagger:model
+type ResourceSecret struct {
+	model.Secret
+	ID          string `json:"_id" bson:"_id,omitempty"`
+	Deleted     bool   `json:"deleted"`
+	NamespaceID string `json:"namespaceid"`
+
+	//incremented on every change of resource, returned in ETag header and checked against If-Match header
+	ResourceVersion int64 `json:"resource_version,omitempty" bson:"resourceversion"`
+}
+
+// ListSecrets -- Secrets list
+//
+// swagger:model
+type ListSecrets []ResourceSecret
+
+// SecretsResponse -- secrets response
+//
+// swagger:model
+type SecretsResponse struct {
+	Secrets ListSecrets `json:"secrets"`
+	//number of resources matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
+}
+
+func FromKube(nsID, owner string, secret model.Secret) ResourceSecret {
+	if owner == "" {
+		owner = "00000000-0000-0000-0000-000000000000"
+	}
+
+	secret.Owner = owner
+	return ResourceSecret{
+		Secret:      secret,
+		NamespaceID: nsID,
+		ID:          uuid.New().String(),
+	}
+}
+
+func (secret ResourceSecret) Copy() ResourceSecret {
+	var cp = secret
+	if secret.Data != nil {
+		cp.Data = make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			cp.Data[k] = v
+		}
+	}
+	return cp
+}
+
+// WithoutData returns copy of secret without values
+func (secret ResourceSecret) WithoutData() ResourceSecret {
+	var cp = secret
+	cp.Data = nil
+	return cp
+}
+
+func (secret ResourceSecret) OneSelectQuery() interface{} {
+	return bson.M{
+		"namespaceid": secret.NamespaceID,
+		"deleted":     false,
+		"secret.name": secret.Name,
+	}
+}
+
+func (secret ResourceSecret) OneSelectDeletedQuery() interface{} {
+	return bson.M{
+		"namespaceid": secret.NamespaceID,
+		"deleted":     true,
+		"secret.name": secret.Name,
+	}
+}
+
+func (secret ResourceSecret) AllSelectQuery() interface{} {
+	return bson.M{
+		"namespaceid": secret.NamespaceID,
+		"deleted":     false,
+	}
+}
+
+func (secret ResourceSecret) AllSelectOwnerQuery() interface{} {
+	return bson.M{
+		"secret.owner": secret.Owner,
+		"deleted":      false,
+	}
+}
+
+func (secret ResourceSecret) UpdateQuery() interface{} {
+	return bson.M{
+		"$set": bson.M{
+			"secret": secret.Secret,
+		},
+	}
+}
+
+func OneSelectQuery(namespaceID, name string) interface{} {
+	return ResourceSecret{
+		NamespaceID: namespaceID,
+		Secret: model.Secret{
+			Name: name,
+		},
+	}.OneSelectQuery()
+}
+
+func (list ListSecrets) Len() int {
+	return len(list)
+}
+
+func (list ListSecrets) Names() []string {
+	var names = make([]string, 0, len(list))
+	for _, secret := range list {
+		names = append(names, secret.Name)
+	}
+	return names
+}
+
+func (list ListSecrets) Copy() ListSecrets {
+	var cp = make(ListSecrets, 0, list.Len())
+	for _, secret := range list {
+		cp = append(cp, secret.Copy())
+	}
+	return cp
+}
+
+// WithoutData returns copy of list without secret values
+func (list ListSecrets) WithoutData() ListSecrets {
+	var cp = make(ListSecrets, 0, list.Len())
+	for _, secret := range list {
+		cp = append(cp, secret.WithoutData())
+	}
+	return cp
+}
+
+func (list ListSecrets) Filter(pred func(ResourceSecret) bool) ListSecrets {
+	var filtered = make(ListSecrets, 0, list.Len())
+	for _, secret := range list {
+		if pred(secret.Copy()) {
+			filtered = append(filtered, secret.Copy())
+		}
+	}
+	return filtered
+}
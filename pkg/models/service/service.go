@@ -1,6 +1,8 @@
 package service
 
 import (
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
 	"github.com/containerum/kube-client/pkg/model"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
@@ -11,10 +13,15 @@ import (
 // swagger:model
 type ResourceService struct {
 	model.Service
+	labels.Metadata `bson:",inline"`
+
 	ID          string `json:"_id" bson:"_id,omitempty"`
 	Deleted     bool   `json:"deleted"`
 	NamespaceID string `json:"namespaceid"`
 	Type        Type   `json:"type" bson:"type"`
+
+	//incremented on every change of resource, returned in ETag header and checked against If-Match header
+	ResourceVersion int64 `json:"resource_version,omitempty" bson:"resourceversion"`
 }
 
 // ListService -- services list
@@ -27,6 +34,16 @@ type ListService []ResourceService
 // swagger:model
 type ServicesResponse struct {
 	Services ListService `json:"services"`
+	//number of resources matched by filters, also returned in X-Total-Count header
+	Total int `json:"total,omitempty"`
+}
+
+// ServiceRequest -- service with labels and annotations
+//
+// swagger:model
+type ServiceRequest struct {
+	model.Service   `yaml:",inline"`
+	labels.Metadata `yaml:",inline"`
 }
 
 type Type string
@@ -53,9 +70,18 @@ func (serv ResourceService) Copy() ResourceService {
 	var cp = serv
 	cp.IPs = append(make([]string, 0, len(cp.IPs)), cp.IPs...)
 	cp.Ports = append(make([]model.ServicePort, 0, len(cp.Ports)), cp.Ports...)
+	cp.Metadata = serv.Metadata.Copy()
 	return cp
 }
 
+// PortAllocations returns external ports reserved by service, nil for internal service
+func (serv ResourceService) PortAllocations() []port.Allocation {
+	if serv.Type != External || serv.Domain == "" {
+		return nil
+	}
+	return port.Allocations(serv.NamespaceID, serv.Name, serv.Owner, serv.Domain, serv.Ports)
+}
+
 func (serv ResourceService) OneSelectQuery() interface{} {
 	return bson.M{
 		"namespaceid":  serv.NamespaceID,
@@ -89,7 +115,9 @@ func (serv ResourceService) AllSelectOwnerQuery() interface{} {
 func (serv ResourceService) UpdateQuery() interface{} {
 	return bson.M{
 		"$set": bson.M{
-			"service": serv.Service,
+			"service":     serv.Service,
+			"labels":      serv.Labels,
+			"annotations": serv.Annotations,
 		},
 	}
 }
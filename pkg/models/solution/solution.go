@@ -0,0 +1,163 @@
+package solution
+
+import (
+	"sort"
+
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+)
+
+// Phase -- aggregated state of solution deployments
+type Phase string
+
+const (
+	// all deployments have all replicas ready
+	Running Phase = "running"
+	// some deployments have replicas which are not ready yet
+	Pending Phase = "pending"
+)
+
+// Status -- aggregated status of solution
+//
+// swagger:model SolutionStatus
+type Status struct {
+	Phase            Phase `json:"phase"`
+	Deployments      int   `json:"deployments"`
+	ReadyDeployments int   `json:"ready_deployments"`
+	Replicas         int   `json:"replicas"`
+	ReadyReplicas    int   `json:"ready_replicas"`
+}
+
+// Solution -- resources of namespace which belong to solution.
+// Deployments and services have solution id, ingresses and configmaps belong to solution
+// if they are used only with resources of solution.
+//
+// swagger:model
+type Solution struct {
+	Name        string                   `json:"name"`
+	Status      Status                   `json:"status"`
+	Deployments deployment.ListDeploy    `json:"deployments"`
+	Services    service.ListService      `json:"services"`
+	Ingresses   ingress.ListIngress      `json:"ingresses"`
+	ConfigMaps  configmap.ListConfigMaps `json:"configmaps"`
+}
+
+// SolutionsResponse -- solutions list
+//
+// swagger:model
+type SolutionsResponse struct {
+	Solutions []Solution `json:"solutions"`
+}
+
+// Group returns solutions of namespace resources sorted by name
+func Group(res graph.Resources) []Solution {
+	var owners = make(map[graph.Node]string)
+	var solutions = make(map[string]*Solution)
+	get := func(name string) *Solution {
+		if sol, ok := solutions[name]; ok {
+			return sol
+		}
+		sol := &Solution{
+			Name:        name,
+			Deployments: make(deployment.ListDeploy, 0),
+			Services:    make(service.ListService, 0),
+			Ingresses:   make(ingress.ListIngress, 0),
+			ConfigMaps:  make(configmap.ListConfigMaps, 0),
+		}
+		solutions[name] = sol
+		return sol
+	}
+
+	for _, depl := range res.Deployments {
+		if depl.SolutionID == "" {
+			continue
+		}
+		sol := get(depl.SolutionID)
+		sol.Deployments = append(sol.Deployments, depl)
+		owners[graph.Node{Kind: graph.Deployment, Name: depl.Name}] = depl.SolutionID
+	}
+	for _, svc := range res.Services {
+		if svc.SolutionID == "" {
+			continue
+		}
+		sol := get(svc.SolutionID)
+		sol.Services = append(sol.Services, svc)
+		owners[graph.Node{Kind: graph.Service, Name: svc.Name}] = svc.SolutionID
+	}
+
+	resGraph := graph.Build(res)
+	for _, ingr := range res.Ingresses {
+		var used []graph.Node
+		for _, edge := range resGraph.Edges {
+			if edge.From.Kind == graph.Ingress && edge.From.Name == ingr.Name && edge.To.Kind == graph.Service {
+				used = append(used, edge.To)
+			}
+		}
+		if name, ok := soleOwner(owners, used); ok {
+			sol := get(name)
+			sol.Ingresses = append(sol.Ingresses, ingr)
+		}
+	}
+	for _, cm := range res.ConfigMaps {
+		var users []graph.Node
+		for _, edge := range resGraph.Edges {
+			if edge.To.Kind == graph.ConfigMap && edge.To.Name == cm.Name {
+				users = append(users, edge.From)
+			}
+		}
+		if name, ok := soleOwner(owners, users); ok {
+			sol := get(name)
+			sol.ConfigMaps = append(sol.ConfigMaps, cm)
+		}
+	}
+
+	var ret = make([]Solution, 0, len(solutions))
+	for _, sol := range solutions {
+		ret = append(ret, *sol)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// SetStatus aggregates status of solution deployments from their kube-api state
+func (sol *Solution) SetStatus(kubeDeploys []kubtypes.Deployment) {
+	var kubeStatus = make(map[string]*kubtypes.DeploymentStatus, len(kubeDeploys))
+	for _, depl := range kubeDeploys {
+		kubeStatus[depl.Name] = depl.Status
+	}
+
+	var status = Status{Phase: Running, Deployments: len(sol.Deployments)}
+	for _, depl := range sol.Deployments {
+		status.Replicas += depl.Replicas
+		ready := 0
+		if st := kubeStatus[depl.Name]; st != nil {
+			ready = st.ReadyReplicas
+		}
+		status.ReadyReplicas += ready
+		if ready >= depl.Replicas {
+			status.ReadyDeployments++
+		} else {
+			status.Phase = Pending
+		}
+	}
+	sol.Status = status
+}
+
+// soleOwner returns solution if all nodes belong to it
+func soleOwner(owners map[graph.Node]string, nodes []graph.Node) (string, bool) {
+	var owner string
+	for _, node := range nodes {
+		name, ok := owners[node]
+		if !ok || (owner != "" && name != owner) {
+			return "", false
+		}
+		owner = name
+	}
+	return owner, owner != ""
+}
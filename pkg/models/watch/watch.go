@@ -0,0 +1,84 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// EventType -- type of resource change
+type EventType string
+
+const (
+	Added    EventType = "added"
+	Modified EventType = "modified"
+	// Name of deleted event is empty if all resources of kind were deleted
+	Deleted EventType = "deleted"
+)
+
+// Kind -- kind of watched resource
+type Kind string
+
+const (
+	Deployment Kind = "deployment"
+	Service    Kind = "service"
+	Ingress    Kind = "ingress"
+	ConfigMap  Kind = "configmap"
+)
+
+// Kinds -- all watched kinds
+var Kinds = []Kind{Deployment, Service, Ingress, ConfigMap}
+
+// Position -- place of event in event stream of service instance.
+// Epoch changes when instance is restarted, so positions of previous runs are stale.
+type Position struct {
+	Epoch string
+	Seq   uint64
+}
+
+// String returns resume token of position
+func (pos Position) String() string {
+	return pos.Epoch + ":" + strconv.FormatUint(pos.Seq, 10)
+}
+
+// ParsePosition parses resume token
+func ParsePosition(token string) (Position, error) {
+	sep := strings.LastIndex(token, ":")
+	if sep <= 0 {
+		return Position{}, errors.New("invalid resume token")
+	}
+	seq, err := strconv.ParseUint(token[sep+1:], 10, 64)
+	if err != nil {
+		return Position{}, errors.New("invalid resume token")
+	}
+	return Position{Epoch: token[:sep], Seq: seq}, nil
+}
+
+// Event -- change of namespace resource.
+// Seq grows with every event, client resumes watch from resume token of last received event.
+//
+// swagger:model WatchEvent
+type Event struct {
+	Epoch       string    `json:"epoch"`
+	Seq         uint64    `json:"seq"`
+	ResumeToken string    `json:"resume_token"`
+	Type        EventType `json:"type"`
+	Kind        Kind      `json:"kind"`
+	NamespaceID string    `json:"namespace"`
+	Name        string    `json:"name,omitempty"`
+	// resource after change, resource before delete for deleted event
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// Subscription -- events of namespace. Events channel is closed when subscription is stopped
+// or when client doesn't read events fast enough; in that case client should resume watch from last received event.
+type Subscription struct {
+	Events <-chan Event
+	Stop   func()
+}
+
+// Position returns position of event in event stream
+func (event Event) Position() Position {
+	return Position{Epoch: event.Epoch, Seq: event.Seq}
+}
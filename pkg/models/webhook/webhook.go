@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+)
+
+// Webhook -- endpoint which is notified about resource events of namespace
+//
+// swagger:model
+type Webhook struct {
+	ID          string `json:"_id,omitempty" bson:"_id"`
+	NamespaceID string `json:"namespace,omitempty" bson:"namespaceid"`
+	// required: true
+	Name string `json:"name" bson:"name" binding:"required,dns"`
+	// http or https URL which receives POST requests
+	// required: true
+	URL string `json:"url" bson:"url" binding:"required,url"`
+	// key of HMAC-SHA256 signature of X-Webhook-Timestamp header and payload, it is never returned
+	Secret string `json:"secret,omitempty" bson:"secret"`
+	// event patterns like "deployment.activate" or "ingress.*", webhook without patterns receives all events
+	Events    []string  `json:"events,omitempty" bson:"events,omitempty"`
+	Owner     string    `json:"owner,omitempty" bson:"owner"`
+	CreatedAt time.Time `json:"created_at,omitempty" bson:"createdat"`
+}
+
+// WebhooksResponse -- webhooks list
+//
+// swagger:model
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// EventType returns event name of resource kind and action, e.g. "deployment.activate"
+func EventType(kind, action string) string {
+	return kind + "." + action
+}
+
+// Validate checks URL scheme and event patterns
+func (hook Webhook) Validate() []string {
+	var errs []string
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, "url must be absolute http or https URL")
+	}
+	for _, pattern := range hook.Events {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, "invalid event pattern "+pattern)
+		}
+	}
+	return errs
+}
+
+// Matches returns true if webhook is subscribed to event type
+func (hook Webhook) Matches(eventType string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, pattern := range hook.Events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutSecret returns copy of webhook without signature key
+func (hook Webhook) WithoutSecret() Webhook {
+	var cp = hook
+	cp.Secret = ""
+	cp.Events = append([]string(nil), hook.Events...)
+	return cp
+}
+
+// Payload -- body of webhook request
+//
+// swagger:model WebhookPayload
+type Payload struct {
+	DeliveryID  string    `json:"delivery_id" bson:"deliveryid"`
+	Event       string    `json:"event" bson:"event"`
+	Time        time.Time `json:"time" bson:"time"`
+	NamespaceID string    `json:"namespace" bson:"namespaceid"`
+	Kind        string    `json:"kind" bson:"kind"`
+	Name        string    `json:"name,omitempty" bson:"name,omitempty"`
+	UserID      string    `json:"user_id" bson:"userid"`
+	// resource after change, resource before delete for delete events
+	Object json.RawMessage `json:"object,omitempty" bson:"object,omitempty"`
+}
+
+// DeliveryStatus -- status of webhook delivery
+type DeliveryStatus string
+
+const (
+	// Pending -- delivery is waiting for next attempt
+	Pending DeliveryStatus = "pending"
+	// Delivered -- webhook responded with 2xx status
+	Delivered DeliveryStatus = "delivered"
+	// Failed -- all attempts failed or webhook was deleted
+	Failed DeliveryStatus = "failed"
+)
+
+// Attempt -- result of single delivery attempt
+//
+// swagger:model WebhookAttempt
+type Attempt struct {
+	Time time.Time `json:"time" bson:"time"`
+	// http status of webhook response, 0 if request failed
+	StatusCode int    `json:"status_code,omitempty" bson:"statuscode,omitempty"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Delivery -- event which is sent to webhook, with log of all attempts
+//
+// swagger:model WebhookDelivery
+type Delivery struct {
+	ID            string         `json:"_id" bson:"_id"`
+	WebhookID     string         `json:"webhook_id" bson:"webhookid"`
+	WebhookName   string         `json:"webhook" bson:"webhookname"`
+	NamespaceID   string         `json:"namespace" bson:"namespaceid"`
+	Payload       Payload        `json:"payload" bson:"payload"`
+	Status        DeliveryStatus `json:"status" bson:"status"`
+	Attempts      int            `json:"attempts" bson:"attempts"`
+	Log           []Attempt      `json:"log" bson:"log"`
+	CreatedAt     time.Time      `json:"created_at" bson:"createdat"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" bson:"nextattemptat"`
+	DoneAt        *time.Time     `json:"done_at,omitempty" bson:"doneat,omitempty"`
+}
+
+// DeliveriesResponse -- webhook deliveries list
+//
+// swagger:model WebhookDeliveriesResponse
+type DeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// MaxDeliveries -- max number of deliveries returned by list, newest deliveries are returned
+const MaxDeliveries = 100
+
+// NewDelivery creates pending delivery of payload which is ready to be sent
+func NewDelivery(hook Webhook, payload Payload) Delivery {
+	var now = time.Now().UTC()
+	payload.DeliveryID = uuid.New().String()
+	return Delivery{
+		ID:            payload.DeliveryID,
+		WebhookID:     hook.ID,
+		WebhookName:   hook.Name,
+		NamespaceID:   hook.NamespaceID,
+		Payload:       payload,
+		Status:        Pending,
+		Log:           make([]Attempt, 0),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+}
+
+// Finish records last attempt and sets final status
+func (d *Delivery) Finish(status DeliveryStatus, attempt Attempt) {
+	var now = time.Now().UTC()
+	d.Log = append(d.Log, attempt)
+	d.Status = status
+	d.DoneAt = &now
+}
+
+// Retry records failed attempt and keeps delivery pending until next attempt
+func (d *Delivery) Retry(nextAttempt time.Time, attempt Attempt) {
+	d.Log = append(d.Log, attempt)
+	d.Status = Pending
+	d.NextAttemptAt = nextAttempt.UTC()
+}
+
+func (d Delivery) UpdateQuery() interface{} {
+	return bson.M{
+		"$set": bson.M{
+			"status":        d.Status,
+			"log":           d.Log,
+			"nextattemptat": d.NextAttemptAt,
+			"doneat":        d.DoneAt,
+		},
+	}
+}
+
+// ClaimSelectQuery selects pending deliveries which are ready to be sent
+func ClaimSelectQuery(now time.Time) interface{} {
+	return bson.M{
+		"status":        Pending,
+		"nextattemptat": bson.M{"$lte": now.UTC()},
+	}
+}
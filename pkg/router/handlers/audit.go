@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandlers struct {
+	server.AuditActions
+	*m.TranslateValidate
+}
+
+// auditQuery parses audit log filters from query parameters
+func auditQuery(ctx *gin.Context) (audit.Query, error) {
+	query := audit.Query{UserID: ctx.Query("user_id")}
+	var err error
+	if from := ctx.Query("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, err
+		}
+	}
+	if to := ctx.Query("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, err
+		}
+	}
+	return query, nil
+}
+
+// swagger:operation GET /audit Audit GetAuditLog
+// Get audit log of resource changes. Newest entries are returned first, 100 entries per page by default.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - name: user_id
+//    in: query
+//    type: string
+//    required: false
+//  - name: from
+//    in: query
+//    type: string
+//    format: date-time
+//    required: false
+//  - name: to
+//    in: query
+//    type: string
+//    format: date-time
+//    required: false
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - name: kind
+//    in: query
+//    type: string
+//  - name: name
+//    in: query
+//    type: string
+//  - name: action
+//    in: query
+//    type: string
+//  - name: outcome
+//    in: query
+//    type: string
+// responses:
+//  '200':
+//    description: audit log
+//    schema:
+//      $ref: '#/definitions/AuditEntriesResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *AuditHandlers) GetAuditLogHandler(ctx *gin.Context) {
+	query, err := auditQuery(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.GetAuditLog(ctx.Request.Context(), query, ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setTotalCount(ctx, resp.Total)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/audit Audit GetNamespaceAuditLog
+// Get audit log of namespace resource changes. Newest entries are returned first, 100 entries per page by default.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: user_id
+//    in: query
+//    type: string
+//    required: false
+//  - name: from
+//    in: query
+//    type: string
+//    format: date-time
+//    required: false
+//  - name: to
+//    in: query
+//    type: string
+//    format: date-time
+//    required: false
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - name: kind
+//    in: query
+//    type: string
+//  - name: name
+//    in: query
+//    type: string
+//  - name: action
+//    in: query
+//    type: string
+//  - name: outcome
+//    in: query
+//    type: string
+// responses:
+//  '200':
+//    description: audit log
+//    schema:
+//      $ref: '#/definitions/AuditEntriesResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *AuditHandlers) GetNamespaceAuditLogHandler(ctx *gin.Context) {
+	query, err := auditQuery(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.GetNamespaceAuditLog(ctx.Request.Context(), ctx.Param("namespace"), query, ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setTotalCount(ctx, resp.Total)
+	ctx.JSON(http.StatusOK, resp)
+}
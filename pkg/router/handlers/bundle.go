@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/bundle"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type BundleHandlers struct {
+	server.BundleActions
+	*m.TranslateValidate
+}
+
+// swagger:operation POST /namespaces/{namespace}/apply Bundle ApplyBundle
+// Create configmaps, deployments, services and ingresses at once.
+// If some resource can't be created, all resources created before it are deleted.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/Bundle'
+// responses:
+//  '201':
+//    description: bundle applied
+//    schema:
+//      $ref: '#/definitions/BundleResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *BundleHandlers) ApplyBundleHandler(ctx *gin.Context) {
+	var req bundle.Bundle
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.ApplyBundle(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
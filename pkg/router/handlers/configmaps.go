@@ -31,6 +31,16 @@ type ConfigMapHandlers struct {
 //    in: path
 //    type: string
 //    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: configmaps list
@@ -39,12 +49,13 @@ type ConfigMapHandlers struct {
 //  default:
 //    $ref: '#/responses/error'
 func (h *ConfigMapHandlers) GetConfigMapsListHandler(ctx *gin.Context) {
-	resp, err := h.GetConfigMapsList(ctx.Request.Context(), ctx.Param("namespace"))
+	resp, err := h.GetConfigMapsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Request.URL.Query())
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -57,6 +68,19 @@ func (h *ConfigMapHandlers) GetConfigMapsListHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: namespace
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: configmaps list
@@ -73,7 +97,7 @@ func (h *ConfigMapHandlers) GetSelectedConfigMapsListHandler(ctx *gin.Context) {
 		for k := range *nsList {
 			nss = append(nss, k)
 		}
-		ret, err := h.GetSelectedConfigMapsList(ctx.Request.Context(), nss)
+		ret, err := h.GetSelectedConfigMapsList(ctx.Request.Context(), nss, ctx.Request.URL.Query())
 		if err != nil {
 			ctx.AbortWithStatusJSON(h.HandleError(err))
 			return
@@ -81,6 +105,7 @@ func (h *ConfigMapHandlers) GetSelectedConfigMapsListHandler(ctx *gin.Context) {
 		resp = *ret
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -116,6 +141,170 @@ func (h *ConfigMapHandlers) GetConfigMapHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, resp.ResourceVersion)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/configmaps/{configmap}/versions ConfigMap GetConfigMapVersionsList
+// Get configmap versions list.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: active
+//    in: query
+//    type: boolean
+// responses:
+//  '200':
+//    description: configmap versions list
+//    schema:
+//      $ref: '#/definitions/ConfigMapsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) GetConfigMapVersionsListHandler(ctx *gin.Context) {
+	resp, err := h.GetConfigMapVersionsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setTotalCount(ctx, resp.Total)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/configmaps/{configmap}/versions/{version} ConfigMap GetConfigMapVersion
+// Get configmap version.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - name: version
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: configmap version
+//    schema:
+//      $ref: '#/definitions/ResourceConfigMap'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) GetConfigMapVersionHandler(ctx *gin.Context) {
+	resp, err := h.GetConfigMapVersion(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), ctx.Param("version"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/configmaps/{configmap}/versions/{version}/diff/{version2} ConfigMap DiffConfigMapVersions
+// Compare two configmap versions.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - name: version
+//    in: path
+//    type: string
+//    required: true
+//  - name: version2
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: diff
+//    schema:
+//      $ref: '#/definitions/ConfigMapDiff'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) DiffConfigMapVersionsHandler(ctx *gin.Context) {
+	resp, err := h.DiffConfigMaps(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), ctx.Param("version"), ctx.Param("version2"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/configmaps/{configmap}/versions/{version}/diff ConfigMap DiffConfigMapPreviousVersions
+// Compare configmap version with previous one.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - name: version
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: diff
+//    schema:
+//      $ref: '#/definitions/ConfigMapDiff'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) DiffConfigMapPreviousVersionsHandler(ctx *gin.Context) {
+	resp, err := h.DiffConfigMapsPrevious(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), ctx.Param("version"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -128,6 +317,8 @@ func (h *ConfigMapHandlers) GetConfigMapHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -135,7 +326,7 @@ func (h *ConfigMapHandlers) GetConfigMapHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//     $ref: '#/definitions/ConfigMap'
+//     $ref: '#/definitions/ConfigMapRequest'
 // responses:
 //  '201':
 //    description: configmap created
@@ -144,7 +335,7 @@ func (h *ConfigMapHandlers) GetConfigMapHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *ConfigMapHandlers) CreateConfigMapHandler(ctx *gin.Context) {
-	var req kubtypes.ConfigMap
+	var req configmap.ConfigMapRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -168,6 +359,8 @@ func (h *ConfigMapHandlers) CreateConfigMapHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: body
 //    in: body
 //    schema:
@@ -203,6 +396,101 @@ func (h *ConfigMapHandlers) ImportConfigMapsHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusAccepted, resp)
 }
 
+// swagger:operation PUT /namespaces/{namespace}/configmaps/{configmap} ConfigMap UpdateConfigMap
+// Replace configmap data. New configmap version is created.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//     $ref: '#/definitions/ConfigMapRequest'
+// responses:
+//  '202':
+//    description: configmap updated
+//    schema:
+//     $ref: '#/definitions/ResourceConfigMap'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) UpdateConfigMapHandler(ctx *gin.Context) {
+	var req configmap.ConfigMapRequest
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	req.Name = ctx.Param("configmap")
+	updatedCM, err := h.UpdateConfigMap(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setETag(ctx, updatedCM.ResourceVersion)
+	ctx.JSON(http.StatusAccepted, updatedCM)
+}
+
+// swagger:operation PATCH /namespaces/{namespace}/configmaps/{configmap} ConfigMap PatchConfigMap
+// Set or remove configmap keys. New configmap version is created.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: configmap
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//     $ref: '#/definitions/ConfigMapPatch'
+// responses:
+//  '202':
+//    description: configmap updated
+//    schema:
+//     $ref: '#/definitions/ResourceConfigMap'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ConfigMapHandlers) PatchConfigMapHandler(ctx *gin.Context) {
+	var req configmap.ConfigMapPatch
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	updatedCM, err := h.PatchConfigMap(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setETag(ctx, updatedCM.ResourceVersion)
+	ctx.JSON(http.StatusAccepted, updatedCM)
+}
+
 // swagger:operation DELETE /namespaces/{namespace}/configmaps/{configmap} ConfigMap DeleteConfigMap
 // Delete configmap.
 //
@@ -212,6 +500,8 @@ func (h *ConfigMapHandlers) ImportConfigMapsHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -220,13 +510,17 @@ func (h *ConfigMapHandlers) ImportConfigMapsHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on this one
 // responses:
 //  '202':
 //    description: configmap deleted
 //  default:
 //    $ref: '#/responses/error'
 func (h *ConfigMapHandlers) DeleteConfigMapHandler(ctx *gin.Context) {
-	err := h.DeleteConfigMap(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"))
+	err := h.DeleteConfigMap(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("configmap"), ctx.Query("cascade") == "true")
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
@@ -241,6 +535,7 @@ func (h *ConfigMapHandlers) DeleteConfigMapHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
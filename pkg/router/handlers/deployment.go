@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
 	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/server"
@@ -30,6 +31,22 @@ type DeployHandlers struct {
 //    in: path
 //    type: string
 //    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: solution_id
+//    in: query
+//    type: string
+//  - name: image
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: deployments list
@@ -38,12 +55,13 @@ type DeployHandlers struct {
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) GetDeploymentsListHandler(ctx *gin.Context) {
-	resp, err := h.GetDeploymentsList(ctx.Request.Context(), ctx.Param("namespace"))
+	resp, err := h.GetDeploymentsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Request.URL.Query())
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -64,6 +82,22 @@ func (h *DeployHandlers) GetDeploymentsListHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: image
+//    in: query
+//    type: string
+//  - name: active
+//    in: query
+//    type: boolean
+//  - name: failed
+//    in: query
+//    type: boolean
 // responses:
 //  '200':
 //    description: deployment versions list
@@ -72,12 +106,13 @@ func (h *DeployHandlers) GetDeploymentsListHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) GetDeploymentVersionsListHandler(ctx *gin.Context) {
-	resp, err := h.GetDeploymentVersionsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"))
+	resp, err := h.GetDeploymentVersionsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"), ctx.Request.URL.Query())
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -112,6 +147,7 @@ func (h *DeployHandlers) GetActiveDeploymentHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, resp.ResourceVersion)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -162,6 +198,8 @@ func (h *DeployHandlers) GetDeploymentVersionHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -169,7 +207,7 @@ func (h *DeployHandlers) GetDeploymentVersionHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/Deployment'
+//      $ref: '#/definitions/DeploymentRequest'
 // responses:
 //  '201':
 //    description: deployment created
@@ -178,7 +216,7 @@ func (h *DeployHandlers) GetDeploymentVersionHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) CreateDeploymentHandler(ctx *gin.Context) {
-	var req kubtypes.Deployment
+	var req deployment.DeploymentRequest
 
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
@@ -201,6 +239,8 @@ func (h *DeployHandlers) CreateDeploymentHandler(ctx *gin.Context) {
 // parameters:
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: body
 //    in: body
 //    schema:
@@ -245,6 +285,8 @@ func (h *DeployHandlers) ImportDeploymentsHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -274,6 +316,122 @@ func (h *DeployHandlers) ChangeActiveDeploymentHandler(ctx *gin.Context) {
 	ctx.JSON(http.StatusAccepted, resp)
 }
 
+// swagger:operation POST /namespaces/{namespace}/deployments/{deployment}/versions/{version}/promote Deployment PromoteDeployment
+// Promote canary deployment version.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: deployment
+//    in: path
+//    type: string
+//    required: true
+//  - name: version
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: canary deployment version promoted
+//    schema:
+//      $ref: '#/definitions/ResourceDeploy'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DeployHandlers) PromoteDeploymentHandler(ctx *gin.Context) {
+	resp, err := h.PromoteDeployment(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"), ctx.Param("version"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation POST /namespaces/{namespace}/deployments/{deployment}/versions/{version}/abort Deployment AbortDeployment
+// Abort canary deployment version rollout.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: deployment
+//    in: path
+//    type: string
+//    required: true
+//  - name: version
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: canary deployment version rollout aborted
+//    schema:
+//      $ref: '#/definitions/ResourceDeploy'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DeployHandlers) AbortDeploymentHandler(ctx *gin.Context) {
+	resp, err := h.AbortDeployment(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"), ctx.Param("version"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation POST /namespaces/{namespace}/deployments/{deployment}/rollback Deployment RollbackDeployment
+// Rollback deployment to the last good version.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: deployment
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: deployment rolled back
+//    schema:
+//      $ref: '#/definitions/ResourceDeploy'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DeployHandlers) RollbackDeploymentHandler(ctx *gin.Context) {
+	resp, err := h.RollbackDeployment(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
 // swagger:operation PUT /namespaces/{namespace}/deployments/{deployment}/versions/{version} Deployment RenameVersion
 // Rename deployment version.
 //
@@ -283,6 +441,7 @@ func (h *DeployHandlers) ChangeActiveDeploymentHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -337,6 +496,8 @@ func (h *DeployHandlers) RenameVersionHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -348,7 +509,7 @@ func (h *DeployHandlers) RenameVersionHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/Deployment'
+//      $ref: '#/definitions/DeploymentRequest'
 // responses:
 //  '202':
 //    description: deployment updated
@@ -357,7 +518,7 @@ func (h *DeployHandlers) RenameVersionHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) UpdateDeploymentHandler(ctx *gin.Context) {
-	var req kubtypes.Deployment
+	var req deployment.DeploymentRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -370,6 +531,7 @@ func (h *DeployHandlers) UpdateDeploymentHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, updDeploy.ResourceVersion)
 	ctx.JSON(http.StatusAccepted, updDeploy)
 }
 
@@ -381,6 +543,8 @@ func (h *DeployHandlers) UpdateDeploymentHandler(ctx *gin.Context) {
 // parameters:
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //
 //  - name: namespace
 //    in: path
@@ -414,6 +578,7 @@ func (h *DeployHandlers) SetContainerImageHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, updatedDeploy.ResourceVersion)
 	ctx.JSON(http.StatusAccepted, updatedDeploy)
 }
 
@@ -426,6 +591,8 @@ func (h *DeployHandlers) SetContainerImageHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -457,6 +624,7 @@ func (h *DeployHandlers) SetReplicasHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, updatedDeploy.ResourceVersion)
 	ctx.JSON(http.StatusAccepted, updatedDeploy)
 }
 
@@ -469,6 +637,8 @@ func (h *DeployHandlers) SetReplicasHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -477,13 +647,17 @@ func (h *DeployHandlers) SetReplicasHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on this one
 // responses:
 //  '202':
 //    description: deployment deleted
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) DeleteDeploymentHandler(ctx *gin.Context) {
-	err := h.DeleteDeployment(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"))
+	err := h.DeleteDeployment(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("deployment"), ctx.Query("cascade") == "true")
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
@@ -501,6 +675,7 @@ func (h *DeployHandlers) DeleteDeploymentHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -534,6 +709,7 @@ func (h *DeployHandlers) DeleteDeploymentVersionHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -559,6 +735,7 @@ func (h *DeployHandlers) DeleteAllDeploymentsHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -567,13 +744,17 @@ func (h *DeployHandlers) DeleteAllDeploymentsHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on solution deployments
 // responses:
 //  '202':
 //    description: all solution deployments deleted
 //  default:
 //    $ref: '#/responses/error'
 func (h *DeployHandlers) DeleteAllSolutionDeploymentsHandler(ctx *gin.Context) {
-	if err := h.DeleteAllSolutionDeployments(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution")); err != nil {
+	if err := h.DeleteAllSolutionDeployments(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution"), ctx.Query("cascade") == "true"); err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
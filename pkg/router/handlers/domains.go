@@ -87,6 +87,8 @@ func (h *DomainHandlers) GetDomainHandler(ctx *gin.Context) {
 // x-method-visibility: public
 // parameters:
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: body
 //    in: body
 //    schema:
@@ -144,6 +146,7 @@ func (h *DomainHandlers) AddDomainHandler(ctx *gin.Context) {
 // x-method-visibility: public
 // parameters:
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: domain
 //    in: path
 //    type: string
@@ -161,3 +164,220 @@ func (h *DomainHandlers) DeleteDomainHandler(ctx *gin.Context) {
 
 	ctx.Status(http.StatusAccepted)
 }
+
+// swagger:operation PUT /domains/{domain} Domain UpdateDomain
+// Update domain addresses or group. External services of domain get new addresses.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: domain
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/DomainUpdateRequest'
+// responses:
+//  '202':
+//    description: domain updated
+//    schema:
+//      $ref: '#/definitions/Domain'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) UpdateDomainHandler(ctx *gin.Context) {
+	var req domain.UpdateRequest
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.UpdateDomain(ctx.Request.Context(), ctx.Param("domain"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation POST /domains/{domain}/drain Domain DrainDomain
+// Stop placing services on domain and move its external services to other domains of the same group.
+// Services get new external ports. Domain can be deleted when all services are moved.
+// Drain stops on first service which can't be moved, moved services are not moved back: response is not complete
+// and lists failed and remaining services, drain can be repeated.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: domain
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: moved services
+//    schema:
+//      $ref: '#/definitions/DomainDrainResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) DrainDomainHandler(ctx *gin.Context) {
+	resp, err := h.DrainDomain(ctx.Request.Context(), ctx.Param("domain"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation PUT /domains/{domain}/pool Domain SetDomainPoolSettings
+// Set domain weight and state used in domain selection for external services.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: domain
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/DomainPoolSettings'
+// responses:
+//  '202':
+//    description: domain updated
+//    schema:
+//      $ref: '#/definitions/Domain'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) SetDomainPoolSettingsHandler(ctx *gin.Context) {
+	var req domain.PoolSettings
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.SetDomainPoolSettings(ctx.Request.Context(), ctx.Param("domain"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation GET /admin/domain_pins Domain GetDomainPinsList
+// Get namespaces and users pinned to domain groups.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+// responses:
+//  '200':
+//    description: domain pins
+//    schema:
+//      $ref: '#/definitions/DomainPinsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) GetDomainPinsListHandler(ctx *gin.Context) {
+	resp, err := h.GetDomainPinsList(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation PUT /admin/domain_pins/{kind}/{id} Domain SetDomainPin
+// Pin namespace or user to domain group. External services of namespace or user are placed only on domains of the group.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: kind
+//    in: path
+//    type: string
+//    enum: [namespace, user]
+//    required: true
+//  - name: id
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/DomainPin'
+// responses:
+//  '202':
+//    description: domain pin set
+//    schema:
+//      $ref: '#/definitions/DomainPin'
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) SetDomainPinHandler(ctx *gin.Context) {
+	var req domain.Pin
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+	req.Kind = domain.PinKind(ctx.Param("kind"))
+	req.ID = ctx.Param("id")
+
+	resp, err := h.SetDomainPin(ctx.Request.Context(), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation DELETE /admin/domain_pins/{kind}/{id} Domain DeleteDomainPin
+// Unpin namespace or user from domain group.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: kind
+//    in: path
+//    type: string
+//    enum: [namespace, user]
+//    required: true
+//  - name: id
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: domain pin deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *DomainHandlers) DeleteDomainPinHandler(ctx *gin.Context) {
+	if err := h.DeleteDomainPin(ctx.Request.Context(), domain.PinKind(ctx.Param("kind")), ctx.Param("id")); err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag returns resource version in ETag header, client sends it back in If-Match header to update or delete this version only
+func setETag(ctx *gin.Context, version int64) {
+	if version > 0 {
+		ctx.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+)
+
+type GraphHandlers struct {
+	server.GraphActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /namespaces/{namespace}/graph Graph GetGraph
+// Get graph of namespace resources and dependencies between them.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: resources graph
+//    schema:
+//      $ref: '#/definitions/Graph'
+//  default:
+//    $ref: '#/responses/error'
+func (h *GraphHandlers) GetGraphHandler(ctx *gin.Context) {
+	resp, err := h.GetGraph(ctx.Request.Context(), ctx.Param("namespace"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type HostHandlers struct {
+	server.HostActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /hosts Host GetHostReservationsList
+// Get hosts reserved by user.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+// responses:
+//  '200':
+//    description: host reservations
+//    schema:
+//      $ref: '#/definitions/HostReservationsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *HostHandlers) GetHostReservationsListHandler(ctx *gin.Context) {
+	resp, err := h.GetHostReservationsList(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /admin/hosts Host GetAllHostReservationsList
+// Get hosts reserved by all users.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+// responses:
+//  '200':
+//    description: host reservations
+//    schema:
+//      $ref: '#/definitions/HostReservationsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *HostHandlers) GetAllHostReservationsListHandler(ctx *gin.Context) {
+	resp, err := h.GetAllHostReservationsList(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /hosts Host ReserveHost
+// Reserve host or wildcard subtree (*.example.com), so ingresses of other users can't use it.
+// Ingress suffix is appended to host like to ingress hosts.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/HostReservationRequest'
+// responses:
+//  '201':
+//    description: host reserved
+//    schema:
+//      $ref: '#/definitions/HostReservation'
+//  default:
+//    $ref: '#/responses/error'
+func (h *HostHandlers) ReserveHostHandler(ctx *gin.Context) {
+	var req host.ReservationRequest
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.ReserveHost(ctx.Request.Context(), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation DELETE /hosts/{host} Host DeleteHostReservation
+// Delete host reservation of user.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: host
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: host reservation deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *HostHandlers) DeleteHostReservationHandler(ctx *gin.Context) {
+	if err := h.DeleteHostReservation(ctx.Request.Context(), ctx.Param("host")); err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation DELETE /admin/hosts/{host} Host DeleteAnyHostReservation
+// Delete host reservation of any user.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: host
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: host reservation deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *HostHandlers) DeleteAnyHostReservationHandler(ctx *gin.Context) {
+	if err := h.DeleteAnyHostReservation(ctx.Request.Context(), ctx.Param("host")); err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
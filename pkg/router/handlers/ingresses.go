@@ -32,6 +32,19 @@ type IngressHandlers struct {
 //    in: path
 //    type: string
 //    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: host
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: ingresses list
@@ -40,12 +53,13 @@ type IngressHandlers struct {
 //  default:
 //    $ref: '#/responses/error'
 func (h *IngressHandlers) GetIngressesListHandler(ctx *gin.Context) {
-	resp, err := h.GetIngressesList(ctx.Request.Context(), ctx.Param("namespace"))
+	resp, err := h.GetIngressesList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Request.URL.Query())
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -58,6 +72,22 @@ func (h *IngressHandlers) GetIngressesListHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: namespace
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: host
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: ingresses list
@@ -74,7 +104,7 @@ func (h *IngressHandlers) GetSelectedIngressesListHandler(ctx *gin.Context) {
 		for k := range *nsList {
 			nss = append(nss, k)
 		}
-		ret, err := h.GetSelectedIngressesList(ctx.Request.Context(), nss)
+		ret, err := h.GetSelectedIngressesList(ctx.Request.Context(), nss, ctx.Request.URL.Query())
 		if err != nil {
 			ctx.AbortWithStatusJSON(h.HandleError(err))
 			return
@@ -82,6 +112,7 @@ func (h *IngressHandlers) GetSelectedIngressesListHandler(ctx *gin.Context) {
 		resp = *ret
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -116,6 +147,7 @@ func (h *IngressHandlers) GetIngressHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, resp.ResourceVersion)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -128,6 +160,8 @@ func (h *IngressHandlers) GetIngressHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -135,7 +169,7 @@ func (h *IngressHandlers) GetIngressHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/Ingress'
+//      $ref: '#/definitions/IngressRequest'
 // responses:
 //  '201':
 //    description: ingress created
@@ -144,7 +178,7 @@ func (h *IngressHandlers) GetIngressHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *IngressHandlers) CreateIngressHandler(ctx *gin.Context) {
-	var req kubtypes.Ingress
+	var req ingress.IngressRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -167,6 +201,8 @@ func (h *IngressHandlers) CreateIngressHandler(ctx *gin.Context) {
 // parameters:
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: body
 //    in: body
 //    schema:
@@ -211,6 +247,8 @@ func (h *IngressHandlers) ImportIngressesHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -222,7 +260,7 @@ func (h *IngressHandlers) ImportIngressesHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//      $ref: '#/definitions/Ingress'
+//      $ref: '#/definitions/IngressRequest'
 // responses:
 //  '202':
 //    description: ingress updated
@@ -231,7 +269,7 @@ func (h *IngressHandlers) ImportIngressesHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *IngressHandlers) UpdateIngressHandler(ctx *gin.Context) {
-	var req kubtypes.Ingress
+	var req ingress.IngressRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -244,6 +282,7 @@ func (h *IngressHandlers) UpdateIngressHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, updatedIngress.ResourceVersion)
 	ctx.JSON(http.StatusAccepted, updatedIngress)
 }
 
@@ -256,6 +295,8 @@ func (h *IngressHandlers) UpdateIngressHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -284,6 +325,7 @@ func (h *IngressHandlers) DeleteIngressHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TotalCountHeader -- number of resources matched by list request filters, regardless of requested page
+const TotalCountHeader = "X-Total-Count"
+
+func setTotalCount(ctx *gin.Context, total int) {
+	ctx.Header(TotalCountHeader, strconv.Itoa(total))
+}
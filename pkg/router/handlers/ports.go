@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+)
+
+type PortHandlers struct {
+	server.PortActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /admin/ports Port GetPortsUsage
+// Get external ports utilisation of domains.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+// responses:
+//  '200':
+//    description: ports usage
+//    schema:
+//      $ref: '#/definitions/PortsUsageResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *PortHandlers) GetPortsUsageHandler(ctx *gin.Context) {
+	resp, err := h.GetPortsUsage(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type ReconcileHandlers struct {
+	server.ReconcileActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /admin/drift Reconcile GetDrift
+// Get drift between db and kube-api found by last reconciliation.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+// responses:
+//  '200':
+//    description: drift report
+//    schema:
+//      $ref: '#/definitions/DriftResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ReconcileHandlers) GetDriftHandler(ctx *gin.Context) {
+	resp, err := h.GetDrift(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /admin/reconcile Reconcile Reconcile
+// Find drift between db and kube-api and repair it.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/ReconcileRequest'
+// responses:
+//  '200':
+//    description: drift report
+//    schema:
+//      $ref: '#/definitions/DriftResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *ReconcileHandlers) ReconcileHandler(ctx *gin.Context) {
+	var req reconcile.ReconcileRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+			ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+			return
+		}
+	}
+
+	resp, err := h.Reconcile(ctx.Request.Context(), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -57,6 +57,7 @@ func (h *ResourceHandlers) GetResourcesCountHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -83,6 +84,7 @@ func (h *ResourceHandlers) DeleteAllResourcesInNamespaceHandler(ctx *gin.Context
 // parameters:
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
 // responses:
 //  '202':
 //    description: all user resources deleted
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sirupsen/logrus"
+)
+
+type SecretHandlers struct {
+	server.SecretActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /namespaces/{namespace}/secrets Secret GetSecretsList
+// Get secrets list. Secret values are not returned.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+// responses:
+//  '200':
+//    description: secrets list
+//    schema:
+//      $ref: '#/definitions/SecretsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) GetSecretsListHandler(ctx *gin.Context) {
+	resp, err := h.GetSecretsList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Request.URL.Query())
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setTotalCount(ctx, resp.Total)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /secrets Secret GetSelectedSecretsList
+// Get user secrets list. Secret values are not returned.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: namespace
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+// responses:
+//  '200':
+//    description: secrets list
+//    schema:
+//      $ref: '#/definitions/SecretsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) GetSelectedSecretsListHandler(ctx *gin.Context) {
+	resp := secret.SecretsResponse{Secrets: secret.ListSecrets{}}
+	role := m.GetHeader(ctx, httputil.UserRoleXHeader)
+	if role == m.RoleUser {
+		nsList := ctx.MustGet(m.UserNamespaces).(*m.UserHeaderDataMap)
+		var nss []string
+		for k := range *nsList {
+			nss = append(nss, k)
+		}
+		ret, err := h.GetSelectedSecretsList(ctx.Request.Context(), nss, ctx.Request.URL.Query())
+		if err != nil {
+			ctx.AbortWithStatusJSON(h.HandleError(err))
+			return
+		}
+		resp = *ret
+	}
+
+	setTotalCount(ctx, resp.Total)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/secrets/{secret} Secret GetSecret
+// Get secret with decrypted values.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: secret
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: secret
+//    schema:
+//     $ref: '#/definitions/ResourceSecret'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) GetSecretHandler(ctx *gin.Context) {
+	resp, err := h.GetSecret(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("secret"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setETag(ctx, resp.ResourceVersion)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /namespaces/{namespace}/secrets Secret CreateSecret
+// Create secret.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//     $ref: '#/definitions/Secret'
+// responses:
+//  '201':
+//    description: secret created
+//    schema:
+//     $ref: '#/definitions/ResourceSecret'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) CreateSecretHandler(ctx *gin.Context) {
+	var req kubtypes.Secret
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	createdSecret, err := h.CreateSecret(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdSecret)
+}
+
+// swagger:operation POST /import/secrets Secret ImportSecrets
+// Import secrets.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: body
+//    in: body
+//    schema:
+//     $ref: '#/definitions/SecretsList'
+// responses:
+//  '202':
+//    description: secrets imported
+//    schema:
+//      $ref: '#/definitions/ImportResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) ImportSecretsHandler(ctx *gin.Context) {
+	var req kubtypes.SecretsList
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp := kubtypes.ImportResponse{
+		Imported: []kubtypes.ImportResult{},
+		Failed:   []kubtypes.ImportResult{},
+	}
+
+	for _, s := range req.Secrets {
+		if err := h.ImportSecret(ctx.Request.Context(), s.Namespace, s); err != nil {
+			logrus.Warn(err)
+			resp.ImportFailed(s.Name, s.Namespace, err.Error())
+		} else {
+			resp.ImportSuccessful(s.Name, s.Namespace)
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation PUT /namespaces/{namespace}/secrets/{secret} Secret UpdateSecret
+// Update secret values.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: secret
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//     $ref: '#/definitions/Secret'
+// responses:
+//  '202':
+//    description: secret updated
+//    schema:
+//     $ref: '#/definitions/ResourceSecret'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) UpdateSecretHandler(ctx *gin.Context) {
+	var req kubtypes.Secret
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	req.Name = ctx.Param("secret")
+	updatedSecret, err := h.UpdateSecret(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	setETag(ctx, updatedSecret.ResourceVersion)
+	ctx.JSON(http.StatusAccepted, updatedSecret)
+}
+
+// swagger:operation DELETE /namespaces/{namespace}/secrets/{secret} Secret DeleteSecret
+// Delete secret.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: secret
+//    in: path
+//    type: string
+//    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on this one
+// responses:
+//  '202':
+//    description: secret deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) DeleteSecretHandler(ctx *gin.Context) {
+	err := h.DeleteSecret(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("secret"), ctx.Query("cascade") == "true")
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// swagger:operation DELETE /namespaces/{namespace}/secrets Secret DeleteAllSecrets
+// Delete all secrets in namespace.
+//
+// ---
+// x-method-visibility: private
+// parameters:
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: all secrets in namespace deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *SecretHandlers) DeleteAllSecretsHandler(ctx *gin.Context) {
+	err := h.DeleteAllSecrets(ctx.Request.Context(), ctx.Param("namespace"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
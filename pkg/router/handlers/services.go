@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"git.containerum.net/ch/resource-service/pkg/models/service"
 	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
 	"git.containerum.net/ch/resource-service/pkg/server"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
@@ -29,6 +30,25 @@ type ServiceHandlers struct {
 //    in: path
 //    type: string
 //    required: true
+//  - $ref: '#/parameters/PageQuery'
+//  - $ref: '#/parameters/PerPageQuery'
+//  - $ref: '#/parameters/SortQuery'
+//  - $ref: '#/parameters/SelectorQuery'
+//  - name: name
+//    in: query
+//    type: string
+//  - name: owner
+//    in: query
+//    type: string
+//  - name: solution_id
+//    in: query
+//    type: string
+//  - name: type
+//    in: query
+//    type: string
+//  - name: deploy
+//    in: query
+//    type: string
 // responses:
 //  '200':
 //    description: services list
@@ -37,12 +57,13 @@ type ServiceHandlers struct {
 //  default:
 //    $ref: '#/responses/error'
 func (h *ServiceHandlers) GetServicesListHandler(ctx *gin.Context) {
-	resp, err := h.GetServicesList(ctx.Request.Context(), ctx.Param("namespace"))
+	resp, err := h.GetServicesList(ctx.Request.Context(), ctx.Param("namespace"), ctx.Request.URL.Query())
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
 
+	setTotalCount(ctx, resp.Total)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -78,6 +99,7 @@ func (h *ServiceHandlers) GetServiceHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, resp.ResourceVersion)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -90,6 +112,8 @@ func (h *ServiceHandlers) GetServiceHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -97,7 +121,7 @@ func (h *ServiceHandlers) GetServiceHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//     $ref: '#/definitions/Service'
+//     $ref: '#/definitions/ServiceRequest'
 // responses:
 //  '201':
 //    description: service created
@@ -106,7 +130,7 @@ func (h *ServiceHandlers) GetServiceHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *ServiceHandlers) CreateServiceHandler(ctx *gin.Context) {
-	var req kubtypes.Service
+	var req service.ServiceRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -129,6 +153,8 @@ func (h *ServiceHandlers) CreateServiceHandler(ctx *gin.Context) {
 // parameters:
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
 //  - name: body
 //    in: body
 //    schema:
@@ -173,6 +199,8 @@ func (h *ServiceHandlers) ImportServicesHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -184,7 +212,7 @@ func (h *ServiceHandlers) ImportServicesHandler(ctx *gin.Context) {
 //  - name: body
 //    in: body
 //    schema:
-//     $ref: '#/definitions/Service'
+//     $ref: '#/definitions/ServiceRequest'
 // responses:
 //  '202':
 //    description: service updated
@@ -193,7 +221,7 @@ func (h *ServiceHandlers) ImportServicesHandler(ctx *gin.Context) {
 //  default:
 //    $ref: '#/responses/error'
 func (h *ServiceHandlers) UpdateServiceHandler(ctx *gin.Context) {
-	var req kubtypes.Service
+	var req service.ServiceRequest
 	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
 		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
 		return
@@ -206,6 +234,7 @@ func (h *ServiceHandlers) UpdateServiceHandler(ctx *gin.Context) {
 		return
 	}
 
+	setETag(ctx, updatedService.ResourceVersion)
 	ctx.JSON(http.StatusAccepted, updatedService)
 }
 
@@ -218,6 +247,8 @@ func (h *ServiceHandlers) UpdateServiceHandler(ctx *gin.Context) {
 //  - $ref: '#/parameters/UserIDHeader'
 //  - $ref: '#/parameters/UserRoleHeader'
 //  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IfMatchHeader'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -226,13 +257,17 @@ func (h *ServiceHandlers) UpdateServiceHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on this one
 // responses:
 //  '202':
 //    description: service deleted
 //  default:
 //    $ref: '#/responses/error'
 func (h *ServiceHandlers) DeleteServiceHandler(ctx *gin.Context) {
-	err := h.DeleteService(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("service"))
+	err := h.DeleteService(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("service"), ctx.Query("cascade") == "true")
 	if err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
@@ -247,6 +282,7 @@ func (h *ServiceHandlers) DeleteServiceHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -272,6 +308,7 @@ func (h *ServiceHandlers) DeleteAllServicesHandler(ctx *gin.Context) {
 // ---
 // x-method-visibility: private
 // parameters:
+//  - $ref: '#/parameters/DryRunQuery'
 //  - name: namespace
 //    in: path
 //    type: string
@@ -280,13 +317,17 @@ func (h *ServiceHandlers) DeleteAllServicesHandler(ctx *gin.Context) {
 //    in: path
 //    type: string
 //    required: true
+//  - name: cascade
+//    in: query
+//    type: boolean
+//    description: delete resources which depend on solution services
 // responses:
 //  '202':
 //    description: all solution services deleted
 //  default:
 //    $ref: '#/responses/error'
 func (h *ServiceHandlers) DeleteAllSolutionServicesHandler(ctx *gin.Context) {
-	if err := h.DeleteAllSolutionServices(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution")); err != nil {
+	if err := h.DeleteAllSolutionServices(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution"), ctx.Query("cascade") == "true"); err != nil {
 		ctx.AbortWithStatusJSON(h.HandleError(err))
 		return
 	}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+)
+
+type SolutionHandlers struct {
+	server.SolutionActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /namespaces/{namespace}/solutions Solution GetSolutionsList
+// Get solutions list.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: solutions list
+//    schema:
+//      $ref: '#/definitions/SolutionsResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SolutionHandlers) GetSolutionsListHandler(ctx *gin.Context) {
+	resp, err := h.GetSolutionsList(ctx.Request.Context(), ctx.Param("namespace"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/solutions/{solution} Solution GetSolution
+// Get solution resources and status.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: solution
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: solution
+//    schema:
+//      $ref: '#/definitions/Solution'
+//  default:
+//    $ref: '#/responses/error'
+func (h *SolutionHandlers) GetSolutionHandler(ctx *gin.Context) {
+	resp, err := h.GetSolution(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation DELETE /namespaces/{namespace}/solutions/{solution} Solution DeleteSolution
+// Delete all solution resources.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: solution
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: solution deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *SolutionHandlers) DeleteSolutionHandler(ctx *gin.Context) {
+	err := h.DeleteSolution(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("solution"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
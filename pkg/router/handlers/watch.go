@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// watchHeartbeat is a period of SSE comments which keep idle connection open
+const watchHeartbeat = 30 * time.Second
+
+// watchUpgrader checks Origin like browsers do for plain requests: cross-origin pages can't open watch
+// with credentials of user. Requests without Origin header are not made by browsers and are allowed.
+var watchUpgrader = websocket.Upgrader{}
+
+type WatchHandlers struct {
+	server.WatchActions
+	*m.TranslateValidate
+}
+
+// watchSince returns position of last received event from "resume" query parameter or Last-Event-ID header
+func watchSince(ctx *gin.Context) (*watch.Position, error) {
+	value := ctx.Query("resume")
+	if value == "" {
+		value = ctx.GetHeader("Last-Event-ID")
+	}
+	if value == "" {
+		return nil, nil
+	}
+	since, err := watch.ParsePosition(value)
+	if err != nil {
+		return nil, err
+	}
+	return &since, nil
+}
+
+// swagger:operation GET /namespaces/{namespace}/watch Watch WatchNamespace
+// Stream changes of namespace deployments, services, ingresses and configmaps.
+// Events are sent as Server-Sent Events or, if connection is upgraded, as WebSocket text messages.
+// Changes made through API and by background workers (reconciliation, automatic rollback, outbox, certificates renewal) are sent,
+// changes made directly in kubernetes are sent only after they are imported by reconciliation.
+// Stream is closed if client doesn't read events fast enough, client should reconnect with resume token of last received event.
+// Resume tokens are valid until instance restart, then resources should be listed again.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: resume
+//    in: query
+//    type: string
+//    description: resume token of last received event, events after it are sent first
+//    required: false
+//  - name: Last-Event-ID
+//    in: header
+//    type: string
+//    description: same as resume, set by SSE clients on reconnect
+//    required: false
+// responses:
+//  '200':
+//    description: event stream
+//    schema:
+//      $ref: '#/definitions/WatchEvent'
+//  '101':
+//    description: websocket event stream
+//  default:
+//    $ref: '#/responses/error'
+func (h *WatchHandlers) WatchHandler(ctx *gin.Context) {
+	since, err := watchSince(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	sub, err := h.Watch(ctx.Request.Context(), ctx.Param("namespace"), since)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+	defer sub.Stop()
+
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		h.streamWebSocket(ctx, sub)
+		return
+	}
+	h.streamSSE(ctx, sub)
+}
+
+func (h *WatchHandlers) streamSSE(ctx *gin.Context, sub *watch.Subscription) {
+	ctx.Header("Content-Type", sse.ContentType)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			return sse.Encode(w, sse.Event{
+				Id:    event.ResumeToken,
+				Event: string(event.Type),
+				Data:  event,
+			}) == nil
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ":\n\n")
+			return err == nil
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func (h *WatchHandlers) streamWebSocket(ctx *gin.Context, sub *watch.Subscription) {
+	// upgrader writes error response itself
+	conn, err := watchUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// client messages are discarded, reading is needed to handle control frames and to detect close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
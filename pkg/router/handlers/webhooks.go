@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type WebhookHandlers struct {
+	server.WebhookActions
+	*m.TranslateValidate
+}
+
+// swagger:operation GET /namespaces/{namespace}/webhooks Webhook GetWebhooksList
+// Get namespace webhooks list.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: webhooks list
+//    schema:
+//      $ref: '#/definitions/WebhooksResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) GetWebhooksListHandler(ctx *gin.Context) {
+	resp, err := h.GetWebhooksList(ctx.Request.Context(), ctx.Param("namespace"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/webhooks/{webhook} Webhook GetWebhook
+// Get namespace webhook.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: webhook
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: webhook
+//    schema:
+//      $ref: '#/definitions/Webhook'
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) GetWebhookHandler(ctx *gin.Context) {
+	resp, err := h.GetWebhook(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("webhook"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation GET /namespaces/{namespace}/webhooks/{webhook}/deliveries Webhook GetWebhookDeliveries
+// Get log of webhook deliveries. Newest deliveries are returned first.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: webhook
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '200':
+//    description: webhook deliveries
+//    schema:
+//      $ref: '#/definitions/WebhookDeliveriesResponse'
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) GetWebhookDeliveriesHandler(ctx *gin.Context) {
+	resp, err := h.GetWebhookDeliveries(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("webhook"))
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// swagger:operation POST /namespaces/{namespace}/webhooks Webhook CreateWebhook
+// Create webhook. Requests to webhook are signed with HMAC-SHA256 of X-Webhook-Timestamp header value, "." and body
+// in X-Webhook-Signature header. Receivers should reject requests with old timestamps.
+// Webhook host can't resolve to loopback, private, link-local or cluster addresses, redirects are not followed.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - $ref: '#/parameters/IdempotencyKeyHeader'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/Webhook'
+// responses:
+//  '201':
+//    description: webhook created
+//    schema:
+//      $ref: '#/definitions/Webhook'
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) CreateWebhookHandler(ctx *gin.Context) {
+	var req webhook.Webhook
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	resp, err := h.CreateWebhook(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// swagger:operation PUT /namespaces/{namespace}/webhooks/{webhook} Webhook UpdateWebhook
+// Update webhook URL and events. Secret is kept if it's not provided.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: webhook
+//    in: path
+//    type: string
+//    required: true
+//  - name: body
+//    in: body
+//    schema:
+//      $ref: '#/definitions/Webhook'
+// responses:
+//  '202':
+//    description: webhook updated
+//    schema:
+//      $ref: '#/definitions/Webhook'
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) UpdateWebhookHandler(ctx *gin.Context) {
+	var req webhook.Webhook
+	if err := ctx.ShouldBindWith(&req, binding.JSON); err != nil {
+		ctx.AbortWithStatusJSON(h.BadRequest(ctx, err))
+		return
+	}
+
+	req.Name = ctx.Param("webhook")
+	resp, err := h.UpdateWebhook(ctx.Request.Context(), ctx.Param("namespace"), req)
+	if err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// swagger:operation DELETE /namespaces/{namespace}/webhooks/{webhook} Webhook DeleteWebhook
+// Delete webhook. Pending deliveries are not sent.
+//
+// ---
+// x-method-visibility: public
+// parameters:
+//  - $ref: '#/parameters/UserIDHeader'
+//  - $ref: '#/parameters/UserRoleHeader'
+//  - $ref: '#/parameters/UserNamespaceHeader'
+//  - $ref: '#/parameters/DryRunQuery'
+//  - name: namespace
+//    in: path
+//    type: string
+//    required: true
+//  - name: webhook
+//    in: path
+//    type: string
+//    required: true
+// responses:
+//  '202':
+//    description: webhook deleted
+//  default:
+//    $ref: '#/responses/error'
+func (h *WebhookHandlers) DeleteWebhookHandler(ctx *gin.Context) {
+	if err := h.DeleteWebhook(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("webhook")); err != nil {
+		ctx.AbortWithStatusJSON(h.HandleError(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
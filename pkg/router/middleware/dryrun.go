@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+)
+
+const DryRunQuery = "dry_run"
+
+// DryRun marks request context if mutating request has "dry_run=true" query parameter
+func DryRun() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, ok := ctx.GetQuery(DryRunQuery)
+		if !ok {
+			return
+		}
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			gonic.Gonic(rserrors.ErrValidation().AddDetailF("invalid %v value: %v", DryRunQuery, value), ctx)
+			return
+		}
+		if dryRun && ctx.Request.Method != http.MethodGet {
+			ctx.Request = ctx.Request.WithContext(server.WithDryRun(ctx.Request.Context()))
+		}
+	}
+}
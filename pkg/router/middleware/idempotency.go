@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from idempotency keys storage
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders -- response headers stored with idempotent response
+var replayedHeaders = []string{"Content-Type", "ETag"}
+
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST requests with "Idempotency-Key" header safe to retry.
+// Response of first request is stored and replayed on retries with the same key and body,
+// the same key with other request is rejected. Server errors are not stored, so such requests can be retried.
+func (tv *TranslateValidate) Idempotency(backend server.IdempotencyActions) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" || ctx.Request.Method != http.MethodPost || server.IsDryRun(ctx.Request.Context()) {
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			gonic.Gonic(rserrors.ErrValidation().AddDetailF("%v must be at most %v characters", IdempotencyKeyHeader, maxIdempotencyKeyLength), ctx)
+			return
+		}
+
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if err != nil {
+			gonic.Gonic(rserrors.ErrValidation().AddDetailsErr(err), ctx)
+			return
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		stored, err := backend.Begin(ctx.Request.Context(), key, idempotency.RequestHash(ctx.Request.Method, ctx.Request.URL.RequestURI(), body))
+		if err != nil {
+			ctx.AbortWithStatusJSON(tv.HandleError(err))
+			return
+		}
+		if stored != nil {
+			for name, value := range stored.Header {
+				ctx.Header(name, value)
+			}
+			ctx.Header(IdempotentReplayedHeader, "true")
+			ctx.Status(stored.Status)
+			ctx.Writer.Write(stored.Body)
+			ctx.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		if ctx.Writer.Status() >= http.StatusInternalServerError {
+			if err := backend.Abandon(ctx.Request.Context(), key); err != nil {
+				ctx.Error(err)
+			}
+			return
+		}
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := ctx.Writer.Header().Get(name); value != "" {
+				header[name] = value
+			}
+		}
+		if err := backend.Finish(ctx.Request.Context(), key, ctx.Writer.Status(), header, writer.body.Bytes()); err != nil {
+			ctx.Error(err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/gonic"
+	"github.com/gin-gonic/gin"
+)
+
+const IfMatchHeader = "If-Match"
+
+// IfMatch makes mutating request conditional if it has "If-Match" header with resource version from ETag.
+// "*" matches any version.
+func IfMatch() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value := strings.TrimSpace(ctx.GetHeader(IfMatchHeader))
+		if value == "" || value == "*" || ctx.Request.Method == http.MethodGet {
+			return
+		}
+		version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+		if err != nil || version <= 0 {
+			gonic.Gonic(rserrors.ErrValidation().AddDetailF("invalid %v value: %v", IfMatchHeader, value), ctx)
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(server.WithIfMatch(ctx.Request.Context(), version))
+	}
+}
@@ -7,11 +7,14 @@ import (
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
 	h "git.containerum.net/ch/resource-service/pkg/router/handlers"
 	m "git.containerum.net/ch/resource-service/pkg/router/middleware"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/server/impl"
+	"git.containerum.net/ch/resource-service/pkg/util/certs"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
 	"git.containerum.net/ch/resource-service/pkg/util/validation"
 	"git.containerum.net/ch/resource-service/static"
 	"github.com/containerum/cherry/adaptors/cherrylog"
@@ -25,21 +28,59 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func CreateRouter(mongo *db.MongoStorage, permissions *clients.Permissions, kube *clients.Kube, status *model.ServiceStatus, tv *m.TranslateValidate, enableCORS bool, ingressSuffix string, minPort, maxPort uint) http.Handler {
+// Workers -- actions which are also run in background by server, so they are created outside of router
+type Workers struct {
+	Outbox         *impl.OutboxImpl
+	Webhooks       *impl.WebhookImpl
+	Reconciler     *impl.ReconcileActionsImpl
+	Certificates   *impl.CertificatesImpl
+	Watch          *impl.WatchImpl
+	ACMEChallenges http.Handler
+}
+
+// Config -- router settings
+type Config struct {
+	EnableCORS        bool
+	IngressSuffix     string
+	DomainPolicy      domain.SelectionPolicy
+	MinPort           uint
+	MaxPort           uint
+	RollbackDeadline  time.Duration
+	IdempotencyWindow time.Duration
+}
+
+func CreateRouter(mongo db.Storage, permissions *clients.Permissions, kube *clients.Kube, box *secretbox.Box, status *model.ServiceStatus, tv *m.TranslateValidate, workers Workers, cfg Config) http.Handler {
 	e := gin.New()
-	systemHandlersSetup(e, status, enableCORS)
-	initMiddlewares(e, tv)
-	deployHandlersSetup(e, tv, impl.NewDeployActionsImpl(mongo, permissions, kube))
-	domainHandlersSetup(e, tv, impl.NewDomainActionsImpl(mongo))
-	ingressHandlersSetup(e, tv, impl.NewIngressActionsImpl(mongo, kube, ingressSuffix))
-	serviceHandlersSetup(e, tv, impl.NewServiceActionsImpl(mongo, permissions, kube, minPort, maxPort))
-	confgimapHandlersSetup(e, tv, impl.NewConfigMapsActionsImpl(mongo, kube))
-	resourceCountHandlersSetup(e, tv, impl.NewResourcesActionsImpl(mongo))
+	systemHandlersSetup(e, status, cfg.EnableCORS)
+	acmeHandlersSetup(e, workers.ACMEChallenges)
+	initMiddlewares(e, tv, impl.NewIdempotencyImpl(mongo, cfg.IdempotencyWindow))
+	deps := impl.NewGraphActionsImpl(mongo)
+	auditLog := impl.NewAuditImpl(mongo, workers.Watch, workers.Webhooks)
+	deployer := impl.NewDeployActionsImpl(mongo, permissions, kube, workers.Outbox, deps, auditLog, cfg.RollbackDeadline)
+	deployHandlersSetup(e, tv, deployer)
+	ingresses := impl.NewIngressActionsImpl(mongo, kube, workers.Outbox, deps, auditLog, workers.Certificates, cfg.IngressSuffix)
+	ingressHandlersSetup(e, tv, ingresses)
+	services := impl.NewServiceActionsImpl(mongo, permissions, kube, workers.Outbox, deps, auditLog, impl.NewDomainPool(mongo, cfg.DomainPolicy, cfg.MinPort, cfg.MaxPort))
+	serviceHandlersSetup(e, tv, services)
+	domainHandlersSetup(e, tv, impl.NewDomainActionsImpl(mongo, auditLog, services))
+	portHandlersSetup(e, tv, impl.NewPortActionsImpl(mongo, cfg.MinPort, cfg.MaxPort))
+	hostHandlersSetup(e, tv, impl.NewHostActionsImpl(mongo, auditLog, cfg.IngressSuffix))
+	configmaps := impl.NewConfigMapsActionsImpl(mongo, kube, workers.Outbox, deployer, deps, auditLog)
+	confgimapHandlersSetup(e, tv, configmaps)
+	secretHandlersSetup(e, tv, impl.NewSecretActionsImpl(mongo, kube, workers.Outbox, deps, auditLog, box))
+	resourceCountHandlersSetup(e, tv, impl.NewResourcesActionsImpl(mongo, auditLog))
+	graphHandlersSetup(e, tv, deps)
+	solutionHandlersSetup(e, tv, impl.NewSolutionActionsImpl(mongo, kube, deps))
+	bundleHandlersSetup(e, tv, impl.NewBundleActionsImpl(mongo, permissions, deps, configmaps, deployer, services, ingresses))
+	reconcileHandlersSetup(e, tv, workers.Reconciler)
+	auditHandlersSetup(e, tv, auditLog)
+	watchHandlersSetup(e, tv, workers.Watch)
+	webhookHandlersSetup(e, tv, workers.Webhooks)
 
 	return e
 }
 
-func initMiddlewares(e gin.IRouter, tv *m.TranslateValidate) {
+func initMiddlewares(e gin.IRouter, tv *m.TranslateValidate, idempotency server.IdempotencyActions) {
 	e.Use(ginrus.Ginrus(logrus.StandardLogger(), time.RFC3339, true))
 	binding.Validator = &validation.GinValidatorV9{Validate: tv.Validate} // gin has no local validator
 	e.Use(httputil.SaveHeaders)
@@ -51,6 +92,9 @@ func initMiddlewares(e gin.IRouter, tv *m.TranslateValidate) {
 	}))
 	e.Use(httputil.SubstituteUserMiddleware(tv.Validate, tv.UniversalTranslator, rserrors.ErrValidation))
 	e.Use(m.RequiredUserHeaders())
+	e.Use(m.DryRun())
+	e.Use(m.IfMatch())
+	e.Use(tv.Idempotency(idempotency))
 }
 
 func systemHandlersSetup(router gin.IRouter, status *model.ServiceStatus, enableCORS bool) {
@@ -58,7 +102,8 @@ func systemHandlersSetup(router gin.IRouter, status *model.ServiceStatus, enable
 		cfg := cors.DefaultConfig()
 		cfg.AllowAllOrigins = true
 		cfg.AddAllowMethods(http.MethodDelete)
-		cfg.AddAllowHeaders(httputil.UserRoleXHeader, httputil.UserIDXHeader, httputil.UserNamespacesXHeader)
+		cfg.AddAllowHeaders(httputil.UserRoleXHeader, httputil.UserIDXHeader, httputil.UserNamespacesXHeader, m.IdempotencyKeyHeader)
+		cfg.AddExposeHeaders(h.TotalCountHeader)
 		router.Use(cors.New(cfg))
 	}
 	router.Group("/static").
@@ -68,6 +113,14 @@ func systemHandlersSetup(router gin.IRouter, status *model.ServiceStatus, enable
 	router.GET("/status", httputil.ServiceStatus(status))
 }
 
+// acmeHandlersSetup serves ACME http-01 challenges, they are requested by ACME server without user headers
+func acmeHandlersSetup(router gin.IRouter, challenges http.Handler) {
+	if challenges == nil {
+		return
+	}
+	router.GET(certs.ChallengePath+":token", gin.WrapH(challenges))
+}
+
 func deployHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.DeployActions) {
 	deployHandlers := h.DeployHandlers{DeployActions: backend, TranslateValidate: tv}
 
@@ -82,6 +135,9 @@ func deployHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend se
 
 		deployment.POST("", m.WriteAccess, deployHandlers.CreateDeploymentHandler)
 		deployment.POST("/:deployment/versions/:version", m.WriteAccess, deployHandlers.ChangeActiveDeploymentHandler)
+		deployment.POST("/:deployment/versions/:version/promote", m.WriteAccess, deployHandlers.PromoteDeploymentHandler)
+		deployment.POST("/:deployment/versions/:version/abort", m.WriteAccess, deployHandlers.AbortDeploymentHandler)
+		deployment.POST("/:deployment/rollback", m.WriteAccess, deployHandlers.RollbackDeploymentHandler)
 
 		deployment.PUT("/:deployment", m.WriteAccess, deployHandlers.UpdateDeploymentHandler)
 		deployment.PUT("/:deployment/image", m.WriteAccess, deployHandlers.SetContainerImageHandler)
@@ -106,8 +162,22 @@ func domainHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend se
 
 		domain.POST("", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), domainHandlers.AddDomainHandler)
 
+		domain.PUT("/:domain", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), domainHandlers.UpdateDomainHandler)
+		domain.PUT("/:domain/pool", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), domainHandlers.SetDomainPoolSettingsHandler)
+
+		domain.POST("/:domain/drain", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), domainHandlers.DrainDomainHandler)
+
 		domain.DELETE("/:domain", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), domainHandlers.DeleteDomainHandler)
 	}
+
+	pins := router.Group("/admin/domain_pins", httputil.RequireAdminRole(rserrors.ErrPermissionDenied))
+	{
+		pins.GET("", domainHandlers.GetDomainPinsListHandler)
+
+		pins.PUT("/:kind/:id", domainHandlers.SetDomainPinHandler)
+
+		pins.DELETE("/:kind/:id", domainHandlers.DeleteDomainPinHandler)
+	}
 }
 
 func ingressHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.IngressActions) {
@@ -155,9 +225,17 @@ func confgimapHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend
 	{
 		configmap.GET("", m.ReadAccess, cmHandlers.GetConfigMapsListHandler)
 		configmap.GET("/:configmap", m.ReadAccess, cmHandlers.GetConfigMapHandler)
+		configmap.GET("/:configmap/versions", m.ReadAccess, cmHandlers.GetConfigMapVersionsListHandler)
+		configmap.GET("/:configmap/versions/:version", m.ReadAccess, cmHandlers.GetConfigMapVersionHandler)
+		configmap.GET("/:configmap/versions/:version/diff", m.ReadAccess, cmHandlers.DiffConfigMapPreviousVersionsHandler)
+		configmap.GET("/:configmap/versions/:version/diff/:version2", m.ReadAccess, cmHandlers.DiffConfigMapVersionsHandler)
 
 		configmap.POST("", m.WriteAccess, cmHandlers.CreateConfigMapHandler)
 
+		configmap.PUT("/:configmap", m.WriteAccess, cmHandlers.UpdateConfigMapHandler)
+
+		configmap.PATCH("/:configmap", m.WriteAccess, cmHandlers.PatchConfigMapHandler)
+
 		configmap.DELETE("/:configmap", m.WriteAccess, cmHandlers.DeleteConfigMapHandler)
 		configmap.DELETE("", cmHandlers.DeleteAllConfigMapsHandler)
 	}
@@ -165,9 +243,111 @@ func confgimapHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend
 	router.POST("/import/configmaps", cmHandlers.ImportConfigMapsHandler)
 }
 
+func secretHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.SecretActions) {
+	secretHandlers := h.SecretHandlers{SecretActions: backend, TranslateValidate: tv}
+
+	secret := router.Group("/namespaces/:namespace/secrets")
+	{
+		secret.GET("", m.ReadAccess, secretHandlers.GetSecretsListHandler)
+		secret.GET("/:secret", m.ReadAccess, secretHandlers.GetSecretHandler)
+
+		secret.POST("", m.WriteAccess, secretHandlers.CreateSecretHandler)
+
+		secret.PUT("/:secret", m.WriteAccess, secretHandlers.UpdateSecretHandler)
+
+		secret.DELETE("/:secret", m.WriteAccess, secretHandlers.DeleteSecretHandler)
+		secret.DELETE("", secretHandlers.DeleteAllSecretsHandler)
+	}
+	router.GET("/secrets", secretHandlers.GetSelectedSecretsListHandler)
+	router.POST("/import/secrets", secretHandlers.ImportSecretsHandler)
+}
+
 func resourceCountHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.ResourcesActions) {
 	resourceHandlers := h.ResourceHandlers{ResourcesActions: backend, TranslateValidate: tv}
 	router.DELETE("/namespaces/:namespace", resourceHandlers.DeleteAllResourcesInNamespaceHandler)
 	router.DELETE("/namespaces", resourceHandlers.DeleteAllResourcesHandler)
 	router.GET("/resources", resourceHandlers.GetResourcesCountHandler)
 }
+
+func graphHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.GraphActions) {
+	graphHandlers := h.GraphHandlers{GraphActions: backend, TranslateValidate: tv}
+	router.GET("/namespaces/:namespace/graph", m.ReadAccess, graphHandlers.GetGraphHandler)
+}
+
+func solutionHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.SolutionActions) {
+	solutionHandlers := h.SolutionHandlers{SolutionActions: backend, TranslateValidate: tv}
+
+	solution := router.Group("/namespaces/:namespace/solutions")
+	{
+		solution.GET("", m.ReadAccess, solutionHandlers.GetSolutionsListHandler)
+		solution.GET("/:solution", m.ReadAccess, solutionHandlers.GetSolutionHandler)
+
+		solution.DELETE("/:solution", m.WriteAccess, solutionHandlers.DeleteSolutionHandler)
+	}
+}
+
+func bundleHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.BundleActions) {
+	bundleHandlers := h.BundleHandlers{BundleActions: backend, TranslateValidate: tv}
+	router.POST("/namespaces/:namespace/apply", m.WriteAccess, bundleHandlers.ApplyBundleHandler)
+}
+
+func reconcileHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.ReconcileActions) {
+	reconcileHandlers := h.ReconcileHandlers{ReconcileActions: backend, TranslateValidate: tv}
+
+	admin := router.Group("/admin", httputil.RequireAdminRole(rserrors.ErrPermissionDenied))
+	{
+		admin.GET("/drift", reconcileHandlers.GetDriftHandler)
+
+		admin.POST("/reconcile", reconcileHandlers.ReconcileHandler)
+	}
+}
+
+func portHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.PortActions) {
+	portHandlers := h.PortHandlers{PortActions: backend, TranslateValidate: tv}
+	router.GET("/admin/ports", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), portHandlers.GetPortsUsageHandler)
+}
+
+func hostHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.HostActions) {
+	hostHandlers := h.HostHandlers{HostActions: backend, TranslateValidate: tv}
+
+	host := router.Group("/hosts")
+	{
+		host.GET("", hostHandlers.GetHostReservationsListHandler)
+
+		host.POST("", hostHandlers.ReserveHostHandler)
+
+		host.DELETE("/:host", hostHandlers.DeleteHostReservationHandler)
+	}
+
+	adminHost := router.Group("/admin/hosts", httputil.RequireAdminRole(rserrors.ErrPermissionDenied))
+	{
+		adminHost.GET("", hostHandlers.GetAllHostReservationsListHandler)
+
+		adminHost.DELETE("/:host", hostHandlers.DeleteAnyHostReservationHandler)
+	}
+}
+
+func auditHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.AuditActions) {
+	auditHandlers := h.AuditHandlers{AuditActions: backend, TranslateValidate: tv}
+	router.GET("/audit", httputil.RequireAdminRole(rserrors.ErrPermissionDenied), auditHandlers.GetAuditLogHandler)
+	router.GET("/namespaces/:namespace/audit", m.ReadAccess, auditHandlers.GetNamespaceAuditLogHandler)
+}
+
+func watchHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.WatchActions) {
+	watchHandlers := h.WatchHandlers{WatchActions: backend, TranslateValidate: tv}
+	router.GET("/namespaces/:namespace/watch", m.ReadAccess, watchHandlers.WatchHandler)
+}
+
+func webhookHandlersSetup(router gin.IRouter, tv *m.TranslateValidate, backend server.WebhookActions) {
+	webhookHandlers := h.WebhookHandlers{WebhookActions: backend, TranslateValidate: tv}
+	webhook := router.Group("/namespaces/:namespace/webhooks")
+	{
+		webhook.GET("", m.ReadAccess, webhookHandlers.GetWebhooksListHandler)
+		webhook.GET("/:webhook", m.ReadAccess, webhookHandlers.GetWebhookHandler)
+		webhook.GET("/:webhook/deliveries", m.ReadAccess, webhookHandlers.GetWebhookDeliveriesHandler)
+
+		webhook.POST("", m.WriteAccess, webhookHandlers.CreateWebhookHandler)
+		webhook.PUT("/:webhook", m.WriteAccess, webhookHandlers.UpdateWebhookHandler)
+		webhook.DELETE("/:webhook", m.WriteAccess, webhookHandlers.DeleteWebhookHandler)
+	}
+}
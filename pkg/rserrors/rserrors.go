@@ -250,6 +250,160 @@ func ErrNoDomainsAvailable(params ...func(*cherry.Err)) *cherry.Err {
 	}
 	return err
 }
+func ErrRolloutInProgress(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Deployment rollout is in progress", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x16}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrNoRollbackVersion(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "No version to rollback to", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x17}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrResourceHasDependents(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Resource has dependent resources", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x18}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrWatchExpired(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Watch sequence expired, list resources and watch again", StatusHTTP: 410, ID: cherry.ErrID{SID: "resource-service", Kind: 0x19}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrResourceVersionConflict(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Resource was changed by another request", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1a}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrIdempotencyKeyReused(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Idempotency key was already used with other request", StatusHTTP: 422, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1b}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrIdempotentRequestInProgress(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Request with this idempotency key is in progress", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1c}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrPortReserved(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Port is reserved by another service", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1d}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrDomainInUse(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Domain is used by external services", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1e}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrIngressPathConflict(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Ingress path is already used in namespace", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x1f}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrIngressHostConflict(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Ingress host is used by another ingress", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x20}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrHostReserved(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Host is reserved by another user", StatusHTTP: 409, ID: cherry.ErrID{SID: "resource-service", Kind: 0x21}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrCertificateIssue(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Unable to issue TLS certificate", StatusHTTP: 503, ID: cherry.ErrID{SID: "resource-service", Kind: 0x22}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
+func ErrTooManyHostReservations(params ...func(*cherry.Err)) *cherry.Err {
+	err := &cherry.Err{Message: "Too many host reservations", StatusHTTP: 403, ID: cherry.ErrID{SID: "resource-service", Kind: 0x23}, Details: []string(nil), Fields: cherry.Fields(nil)}
+	for _, param := range params {
+		param(err)
+	}
+	for i, detail := range err.Details {
+		det := renderTemplate(detail)
+		err.Details[i] = det
+	}
+	return err
+}
 func renderTemplate(templText string) string {
 	buf := &bytes.Buffer{}
 	templ, err := template.New("").Parse(templText)
@@ -0,0 +1,17 @@
+package server
+
+import "context"
+
+type dryRunKey struct{}
+
+// WithDryRun returns context of dry run request.
+// Actions called with such context run all checks and return result, but don't change db and kube-api.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun returns true if context is created by WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+)
+
+type ifMatchKey struct{}
+
+// WithIfMatch returns context of conditional request.
+// Actions called with such context change resource only if it has provided resource version.
+func WithIfMatch(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, version)
+}
+
+// IfMatch returns resource version required by request, ok is false if request is not conditional
+func IfMatch(ctx context.Context) (version int64, ok bool) {
+	version, ok = ctx.Value(ifMatchKey{}).(int64)
+	return version, ok
+}
+
+// CheckIfMatch returns conflict error if request requires other resource version
+func CheckIfMatch(ctx context.Context, version int64) error {
+	if expected, ok := IfMatch(ctx); ok && expected != version {
+		return rserrors.ErrResourceVersionConflict().AddDetailF("resource version is %v, If-Match requires %v", version, expected)
+	}
+	return nil
+}
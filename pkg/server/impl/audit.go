@@ -0,0 +1,89 @@
+package impl
+
+import (
+	"context"
+	"net/url"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditImpl records resource mutations to append-only audit log and publishes them to watchers and webhooks.
+// Nil *AuditImpl doesn't record anything.
+type AuditImpl struct {
+	mongo    db.Storage
+	log      *cherrylog.LogrusAdapter
+	watch    *WatchImpl
+	webhooks *WebhookImpl
+}
+
+func NewAuditImpl(mongo db.Storage, watch *WatchImpl, webhooks *WebhookImpl) *AuditImpl {
+	return &AuditImpl{
+		mongo:    mongo,
+		log:      cherrylog.NewLogrusAdapter(logrus.WithField("component", "audit")),
+		watch:    watch,
+		webhooks: webhooks,
+	}
+}
+
+// Record writes entry of mutation made by user from context. Mutation failed if err is not nil.
+// Dry runs are not recorded. Audit write errors are logged and don't fail the mutation.
+func (a *AuditImpl) Record(ctx context.Context, nsID string, kind audit.Kind, name string, action audit.Action, before, after interface{}, err error) {
+	if a == nil || server.IsDryRun(ctx) {
+		return
+	}
+	role, _ := ctx.Value(httputil.UserRoleContextKey).(string)
+	entry := audit.NewEntry(httputil.MustGetUserID(ctx), role, nsID, kind, name, action, err)
+	log := a.log.WithFields(logrus.Fields{
+		"ns_id":  nsID,
+		"kind":   kind,
+		"name":   name,
+		"action": action,
+	})
+	var snapErr error
+	if entry.Before, snapErr = audit.Snapshot(before); snapErr != nil {
+		log.WithError(snapErr).Error("unable to encode resource state before mutation")
+	}
+	if entry.After, snapErr = audit.Snapshot(after); snapErr != nil {
+		log.WithError(snapErr).Error("unable to encode resource state after mutation")
+	}
+	if err := a.mongo.CreateAuditEntry(entry); err != nil {
+		log.WithError(err).Error("unable to write audit entry")
+	}
+	a.watch.publish(entry)
+	a.webhooks.notify(entry)
+}
+
+// GetAuditLog returns page of audit log, first page of default size if page is not requested
+func (a *AuditImpl) GetAuditLog(ctx context.Context, query audit.Query, params url.Values) (*audit.EntriesResponse, error) {
+	a.log.WithFields(logrus.Fields{
+		"ns_id":   query.NamespaceID,
+		"user_id": query.UserID,
+		"from":    query.From,
+		"to":      query.To,
+	}).Info("get audit log")
+
+	list, err := db.ParseListQuery(params, db.AuditListFields)
+	if err != nil {
+		return nil, err
+	}
+	// audit log grows without bound, so it is always paginated
+	if list.Page == nil {
+		list.Page = &db.PageInfo{Page: 1, DefaultPerPage: 100}
+	}
+
+	entries, total, err := a.mongo.GetAuditEntries(query, list)
+	if err != nil {
+		return nil, err
+	}
+	return &audit.EntriesResponse{Entries: entries, Total: total}, nil
+}
+
+func (a *AuditImpl) GetNamespaceAuditLog(ctx context.Context, nsID string, query audit.Query, params url.Values) (*audit.EntriesResponse, error) {
+	query.NamespaceID = nsID
+	return a.GetAuditLog(ctx, query, params)
+}
@@ -0,0 +1,109 @@
+package impl
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	box, err := secretbox.New(testSecretKey)
+	assert.NoError(t, err)
+	var al = NewAuditImpl(mongo, nil, nil)
+	var sa = NewSecretActionsImpl(mongo, &kube, NewOutboxImpl(mongo, &kube, box, nil), NewGraphActionsImpl(mongo), al, box)
+	const user1, user2 = "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"
+	var ctx1 = context.WithValue(context.WithValue(context.Background(), httputil.UserIDContextKey, user1), httputil.UserRoleContextKey, "user")
+	var ctx2 = context.WithValue(context.Background(), httputil.UserIDContextKey, user2)
+	// stored time has millisecond precision
+	start := time.Now().Truncate(time.Millisecond)
+
+	_, err = sa.CreateSecret(ctx1, "ns", kubtypes.Secret{Name: "creds", Data: map[string]string{"password": "qwerty"}})
+	assert.NoError(t, err)
+	_, err = sa.CreateSecret(ctx1, "ns", kubtypes.Secret{Name: "creds", Data: map[string]string{"password": "qwerty"}})
+	assert.Error(t, err)
+	_, err = sa.UpdateSecret(ctx1, "ns", kubtypes.Secret{Name: "creds", Data: map[string]string{"password": "12345"}})
+	assert.NoError(t, err)
+	_, err = sa.CreateSecret(server.WithDryRun(ctx2), "ns", kubtypes.Secret{Name: "dry"})
+	assert.NoError(t, err)
+	assert.NoError(t, sa.DeleteSecret(ctx2, "ns", "creds", false))
+	_, err = sa.CreateSecret(ctx2, "other", kubtypes.Secret{Name: "creds"})
+	assert.NoError(t, err)
+
+	log, err := al.GetNamespaceAuditLog(ctx1, "ns", audit.Query{}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, log.Entries, 4, "dry run must not be recorded") {
+		// newest entries first
+		del, upd, failed, created := log.Entries[0], log.Entries[1], log.Entries[2], log.Entries[3]
+		assert.Equal(t, audit.Delete, del.Action)
+		assert.Equal(t, user2, del.UserID)
+		assert.NotEmpty(t, del.Before)
+		assert.Empty(t, del.After)
+
+		assert.Equal(t, audit.Update, upd.Action)
+		assert.NotEmpty(t, upd.Before)
+		assert.NotEmpty(t, upd.After)
+
+		assert.Equal(t, audit.Create, failed.Action)
+		assert.Equal(t, audit.Failure, failed.Outcome)
+		assert.NotEmpty(t, failed.Error)
+
+		assert.Equal(t, audit.Create, created.Action)
+		assert.Equal(t, audit.Success, created.Outcome)
+		assert.Equal(t, "user", created.Role)
+		assert.Equal(t, audit.Secret, created.Kind)
+		assert.Equal(t, "creds", created.Name)
+
+		for _, entry := range log.Entries {
+			assert.False(t, strings.Contains(string(entry.Before)+string(entry.After), "qwerty"), "secret values must not be recorded")
+		}
+	}
+
+	log, err = al.GetAuditLog(ctx1, audit.Query{UserID: user2}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, log.Entries, 2)
+
+	log, err = al.GetAuditLog(ctx1, audit.Query{From: start, To: time.Now()}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, log.Entries, 5)
+
+	log, err = al.GetAuditLog(ctx1, audit.Query{From: time.Now().Add(time.Hour)}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, log.Entries)
+
+	log, err = al.GetAuditLog(ctx1, audit.Query{}, url.Values{"per_page": {"2"}, "page": {"2"}, "sort": {"time"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, log.Total)
+	if assert.Len(t, log.Entries, 2) {
+		assert.False(t, log.Entries[1].Time.Before(log.Entries[0].Time), "oldest entries first")
+	}
+	log, err = al.GetAuditLog(ctx1, audit.Query{}, url.Values{"action": {"delete"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, log.Total)
+
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var deps = NewGraphActionsImpl(mongo)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0), deps, al)
+	_, err = ca.CreateConfigMap(ctx1, "cm", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "config", Data: kubtypes.ConfigMapData{"password": "qwerty"}}})
+	assert.NoError(t, err)
+	log, err = al.GetNamespaceAuditLog(ctx1, "cm", audit.Query{}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, log.Entries, 1) {
+		assert.NotEmpty(t, log.Entries[0].After)
+		assert.NotContains(t, string(log.Entries[0].After), "qwerty", "configmap data must not be recorded")
+	}
+}
@@ -0,0 +1,280 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/bundle"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// BundleActionsImpl applies bundles of resources to namespace.
+// Bundle is applied all-or-nothing: if some resource can't be created, resources created before it are deleted.
+type BundleActionsImpl struct {
+	permissions clients.Permissions
+	mongo       db.Storage
+	log         *cherrylog.LogrusAdapter
+	deps        *GraphActionsImpl
+	configmaps  *ConfigMapsActionsImpl
+	deployments *DeployActionsImpl
+	services    *ServiceActionsImpl
+	ingresses   *IngressActionsImpl
+}
+
+func NewBundleActionsImpl(mongo db.Storage, permissions *clients.Permissions, deps *GraphActionsImpl, configmaps *ConfigMapsActionsImpl, deployments *DeployActionsImpl, services *ServiceActionsImpl, ingresses *IngressActionsImpl) *BundleActionsImpl {
+	return &BundleActionsImpl{
+		permissions: *permissions,
+		mongo:       mongo,
+		log:         cherrylog.NewLogrusAdapter(logrus.WithField("component", "bundle_actions")),
+		deps:        deps,
+		configmaps:  configmaps,
+		deployments: deployments,
+		services:    services,
+		ingresses:   ingresses,
+	}
+}
+
+func (ba *BundleActionsImpl) ApplyBundle(ctx context.Context, nsID string, req bundle.Bundle) (*bundle.BundleResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	ba.log.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"ns_id":     nsID,
+		"resources": req.Len(),
+	}).Info("apply bundle")
+
+	if req.Len() == 0 {
+		return nil, rserrors.ErrValidation().AddDetails("bundle is empty")
+	}
+
+	if err := ba.deps.CheckReferences(nsID, bundleResources(nsID, req)); err != nil {
+		return nil, err
+	}
+
+	nsLimits, err := ba.permissions.GetNamespaceLimits(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	nsUsage, err := ba.mongo.GetNamespaceResourcesLimits(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	svcUsage, err := ba.mongo.CountServicesInNamespace(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceTypes := make([]service.Type, 0, len(req.Services))
+	for _, svc := range req.Services {
+		serviceTypes = append(serviceTypes, server.DetermineServiceType(svc.Service))
+	}
+
+	if err := server.CheckBundleCreateQuotas(nsLimits, nsUsage, svcUsage, req.Deployments, serviceTypes); err != nil {
+		return nil, err
+	}
+
+	if server.IsDryRun(ctx) {
+		return ba.dryRun(ctx, nsID, req)
+	}
+
+	resp, created, err := ba.apply(ctx, nsID, req)
+	if err != nil {
+		ba.log.WithError(err).Debug("Unable to apply bundle! Deleting created resources.")
+		ba.undo(ctx, nsID, created)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// apply creates bundle resources in dependency order: configmaps are mounted to deployments,
+// services point to deployments and ingresses route to services. Created resources are returned even on error.
+func (ba *BundleActionsImpl) apply(ctx context.Context, nsID string, req bundle.Bundle) (*bundle.BundleResponse, []graph.Node, error) {
+	var resp = newBundleResponse(req)
+	var created []graph.Node
+
+	for _, cm := range req.ConfigMaps {
+		createdCM, err := ba.configmaps.CreateConfigMap(ctx, nsID, cm)
+		if err != nil {
+			return nil, created, err
+		}
+		resp.ConfigMaps = append(resp.ConfigMaps, *createdCM)
+		created = append(created, graph.Node{Kind: graph.ConfigMap, Name: cm.Name})
+	}
+
+	for _, deployReq := range req.Deployments {
+		createdDeploy, err := ba.deployments.CreateDeployment(ctx, nsID, deployReq)
+		if err != nil {
+			return nil, created, err
+		}
+		resp.Deployments = append(resp.Deployments, *createdDeploy)
+		created = append(created, graph.Node{Kind: graph.Deployment, Name: deployReq.Name})
+	}
+
+	for _, svc := range req.Services {
+		createdService, err := ba.services.CreateService(ctx, nsID, svc)
+		if err != nil {
+			return nil, created, err
+		}
+		resp.Services = append(resp.Services, *createdService)
+		created = append(created, graph.Node{Kind: graph.Service, Name: svc.Name})
+	}
+
+	for _, ingr := range req.Ingresses {
+		createdIngress, err := ba.ingresses.CreateIngress(ctx, nsID, ingr)
+		if err != nil {
+			return nil, created, err
+		}
+		resp.Ingresses = append(resp.Ingresses, *createdIngress)
+		created = append(created, graph.Node{Kind: graph.Ingress, Name: ingr.Name})
+	}
+
+	return &resp, created, nil
+}
+
+// dryRun returns resources which would be created from bundle.
+// Services and ingresses may refer to resources of the same bundle which are not saved on dry run,
+// so they are prepared here instead of create actions. References are already checked at this point.
+func (ba *BundleActionsImpl) dryRun(ctx context.Context, nsID string, req bundle.Bundle) (*bundle.BundleResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	var resp = newBundleResponse(req)
+
+	for _, cm := range req.ConfigMaps {
+		createdCM, err := ba.configmaps.CreateConfigMap(ctx, nsID, cm)
+		if err != nil {
+			return nil, err
+		}
+		resp.ConfigMaps = append(resp.ConfigMaps, *createdCM)
+	}
+
+	for _, deployReq := range req.Deployments {
+		createdDeploy, err := ba.deployments.CreateDeployment(ctx, nsID, deployReq)
+		if err != nil {
+			return nil, err
+		}
+		resp.Deployments = append(resp.Deployments, *createdDeploy)
+	}
+
+	// ports of all bundle services are planned together, so domains are checked to have free ports for all of them
+	var planned []port.Allocation
+	var services = make(map[string]kubtypes.Service, len(req.Services))
+	for _, svc := range req.Services {
+		_, err := ba.mongo.GetService(nsID, svc.Name)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		if errs := svc.Metadata.Validate(); len(errs) > 0 {
+			return nil, rserrors.ErrValidation().AddDetails(errs...)
+		}
+		serviceType := server.DetermineServiceType(svc.Service)
+		if serviceType == service.External {
+			allocs, err := ba.services.pool.CheckPlace(nsID, userID, &svc.Service, planned)
+			if err != nil {
+				return nil, err
+			}
+			planned = append(planned, allocs...)
+		}
+		newService := service.FromKube(nsID, userID, serviceType, svc.Service)
+		newService.Metadata = svc.Metadata.Copy()
+		resp.Services = append(resp.Services, newService)
+		services[svc.Name] = svc.Service
+	}
+
+	ingresses, _, err := ba.mongo.GetIngressList(nsID, nil)
+	if err != nil {
+		return nil, err
+	}
+	// hosts are planned like ports, so bundle ingresses are checked against each other too
+	var claimed []host.Claim
+	for _, ingr := range req.Ingresses {
+		_, err := ba.mongo.GetIngress(nsID, ingr.Name)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		if errs := ingr.Metadata.Validate(); len(errs) > 0 {
+			return nil, rserrors.ErrValidation().AddDetails(errs...)
+		}
+		if err := ba.ingresses.prepareHost(&ingr.Ingress); err != nil {
+			return nil, err
+		}
+		if err := ba.ingresses.prepareTLS(&ingr.Ingress, ingr.TLS, ""); err != nil {
+			return nil, err
+		}
+		if err := resolvePaths(&ingr.Ingress, func(name string) (kubtypes.Service, error) {
+			if svc, ok := services[name]; ok {
+				return svc, nil
+			}
+			existing, err := ba.mongo.GetService(nsID, name)
+			return existing.Service, err
+		}); err != nil {
+			return nil, err
+		}
+		if err := ingressPathConflicts(ingr.Ingress, ingresses); err != nil {
+			return nil, err
+		}
+		newIngress := ingress.FromKube(nsID, userID, ingr.Ingress)
+		newIngress.Metadata = ingr.Metadata.Copy()
+		newIngress.TLS = ingr.TLS
+		claims := host.Claims(nsID, newIngress.Ingress)
+		if err := ba.ingresses.checkHosts(ctx, claims, claimed); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, claims...)
+		resp.Ingresses = append(resp.Ingresses, newIngress)
+		ingresses = append(ingresses, newIngress)
+	}
+
+	return &resp, nil
+}
+
+// undo deletes created resources in reverse order, so resources are deleted before resources they depend on
+func (ba *BundleActionsImpl) undo(ctx context.Context, nsID string, created []graph.Node) {
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := ba.deps.delete(ctx, nsID, created[i]); err != nil {
+			ba.log.WithError(err).WithFields(logrus.Fields{
+				"ns_id":    nsID,
+				"resource": created[i].String(),
+			}).Error("unable to delete resource created from bundle")
+		}
+	}
+}
+
+func newBundleResponse(req bundle.Bundle) bundle.BundleResponse {
+	return bundle.BundleResponse{
+		ConfigMaps:  make(configmap.ListConfigMaps, 0, len(req.ConfigMaps)),
+		Deployments: make(deployment.ListDeploy, 0, len(req.Deployments)),
+		Services:    make(service.ListService, 0, len(req.Services)),
+		Ingresses:   make(ingress.ListIngress, 0, len(req.Ingresses)),
+	}
+}
+
+func bundleResources(nsID string, req bundle.Bundle) graph.Resources {
+	var res graph.Resources
+	for _, cm := range req.ConfigMaps {
+		res.ConfigMaps = append(res.ConfigMaps, configmap.FromKube(nsID, "", cm.ConfigMap))
+	}
+	for _, deployReq := range req.Deployments {
+		res.Deployments = append(res.Deployments, deployment.FromKube(nsID, "", deployReq.Deployment))
+	}
+	for _, svc := range req.Services {
+		res.Services = append(res.Services, service.FromKube(nsID, "", server.DetermineServiceType(svc.Service), svc.Service))
+	}
+	for _, ingr := range req.Ingresses {
+		res.Ingresses = append(res.Ingresses, ingress.FromKube(nsID, "", ingr.Ingress))
+	}
+	return res
+}
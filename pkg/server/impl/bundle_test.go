@@ -0,0 +1,117 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/bundle"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyBundle(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0)
+	var ba = NewBundleActionsImpl(mongo, &permissions, deps,
+		NewConfigMapsActionsImpl(mongo, &kube, ob, da, deps, nil),
+		da,
+		NewServiceActionsImpl(mongo, &permissions, &kube, ob, deps, nil, NewDomainPool(mongo, domain.Weighted, 0, 0)),
+		NewIngressActionsImpl(mongo, &kube, ob, deps, nil, nil, ""))
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var port = 80
+	var req = bundle.Bundle{
+		ConfigMaps: []configmap.ConfigMapRequest{{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}}},
+		Deployments: []deployment.DeploymentRequest{{Deployment: kubtypes.Deployment{
+			Name:     "app",
+			Replicas: 1,
+			Containers: []kubtypes.Container{{
+				Name:       "app",
+				Image:      "nginx:1.0.0",
+				Limits:     kubtypes.Resource{CPU: 100, Memory: 100},
+				ConfigMaps: []kubtypes.ContainerVolume{{Name: "cfg", MountPath: "/etc/cfg"}},
+			}},
+		}}},
+		Services: []service.ServiceRequest{{Service: kubtypes.Service{
+			Name:   "app",
+			Deploy: "app",
+			Ports:  []kubtypes.ServicePort{{Name: "http", Port: &port, TargetPort: 80, Protocol: kubtypes.TCP}},
+		}}},
+		Ingresses: []ingress.IngressRequest{{Ingress: kubtypes.Ingress{
+			Name:  "app",
+			Rules: []kubtypes.Rule{{Host: "app.test", Path: []kubtypes.Path{{Path: "/", ServiceName: "app", ServicePort: 8080}}}},
+		}}},
+	}
+
+	// ingress refers to port which service doesn't have
+	_, err := ba.ApplyBundle(ctx, "ns", req)
+	assert.True(t, cherry.Equals(err, rserrors.ErrTCPPortNotFound()), "%v", err)
+	resGraph, err := deps.GetGraph(ctx, "ns")
+	assert.NoError(t, err)
+	assert.Empty(t, resGraph.Nodes, "created resources must be deleted")
+
+	// CreateIngress changes rules of request
+	req.Ingresses = []ingress.IngressRequest{{Ingress: kubtypes.Ingress{
+		Name:  "app",
+		Rules: []kubtypes.Rule{{Host: "app.test", Path: []kubtypes.Path{{Path: "/", ServiceName: "app", ServicePort: port}}}},
+	}}}
+	resp, err := ba.ApplyBundle(ctx, "ns", req)
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Len(t, resp.ConfigMaps, 1)
+		assert.Len(t, resp.Deployments, 1)
+		assert.Len(t, resp.Services, 1)
+		assert.Len(t, resp.Ingresses, 1)
+	}
+
+	_, err = ba.ApplyBundle(ctx, "ns", bundle.Bundle{Services: []service.ServiceRequest{{Service: kubtypes.Service{Name: "other", Deploy: "missing"}}}})
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+}
+
+func TestBundleQuota(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = &limitedPermissions{cpu: 250, memory: 1000}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0)
+	var ba = NewBundleActionsImpl(mongo, &permissions, deps,
+		NewConfigMapsActionsImpl(mongo, &kube, ob, da, deps, nil),
+		da,
+		NewServiceActionsImpl(mongo, &permissions, &kube, ob, deps, nil, NewDomainPool(mongo, domain.Weighted, 0, 0)),
+		NewIngressActionsImpl(mongo, &kube, ob, deps, nil, nil, ""))
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var req = bundle.Bundle{
+		Deployments: []deployment.DeploymentRequest{{
+			Deployment: kubtypes.Deployment{
+				Name:       "app",
+				Replicas:   2,
+				Containers: []kubtypes.Container{{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}}},
+			},
+			Strategy: &deployment.Strategy{Type: deployment.StrategyRolling, MaxSurge: 1},
+		}},
+	}
+
+	// 2 replicas fit, but surge replica started during rollout doesn't
+	_, err := ba.ApplyBundle(ctx, "ns", req)
+	assert.True(t, cherry.Equals(err, rserrors.ErrQuotaExceeded()), "%v", err)
+
+	req.Deployments[0].Strategy = nil
+	_, err = ba.ApplyBundle(ctx, "ns", req)
+	assert.NoError(t, err)
+}
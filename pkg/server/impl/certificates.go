@@ -0,0 +1,270 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/certs"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// tlsCertKey and tlsKeyKey are data keys of kubernetes tls secrets
+	tlsCertKey = "tls.crt"
+	tlsKeyKey  = "tls.key"
+)
+
+// CertificatesImpl issues certificates for ingresses with "auto" tls mode and renews them before expiration.
+// Certificate of ingress is stored in "<ingress>-tls" secret, which is used as tls secret of all ingress rules.
+type CertificatesImpl struct {
+	kube        clients.Kube
+	mongo       db.Storage
+	log         *cherrylog.LogrusAdapter
+	box         *secretbox.Box
+	issuer      certs.Issuer
+	renewBefore time.Duration
+	watch       *WatchImpl
+	// wake starts renewal before next period when certificate is requested
+	wake chan struct{}
+}
+
+// NewCertificatesImpl creates certificates manager, nil issuer disables "auto" tls mode.
+// Ingresses which got certificates are published to watch.
+func NewCertificatesImpl(mongo db.Storage, kube *clients.Kube, box *secretbox.Box, issuer certs.Issuer, renewBefore time.Duration, watcher *WatchImpl) *CertificatesImpl {
+	return &CertificatesImpl{
+		kube:        *kube,
+		mongo:       mongo,
+		log:         cherrylog.NewLogrusAdapter(logrus.WithField("component", "certificates")),
+		box:         box,
+		issuer:      issuer,
+		renewBefore: renewBefore,
+		watch:       watcher,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Enabled returns true if certificate issuer is configured
+func (ci *CertificatesImpl) Enabled() bool {
+	return ci != nil && ci.issuer != nil
+}
+
+// tlsSecretName returns name of secret with certificate issued for ingress
+func tlsSecretName(ingressName string) string {
+	return ingressName + "-tls"
+}
+
+// Check checks that certificate of ingress can be saved to its tls secret,
+// i.e. ingress already has certificate or secret with the same name is not created by user.
+func (ci *CertificatesImpl) Check(nsID, ingressName string) error {
+	switch _, err := ci.mongo.GetCertificate(nsID, ingressName); {
+	case err == nil:
+		return nil
+	case !cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		return err
+	}
+	switch _, err := ci.mongo.GetSecret(nsID, tlsSecretName(ingressName)); {
+	case err == nil:
+		return rserrors.ErrResourceAlreadyExists().AddDetailF("secret '%v' exists, certificate can't be saved to it", tlsSecretName(ingressName))
+	case !cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		return err
+	}
+	return nil
+}
+
+// Request marks certificate of ingress for issue if ingress has no valid certificate for hosts.
+// Certificate is issued asynchronously by Run, ingress uses default certificate of ingress controller until then.
+func (ci *CertificatesImpl) Request(nsID, owner, ingressName string, hosts []string) error {
+	cert, err := ci.mongo.GetCertificate(nsID, ingressName)
+	switch {
+	case err == nil:
+		if cert.Error == "" && cert.Covers(hosts) && time.Until(cert.NotAfter) > ci.renewBefore {
+			return nil
+		}
+	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		cert = ingress.Certificate{
+			NamespaceID: nsID,
+			Ingress:     ingressName,
+			Secret:      tlsSecretName(ingressName),
+		}
+	default:
+		return err
+	}
+	ci.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"ingress": ingressName,
+		"hosts":   hosts,
+	}).Info("request certificate")
+
+	// zero expiration time makes certificate expiring, so it's issued on next renewal
+	cert.Owner = owner
+	cert.NotAfter = time.Time{}
+	if err := ci.mongo.SetCertificate(cert); err != nil {
+		return err
+	}
+	select {
+	case ci.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// issue issues certificate and saves it to tls secret
+func (ci *CertificatesImpl) issue(ctx context.Context, cert ingress.Certificate) error {
+	ci.log.WithFields(logrus.Fields{
+		"ns_id":   cert.NamespaceID,
+		"ingress": cert.Ingress,
+		"hosts":   cert.Hosts,
+	}).Info("issue certificate")
+
+	issued, err := ci.issuer.Issue(ctx, cert.Hosts)
+	if err != nil {
+		ci.log.WithError(err).Error("unable to issue certificate")
+		return rserrors.ErrCertificateIssue().AddDetailsErr(err)
+	}
+
+	if err := ci.saveSecret(ctx, cert, issued); err != nil {
+		return err
+	}
+
+	cert.Issuer = ci.issuer.Name()
+	cert.NotAfter = issued.NotAfter
+	cert.RenewedAt = time.Now().UTC()
+	cert.Error = ""
+	return ci.mongo.SetCertificate(cert)
+}
+
+func (ci *CertificatesImpl) saveSecret(ctx context.Context, cert ingress.Certificate, issued *certs.Certificate) error {
+	kubeSecret := kubtypes.Secret{
+		Name:  cert.Secret,
+		Owner: cert.Owner,
+		Data: map[string]string{
+			tlsCertKey: string(issued.CertPEM),
+			tlsKeyKey:  string(issued.KeyPEM),
+		},
+	}
+	sealed, err := ci.box.Seal(kubeSecret.Data)
+	if err != nil {
+		return rserrors.ErrInternal().Log(err, ci.log)
+	}
+
+	oldSecret, err := ci.mongo.GetSecret(cert.NamespaceID, cert.Secret)
+	switch {
+	case err == nil:
+		newSecret := oldSecret.Copy()
+		newSecret.Data = sealed
+		if _, err := ci.mongo.UpdateSecret(newSecret); err != nil {
+			return err
+		}
+		if err := ci.kube.UpdateSecret(ctx, cert.NamespaceID, kubeSecret); err != nil {
+			ci.log.Debug("Kube-API error! Reverting changes.")
+			oldSecret.ResourceVersion = 0
+			if _, err := ci.mongo.UpdateSecret(oldSecret); err != nil {
+				return err
+			}
+			return err
+		}
+	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		newSecret := secret.FromKube(cert.NamespaceID, cert.Owner, kubeSecret)
+		newSecret.Data = sealed
+		if _, err := ci.mongo.CreateSecret(newSecret); err != nil {
+			return err
+		}
+		if err := ci.kube.CreateSecret(ctx, cert.NamespaceID, kubeSecret); err != nil {
+			ci.log.Debug("Kube-API error! Deleting secret from DB.")
+			if err := ci.mongo.DeleteSecret(cert.NamespaceID, cert.Secret); err != nil {
+				return err
+			}
+			return err
+		}
+	default:
+		return err
+	}
+	return nil
+}
+
+// Delete deletes certificate of ingress and its tls secret
+func (ci *CertificatesImpl) Delete(ctx context.Context, nsID, ingressName string) error {
+	cert, err := ci.mongo.GetCertificate(nsID, ingressName)
+	if err != nil {
+		if cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+			return nil
+		}
+		return err
+	}
+	ci.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"ingress": ingressName,
+	}).Info("delete certificate")
+
+	if err := ci.mongo.DeleteSecret(nsID, cert.Secret); err != nil && !cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+		return err
+	}
+	if err := ci.kube.DeleteSecret(ctx, nsID, cert.Secret); err != nil {
+		ci.log.WithError(err).Error("unable to delete tls secret")
+	}
+	return ci.mongo.DeleteCertificate(nsID, ingressName)
+}
+
+// Run issues requested and renews expiring certificates every period or on request until ctx is done
+func (ci *CertificatesImpl) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-ci.wake:
+		}
+		if err := ci.renew(headersContext(reconcilerHeaders)); err != nil {
+			ci.log.WithError(err).Error("unable to renew certificates")
+		}
+	}
+}
+
+// renew issues requested certificates and re-issues certificates expiring in renewBefore period, failed issues are retried on next run.
+// Certificates of deleted ingresses and ingresses with manual tls are deleted.
+func (ci *CertificatesImpl) renew(ctx context.Context) error {
+	expiring, err := ci.mongo.GetCertificatesExpiringBefore(time.Now().Add(ci.renewBefore))
+	if err != nil {
+		return err
+	}
+	for _, cert := range expiring {
+		entry := ci.log.WithFields(logrus.Fields{
+			"ns_id":   cert.NamespaceID,
+			"ingress": cert.Ingress,
+		})
+		ingr, err := ci.mongo.GetIngress(cert.NamespaceID, cert.Ingress)
+		if err != nil && !cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+			entry.WithError(err).Error("unable to get ingress")
+			continue
+		}
+		if err != nil || ingr.TLS != ingress.TLSAuto {
+			if err := ci.Delete(ctx, cert.NamespaceID, cert.Ingress); err != nil {
+				entry.WithError(err).Error("unable to delete certificate")
+			}
+			continue
+		}
+		cert.Hosts = ingr.Hosts()
+		if err := ci.issue(ctx, cert); err != nil {
+			entry.WithError(err).Error("unable to renew certificate")
+			cert.Error = err.Error()
+			if err := ci.mongo.SetCertificate(cert); err != nil {
+				entry.WithError(err).Error("unable to save certificate error")
+			}
+			continue
+		}
+		ci.watch.notify(cert.NamespaceID, watch.Ingress, cert.Ingress, watch.Modified, nil)
+	}
+	return nil
+}
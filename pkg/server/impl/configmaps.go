@@ -2,11 +2,20 @@ package impl
 
 import (
 	"context"
+	"net/url"
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
 	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
+	"github.com/blang/semver"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
@@ -14,47 +23,69 @@ import (
 )
 
 type ConfigMapsActionsImpl struct {
-	kube  clients.Kube
-	mongo *db.MongoStorage
-	log   *cherrylog.LogrusAdapter
+	kube     clients.Kube
+	mongo    db.Storage
+	log      *cherrylog.LogrusAdapter
+	outbox   *OutboxImpl
+	deployer *DeployActionsImpl
+	deps     *GraphActionsImpl
+	audit    *AuditImpl
 }
 
-func NewConfigMapsActionsImpl(mongo *db.MongoStorage, kube *clients.Kube) *ConfigMapsActionsImpl {
-	return &ConfigMapsActionsImpl{
-		kube:  *kube,
-		mongo: mongo,
-		log:   cherrylog.NewLogrusAdapter(logrus.WithField("component", "configmaps_actions")),
+// NewConfigMapsActionsImpl creates configmap actions.
+// Deployer is used to redeploy deployments with reload_on_config_change enabled when configmap changes.
+func NewConfigMapsActionsImpl(mongo db.Storage, kube *clients.Kube, outbox *OutboxImpl, deployer *DeployActionsImpl, deps *GraphActionsImpl, audit *AuditImpl) *ConfigMapsActionsImpl {
+	ia := &ConfigMapsActionsImpl{
+		kube:     *kube,
+		mongo:    mongo,
+		log:      cherrylog.NewLogrusAdapter(logrus.WithField("component", "configmaps_actions")),
+		outbox:   outbox,
+		deployer: deployer,
+		deps:     deps,
+		audit:    audit,
 	}
+	deps.SetDeleter(graph.ConfigMap, ia.deleteConfigMap)
+	return ia
 }
 
-func (ia *ConfigMapsActionsImpl) GetConfigMapsList(ctx context.Context, nsID string) (*configmap.ConfigMapsResponse, error) {
+func (ia *ConfigMapsActionsImpl) GetConfigMapsList(ctx context.Context, nsID string, params url.Values) (*configmap.ConfigMapsResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id":   userID,
 		"namespace": nsID,
 	}).Info("get user configmaps")
 
-	cms, err := ia.mongo.GetConfigMapList(nsID)
+	query, err := db.ParseListQuery(params, db.ConfigMapListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &configmap.ConfigMapsResponse{ConfigMaps: cms}, nil
+	cms, total, err := ia.mongo.GetConfigMapList(nsID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &configmap.ConfigMapsResponse{ConfigMaps: cms, Total: total}, nil
 }
 
-func (ia *ConfigMapsActionsImpl) GetSelectedConfigMapsList(ctx context.Context, namespaces []string) (*configmap.ConfigMapsResponse, error) {
+func (ia *ConfigMapsActionsImpl) GetSelectedConfigMapsList(ctx context.Context, namespaces []string, params url.Values) (*configmap.ConfigMapsResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id":    userID,
 		"namespaces": namespaces,
 	}).Info("get selected configmaps")
 
-	cms, err := ia.mongo.GetSelectedConfigMaps(namespaces)
+	query, err := db.ParseListQuery(params, db.ConfigMapListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &configmap.ConfigMapsResponse{ConfigMaps: cms}, nil
+	cms, total, err := ia.mongo.GetSelectedConfigMaps(namespaces, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &configmap.ConfigMapsResponse{ConfigMaps: cms, Total: total}, nil
 }
 
 func (ia *ConfigMapsActionsImpl) GetConfigMap(ctx context.Context, nsID, cmName string) (*configmap.ResourceConfigMap, error) {
@@ -65,7 +96,110 @@ func (ia *ConfigMapsActionsImpl) GetConfigMap(ctx context.Context, nsID, cmName
 	return &resp, err
 }
 
-func (ia *ConfigMapsActionsImpl) CreateConfigMap(ctx context.Context, nsID string, req kubtypes.ConfigMap) (*configmap.ResourceConfigMap, error) {
+func (ia *ConfigMapsActionsImpl) GetConfigMapVersionsList(ctx context.Context, nsID, cmName string, params url.Values) (*configmap.ConfigMapsResponse, error) {
+	ia.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"cm":    cmName,
+	}).Info("get configmap versions")
+
+	query, err := db.ParseListQuery(params, db.ConfigMapVersionListFields)
+	if err != nil {
+		return nil, err
+	}
+
+	cms, total, err := ia.mongo.GetConfigMapVersionsList(nsID, cmName, query)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 && len(query.Filters) == 0 {
+		return nil, rserrors.ErrResourceNotExists().AddDetails(cmName)
+	}
+
+	return &configmap.ConfigMapsResponse{ConfigMaps: cms, Total: total}, nil
+}
+
+func (ia *ConfigMapsActionsImpl) GetConfigMapVersion(ctx context.Context, nsID, cmName, version string) (*configmap.ResourceConfigMap, error) {
+	ia.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"cm":      cmName,
+		"version": version,
+	}).Info("get configmap version")
+
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil, rserrors.ErrValidation().AddDetailsErr(err)
+	}
+
+	resp, err := ia.mongo.GetConfigMapVersion(nsID, cmName, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (ia *ConfigMapsActionsImpl) DiffConfigMaps(ctx context.Context, nsID, cmName, version1, version2 string) (*configmap.ConfigMapDiff, error) {
+	ia.log.WithFields(logrus.Fields{
+		"ns_id":    nsID,
+		"cm":       cmName,
+		"version1": version1,
+		"version2": version2,
+	}).Info("diff configmap versions")
+
+	v1, err := semver.ParseTolerant(version1)
+	if err != nil {
+		return nil, rserrors.ErrValidation().AddDetailsErr(err)
+	}
+
+	v2, err := semver.ParseTolerant(version2)
+	if err != nil {
+		return nil, rserrors.ErrValidation().AddDetailsErr(err)
+	}
+
+	cm1, err := ia.mongo.GetConfigMapVersion(nsID, cmName, v1)
+	if err != nil {
+		return nil, err
+	}
+
+	cm2, err := ia.mongo.GetConfigMapVersion(nsID, cmName, v2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &configmap.ConfigMapDiff{Diff: configmap.Diff(cm1, cm2)}, nil
+}
+
+func (ia *ConfigMapsActionsImpl) DiffConfigMapsPrevious(ctx context.Context, nsID, cmName, version string) (*configmap.ConfigMapDiff, error) {
+	ia.log.WithFields(logrus.Fields{
+		"ns_id":   nsID,
+		"cm":      cmName,
+		"version": version,
+	}).Info("diff configmap versions")
+
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil, rserrors.ErrValidation().AddDetailsErr(err)
+	}
+
+	cms, _, err := ia.mongo.GetConfigMapVersionsList(nsID, cmName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// versions are sorted from newest to oldest
+	for i, cm := range cms {
+		if cm.Version.EQ(v) {
+			if i+1 == len(cms) {
+				return nil, rserrors.ErrResourceNotExists().AddDetails("no previous version found")
+			}
+			return &configmap.ConfigMapDiff{Diff: configmap.Diff(cms[i+1], cm)}, nil
+		}
+	}
+
+	return nil, rserrors.ErrResourceNotExists().AddDetailF("%v %v", cmName, v.String())
+}
+
+func (ia *ConfigMapsActionsImpl) CreateConfigMap(ctx context.Context, nsID string, req configmap.ConfigMapRequest) (ret *configmap.ResourceConfigMap, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id": userID,
@@ -73,29 +207,61 @@ func (ia *ConfigMapsActionsImpl) CreateConfigMap(ctx context.Context, nsID strin
 	}).Info("create configmap")
 	coblog.Std.Struct(req)
 
-	createdCM, err := ia.mongo.CreateConfigMap(configmap.FromKube(nsID, userID, req))
-	if err != nil {
-		return nil, err
+	defer func() {
+		ia.audit.Record(ctx, nsID, audit.ConfigMap, req.Name, audit.Create, nil, auditConfigMap(ret), err)
+	}()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
 	}
 
-	if err := ia.kube.CreateConfigMap(ctx, nsID, req); err != nil {
-		ia.log.Debug("Kube-API error! Deleting configmap from DB.")
-		if err := ia.mongo.DeleteConfigMap(nsID, req.Name); err != nil {
+	newCM := configmap.FromKube(nsID, userID, req.ConfigMap)
+	newCM.Metadata = req.Metadata.Copy()
+
+	if server.IsDryRun(ctx) {
+		_, err := ia.mongo.GetConfigMap(nsID, req.Name)
+		if err := checkNotExists(err); err != nil {
 			return nil, err
 		}
+		return &newCM, nil
+	}
+
+	op, err := ia.outbox.Enqueue(ctx, outbox.CreateConfigMap, nsID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	createdCM, err := ia.mongo.CreateConfigMap(newCM)
+	if err != nil {
+		ia.outbox.Cancel(op, err)
+		return nil, err
+	}
+
+	if err := ia.outbox.Apply(ctx, op, func() error {
+		return ia.mongo.DeleteConfigMap(nsID, req.Name)
+	}); err != nil {
 		return nil, err
 	}
 
 	return &createdCM, nil
 }
 
-func (ia *ConfigMapsActionsImpl) ImportConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) error {
+func (ia *ConfigMapsActionsImpl) ImportConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) (err error) {
 	ia.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("import configmap")
 	coblog.Std.Struct(cm)
 
-	_, err := ia.mongo.CreateConfigMap(configmap.FromKube(nsID, cm.Owner, cm))
+	defer func() {
+		ia.audit.Record(ctx, nsID, audit.ConfigMap, cm.Name, audit.Import, nil, configmap.FromKube(nsID, cm.Owner, cm).WithoutData(), err)
+	}()
+
+	if server.IsDryRun(ctx) {
+		_, err := ia.mongo.GetConfigMap(nsID, cm.Name)
+		return checkNotExists(err)
+	}
+
+	_, err = ia.mongo.CreateConfigMap(configmap.FromKube(nsID, cm.Owner, cm))
 	if err != nil {
 		return err
 	}
@@ -103,14 +269,218 @@ func (ia *ConfigMapsActionsImpl) ImportConfigMap(ctx context.Context, nsID strin
 	return nil
 }
 
-func (ia *ConfigMapsActionsImpl) DeleteConfigMap(ctx context.Context, nsID, cmName string) error {
+func (ia *ConfigMapsActionsImpl) UpdateConfigMap(ctx context.Context, nsID string, req configmap.ConfigMapRequest) (ret *configmap.ResourceConfigMap, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	ia.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+		"cm":      req.Name,
+	}).Info("update configmap")
+	coblog.Std.Struct(req)
+
+	before := ia.auditState(nsID, req.Name)
+	defer func() {
+		ia.audit.Record(ctx, nsID, audit.ConfigMap, req.Name, audit.Update, before, auditConfigMap(ret), err)
+	}()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
+	oldCM, err := ia.mongo.GetConfigMap(nsID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.CheckIfMatch(ctx, oldCM.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	return ia.updateConfigMap(ctx, oldCM, req.Data, req.Metadata.Merge(oldCM.Metadata))
+}
+
+func (ia *ConfigMapsActionsImpl) PatchConfigMap(ctx context.Context, nsID, cmName string, patch configmap.ConfigMapPatch) (ret *configmap.ResourceConfigMap, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	ia.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+		"cm":      cmName,
+	}).Info("patch configmap")
+	coblog.Std.Struct(patch)
+
+	before := ia.auditState(nsID, cmName)
+	defer func() {
+		ia.audit.Record(ctx, nsID, audit.ConfigMap, cmName, audit.Update, before, auditConfigMap(ret), err)
+	}()
+
+	oldCM, err := ia.mongo.GetConfigMap(nsID, cmName)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.CheckIfMatch(ctx, oldCM.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	if err := ia.loadData(ctx, &oldCM); err != nil {
+		return nil, err
+	}
+
+	return ia.updateConfigMap(ctx, oldCM, configmap.Patch(oldCM.Data, patch), oldCM.Metadata)
+}
+
+// updateConfigMap creates new active version of configmap with provided data and metadata.
+// If neither is changed, active version is returned. Metadata change without data change bumps patch version.
+func (ia *ConfigMapsActionsImpl) updateConfigMap(ctx context.Context, oldCM configmap.ResourceConfigMap, data kubtypes.ConfigMapData, meta labels.Metadata) (*configmap.ResourceConfigMap, error) {
+	if err := ia.loadData(ctx, &oldCM); err != nil {
+		return nil, err
+	}
+
+	versions, _, err := ia.mongo.GetConfigMapVersionsList(oldCM.NamespaceID, oldCM.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	var latest = oldCM.Version
+	if len(versions) > 0 {
+		latest = versions[0].Version
+	}
+
+	newVersion := configmap.NewVersion(latest, oldCM.Data, data)
+	if newVersion.EQ(latest) {
+		if meta.Equal(oldCM.Metadata) {
+			ia.log.Debug("configmap data is not changed")
+			return &oldCM, nil
+		}
+		newVersion.Patch++
+	}
+
+	newCM := oldCM.Copy()
+	newCM.ID = ""
+	newCM.Data = data
+	newCM.Metadata = meta.Copy()
+	newCM.Version = newVersion
+	newCM.Active = true
+
+	if server.IsDryRun(ctx) {
+		return &newCM, nil
+	}
+
+	// only version which was read is deactivated, so concurrent update fails with conflict instead of overwriting it
+	if err := ia.mongo.DeactivateConfigMap(oldCM.NamespaceID, oldCM.Name, oldCM.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	createdCM, err := ia.mongo.CreateConfigMap(newCM)
+	if err != nil {
+		if err := ia.mongo.ActivateConfigMap(oldCM.NamespaceID, oldCM.Name, oldCM.Version); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := ia.kube.UpdateConfigMap(ctx, oldCM.NamespaceID, createdCM.ConfigMap, createdCM.Metadata); err != nil {
+		ia.log.Debug("Kube-API error! Reverting changes.")
+		if err := ia.mongo.DeactivateConfigMap(oldCM.NamespaceID, oldCM.Name, createdCM.ResourceVersion); err != nil {
+			return nil, err
+		}
+		if err := ia.mongo.DeleteConfigMapVersion(oldCM.NamespaceID, oldCM.Name, newVersion); err != nil {
+			return nil, err
+		}
+		if err := ia.mongo.ActivateConfigMap(oldCM.NamespaceID, oldCM.Name, oldCM.Version); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	createdCM.Reloads = ia.reloadDeployments(oldCM.NamespaceID, oldCM.Name)
+
+	return &createdCM, nil
+}
+
+// reloadDeployments rolls out new versions of deployments which mount configmap and have reload_on_config_change enabled.
+// Deployments are updated on behalf of their owners, not configmap editor.
+// Configmap is already updated at this point, so failed rollouts are returned to be shown in response instead of failing request.
+func (ia *ConfigMapsActionsImpl) reloadDeployments(nsID, cmName string) []configmap.Reload {
+	deploys, _, err := ia.mongo.GetDeploymentList(nsID, nil)
+	if err != nil {
+		ia.log.WithError(err).Error("unable to get deployments to reload")
+		return []configmap.Reload{{Error: err.Error()}}
+	}
+	var reloads []configmap.Reload
+	for _, depl := range deploys {
+		if !depl.ReloadOnConfigChange || !depl.MountsConfigMap(cmName) {
+			continue
+		}
+		entry := ia.log.WithFields(logrus.Fields{
+			"ns_id":       nsID,
+			"cm":          cmName,
+			"deploy_name": depl.Name,
+		})
+		spec := depl.Deployment
+		spec.Status = nil
+		updated, err := ia.deployer.UpdateDeployment(ownerContext(depl.Owner), nsID, deployment.DeploymentRequest{Deployment: spec})
+		if err != nil {
+			entry.WithError(err).Error("unable to reload deployment after configmap change")
+			reloads = append(reloads, configmap.Reload{Deployment: depl.Name, Error: err.Error()})
+			continue
+		}
+		entry.WithField("version", updated.Version.String()).Info("deployment reloaded after configmap change")
+		reloads = append(reloads, configmap.Reload{Deployment: depl.Name, Version: updated.Version.String()})
+	}
+	return reloads
+}
+
+// loadData gets data of configmaps created before data was stored in db from kube-api
+func (ia *ConfigMapsActionsImpl) loadData(ctx context.Context, cm *configmap.ResourceConfigMap) error {
+	if cm.Data != nil {
+		return nil
+	}
+	cms, err := ia.kube.GetConfigMapList(ctx, cm.NamespaceID)
+	if err != nil {
+		return err
+	}
+	for _, kubeCM := range cms {
+		if kubeCM.Name == cm.Name {
+			cm.Data = kubeCM.Data
+			return nil
+		}
+	}
+	cm.Data = kubtypes.ConfigMapData{}
+	return nil
+}
+
+func (ia *ConfigMapsActionsImpl) DeleteConfigMap(ctx context.Context, nsID, cmName string, cascade bool) error {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id": userID,
 		"ns_id":   nsID,
 		"cm":      cmName,
+		"cascade": cascade,
 	}).Info("delete configmap")
 
+	if _, ok := server.IfMatch(ctx); ok {
+		cm, err := ia.mongo.GetConfigMap(nsID, cmName)
+		if err != nil {
+			return err
+		}
+		if err := server.CheckIfMatch(ctx, cm.ResourceVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := ia.deps.CheckDelete(ctx, nsID, graph.Node{Kind: graph.ConfigMap, Name: cmName}, cascade); err != nil {
+		ia.audit.Record(ctx, nsID, audit.ConfigMap, cmName, audit.Delete, ia.auditState(nsID, cmName), nil, err)
+		return err
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	return ia.deleteConfigMap(ctx, nsID, cmName)
+}
+
+func (ia *ConfigMapsActionsImpl) deleteConfigMap(ctx context.Context, nsID, cmName string) (err error) {
+	before := ia.auditState(nsID, cmName)
+	defer func() { ia.audit.Record(ctx, nsID, audit.ConfigMap, cmName, audit.Delete, before, nil, err) }()
+
 	if err := ia.mongo.DeleteConfigMap(nsID, cmName); err != nil {
 		return err
 	}
@@ -126,14 +496,40 @@ func (ia *ConfigMapsActionsImpl) DeleteConfigMap(ctx context.Context, nsID, cmNa
 	return nil
 }
 
-func (ia *ConfigMapsActionsImpl) DeleteAllConfigMaps(ctx context.Context, nsID string) error {
+func (ia *ConfigMapsActionsImpl) DeleteAllConfigMaps(ctx context.Context, nsID string) (err error) {
 	ia.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("delete all configmaps")
 
+	defer func() { ia.audit.Record(ctx, nsID, audit.ConfigMap, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
 	if err := ia.mongo.DeleteAllConfigMapsInNamespace(nsID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// auditState returns active configmap version without data for audit log, nil if configmap doesn't exist
+func (ia *ConfigMapsActionsImpl) auditState(nsID, cmName string) interface{} {
+	if ia.audit == nil {
+		return nil
+	}
+	cm, err := ia.mongo.GetConfigMap(nsID, cmName)
+	if err != nil {
+		return nil
+	}
+	return cm.WithoutData()
+}
+
+// auditConfigMap returns configmap without data for audit log, data is never written to it like secret values
+func auditConfigMap(cm *configmap.ResourceConfigMap) interface{} {
+	if cm == nil {
+		return nil
+	}
+	return cm.WithoutData()
+}
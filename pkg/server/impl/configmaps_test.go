@@ -0,0 +1,120 @@
+package impl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigMapVersions(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0), deps, nil)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	created, err := ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", created.Version.String())
+
+	added, err := ca.PatchConfigMap(ctx, "ns", "cfg", configmap.ConfigMapPatch{Set: kubtypes.ConfigMapData{"b": "2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", added.Version.String())
+	assert.Equal(t, kubtypes.ConfigMapData{"a": "1", "b": "2"}, added.Data)
+
+	changed, err := ca.UpdateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1", "b": "3"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1", changed.Version.String())
+
+	removed, err := ca.PatchConfigMap(ctx, "ns", "cfg", configmap.ConfigMapPatch{Remove: []string{"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0", removed.Version.String())
+
+	same, err := ca.UpdateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"b": "3"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0", same.Version.String(), "unchanged data must not create new version")
+
+	versions, err := ca.GetConfigMapVersionsList(ctx, "ns", "cfg", nil)
+	assert.NoError(t, err)
+	assert.Len(t, versions.ConfigMaps, 4)
+
+	active, err := ca.GetConfigMap(ctx, "ns", "cfg")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0", active.Version.String())
+
+	list, err := ca.GetConfigMapsList(ctx, "ns", nil)
+	assert.NoError(t, err)
+	assert.Len(t, list.ConfigMaps, 1)
+
+	diff, err := ca.DiffConfigMapsPrevious(ctx, "ns", "cfg", "2.0.0")
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(diff.Diff, `-a: "1"`), diff.Diff)
+
+	assert.NoError(t, ca.DeleteConfigMap(ctx, "ns", "cfg", false))
+	_, err = ca.GetConfigMapVersionsList(ctx, "ns", "cfg", nil)
+	assert.Error(t, err)
+}
+
+func TestConfigMapReload(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, da, deps, nil)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+
+	var reload = true
+	for _, name := range []string{"reloaded", "static"} {
+		_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+			Deployment: kubtypes.Deployment{
+				Name:     name,
+				Replicas: 1,
+				Containers: []kubtypes.Container{{
+					Name:       "app",
+					Image:      "nginx:1.0.0",
+					Limits:     kubtypes.Resource{CPU: 100, Memory: 100},
+					ConfigMaps: []kubtypes.ContainerVolume{{Name: "cfg", MountPath: "/etc/cfg"}},
+				}},
+			},
+			ReloadOnConfigChange: &reload,
+		})
+		assert.NoError(t, err)
+		reload = false
+	}
+
+	created, err := da.GetDeployment(ctx, "ns", "reloaded")
+	assert.NoError(t, err)
+	assert.Equal(t, configmap.Hash(kubtypes.ConfigMapData{"a": "1"}), created.ConfigHashes["cfg"])
+
+	updated, err := ca.UpdateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "2"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []configmap.Reload{{Deployment: "reloaded", Version: "1.0.1"}}, updated.Reloads)
+
+	reloaded, err := da.GetDeployment(ctx, "ns", "reloaded")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.1", reloaded.Version.String())
+	assert.Equal(t, created.Version.String(), reloaded.PreviousVersion.String())
+	assert.Equal(t, configmap.Hash(kubtypes.ConfigMapData{"a": "2"}), reloaded.ConfigHashes["cfg"])
+	assert.Empty(t, reloaded.Containers[0].Env, "configmaps hash must be sent as pod annotation, not env")
+	assert.NotEqual(t, deployment.PodAnnotations(created.ConfigHashes), deployment.PodAnnotations(reloaded.ConfigHashes))
+
+	static, err := da.GetDeployment(ctx, "ns", "static")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", static.Version.String(), "deployment without reload_on_config_change must not be redeployed")
+	assert.Empty(t, static.ConfigHashes)
+}
@@ -0,0 +1,52 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/containerum/utils/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// requestUser returns user id and role saved in context. They are absent if context is not created by http request.
+func requestUser(ctx context.Context) (userID, role string) {
+	userID, _ = ctx.Value(httputil.UserIDContextKey).(string)
+	role, _ = ctx.Value(httputil.UserRoleContextKey).(string)
+	return userID, role
+}
+
+// headersContext creates context with provided X-headers for kube-api requests made outside of http request
+func headersContext(headers map[string]string) context.Context {
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	gctx := &gin.Context{Request: req}
+	httputil.SaveHeaders(gctx)
+	return gctx.Request.Context()
+}
+
+// userContext creates context with user id and role for kube-api requests made outside of http request
+func userContext(userID, role string) context.Context {
+	headers := make(map[string]string)
+	if userID != "" {
+		headers[httputil.UserIDXHeader] = userID
+	}
+	if role != "" {
+		headers[httputil.UserRoleXHeader] = role
+	}
+	return headersContext(headers)
+}
+
+// systemContext creates context of change made by resource-service itself, e.g. by automatic rollback
+func systemContext() context.Context {
+	ctx := headersContext(reconcilerHeaders)
+	ctx = context.WithValue(ctx, httputil.UserIDContextKey, reconcilerHeaders[httputil.UserIDXHeader])
+	return context.WithValue(ctx, httputil.UserRoleContextKey, reconcilerHeaders[httputil.UserRoleXHeader])
+}
+
+// ownerContext creates context of request made on behalf of resource owner, e.g. to reload deployment after configmap change.
+// Nothing from original request, like If-Match header or dry run flag, gets to it.
+func ownerContext(owner string) context.Context {
+	return context.WithValue(userContext(owner, ""), httputil.UserIDContextKey, owner)
+}
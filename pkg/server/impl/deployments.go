@@ -2,14 +2,22 @@ package impl
 
 import (
 	"context"
+	"net/url"
+	"time"
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
 	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
 	"github.com/blang/semver"
+	"github.com/containerum/cherry"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	"github.com/containerum/kube-client/pkg/diff"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
@@ -21,32 +29,54 @@ import (
 type DeployActionsImpl struct {
 	kube        clients.Kube
 	permissions clients.Permissions
-	mongo       *db.MongoStorage
+	mongo       db.Storage
 	log         *cherrylog.LogrusAdapter
+	outbox      *OutboxImpl
+	deps        *GraphActionsImpl
+	audit       *AuditImpl
+
+	rollbackDeadline time.Duration
 }
 
-func NewDeployActionsImpl(mongo *db.MongoStorage, permissions *clients.Permissions, kube *clients.Kube) *DeployActionsImpl {
-	return &DeployActionsImpl{
-		kube:        *kube,
-		permissions: *permissions,
-		mongo:       mongo,
-		log:         cherrylog.NewLogrusAdapter(logrus.WithField("component", "deploy_actions")),
-	}
+// rolloutCheckPeriod is a period of polling kube-api for deployment status while waiting for rollout
+const rolloutCheckPeriod = 5 * time.Second
+
+// NewDeployActionsImpl creates deployment actions.
+// If rollbackDeadline is not zero, new deployment version is rolled back when its replicas are not ready in time.
+func NewDeployActionsImpl(mongo db.Storage, permissions *clients.Permissions, kube *clients.Kube, outbox *OutboxImpl, deps *GraphActionsImpl, audit *AuditImpl, rollbackDeadline time.Duration) *DeployActionsImpl {
+	da := &DeployActionsImpl{
+		kube:             *kube,
+		permissions:      *permissions,
+		mongo:            mongo,
+		log:              cherrylog.NewLogrusAdapter(logrus.WithField("component", "deploy_actions")),
+		outbox:           outbox,
+		deps:             deps,
+		audit:            audit,
+		rollbackDeadline: rollbackDeadline,
+	}
+	deps.SetDeleter(graph.Deployment, da.deleteDeployment)
+	deps.SetRestorer(graph.Deployment, da.restoreDeployment)
+	return da
 }
 
-func (da *DeployActionsImpl) GetDeploymentsList(ctx context.Context, nsID string) (*deployment.DeploymentsResponse, error) {
+func (da *DeployActionsImpl) GetDeploymentsList(ctx context.Context, nsID string, params url.Values) (*deployment.DeploymentsResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":   userID,
 		"namespace": nsID,
 	}).Info("get deployments")
 
-	deployments, err := da.mongo.GetDeploymentList(nsID)
+	query, err := db.ParseListQuery(params, db.DeploymentListFields)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments, total, err := da.mongo.GetDeploymentList(nsID, query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &deployment.DeploymentsResponse{Deployments: deployments}, nil
+	return &deployment.DeploymentsResponse{Deployments: deployments, Total: total}, nil
 }
 
 func (da *DeployActionsImpl) GetDeployment(ctx context.Context, nsID, deplName string) (*deployment.ResourceDeploy, error) {
@@ -79,7 +109,7 @@ func (da *DeployActionsImpl) GetDeploymentVersion(ctx context.Context, nsID, dep
 	return &ret, err
 }
 
-func (da *DeployActionsImpl) GetDeploymentVersionsList(ctx context.Context, nsID, deployName string) (*deployment.DeploymentsResponse, error) {
+func (da *DeployActionsImpl) GetDeploymentVersionsList(ctx context.Context, nsID, deployName string, params url.Values) (*deployment.DeploymentsResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":    userID,
@@ -87,21 +117,35 @@ func (da *DeployActionsImpl) GetDeploymentVersionsList(ctx context.Context, nsID
 		"deployment": deployName,
 	}).Info("get deployments")
 
-	deployments, err := da.mongo.GetDeploymentVersionsList(nsID, deployName)
+	query, err := db.ParseListQuery(params, db.DeploymentVersionListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &deployment.DeploymentsResponse{Deployments: deployments}, nil
+	deployments, total, err := da.mongo.GetDeploymentVersionsList(nsID, deployName, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deployment.DeploymentsResponse{Deployments: deployments, Total: total}, nil
 }
 
-func (da *DeployActionsImpl) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) CreateDeployment(ctx context.Context, nsID string, req deployment.DeploymentRequest) (ret *deployment.ResourceDeploy, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id": userID,
 		"ns_id":   nsID,
 	}).Info("create deployment")
 
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, req.Deployment.Name, audit.Create, nil, ret, err) }()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
+	deploy := req.Deployment
+	reload := req.ReloadOnConfigChange != nil && *req.ReloadOnConfigChange
+
 	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
 	if err != nil {
 		return nil, err
@@ -124,34 +168,64 @@ func (da *DeployActionsImpl) CreateDeployment(ctx context.Context, nsID string,
 
 	deploy.Active = true
 
-	createdDeploy, err := da.mongo.CreateDeployment(deployment.FromKube(nsID, userID, deploy))
+	hashes, err := da.configHashes(nsID, deploy, reload)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := da.kube.CreateDeployment(ctx, nsID, deploy); err != nil {
-		da.log.Debug("Kube-API error! Deleting deployment from DB.")
-		if err := da.mongo.DeleteDeployment(nsID, deploy.Name); err != nil {
+	newDeploy := deployment.FromKube(nsID, userID, deploy)
+	newDeploy.Metadata = req.Metadata.Copy()
+	newDeploy.Strategy = req.Strategy
+	newDeploy.ReloadOnConfigChange = reload
+	newDeploy.ConfigHashes = hashes
+
+	if server.IsDryRun(ctx) {
+		_, err := da.mongo.GetDeployment(nsID, deploy.Name)
+		if err := checkNotExists(err); err != nil {
 			return nil, err
 		}
+		return dryRunDeployment(kubtypes.Deployment{}, newDeploy), nil
+	}
+
+	op, err := da.outbox.Enqueue(ctx, outbox.CreateDeployment, nsID, deploy.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	createdDeploy, err := da.mongo.CreateDeployment(newDeploy)
+	if err != nil {
+		da.outbox.Cancel(op, err)
+		return nil, err
+	}
+
+	if err := da.outbox.Apply(ctx, op, func() error {
+		return da.mongo.DeleteDeployment(nsID, deploy.Name)
+	}); err != nil {
 		return nil, err
 	}
 
 	return &createdDeploy, nil
 }
 
-func (da *DeployActionsImpl) ImportDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error {
+func (da *DeployActionsImpl) ImportDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) (err error) {
 	da.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("importing deployment")
 	coblog.Std.Struct(deploy)
 
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deploy.Name, audit.Import, nil, deploy, err) }()
+
 	server.CalculateDeployResources(&deploy)
 
 	deploy.Version = semver.MustParse("1.0.0")
 	deploy.Active = true
 
-	_, err := da.mongo.CreateDeployment(deployment.FromKube(nsID, deploy.Owner, deploy))
+	if server.IsDryRun(ctx) {
+		_, err := da.mongo.GetDeployment(nsID, deploy.Name)
+		return checkNotExists(err)
+	}
+
+	_, err = da.mongo.CreateDeployment(deployment.FromKube(nsID, deploy.Owner, deploy))
 	if err != nil {
 		return err
 	}
@@ -159,7 +233,8 @@ func (da *DeployActionsImpl) ImportDeployment(ctx context.Context, nsID string,
 	return nil
 }
 
-func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string, req deployment.DeploymentRequest) (ret *deployment.ResourceDeploy, err error) {
+	deploy, strategy := req.Deployment, req.Strategy
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -167,7 +242,13 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 		"deploy_name": deploy.Name,
 	}).Infof("replacing deployment")
 
+	before := da.auditState(nsID, deploy.Name)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deploy.Name, audit.Update, before, ret, err) }()
+
 	coblog.Std.Struct(deploy)
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
 	server.CalculateDeployResources(&deploy)
 
 	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
@@ -184,6 +265,26 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 	if err != nil {
 		return nil, err
 	}
+	if err := server.CheckIfMatch(ctx, oldDeploy.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	if err := da.checkNoRollout(nsID, deploy.Name); err != nil {
+		return nil, err
+	}
+
+	if strategy == nil {
+		strategy = oldDeploy.Strategy
+	}
+
+	reload := oldDeploy.ReloadOnConfigChange
+	if req.ReloadOnConfigChange != nil {
+		reload = *req.ReloadOnConfigChange
+	}
+	hashes, err := da.configHashes(nsID, deploy, reload)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(oldDeploy.Containers) == 0 {
 		return nil, rserrors.ErrNoContainer()
@@ -199,7 +300,7 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 
 	server.CalculateDeployResources(&oldDeploy.Deployment)
 
-	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, oldDeploy.Deployment, deploy); err != nil {
+	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, oldDeploy.Deployment, deploy, strategy); err != nil {
 		return nil, err
 	}
 
@@ -211,36 +312,62 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 	oldversion := oldLatestDeploy.Version
 
 	deploy.Version = diff.NewVersion(oldLatestDeploy.Deployment, deploy)
-	if deploy.Version.EQ(oldLatestDeploy.Version) && oldDeploy.Version.NE(oldLatestDeploy.Version) {
+	// configmap change is rolled out as new version, so history shows which config caused rollout
+	if deploy.Version.EQ(oldLatestDeploy.Version) &&
+		(oldDeploy.Version.NE(oldLatestDeploy.Version) || !configMapDataEqual(oldDeploy.ConfigHashes, hashes)) {
 		deploy.Version.Patch++
 	}
 	deploy.Active = true
 
 	newversion := deploy.Version
 
+	newDeploy := deployment.FromKube(nsID, userID, deploy)
+	newDeploy.Metadata = req.Metadata.Merge(oldDeploy.Metadata)
+	newDeploy.Strategy = strategy
+	newDeploy.ReloadOnConfigChange = reload
+	newDeploy.ConfigHashes = hashes
+	newDeploy.ResourceVersion = oldDeploy.ResourceVersion
+
 	var updatedDeploy deployment.ResourceDeploy
 	if !newversion.Equals(oldversion) {
-		if err := da.mongo.DeactivateDeployment(nsID, deploy.Name); err != nil {
+		newDeploy.PreviousVersion = &oldDeploy.Version
+		if strategy.IsCanary() {
+			return da.startCanary(ctx, oldDeploy, newDeploy)
+		}
+		if server.IsDryRun(ctx) {
+			return dryRunDeployment(oldDeploy.Deployment, newDeploy), nil
+		}
+
+		if err := da.mongo.DeactivateDeployment(nsID, deploy.Name, oldDeploy.ResourceVersion); err != nil {
 			return nil, err
 		}
 
-		updatedDeploy, err = da.mongo.CreateDeployment(deployment.FromKube(nsID, userID, deploy))
+		updatedDeploy, err = da.mongo.CreateDeployment(newDeploy)
 		if err != nil {
+			if err := da.restoreActiveVersion(nsID, deploy.Name, oldDeploy.Version); err != nil {
+				return nil, err
+			}
 			return nil, err
 		}
 
-		if err := da.kube.UpdateDeployment(ctx, nsID, deploy); err != nil {
+		if err := da.updateKubeDeployment(ctx, nsID, deploy, newDeploy.Metadata, strategy, newDeploy.ConfigHashes); err != nil {
 			da.log.Debug("Kube-API error! Reverting changes.")
-			if err := da.mongo.DeleteDeploymentVersion(nsID, deploy.Name, newversion); err != nil {
+			if err := da.restoreActiveVersion(nsID, deploy.Name, oldDeploy.Version); err != nil {
 				return nil, err
 			}
-			if err := da.mongo.ActivateDeployment(nsID, deploy.Name, oldDeploy.Version); err != nil {
+			if err := da.mongo.DeleteDeploymentVersion(nsID, deploy.Name, newversion); err != nil {
 				return nil, err
 			}
 			return nil, err
 		}
+		da.watchRollout(ctx, updatedDeploy)
 	} else {
-		if err := da.mongo.UpdateActiveDeployment(deployment.FromKube(nsID, userID, deploy)); err != nil {
+		if server.IsDryRun(ctx) {
+			newDeploy.ID = oldDeploy.ID
+			newDeploy.PreviousVersion = oldDeploy.PreviousVersion
+			return dryRunDeployment(oldDeploy.Deployment, newDeploy), nil
+		}
+		if err := da.mongo.UpdateActiveDeployment(newDeploy); err != nil {
 			return nil, err
 		}
 		updatedDeploy, err = da.mongo.GetDeployment(nsID, deploy.Name)
@@ -248,8 +375,9 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 			return nil, err
 		}
 
-		if err := da.kube.UpdateDeployment(ctx, nsID, deploy); err != nil {
+		if err := da.kube.UpdateDeployment(ctx, nsID, deploy, newDeploy.Metadata, newDeploy.Strategy, newDeploy.ConfigHashes); err != nil {
 			da.log.Debug("Kube-API error! Reverting changes.")
+			oldDeploy.ResourceVersion = 0
 			if err := da.mongo.UpdateActiveDeployment(oldDeploy); err != nil {
 				return nil, err
 			}
@@ -260,7 +388,7 @@ func (da *DeployActionsImpl) UpdateDeployment(ctx context.Context, nsID string,
 	return &updatedDeploy, nil
 }
 
-func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, deplName string, req kubtypes.UpdateReplicas) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, deplName string, req kubtypes.UpdateReplicas) (ret *deployment.ResourceDeploy, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -269,6 +397,13 @@ func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, de
 	}).Info("set deployment replicas")
 	coblog.Std.Struct(req)
 
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Update, before, ret, err) }()
+
+	if err := da.checkNoRollout(nsID, deplName); err != nil {
+		return nil, err
+	}
+
 	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
 	if err != nil {
 		return nil, err
@@ -283,6 +418,9 @@ func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, de
 	if err != nil {
 		return nil, err
 	}
+	if err := server.CheckIfMatch(ctx, oldDeploy.ResourceVersion); err != nil {
+		return nil, err
+	}
 
 	if len(oldDeploy.Containers) == 0 {
 		return nil, rserrors.ErrNoContainer()
@@ -307,12 +445,17 @@ func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, de
 
 	server.CalculateDeployResources(&newDeploy.Deployment)
 
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(oldDeploy.Deployment, newDeploy), nil
+	}
+
 	if err := da.mongo.UpdateActiveDeployment(newDeploy); err != nil {
 		return nil, err
 	}
 
 	if err := da.kube.SetDeploymentReplicas(ctx, nsID, newDeploy.Name, req.Replicas); err != nil {
 		da.log.Debug("Kube-API error! Reverting changes.")
+		oldDeploy.ResourceVersion = 0
 		if err := da.mongo.UpdateActiveDeployment(oldDeploy); err != nil {
 			return nil, err
 		}
@@ -327,7 +470,7 @@ func (da *DeployActionsImpl) SetDeploymentReplicas(ctx context.Context, nsID, de
 	return &updatedDeploy, nil
 }
 
-func (da *DeployActionsImpl) RenameDeploymentVersion(ctx context.Context, nsID, deplName, oldversion, newversion string) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) RenameDeploymentVersion(ctx context.Context, nsID, deplName, oldversion, newversion string) (ret *deployment.ResourceDeploy, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -337,6 +480,9 @@ func (da *DeployActionsImpl) RenameDeploymentVersion(ctx context.Context, nsID,
 		"new_version": newversion,
 	}).Info("rename deployment version")
 
+	before := da.auditVersionState(nsID, deplName, oldversion)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Rename, before, ret, err) }()
+
 	oldDeplVersion, err := semver.ParseTolerant(oldversion)
 	if err != nil {
 		return nil, rserrors.ErrValidation().AddDetailsErr(err)
@@ -347,6 +493,19 @@ func (da *DeployActionsImpl) RenameDeploymentVersion(ctx context.Context, nsID,
 		return nil, rserrors.ErrValidation().AddDetailsErr(err)
 	}
 
+	if server.IsDryRun(ctx) {
+		renamedDeploy, err := da.mongo.GetDeploymentVersion(nsID, deplName, oldDeplVersion)
+		if err != nil {
+			return nil, err
+		}
+		_, err = da.mongo.GetDeploymentVersion(nsID, deplName, newDeplVersion)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		renamedDeploy.Version = newDeplVersion
+		return &renamedDeploy, nil
+	}
+
 	if err := da.mongo.UpdateDeploymentVersion(nsID, deplName, oldDeplVersion, newDeplVersion); err != nil {
 		return nil, err
 	}
@@ -359,7 +518,7 @@ func (da *DeployActionsImpl) RenameDeploymentVersion(ctx context.Context, nsID,
 	return &updatedDeploy, nil
 }
 
-func (da *DeployActionsImpl) SetDeploymentContainerImage(ctx context.Context, nsID, deplName string, req kubtypes.UpdateImage) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) SetDeploymentContainerImage(ctx context.Context, nsID, deplName string, req kubtypes.UpdateImage) (ret *deployment.ResourceDeploy, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -368,10 +527,20 @@ func (da *DeployActionsImpl) SetDeploymentContainerImage(ctx context.Context, ns
 	}).Info("set container image")
 	coblog.Std.Struct(req)
 
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Update, before, ret, err) }()
+
 	oldDeploy, err := da.mongo.GetDeployment(nsID, deplName)
 	if err != nil {
 		return nil, err
 	}
+	if err := server.CheckIfMatch(ctx, oldDeploy.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	if err := da.checkNoRollout(nsID, deplName); err != nil {
+		return nil, err
+	}
 
 	//TODO
 	//Temporary solution for deployments w/o containers
@@ -387,7 +556,7 @@ func (da *DeployActionsImpl) SetDeploymentContainerImage(ctx context.Context, ns
 		oldDeploy.Containers = kubeDepl.Containers
 	}
 
-	newDeploy := oldDeploy
+	newDeploy := oldDeploy.Copy()
 
 	updated := false
 	containerFound := false
@@ -418,41 +587,61 @@ func (da *DeployActionsImpl) SetDeploymentContainerImage(ctx context.Context, ns
 		newDeploy.Version.Patch++
 	}
 
-	if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name); err != nil {
+	newDeploy.ID = uuid.New().String()
+	newDeploy.Active = true
+	newDeploy.Failed = false
+	newDeploy.PreviousVersion = &oldDeploy.Version
+
+	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	nsUsage, err := da.mongo.GetNamespaceResourcesLimits(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, oldDeploy.Deployment, newDeploy.Deployment, newDeploy.Strategy); err != nil {
+		return nil, err
+	}
+
+	if newDeploy.Strategy.IsCanary() {
+		return da.startCanary(ctx, oldDeploy, newDeploy)
+	}
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(oldDeploy.Deployment, newDeploy), nil
+	}
+
+	if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name, oldDeploy.ResourceVersion); err != nil {
 		return nil, err
 	}
 
-	newDeploy.ID = uuid.New().String()
-	newDeploy.Active = true
 	updatedDeploy, err := da.mongo.CreateDeployment(newDeploy)
 	if err != nil {
-		if err := da.mongo.ActivateDeployment(nsID, newDeploy.Name, oldDeploy.Version); err != nil {
+		if err := da.restoreActiveVersion(nsID, newDeploy.Name, oldDeploy.Version); err != nil {
 			return nil, err
 		}
 		return nil, err
 	}
 
-	if err := da.kube.UpdateDeployment(ctx, nsID, newDeploy.Deployment); err != nil {
+	if err := da.updateKubeDeployment(ctx, nsID, newDeploy.Deployment, newDeploy.Metadata, newDeploy.Strategy, newDeploy.ConfigHashes); err != nil {
 		da.log.Debug("Kube-API error! Reverting changes.")
-		if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name); err != nil {
+		if err := da.restoreActiveVersion(nsID, newDeploy.Name, oldDeploy.Version); err != nil {
 			return nil, err
 		}
-		if acterr := da.mongo.ActivateDeployment(nsID, newDeploy.Name, oldDeploy.Version); acterr != nil {
-			//TODO
-			//Temporary solution for deployments w/o version
-			acterrwo := da.mongo.ActivateDeploymentWOVersion(nsID, newDeploy.Name)
-			if acterrwo != nil {
-				return nil, acterrwo
-			}
+		if err := da.mongo.SetDeploymentVersionFailed(nsID, newDeploy.Name, newDeploy.Version, true); err != nil {
 			return nil, err
 		}
 		return nil, err
 	}
+	da.watchRollout(ctx, updatedDeploy)
 
 	return &updatedDeploy, nil
 }
 
-func (da *DeployActionsImpl) ChangeActiveDeployment(ctx context.Context, nsID, deplName, version string) (*deployment.ResourceDeploy, error) {
+func (da *DeployActionsImpl) ChangeActiveDeployment(ctx context.Context, nsID, deplName, version string) (ret *deployment.ResourceDeploy, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -460,11 +649,18 @@ func (da *DeployActionsImpl) ChangeActiveDeployment(ctx context.Context, nsID, d
 		"deploy_name": deplName,
 	}).Infof("change active version %v", version)
 
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Activate, before, ret, err) }()
+
 	oldDeploy, err := da.mongo.GetDeployment(nsID, deplName)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := da.checkNoRollout(nsID, deplName); err != nil {
+		return nil, err
+	}
+
 	deplVersion, err := semver.ParseTolerant(version)
 	if err != nil {
 		return nil, rserrors.ErrValidation().AddDetailsErr(err)
@@ -500,20 +696,25 @@ func (da *DeployActionsImpl) ChangeActiveDeployment(ctx context.Context, nsID, d
 
 	server.CalculateDeployResources(&oldDeploy.Deployment)
 
-	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, oldDeploy.Deployment, newDeploy.Deployment); err != nil {
+	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, oldDeploy.Deployment, newDeploy.Deployment, newDeploy.Strategy); err != nil {
 		return nil, err
 	}
 
-	if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name); err != nil {
+	if server.IsDryRun(ctx) {
+		if !newDeploy.Version.Equals(oldDeploy.Version) {
+			newDeploy.PreviousVersion = &oldDeploy.Version
+		}
+		newDeploy.Failed = false
+		return dryRunDeployment(oldDeploy.Deployment, newDeploy), nil
+	}
+
+	if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name, oldDeploy.ResourceVersion); err != nil {
 		return nil, err
 	}
 
-	if err := da.kube.UpdateDeployment(ctx, nsID, newDeploy.Deployment); err != nil {
+	if err := da.updateKubeDeployment(ctx, nsID, newDeploy.Deployment, newDeploy.Metadata, newDeploy.Strategy, newDeploy.ConfigHashes); err != nil {
 		da.log.Debug("Kube-API error! Reverting changes.")
-		if err := da.mongo.DeactivateDeployment(nsID, newDeploy.Name); err != nil {
-			return nil, err
-		}
-		if err := da.mongo.ActivateDeployment(nsID, oldDeploy.Name, oldDeploy.Version); err != nil {
+		if err := da.restoreActiveVersion(nsID, oldDeploy.Name, oldDeploy.Version); err != nil {
 			return nil, err
 		}
 		return nil, err
@@ -523,17 +724,60 @@ func (da *DeployActionsImpl) ChangeActiveDeployment(ctx context.Context, nsID, d
 		return nil, err
 	}
 
+	if !newDeploy.Version.Equals(oldDeploy.Version) {
+		if err := da.mongo.SetDeploymentPreviousVersion(nsID, newDeploy.Name, newDeploy.Version, oldDeploy.Version); err != nil {
+			return nil, err
+		}
+		newDeploy.PreviousVersion = &oldDeploy.Version
+	}
+	if newDeploy.Failed {
+		// version was chosen explicitly, so it can be used for rollback again
+		if err := da.mongo.SetDeploymentVersionFailed(nsID, newDeploy.Name, newDeploy.Version, false); err != nil {
+			return nil, err
+		}
+		newDeploy.Failed = false
+	}
+	da.watchRollout(ctx, newDeploy)
+
 	return &newDeploy, nil
 }
 
-func (da *DeployActionsImpl) DeleteDeployment(ctx context.Context, nsID, deplName string) error {
+func (da *DeployActionsImpl) DeleteDeployment(ctx context.Context, nsID, deplName string, cascade bool) error {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
 		"ns_id":       nsID,
 		"deploy_name": deplName,
+		"cascade":     cascade,
 	}).Info("delete deployment")
 
+	if _, ok := server.IfMatch(ctx); ok {
+		deploy, err := da.mongo.GetDeployment(nsID, deplName)
+		if err != nil {
+			return err
+		}
+		if err := server.CheckIfMatch(ctx, deploy.ResourceVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := da.deps.CheckDelete(ctx, nsID, graph.Node{Kind: graph.Deployment, Name: deplName}, cascade); err != nil {
+		da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Delete, da.auditState(nsID, deplName), nil, err)
+		return err
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	return da.deleteDeployment(ctx, nsID, deplName)
+}
+
+func (da *DeployActionsImpl) deleteDeployment(ctx context.Context, nsID, deplName string) (err error) {
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Delete, before, nil, err) }()
+
+	_, canaryErr := da.mongo.GetCanaryDeployment(nsID, deplName)
+
 	if err := da.mongo.DeleteDeployment(nsID, deplName); err != nil {
 		return err
 	}
@@ -546,10 +790,28 @@ func (da *DeployActionsImpl) DeleteDeployment(ctx context.Context, nsID, deplNam
 		return err
 	}
 
+	if canaryErr == nil {
+		if err := da.kube.DeleteDeployment(ctx, nsID, deployment.CanaryName(deplName)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (da *DeployActionsImpl) DeleteDeploymentVersion(ctx context.Context, nsID, deplName, version string) error {
+// restoreDeployment recreates deployment deleted by deleteDeployment
+func (da *DeployActionsImpl) restoreDeployment(ctx context.Context, nsID, deplName string) error {
+	if err := da.mongo.RestoreDeployment(nsID, deplName); err != nil {
+		return err
+	}
+	depl, err := da.mongo.GetDeployment(nsID, deplName)
+	if err != nil {
+		return err
+	}
+	return da.kube.CreateDeployment(ctx, nsID, depl.Deployment, depl.Metadata, depl.Strategy, depl.ConfigHashes)
+}
+
+func (da *DeployActionsImpl) DeleteDeploymentVersion(ctx context.Context, nsID, deplName, version string) (err error) {
 	userID := httputil.MustGetUserID(ctx)
 	da.log.WithFields(logrus.Fields{
 		"user_id":     userID,
@@ -557,38 +819,87 @@ func (da *DeployActionsImpl) DeleteDeploymentVersion(ctx context.Context, nsID,
 		"deploy_name": deplName,
 	}).Info("delete deployment version")
 
+	before := da.auditVersionState(nsID, deplName, version)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Delete, before, nil, err) }()
+
 	deplVersion, err := semver.Parse(version)
 	if err != nil {
 		return err
 	}
 
-	activeDeploy, err := da.mongo.GetDeployment(nsID, deplName)
-	if err == nil {
+	activeDeploy, activeErr := da.mongo.GetDeployment(nsID, deplName)
+	if activeErr == nil {
 		if activeDeploy.Version.Equals(deplVersion) {
 			return rserrors.ErrUnableDeleteActiveDeploymentVersion()
 		}
 	}
 
+	if server.IsDryRun(ctx) {
+		_, err := da.mongo.GetDeploymentVersion(nsID, deplName, deplVersion)
+		return err
+	}
+
+	// deleting last version of deployment without active version deletes deployment itself,
+	// so it must not break resources which still refer to it
+	if activeErr != nil {
+		_, versions, err := da.mongo.GetDeploymentVersionsList(nsID, deplName, nil)
+		if err != nil {
+			return err
+		}
+		if versions <= 1 {
+			if err := da.deps.CheckDelete(ctx, nsID, graph.Node{Kind: graph.Deployment, Name: deplName}, false); err != nil {
+				return err
+			}
+		}
+	}
+
 	return da.mongo.DeleteDeploymentVersion(nsID, deplName, deplVersion)
 }
 
-func (da *DeployActionsImpl) DeleteAllDeployments(ctx context.Context, nsID string) error {
+func (da *DeployActionsImpl) DeleteAllDeployments(ctx context.Context, nsID string) (err error) {
 	da.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("delete all deployments")
 
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
 	if err := da.mongo.DeleteAllDeploymentsInNamespace(nsID); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (da *DeployActionsImpl) DeleteAllSolutionDeployments(ctx context.Context, nsID, solutionName string) error {
+func (da *DeployActionsImpl) DeleteAllSolutionDeployments(ctx context.Context, nsID, solutionName string, cascade bool) (err error) {
 	da.log.WithFields(logrus.Fields{
 		"ns_id":    nsID,
 		"solution": solutionName,
+		"cascade":  cascade,
 	}).Info("delete all solution deployments")
 
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, "", audit.Delete, nil, nil, err) }()
+
+	deploys, _, err := da.mongo.GetDeploymentList(nsID, nil)
+	if err != nil {
+		return err
+	}
+	var nodes []graph.Node
+	for _, depl := range deploys {
+		if depl.SolutionID == solutionName {
+			nodes = append(nodes, graph.Node{Kind: graph.Deployment, Name: depl.Name})
+		}
+	}
+	if err := da.deps.CheckDeleteAll(ctx, nsID, nodes, cascade); err != nil {
+		return err
+	}
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
 	if err := da.kube.DeleteSolutionDeployments(ctx, nsID, solutionName); err != nil {
 		return err
 	}
@@ -643,7 +954,7 @@ func (da *DeployActionsImpl) DiffDeploymentsPrevious(ctx context.Context, nsID,
 		return nil, rserrors.ErrValidation().AddDetailsErr(err)
 	}
 
-	deplList, err := da.mongo.GetDeploymentVersionsList(nsID, deplName)
+	deplList, _, err := da.mongo.GetDeploymentVersionsList(nsID, deplName, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -688,3 +999,567 @@ func (da *DeployActionsImpl) DiffDeploymentsPrevious(ctx context.Context, nsID,
 
 	return &kubtypes.DeploymentDiff{Diff: deplDiff}, nil
 }
+
+func (da *DeployActionsImpl) PromoteDeployment(ctx context.Context, nsID, deplName, version string) (ret *deployment.ResourceDeploy, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	da.log.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"ns_id":       nsID,
+		"deploy_name": deplName,
+	}).Infof("promote deployment version %v", version)
+
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Activate, before, ret, err) }()
+
+	stable, canary, err := da.getCanary(nsID, deplName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	total := stable.Replicas + canary.Replicas
+	percent := 100
+	if canary.Strategy != nil && canary.Strategy.PromotionStep > 0 {
+		percent = canary.Strategy.CanaryPercent + canary.Strategy.PromotionStep
+	}
+	if percent >= 100 {
+		return da.finishCanary(ctx, stable, canary)
+	}
+
+	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	nsUsage, err := da.mongo.GetNamespaceResourcesLimits(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	canaryReplicas := deployment.CanaryReplicas(total, percent)
+	if err := server.CheckCanaryQuotas(nsLimits, nsUsage, stable.Deployment, canary.Deployment, canaryReplicas); err != nil {
+		return nil, err
+	}
+
+	oldStable, oldCanary := stable.Copy(), canary.Copy()
+
+	canary.Strategy.CanaryPercent = percent
+	canary.Replicas = canaryReplicas
+	server.CalculateDeployResources(&canary.Deployment)
+	stable.Replicas = total - canaryReplicas
+	server.CalculateDeployResources(&stable.Deployment)
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(oldCanary.Deployment, canary), nil
+	}
+
+	if err := da.mongo.UpdateCanaryDeployment(canary); err != nil {
+		return nil, err
+	}
+	if err := da.mongo.UpdateActiveDeployment(stable); err != nil {
+		oldCanary.ResourceVersion = 0
+		if err := da.mongo.UpdateCanaryDeployment(oldCanary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := da.kube.SetDeploymentReplicas(ctx, nsID, deployment.CanaryName(deplName), canary.Replicas); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.revertCanaryStep(oldStable, oldCanary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if err := da.kube.SetDeploymentReplicas(ctx, nsID, deplName, stable.Replicas); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.kube.SetDeploymentReplicas(ctx, nsID, deployment.CanaryName(deplName), oldCanary.Replicas); err != nil {
+			return nil, err
+		}
+		if err := da.revertCanaryStep(oldStable, oldCanary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &canary, nil
+}
+
+func (da *DeployActionsImpl) AbortDeployment(ctx context.Context, nsID, deplName, version string) (ret *deployment.ResourceDeploy, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	da.log.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"ns_id":       nsID,
+		"deploy_name": deplName,
+	}).Infof("abort deployment version %v", version)
+
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Activate, before, ret, err) }()
+
+	stable, canary, err := da.getCanary(nsID, deplName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	oldStable, oldCanary := stable.Copy(), canary.Copy()
+	stable.Replicas += canary.Replicas
+	server.CalculateDeployResources(&stable.Deployment)
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(canary.Deployment, stable), nil
+	}
+
+	if err := da.kube.SetDeploymentReplicas(ctx, nsID, deplName, stable.Replicas); err != nil {
+		return nil, err
+	}
+	if err := da.kube.DeleteDeployment(ctx, nsID, deployment.CanaryName(deplName)); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.kube.SetDeploymentReplicas(ctx, nsID, deplName, oldStable.Replicas); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// deactivated canary can't be restored in db, so it is deactivated last
+	if err := da.mongo.UpdateActiveDeployment(stable); err != nil {
+		da.log.Debug("DB error! Reverting changes.")
+		if err := da.revertKubeAbort(ctx, oldStable, oldCanary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	canary.Active = false
+	canary.Canary = false
+	if err := da.mongo.UpdateCanaryDeployment(canary); err != nil {
+		da.log.Debug("DB error! Reverting changes.")
+		oldStable.ResourceVersion = 0
+		if err := da.mongo.UpdateActiveDeployment(oldStable); err != nil {
+			return nil, err
+		}
+		if err := da.revertKubeAbort(ctx, oldStable, oldCanary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &stable, nil
+}
+
+func (da *DeployActionsImpl) RollbackDeployment(ctx context.Context, nsID, deplName string) (ret *deployment.ResourceDeploy, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	da.log.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"ns_id":       nsID,
+		"deploy_name": deplName,
+	}).Info("rollback deployment")
+
+	before := da.auditState(nsID, deplName)
+	defer func() { da.audit.Record(ctx, nsID, audit.Deployment, deplName, audit.Activate, before, ret, err) }()
+
+	if err := da.checkNoRollout(nsID, deplName); err != nil {
+		return nil, err
+	}
+
+	current, err := da.mongo.GetDeployment(nsID, deplName)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := da.lastGoodVersion(current)
+	if err != nil {
+		return nil, err
+	}
+
+	nsLimits, err := da.permissions.GetNamespaceLimits(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	nsUsage, err := da.mongo.GetNamespaceResourcesLimits(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	server.CalculateDeployResources(&current.Deployment)
+
+	if err := server.CheckDeploymentReplaceQuotas(nsLimits, nsUsage, current.Deployment, target.Deployment, target.Strategy); err != nil {
+		return nil, err
+	}
+
+	return da.rollback(ctx, current, target)
+}
+
+// auditState returns active deployment version for audit log, nil if deployment doesn't exist
+func (da *DeployActionsImpl) auditState(nsID, deplName string) interface{} {
+	if da.audit == nil {
+		return nil
+	}
+	deploy, err := da.mongo.GetDeployment(nsID, deplName)
+	if err != nil {
+		return nil
+	}
+	return deploy
+}
+
+// auditVersionState returns deployment version for audit log, nil if version doesn't exist
+func (da *DeployActionsImpl) auditVersionState(nsID, deplName, version string) interface{} {
+	if da.audit == nil {
+		return nil
+	}
+	deplVersion, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil
+	}
+	deploy, err := da.mongo.GetDeploymentVersion(nsID, deplName, deplVersion)
+	if err != nil {
+		return nil
+	}
+	return deploy
+}
+
+// configHashes returns hashes of current data of configmaps mounted by deployment.
+// Hashes are sent to kube-api as pod template annotation, so pods are restarted when configmap data changes.
+// If reload is disabled, no hashes are returned. Configmaps which don't exist yet are not hashed.
+func (da *DeployActionsImpl) configHashes(nsID string, deploy kubtypes.Deployment, reload bool) (map[string]string, error) {
+	if !reload {
+		return nil, nil
+	}
+	var hashes = make(map[string]string)
+	for _, name := range deployment.ConfigMapNames(deploy) {
+		cm, err := da.mongo.GetConfigMap(nsID, name)
+		switch {
+		case err == nil:
+			hashes[name] = configmap.Hash(cm.Data)
+		case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+			continue
+		default:
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// checkNoRollout returns error if deployment has canary version which is not promoted or aborted yet
+func (da *DeployActionsImpl) checkNoRollout(nsID, deplName string) error {
+	_, err := da.mongo.GetCanaryDeployment(nsID, deplName)
+	switch {
+	case err == nil:
+		return rserrors.ErrRolloutInProgress().AddDetailF("deployment %v has canary version", deplName)
+	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		return nil
+	default:
+		return err
+	}
+}
+
+// getCanary returns active stable and canary versions of deployment, canary version must match provided version
+func (da *DeployActionsImpl) getCanary(nsID, deplName, version string) (stable, canary deployment.ResourceDeploy, err error) {
+	deplVersion, err := semver.ParseTolerant(version)
+	if err != nil {
+		return stable, canary, rserrors.ErrValidation().AddDetailsErr(err)
+	}
+
+	canary, err = da.mongo.GetCanaryDeployment(nsID, deplName)
+	if err != nil {
+		return stable, canary, err
+	}
+	if !canary.Version.Equals(deplVersion) {
+		return stable, canary, rserrors.ErrResourceNotExists().AddDetailF("canary %v %v", deplName, version)
+	}
+
+	stable, err = da.mongo.GetDeployment(nsID, deplName)
+	return stable, canary, err
+}
+
+// updateKubeDeployment pushes deployment with its rollout strategy to kube-api
+func (da *DeployActionsImpl) updateKubeDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	return da.kube.UpdateDeployment(ctx, nsID, deploy, meta, strategy, configHashes)
+}
+
+// startCanary runs canary version alongside stable one, replicas of stable version are split between them
+func (da *DeployActionsImpl) startCanary(ctx context.Context, stable, canary deployment.ResourceDeploy) (*deployment.ResourceDeploy, error) {
+	nsID := stable.NamespaceID
+	oldStable := stable.Copy()
+
+	total := canary.Replicas
+	canary.Replicas = deployment.CanaryReplicas(total, canary.Strategy.CanaryPercent)
+	canary.Active = true
+	canary.Canary = true
+	server.CalculateDeployResources(&canary.Deployment)
+	stable.Replicas = total - canary.Replicas
+	server.CalculateDeployResources(&stable.Deployment)
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(oldStable.Deployment, canary), nil
+	}
+
+	createdCanary, err := da.mongo.CreateDeployment(canary)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := da.mongo.UpdateActiveDeployment(stable); err != nil {
+		if err := da.dropCanary(canary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	kubeCanary := canary.Deployment
+	kubeCanary.Name = deployment.CanaryName(canary.Name)
+	if err := da.kube.CreateDeployment(ctx, nsID, kubeCanary, canary.Metadata, canary.Strategy, canary.ConfigHashes); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.revertCanaryStart(oldStable, canary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := da.kube.SetDeploymentReplicas(ctx, nsID, stable.Name, stable.Replicas); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.kube.DeleteDeployment(ctx, nsID, kubeCanary.Name); err != nil {
+			return nil, err
+		}
+		if err := da.revertCanaryStart(oldStable, canary); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	return &createdCanary, nil
+}
+
+// finishCanary makes canary version the only active version of deployment
+func (da *DeployActionsImpl) finishCanary(ctx context.Context, stable, canary deployment.ResourceDeploy) (*deployment.ResourceDeploy, error) {
+	nsID := stable.NamespaceID
+	total := stable.Replicas + canary.Replicas
+
+	canary.Replicas = total
+	canary.Canary = false
+	if canary.Strategy != nil {
+		canary.Strategy.CanaryPercent = 100
+	}
+	server.CalculateDeployResources(&canary.Deployment)
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(stable.Deployment, canary), nil
+	}
+
+	if err := da.kube.UpdateDeployment(ctx, nsID, canary.Deployment, canary.Metadata, canary.Strategy, canary.ConfigHashes); err != nil {
+		return nil, err
+	}
+	if err := da.kube.DeleteDeployment(ctx, nsID, deployment.CanaryName(canary.Name)); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		stable.Replicas = total
+		if err := da.kube.UpdateDeployment(ctx, nsID, stable.Deployment, stable.Metadata, stable.Strategy, stable.ConfigHashes); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := da.mongo.DeactivateDeployment(nsID, stable.Name, stable.ResourceVersion); err != nil {
+		return nil, err
+	}
+	if err := da.mongo.UpdateCanaryDeployment(canary); err != nil {
+		if err := da.mongo.ActivateDeployment(nsID, stable.Name, stable.Version); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	da.watchRollout(ctx, canary)
+
+	return &canary, nil
+}
+
+// dropCanary deactivates canary version and deletes it
+func (da *DeployActionsImpl) dropCanary(canary deployment.ResourceDeploy) error {
+	canary.ResourceVersion = 0
+	canary.Active = false
+	canary.Canary = false
+	if err := da.mongo.UpdateCanaryDeployment(canary); err != nil {
+		return err
+	}
+	return da.mongo.DeleteDeploymentVersion(canary.NamespaceID, canary.Name, canary.Version)
+}
+
+// revertCanaryStart restores stable version which was changed by this request, so its resource version is not checked
+func (da *DeployActionsImpl) revertCanaryStart(stable, canary deployment.ResourceDeploy) error {
+	if err := da.dropCanary(canary); err != nil {
+		return err
+	}
+	stable.ResourceVersion = 0
+	return da.mongo.UpdateActiveDeployment(stable)
+}
+
+// revertCanaryStep restores versions which were changed by this request, so their resource versions are not checked
+func (da *DeployActionsImpl) revertCanaryStep(stable, canary deployment.ResourceDeploy) error {
+	stable.ResourceVersion, canary.ResourceVersion = 0, 0
+	if err := da.mongo.UpdateCanaryDeployment(canary); err != nil {
+		return err
+	}
+	return da.mongo.UpdateActiveDeployment(stable)
+}
+
+// revertKubeAbort runs canary version in kube-api again, so kube-api matches db which still has canary
+func (da *DeployActionsImpl) revertKubeAbort(ctx context.Context, stable, canary deployment.ResourceDeploy) error {
+	kubeCanary := canary.Deployment
+	kubeCanary.Name = deployment.CanaryName(canary.Name)
+	if err := da.kube.CreateDeployment(ctx, stable.NamespaceID, kubeCanary, canary.Metadata, canary.Strategy, canary.ConfigHashes); err != nil {
+		return err
+	}
+	return da.kube.SetDeploymentReplicas(ctx, stable.NamespaceID, stable.Name, stable.Replicas)
+}
+
+// restoreActiveVersion makes provided version the only active version of deployment in db
+func (da *DeployActionsImpl) restoreActiveVersion(nsID, deplName string, version semver.Version) error {
+	if err := da.mongo.DeactivateDeployment(nsID, deplName, 0); err != nil {
+		return err
+	}
+	if err := da.mongo.ActivateDeployment(nsID, deplName, version); err != nil {
+		if !cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+			return err
+		}
+		//Temporary solution for deployments w/o version
+		return da.mongo.ActivateDeploymentWOVersion(nsID, deplName)
+	}
+	return nil
+}
+
+// lastGoodVersion follows previous versions of deployment and returns first one which was not rolled back
+func (da *DeployActionsImpl) lastGoodVersion(current deployment.ResourceDeploy) (deployment.ResourceDeploy, error) {
+	visited := map[string]bool{current.Version.String(): true}
+	previous := current.PreviousVersion
+	for previous != nil && !visited[previous.String()] {
+		visited[previous.String()] = true
+		depl, err := da.mongo.GetDeploymentVersion(current.NamespaceID, current.Name, *previous)
+		if err != nil {
+			if cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+				break
+			}
+			return depl, err
+		}
+		if !depl.Failed {
+			return depl, nil
+		}
+		previous = depl.PreviousVersion
+	}
+	return deployment.ResourceDeploy{}, rserrors.ErrNoRollbackVersion().AddDetailF("deployment %v %v", current.Name, current.Version)
+}
+
+// rollback replaces active version of deployment with target version and marks replaced version as failed
+func (da *DeployActionsImpl) rollback(ctx context.Context, current, target deployment.ResourceDeploy) (*deployment.ResourceDeploy, error) {
+	nsID := current.NamespaceID
+	target.Active = true
+
+	if server.IsDryRun(ctx) {
+		return dryRunDeployment(current.Deployment, target), nil
+	}
+
+	if err := da.restoreActiveVersion(nsID, current.Name, target.Version); err != nil {
+		return nil, err
+	}
+
+	if err := da.updateKubeDeployment(ctx, nsID, target.Deployment, target.Metadata, target.Strategy, target.ConfigHashes); err != nil {
+		da.log.Debug("Kube-API error! Reverting changes.")
+		if err := da.restoreActiveVersion(nsID, current.Name, current.Version); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := da.mongo.SetDeploymentVersionFailed(nsID, current.Name, current.Version, true); err != nil {
+		return nil, err
+	}
+
+	return &target, nil
+}
+
+// watchRollout waits in background until all replicas of deployment version are ready.
+// Version is rolled back if it is still active and not ready when rollback deadline is exceeded.
+func (da *DeployActionsImpl) watchRollout(ctx context.Context, deploy deployment.ResourceDeploy) {
+	if da.rollbackDeadline <= 0 || deploy.PreviousVersion == nil {
+		return
+	}
+	ctx = detachedContext{ctx}
+	go func() {
+		deadline := time.NewTimer(da.rollbackDeadline)
+		defer deadline.Stop()
+		ticker := time.NewTicker(rolloutCheckPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if da.rolloutReady(ctx, deploy) {
+					return
+				}
+			case <-deadline.C:
+				if !da.rolloutReady(ctx, deploy) {
+					da.autoRollback(ctx, deploy)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func (da *DeployActionsImpl) rolloutReady(ctx context.Context, deploy deployment.ResourceDeploy) bool {
+	kubeDepl, err := da.kube.GetDeployment(ctx, deploy.NamespaceID, deploy.Name)
+	if err != nil || kubeDepl == nil || kubeDepl.Status == nil {
+		return false
+	}
+	// ready replicas also count pods of previous version, so only updated replicas show that new version works
+	status := kubeDepl.Status
+	return status.UpdatedReplicas >= deploy.Replicas && status.ReadyReplicas >= deploy.Replicas && status.UnavailableReplicas == 0
+}
+
+func (da *DeployActionsImpl) autoRollback(ctx context.Context, deploy deployment.ResourceDeploy) {
+	entry := da.log.WithFields(logrus.Fields{
+		"ns_id":       deploy.NamespaceID,
+		"deploy_name": deploy.Name,
+		"version":     deploy.Version.String(),
+	})
+
+	current, err := da.mongo.GetDeployment(deploy.NamespaceID, deploy.Name)
+	if err != nil {
+		entry.WithError(err).Error("unable to get deployment for rollback")
+		return
+	}
+	if !current.Version.Equals(deploy.Version) {
+		// version was already replaced
+		return
+	}
+	if err := da.checkNoRollout(deploy.NamespaceID, deploy.Name); err != nil {
+		entry.WithError(err).Warn("deployment is not ready, rollback skipped")
+		return
+	}
+
+	target, err := da.lastGoodVersion(current)
+	if err != nil {
+		entry.WithError(err).Error("deployment is not ready, unable to rollback")
+		return
+	}
+	ret, err := da.rollback(ctx, current, target)
+	// rollback is recorded as change made by service, so it's also published to watchers and webhooks
+	da.audit.Record(systemContext(), deploy.NamespaceID, audit.Deployment, deploy.Name, audit.Activate, current, ret, err)
+	if err != nil {
+		entry.WithError(err).Error("deployment is not ready, unable to rollback")
+		return
+	}
+	entry.Warnf("deployment is not ready in %v, rolled back to version %v", da.rollbackDeadline, target.Version)
+}
+
+// detachedContext keeps values of request context, but is not cancelled when request is finished
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
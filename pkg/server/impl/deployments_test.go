@@ -0,0 +1,396 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type unlimitedPermissions struct{}
+
+func (unlimitedPermissions) GetNamespaceLimits(ctx context.Context, namespaceID string) (kubtypes.Namespace, error) {
+	var ns kubtypes.Namespace
+	ns.Resources.Hard.CPU = 100000
+	ns.Resources.Hard.Memory = 100000
+	ns.MaxIntService = 100
+	ns.MaxExtService = 100
+	return ns, nil
+}
+
+type limitedPermissions struct {
+	cpu, memory uint
+}
+
+func (perm *limitedPermissions) GetNamespaceLimits(ctx context.Context, namespaceID string) (kubtypes.Namespace, error) {
+	var ns kubtypes.Namespace
+	ns.Resources.Hard.CPU = perm.cpu
+	ns.Resources.Hard.Memory = perm.memory
+	return ns, nil
+}
+
+type strategyKube struct {
+	clients.Kube
+	strategies []*deployment.KubeStrategy
+}
+
+func (kube *strategyKube) UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	kube.strategies = append(kube.strategies, strategy.Kube())
+	return nil
+}
+
+func TestDeploymentStrategy(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = &limitedPermissions{cpu: 300, memory: 300}
+	var recorder = &strategyKube{Kube: clients.NewDummyKube()}
+	var kube clients.Kube = recorder
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var deploy = kubtypes.Deployment{
+		Name:     "app",
+		Replicas: 2,
+		Containers: []kubtypes.Container{
+			{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+		},
+	}
+	_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: deploy,
+		Strategy: &deployment.Strategy{
+			Type:           deployment.StrategyRolling,
+			MaxSurge:       1,
+			MaxUnavailable: 1,
+		},
+	})
+	assert.NoError(t, err)
+
+	_, err = da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	if assert.Len(t, recorder.strategies, 1) {
+		assert.Equal(t, &deployment.KubeStrategy{Type: "RollingUpdate", MaxSurge: 1, MaxUnavailable: 1}, recorder.strategies[0])
+	}
+
+	// surge replica does not fit into quota
+	_, err = da.UpdateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: deploy,
+		Strategy: &deployment.Strategy{
+			Type:     deployment.StrategyRolling,
+			MaxSurge: 2,
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestCanaryQuota(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var limits = &limitedPermissions{cpu: 400, memory: 400}
+	var permissions clients.Permissions = limits
+	var kube = clients.NewDummyKube()
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: kubtypes.Deployment{
+			Name:     "app",
+			Replicas: 4,
+			Containers: []kubtypes.Container{
+				{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+			},
+		},
+		Strategy: &deployment.Strategy{
+			Type:          deployment.StrategyCanary,
+			CanaryPercent: 50,
+		},
+	})
+	assert.NoError(t, err)
+
+	// namespace quota was lowered below current usage
+	limits.cpu = 300
+	_, err = da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.Error(t, err)
+	_, err = mongo.GetCanaryDeployment("ns", "app")
+	assert.Error(t, err)
+
+	limits.cpu = 400
+	canary, err := da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, canary.Replicas)
+}
+
+func TestCanaryDeployment(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kube = clients.NewDummyKube()
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var deploy = kubtypes.Deployment{
+		Name:     "app",
+		Replicas: 4,
+		Containers: []kubtypes.Container{
+			{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+		},
+	}
+	_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: deploy,
+		Strategy: &deployment.Strategy{
+			Type:          deployment.StrategyCanary,
+			CanaryPercent: 25,
+			PromotionStep: 25,
+		},
+	})
+	assert.NoError(t, err)
+
+	canary, err := da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	assert.True(t, canary.Canary)
+	assert.Equal(t, 1, canary.Replicas)
+
+	stable, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stable.Replicas)
+	assert.Equal(t, "nginx:1.0.0", stable.Containers[0].Image)
+
+	_, err = da.SetDeploymentReplicas(ctx, "ns", "app", kubtypes.UpdateReplicas{Replicas: 2})
+	assert.Error(t, err, "rollout in progress")
+
+	canary, err = da.PromoteDeployment(ctx, "ns", "app", canary.Version.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, canary.Replicas)
+
+	_, err = da.PromoteDeployment(ctx, "ns", "app", canary.Version.String())
+	assert.NoError(t, err)
+	promoted, err := da.PromoteDeployment(ctx, "ns", "app", canary.Version.String())
+	assert.NoError(t, err)
+	assert.False(t, promoted.Canary)
+	assert.Equal(t, 4, promoted.Replicas)
+
+	active, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, canary.Version, active.Version)
+	assert.Equal(t, "nginx:1.1.0", active.Containers[0].Image)
+
+	_, err = mongo.GetCanaryDeployment("ns", "app")
+	assert.Error(t, err)
+}
+
+func TestRollbackDeployment(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kube = clients.NewDummyKube()
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var deploy = kubtypes.Deployment{
+		Name:     "app",
+		Replicas: 2,
+		Containers: []kubtypes.Container{
+			{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+		},
+	}
+	created, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: deploy})
+	assert.NoError(t, err)
+
+	_, err = da.RollbackDeployment(ctx, "ns", "app")
+	assert.Error(t, err, "no previous version")
+
+	updated, err := da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, created.Version.String(), updated.PreviousVersion.String())
+
+	rolledBack, err := da.RollbackDeployment(ctx, "ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, created.Version.String(), rolledBack.Version.String())
+
+	active, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.0.0", active.Containers[0].Image)
+
+	failed, err := mongo.GetDeploymentVersion("ns", "app", updated.Version)
+	assert.NoError(t, err)
+	assert.True(t, failed.Failed)
+	assert.False(t, failed.Active)
+}
+
+type rolloutKube struct {
+	clients.Kube
+	status kubtypes.DeploymentStatus
+}
+
+func (kube *rolloutKube) GetDeployment(ctx context.Context, nsID, deployName string) (*kubtypes.Deployment, error) {
+	status := kube.status
+	return &kubtypes.Deployment{Name: deployName, Status: &status}, nil
+}
+
+func TestAutoRollback(t *testing.T) {
+	for name, status := range map[string]kubtypes.DeploymentStatus{
+		"no replicas are ready": {Replicas: 2, UnavailableReplicas: 2},
+		// rolling update keeps replicas of old version until new ones are ready
+		"old replicas are ready": {Replicas: 2, ReadyReplicas: 2, AvailableReplicas: 2, UnavailableReplicas: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testAutoRollback(t, status)
+		})
+	}
+}
+
+func testAutoRollback(t *testing.T, status kubtypes.DeploymentStatus) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kube clients.Kube = &rolloutKube{Kube: clients.NewDummyKube(), status: status}
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 50*time.Millisecond)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	created, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: kubtypes.Deployment{
+		Name:     "app",
+		Replicas: 2,
+		Containers: []kubtypes.Container{
+			{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+		},
+	}})
+	assert.NoError(t, err)
+
+	// no replicas of new version become ready
+	updated, err := da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+
+	var active deployment.ResourceDeploy
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		active, err = mongo.GetDeployment("ns", "app")
+		if err == nil && active.Version.Equals(created.Version) {
+			break
+		}
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, created.Version.String(), active.Version.String())
+	assert.Equal(t, "nginx:1.0.0", active.Containers[0].Image)
+
+	failed, err := mongo.GetDeploymentVersion("ns", "app", updated.Version)
+	assert.NoError(t, err)
+	assert.True(t, failed.Failed)
+	assert.False(t, failed.Active)
+}
+
+type failingCreateStorage struct {
+	db.Storage
+	fail bool
+}
+
+func (storage *failingCreateStorage) CreateDeployment(depl deployment.ResourceDeploy) (deployment.ResourceDeploy, error) {
+	if storage.fail {
+		return deployment.ResourceDeploy{}, errors.New("create failed")
+	}
+	return storage.Storage.CreateDeployment(depl)
+}
+
+func TestUpdateDeploymentRestoresActiveVersion(t *testing.T) {
+	var mongo = &failingCreateStorage{Storage: db.NewMemory(nil)}
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kube = clients.NewDummyKube()
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var deploy = kubtypes.Deployment{
+		Name:     "app",
+		Replicas: 1,
+		Containers: []kubtypes.Container{
+			{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+		},
+	}
+	created, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: deploy})
+	assert.NoError(t, err)
+
+	// new version can't be saved, old version stays active
+	mongo.fail = true
+	deploy.Containers[0].Image = "nginx:1.1.0"
+	_, err = da.UpdateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: deploy})
+	assert.Error(t, err)
+
+	active, err := mongo.GetDeployment("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, created.Version.String(), active.Version.String())
+	}
+}
+
+type failingCanaryStorage struct {
+	db.Storage
+	fail bool
+}
+
+func (storage *failingCanaryStorage) UpdateCanaryDeployment(upd deployment.ResourceDeploy) error {
+	if storage.fail {
+		return errors.New("update failed")
+	}
+	return storage.Storage.UpdateCanaryDeployment(upd)
+}
+
+// canaryKube keeps replicas of deployments in cluster
+type canaryKube struct {
+	clients.Kube
+	replicas map[string]int
+}
+
+func (kube *canaryKube) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	kube.replicas[deploy.Name] = deploy.Replicas
+	return nil
+}
+
+func (kube *canaryKube) SetDeploymentReplicas(ctx context.Context, nsID, deplName string, replicas int) error {
+	kube.replicas[deplName] = replicas
+	return nil
+}
+
+func (kube *canaryKube) DeleteDeployment(ctx context.Context, nsID, deplName string) error {
+	delete(kube.replicas, deplName)
+	return nil
+}
+
+func TestAbortDeploymentRevertsKube(t *testing.T) {
+	var mongo = &failingCanaryStorage{Storage: db.NewMemory(nil)}
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var cluster = &canaryKube{Kube: clients.NewDummyKube(), replicas: make(map[string]int)}
+	var kube clients.Kube = cluster
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, NewOutboxImpl(mongo, &kube, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: kubtypes.Deployment{
+			Name:     "app",
+			Replicas: 4,
+			Containers: []kubtypes.Container{
+				{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+			},
+		},
+		Strategy: &deployment.Strategy{Type: deployment.StrategyCanary, CanaryPercent: 25},
+	})
+	assert.NoError(t, err)
+	canary, err := da.SetDeploymentContainerImage(ctx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	var running = map[string]int{"app": 3, deployment.CanaryName("app"): 1}
+	assert.Equal(t, running, cluster.replicas)
+
+	// abort can't be saved, canary keeps running
+	mongo.fail = true
+	_, err = da.AbortDeployment(ctx, "ns", "app", canary.Version.String())
+	assert.Error(t, err)
+	assert.Equal(t, running, cluster.replicas)
+	stable, err := mongo.GetDeployment("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, stable.Replicas)
+	}
+	_, err = mongo.GetCanaryDeployment("ns", "app")
+	assert.NoError(t, err)
+
+	mongo.fail = false
+	_, err = da.AbortDeployment(ctx, "ns", "app", canary.Version.String())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"app": 4}, cluster.replicas)
+}
@@ -0,0 +1,266 @@
+package impl
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+// DomainPool places external services on domains according to selection policy and reserves their ports
+type DomainPool struct {
+	mongo   db.Storage
+	log     *cherrylog.LogrusAdapter
+	policy  domain.SelectionPolicy
+	minPort int
+	maxPort int
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewDomainPool(mongo db.Storage, policy domain.SelectionPolicy, minPort, maxPort uint) *DomainPool {
+	return &DomainPool{
+		mongo:   mongo,
+		log:     cherrylog.NewLogrusAdapter(logrus.WithField("component", "domain_pool")),
+		policy:  policy,
+		minPort: int(minPort),
+		maxPort: int(maxPort),
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Place selects domain of group namespace or owner is pinned to for external service and reserves its ports.
+func (pool *DomainPool) Place(nsID, owner string, req *kubtypes.Service) ([]port.Allocation, error) {
+	group, err := pool.pinnedGroup(nsID, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pool.PlaceInGroup(group, "", nsID, owner, req)
+}
+
+// PlaceInGroup selects domain of group except excluded one for external service and reserves its ports, empty group means all groups.
+// If selected domain runs out of ports concurrently, next candidate is tried.
+func (pool *DomainPool) PlaceInGroup(group, exclude, nsID, owner string, req *kubtypes.Service) ([]port.Allocation, error) {
+	candidates, err := pool.candidates(group, req.Ports, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, dom := range candidates {
+		if dom.Domain == exclude {
+			continue
+		}
+		reserved, err := pool.reservePorts(nsID, owner, dom.Domain, req)
+		if cherry.Equals(err, rserrors.ErrPortsExhausted()) {
+			pool.log.WithField("domain", dom.Domain).Debug("domain ports exhausted, trying next one")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		req.Domain = dom.Domain
+		req.IPs = dom.IP
+		return reserved, nil
+	}
+	return nil, rserrors.ErrNoDomainsAvailable()
+}
+
+// CheckPlace selects domain for external service like Place, but reserves no ports, so it can be used on dry run.
+// Ports are not set. Allocations planned by previous checks are counted as used ones.
+func (pool *DomainPool) CheckPlace(nsID, owner string, req *kubtypes.Service, planned []port.Allocation) ([]port.Allocation, error) {
+	group, err := pool.pinnedGroup(nsID, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pool.CheckPlaceInGroup(group, "", nsID, owner, req, planned)
+}
+
+// CheckPlaceInGroup is PlaceInGroup which reserves no ports, see CheckPlace
+func (pool *DomainPool) CheckPlaceInGroup(group, exclude, nsID, owner string, req *kubtypes.Service, planned []port.Allocation) ([]port.Allocation, error) {
+	candidates, err := pool.candidates(group, req.Ports, planned)
+	if err != nil {
+		return nil, err
+	}
+	for _, dom := range candidates {
+		if dom.Domain == exclude {
+			continue
+		}
+		var allocs = make([]port.Allocation, 0, len(req.Ports))
+		for _, svcPort := range req.Ports {
+			allocs = append(allocs, port.Allocation{
+				Domain:      dom.Domain,
+				Protocol:    svcPort.Protocol,
+				NamespaceID: nsID,
+				Service:     req.Name,
+				Owner:       owner,
+			})
+		}
+		req.Domain = dom.Domain
+		req.IPs = dom.IP
+		return allocs, nil
+	}
+	return nil, rserrors.ErrNoDomainsAvailable()
+}
+
+// CheckFree checks that domains of allocations have enough free ports for them without reserving ports
+func (pool *DomainPool) CheckFree(allocs []port.Allocation) error {
+	used, err := pool.used(nil)
+	if err != nil {
+		return err
+	}
+	var need = make(map[port.Key]int)
+	for _, alloc := range allocs {
+		need[alloc.Key()]++
+	}
+	for key, n := range need {
+		if pool.maxPort-pool.minPort-used[key] < n {
+			return rserrors.ErrPortsExhausted().AddDetailF("%v %v", key.Domain, key.Protocol)
+		}
+	}
+	return nil
+}
+
+// ReservePort reserves free port of allocation domain
+func (pool *DomainPool) ReservePort(alloc port.Allocation) (port.Allocation, error) {
+	return pool.mongo.ReservePort(alloc, pool.minPort, pool.maxPort)
+}
+
+// Release releases reserved ports. Failures are only logged: leaked reservation wastes a port but breaks no service.
+func (pool *DomainPool) Release(allocs []port.Allocation) {
+	for _, alloc := range allocs {
+		if err := pool.mongo.ReleasePort(alloc); err != nil {
+			pool.log.WithError(err).Warn("unable to release port")
+		}
+	}
+}
+
+// reservePorts reserves all service ports on domain or none of them
+func (pool *DomainPool) reservePorts(nsID, owner, domainName string, req *kubtypes.Service) ([]port.Allocation, error) {
+	var reserved []port.Allocation
+	for i, svcPort := range req.Ports {
+		alloc, err := pool.ReservePort(port.Allocation{
+			Domain:      domainName,
+			Protocol:    svcPort.Protocol,
+			NamespaceID: nsID,
+			Service:     req.Name,
+			Owner:       owner,
+		})
+		if err != nil {
+			pool.Release(reserved)
+			return nil, err
+		}
+		reserved = append(reserved, alloc)
+		req.Ports[i].Port = &alloc.Port
+	}
+	return reserved, nil
+}
+
+// used returns number of used ports by domain and protocol, planned allocations are counted as used
+func (pool *DomainPool) used(planned []port.Allocation) (map[port.Key]int, error) {
+	usage, err := pool.mongo.GetPortsUsage(pool.minPort, pool.maxPort)
+	if err != nil {
+		return nil, err
+	}
+	var used = make(map[port.Key]int, len(usage))
+	for _, u := range usage {
+		used[u.Key()] = u.Used
+	}
+	for _, alloc := range planned {
+		used[alloc.Key()]++
+	}
+	return used, nil
+}
+
+// candidates returns schedulable domains of group having enough free ports for service ports, preferred ones first.
+// Planned allocations are counted as used ports.
+func (pool *DomainPool) candidates(group string, ports []kubtypes.ServicePort, planned []port.Allocation) ([]domain.Domain, error) {
+	domains, err := pool.mongo.GetSchedulableDomains(group)
+	if err != nil {
+		return nil, err
+	}
+	used, err := pool.used(planned)
+	if err != nil {
+		return nil, err
+	}
+
+	var usedTotal = make(map[string]int)
+	for key, n := range used {
+		usedTotal[key.Domain] += n
+	}
+	var need = make(map[kubtypes.Protocol]int)
+	for _, svcPort := range ports {
+		need[svcPort.Protocol]++
+	}
+
+	var candidates = make([]domain.Domain, 0, len(domains))
+	for _, dom := range domains {
+		var fits = true
+		for protocol, n := range need {
+			if pool.maxPort-pool.minPort-used[port.Key{Domain: dom.Domain, Protocol: protocol}] < n {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			candidates = append(candidates, dom)
+		}
+	}
+
+	switch pool.policy {
+	case domain.LeastUsed:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return usedTotal[candidates[i].Domain] < usedTotal[candidates[j].Domain]
+		})
+	default:
+		pool.shuffleWeighted(candidates)
+	}
+	return candidates, nil
+}
+
+// pinnedGroup returns domain group of namespace pin or user pin, empty group if neither is pinned
+func (pool *DomainPool) pinnedGroup(nsID, owner string) (string, error) {
+	for _, pinned := range []struct {
+		kind domain.PinKind
+		id   string
+	}{{domain.NamespacePin, nsID}, {domain.UserPin, owner}} {
+		pin, err := pool.mongo.GetDomainPin(pinned.kind, pinned.id)
+		switch {
+		case err == nil:
+			return pin.DomainGroup, nil
+		case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+			continue
+		default:
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// shuffleWeighted orders domains randomly, domain is placed first with probability proportional to its weight
+func (pool *DomainPool) shuffleWeighted(domains []domain.Domain) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for i := range domains {
+		var total uint
+		for _, dom := range domains[i:] {
+			total += dom.SelectionWeight()
+		}
+		var pick = uint(pool.rnd.Int63n(int64(total)))
+		for j := i; j < len(domains); j++ {
+			if pick < domains[j].SelectionWeight() {
+				domains[i], domains[j] = domains[j], domains[i]
+				break
+			}
+			pick -= domains[j].SelectionWeight()
+		}
+	}
+}
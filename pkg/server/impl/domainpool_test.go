@@ -0,0 +1,76 @@
+package impl
+
+import (
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainPool(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	for _, dom := range []domain.Domain{
+		{Domain: "a.test", DomainGroup: "main", IP: []string{"10.0.0.1"}},
+		{Domain: "b.test", DomainGroup: "main", IP: []string{"10.0.0.2"}, State: domain.Disabled},
+		{Domain: "c.test", DomainGroup: "dedicated", IP: []string{"10.0.0.3"}},
+	} {
+		_, err := mongo.CreateDomain(dom)
+		assert.NoError(t, err)
+	}
+	// every domain has single port
+	var pool = NewDomainPool(mongo, domain.LeastUsed, 30000, 30001)
+	var newService = func(name string) kubtypes.Service {
+		return kubtypes.Service{
+			Name:  name,
+			Ports: []kubtypes.ServicePort{{Name: "http", TargetPort: 80, Protocol: kubtypes.TCP}},
+		}
+	}
+
+	_, err := mongo.SetDomainPin(domain.Pin{Kind: domain.NamespacePin, ID: "ns", DomainGroup: "dedicated"})
+	assert.NoError(t, err)
+	var svc = newService("first")
+	planned, err := pool.CheckPlace("ns", "user", &svc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.test", svc.Domain)
+	assert.Len(t, planned, 1)
+	svc = newService("second")
+	_, err = pool.CheckPlace("ns", "user", &svc, planned)
+	assert.True(t, cherry.Equals(err, rserrors.ErrNoDomainsAvailable()), "planned ports are counted: %v", err)
+	usage, err := mongo.GetPortsUsage(30000, 30001)
+	assert.NoError(t, err)
+	assert.Empty(t, usage, "check reserves no ports")
+
+	svc = newService("first")
+	reserved, err := pool.Place("ns", "user", &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.test", svc.Domain, "namespace is pinned to group")
+	assert.Equal(t, []string{"10.0.0.3"}, svc.IPs)
+	if assert.Len(t, reserved, 1) {
+		assert.Equal(t, 30000, *svc.Ports[0].Port)
+	}
+
+	svc = newService("second")
+	_, err = pool.Place("ns", "user", &svc)
+	assert.True(t, cherry.Equals(err, rserrors.ErrNoDomainsAvailable()), "pinned group is exhausted: %v", err)
+
+	svc = newService("second")
+	_, err = pool.Place("other", "user", &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.test", svc.Domain, "least used schedulable domain is selected")
+
+	svc = newService("third")
+	_, err = pool.Place("other", "user", &svc)
+	assert.True(t, cherry.Equals(err, rserrors.ErrNoDomainsAvailable()), "disabled domain is skipped: %v", err)
+
+	var weight uint = 5
+	dom, _ := mongo.GetDomain("b.test")
+	_, err = mongo.UpdateDomain(domain.PoolSettings{Weight: &weight, State: domain.Active}.Apply(*dom))
+	assert.NoError(t, err)
+	_, err = pool.Place("other", "user", &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.test", svc.Domain)
+}
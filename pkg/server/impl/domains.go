@@ -6,21 +6,30 @@ import (
 	"strconv"
 
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
 	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
 	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/sirupsen/logrus"
 )
 
 type DomainActionsImpl struct {
-	mongo *db.MongoStorage
-	log   *cherrylog.LogrusAdapter
+	mongo    db.Storage
+	log      *cherrylog.LogrusAdapter
+	audit    *AuditImpl
+	services *ServiceActionsImpl
 }
 
-func NewDomainActionsImpl(mongo *db.MongoStorage) *DomainActionsImpl {
+func NewDomainActionsImpl(mongo db.Storage, audit *AuditImpl, services *ServiceActionsImpl) *DomainActionsImpl {
 	return &DomainActionsImpl{
-		mongo: mongo,
-		log:   cherrylog.NewLogrusAdapter(logrus.WithField("component", "domain_actions")),
+		mongo:    mongo,
+		audit:    audit,
+		services: services,
+		log:      cherrylog.NewLogrusAdapter(logrus.WithField("component", "domain_actions")),
 	}
 }
 
@@ -55,16 +64,244 @@ func (da *DomainActionsImpl) GetDomain(ctx context.Context, domain string) (*dom
 	return da.mongo.GetDomain(domain)
 }
 
-func (da *DomainActionsImpl) AddDomain(ctx context.Context, req domain.Domain) (*domain.Domain, error) {
+func (da *DomainActionsImpl) AddDomain(ctx context.Context, req domain.Domain) (ret *domain.Domain, err error) {
 	da.log.Info("add domain")
 	coblog.Std.Struct(req)
+
+	defer func() { da.audit.Record(ctx, "", audit.Domain, req.Domain, audit.Create, nil, ret, err) }()
+
+	if server.IsDryRun(ctx) {
+		return &req, nil
+	}
 	return da.mongo.CreateDomain(req)
 }
 
-func (da *DomainActionsImpl) DeleteDomain(ctx context.Context, domain string) error {
+func (da *DomainActionsImpl) DeleteDomain(ctx context.Context, domain string) (err error) {
 	da.log.WithField("domain", domain).Info("delete domain")
 
-	err := da.mongo.DeleteDomain(domain)
+	before, _ := da.mongo.GetDomain(domain)
+	defer func() { da.audit.Record(ctx, "", audit.Domain, domain, audit.Delete, before, nil, err) }()
+
+	if _, err := da.mongo.GetDomain(domain); err != nil {
+		return err
+	}
+	services, err := da.mongo.GetServicesByDomain(domain)
+	if err != nil {
+		return err
+	}
+	if len(services) > 0 {
+		return rserrors.ErrDomainInUse().AddDetailF("%v services are placed on domain, drain it first", len(services))
+	}
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	err = da.mongo.DeleteDomain(domain)
 
 	return err
 }
+
+// UpdateDomain changes domain addresses or group. Services of domain get new addresses.
+func (da *DomainActionsImpl) UpdateDomain(ctx context.Context, domainName string, req domain.UpdateRequest) (ret *domain.Domain, err error) {
+	da.log.WithField("domain", domainName).Info("update domain")
+	coblog.Std.Struct(req)
+
+	before, err := da.mongo.GetDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { da.audit.Record(ctx, "", audit.Domain, domainName, audit.Update, before, ret, err) }()
+
+	var updated = req.Apply(*before)
+	if server.IsDryRun(ctx) {
+		return &updated, nil
+	}
+
+	if ret, err = da.mongo.UpdateDomain(updated); err != nil {
+		return nil, err
+	}
+
+	// services with outdated addresses are updated on every request, so failed ones are fixed by repeated request
+	services, err := da.mongo.GetServicesByDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, svc := range services {
+		if stringsEqual(svc.IPs, updated.IP) {
+			continue
+		}
+		if _, err := da.services.moveService(ctx, svc, func(req *kubtypes.Service) ([]port.Allocation, error) {
+			req.IPs = append([]string(nil), updated.IP...)
+			return nil, nil
+		}); err != nil {
+			da.log.WithError(err).WithField("service", svc.Name).Warn("unable to update service addresses")
+			failed = append(failed, svc.NamespaceID+"/"+svc.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return nil, rserrors.ErrInternal().AddDetailF("addresses of services %v are not updated, repeat request", failed)
+	}
+	return ret, nil
+}
+
+// DrainDomain stops placing new services on domain and moves its external services to other domains of the same group
+// with new external ports. Drain stops on first service which can't be moved and reports moved and remaining services,
+// so drain can be repeated. Dry run checks all services without reserving ports.
+func (da *DomainActionsImpl) DrainDomain(ctx context.Context, domainName string) (ret *domain.DrainResponse, err error) {
+	da.log.WithField("domain", domainName).Info("drain domain")
+
+	dom, err := da.mongo.GetDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !server.IsDryRun(ctx) && dom.State != domain.Draining {
+		var draining = domain.PoolSettings{State: domain.Draining}.Apply(*dom)
+		if _, err := da.mongo.UpdateDomain(draining); err != nil {
+			return nil, err
+		}
+		da.audit.Record(ctx, "", audit.Domain, domainName, audit.Update, dom, draining, nil)
+	}
+
+	services, err := da.mongo.GetServicesByDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp = domain.DrainResponse{Domain: domainName, Moved: make([]domain.MovedService, 0, len(services))}
+	if server.IsDryRun(ctx) {
+		// ports of all moved services are planned together, so domains are checked to have free ports for all of them
+		var planned []port.Allocation
+		for _, svc := range services {
+			var moved = domain.MovedService{Namespace: svc.NamespaceID, Service: svc.Name}
+			var req = svc.Copy().Service
+			allocs, err := da.services.pool.CheckPlaceInGroup(dom.DomainGroup, dom.Domain, svc.NamespaceID, svc.Owner, &req, planned)
+			if err != nil {
+				moved.Error = err.Error()
+				resp.Failed = append(resp.Failed, moved)
+				continue
+			}
+			planned = append(planned, allocs...)
+			moved.Domain = req.Domain
+			resp.Moved = append(resp.Moved, moved)
+		}
+		resp.Complete = len(resp.Failed) == 0
+		return &resp, nil
+	}
+
+	for i, svc := range services {
+		var moved = domain.MovedService{Namespace: svc.NamespaceID, Service: svc.Name}
+		movedService, err := da.services.moveService(ctx, svc, func(req *kubtypes.Service) ([]port.Allocation, error) {
+			return da.services.pool.PlaceInGroup(dom.DomainGroup, dom.Domain, svc.NamespaceID, svc.Owner, req)
+		})
+		if err != nil {
+			da.log.WithError(err).WithField("service", svc.Name).Warn("unable to move service, drain is stopped")
+			moved.Error = err.Error()
+			resp.Failed = append(resp.Failed, moved)
+			for _, rest := range services[i+1:] {
+				resp.Remaining = append(resp.Remaining, domain.MovedService{Namespace: rest.NamespaceID, Service: rest.Name})
+			}
+			return &resp, nil
+		}
+		moved.Domain = movedService.Domain
+		resp.Moved = append(resp.Moved, moved)
+	}
+	resp.Complete = true
+	return &resp, nil
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (da *DomainActionsImpl) SetDomainPoolSettings(ctx context.Context, domainName string, settings domain.PoolSettings) (ret *domain.Domain, err error) {
+	da.log.WithField("domain", domainName).Info("set domain pool settings")
+	coblog.Std.Struct(settings)
+
+	before, _ := da.mongo.GetDomain(domainName)
+	defer func() { da.audit.Record(ctx, "", audit.Domain, domainName, audit.Update, before, ret, err) }()
+
+	if errs := settings.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
+	dom, err := da.mongo.GetDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+	var updated = settings.Apply(*dom)
+	if server.IsDryRun(ctx) {
+		return &updated, nil
+	}
+	return da.mongo.UpdateDomain(updated)
+}
+
+func (da *DomainActionsImpl) GetDomainPinsList(ctx context.Context) (*domain.PinsResponse, error) {
+	da.log.Info("get domain pins")
+
+	pins, err := da.mongo.GetDomainPinsList()
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PinsResponse{Pins: pins}, nil
+}
+
+func (da *DomainActionsImpl) SetDomainPin(ctx context.Context, pin domain.Pin) (ret *domain.Pin, err error) {
+	da.log.WithFields(logrus.Fields{
+		"kind":         pin.Kind,
+		"id":           pin.ID,
+		"domain_group": pin.DomainGroup,
+	}).Info("set domain pin")
+
+	var name = string(pin.Kind) + "/" + pin.ID
+	before := da.pinAuditState(pin.Kind, pin.ID)
+	defer func() { da.audit.Record(ctx, "", audit.DomainPin, name, audit.Update, before, ret, err) }()
+
+	if !domain.ValidPinKind(pin.Kind) {
+		return nil, rserrors.ErrValidation().AddDetailF("pin kind must be %v or %v", domain.NamespacePin, domain.UserPin)
+	}
+
+	if server.IsDryRun(ctx) {
+		return &pin, nil
+	}
+	pin, err = da.mongo.SetDomainPin(pin)
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (da *DomainActionsImpl) DeleteDomainPin(ctx context.Context, kind domain.PinKind, id string) (err error) {
+	da.log.WithFields(logrus.Fields{
+		"kind": kind,
+		"id":   id,
+	}).Info("delete domain pin")
+
+	before := da.pinAuditState(kind, id)
+	defer func() { da.audit.Record(ctx, "", audit.DomainPin, string(kind)+"/"+id, audit.Delete, before, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		_, err := da.mongo.GetDomainPin(kind, id)
+		return err
+	}
+	return da.mongo.DeleteDomainPin(kind, id)
+}
+
+// pinAuditState returns domain pin for audit log, nil if pin doesn't exist
+func (da *DomainActionsImpl) pinAuditState(kind domain.PinKind, id string) interface{} {
+	pin, err := da.mongo.GetDomainPin(kind, id)
+	if err != nil {
+		return nil
+	}
+	return pin
+}
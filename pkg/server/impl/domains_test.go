@@ -0,0 +1,116 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainDomain(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var pool = NewDomainPool(mongo, domain.Weighted, 30000, 30100)
+	var sa = NewServiceActionsImpl(mongo, &permissions, &kube, ob, NewGraphActionsImpl(mongo), nil, pool)
+	var da = NewDomainActionsImpl(mongo, nil, sa)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	for _, dom := range []domain.Domain{
+		{Domain: "old.test", DomainGroup: "main", IP: []string{"10.0.0.1"}},
+		{Domain: "new.test", DomainGroup: "main", IP: []string{"10.0.0.2"}, State: domain.Disabled},
+		{Domain: "other.test", DomainGroup: "other", IP: []string{"10.0.0.3"}},
+	} {
+		_, err := mongo.CreateDomain(dom)
+		assert.NoError(t, err)
+	}
+	_, err := mongo.SetDomainPin(domain.Pin{Kind: domain.NamespacePin, ID: "ns", DomainGroup: "main"})
+	assert.NoError(t, err)
+
+	var req = kubtypes.Service{
+		Name:  "app",
+		Ports: []kubtypes.ServicePort{{Name: "http", TargetPort: 80, Protocol: kubtypes.TCP}},
+	}
+	_, err = pool.Place("ns", "user", &req)
+	assert.NoError(t, err)
+	assert.Equal(t, "old.test", req.Domain)
+	_, err = mongo.CreateService(service.FromKube("ns", "user", service.External, req))
+	assert.NoError(t, err)
+	var api = kubtypes.Service{
+		Name:  "api",
+		Ports: []kubtypes.ServicePort{{Name: "http", TargetPort: 80, Protocol: kubtypes.TCP}},
+	}
+	_, err = pool.Place("ns", "user", &api)
+	assert.NoError(t, err)
+	_, err = mongo.CreateService(service.FromKube("ns", "user", service.External, api))
+	assert.NoError(t, err)
+
+	err = da.DeleteDomain(ctx, "old.test")
+	assert.True(t, cherry.Equals(err, rserrors.ErrDomainInUse()), "%v", err)
+
+	_, err = da.UpdateDomain(ctx, "old.test", domain.UpdateRequest{IP: []string{"10.0.0.10"}})
+	assert.NoError(t, err)
+	svc, err := mongo.GetService("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.10"}, svc.IPs, "services get new domain addresses")
+
+	// no other schedulable domain in group, drain is stopped on first service
+	resp, err := da.DrainDomain(ctx, "old.test")
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp) {
+		assert.False(t, resp.Complete)
+		assert.Empty(t, resp.Moved)
+		assert.Len(t, resp.Failed, 1)
+		assert.Len(t, resp.Remaining, 1)
+	}
+	dom, err := mongo.GetDomain("old.test")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Draining, dom.State)
+
+	_, err = da.SetDomainPoolSettings(ctx, "new.test", domain.PoolSettings{State: domain.Active})
+	assert.NoError(t, err)
+	resp, err = da.DrainDomain(server.WithDryRun(ctx), "old.test")
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp) {
+		assert.True(t, resp.Complete)
+		assert.Len(t, resp.Moved, 2)
+	}
+	usage, err := mongo.GetPortsUsage(30000, 30100)
+	assert.NoError(t, err)
+	if assert.Len(t, usage, 1) {
+		assert.Equal(t, "old.test", usage[0].Domain, "dry run reserves no ports")
+	}
+
+	resp, err = da.DrainDomain(ctx, "old.test")
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp) {
+		assert.True(t, resp.Complete)
+		assert.ElementsMatch(t, []domain.MovedService{
+			{Namespace: "ns", Service: "app", Domain: "new.test"},
+			{Namespace: "ns", Service: "api", Domain: "new.test"},
+		}, resp.Moved)
+		assert.Empty(t, resp.Failed)
+		assert.Empty(t, resp.Remaining)
+	}
+	svc, err = mongo.GetService("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, "new.test", svc.Domain)
+	assert.Equal(t, []string{"10.0.0.2"}, svc.IPs)
+
+	usage, err = mongo.GetPortsUsage(30000, 30100)
+	assert.NoError(t, err)
+	assert.Len(t, usage, 1)
+	assert.Equal(t, "new.test", usage[0].Domain, "ports of drained domain are released")
+
+	assert.NoError(t, da.DeleteDomain(ctx, "old.test"))
+}
@@ -0,0 +1,28 @@
+package impl
+
+import (
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/kube-client/pkg/diff"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+)
+
+// checkNotExists converts result of getting resource by name to error returned by db on create of existing resource.
+// Dry run doesn't insert resource, so name conflicts are checked this way.
+func checkNotExists(getErr error) error {
+	switch {
+	case getErr == nil:
+		return rserrors.ErrResourceAlreadyExists()
+	case cherry.Equals(getErr, rserrors.ErrResourceNotExists()):
+		return nil
+	default:
+		return getErr
+	}
+}
+
+// dryRunDeployment returns deployment which would be saved by request with diff against active version
+func dryRunDeployment(oldDeploy kubtypes.Deployment, newDeploy deployment.ResourceDeploy) *deployment.ResourceDeploy {
+	newDeploy.Diff = &kubtypes.DeploymentDiff{Diff: diff.Diff(oldDeploy, newDeploy.Deployment)}
+	return &newDeploy
+}
@@ -0,0 +1,74 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kube = &listKube{Kube: clients.NewDummyKube()}
+	var kubeClient clients.Kube = kube
+	var da = NewDeployActionsImpl(mongo, &permissions, &kubeClient, NewOutboxImpl(mongo, &kubeClient, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+	var dryRunCtx = server.WithDryRun(ctx)
+
+	var req = deployment.DeploymentRequest{
+		Deployment: kubtypes.Deployment{
+			Name:     "app",
+			Replicas: 1,
+			Containers: []kubtypes.Container{
+				{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+			},
+		},
+	}
+
+	planned, err := da.CreateDeployment(dryRunCtx, "ns", req)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", planned.Version.String())
+	if assert.NotNil(t, planned.Diff) {
+		assert.NotEmpty(t, planned.Diff.Diff)
+	}
+	_, err = mongo.GetDeployment("ns", "app")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+	assert.Empty(t, kube.deployments)
+
+	created, err := da.CreateDeployment(ctx, "ns", req)
+	assert.NoError(t, err)
+	assert.Nil(t, created.Diff)
+
+	_, err = da.CreateDeployment(dryRunCtx, "ns", req)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceAlreadyExists()), "%v", err)
+
+	planned, err = da.SetDeploymentContainerImage(dryRunCtx, "ns", "app", kubtypes.UpdateImage{Container: "app", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	assert.True(t, planned.Version.GT(created.Version))
+	if assert.NotNil(t, planned.Diff) {
+		assert.Contains(t, planned.Diff.Diff, "nginx:1.1.0")
+	}
+
+	versions, _, err := mongo.GetDeploymentVersionsList("ns", "app", nil)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	active, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.0.0", active.Containers[0].Image)
+
+	assert.NoError(t, da.DeleteDeployment(dryRunCtx, "ns", "app", false))
+	_, err = mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+
+	err = da.DeleteDeployment(dryRunCtx, "ns", "missing", false)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+}
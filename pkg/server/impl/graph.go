@@ -0,0 +1,222 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// deleteFunc deletes resource from db and kube-api without dependency checks
+type deleteFunc func(ctx context.Context, nsID, name string) error
+
+// restoreFunc recreates resource deleted by deleteFunc in db and kube-api
+type restoreFunc func(ctx context.Context, nsID, name string) error
+
+// GraphActionsImpl builds dependency graph of namespace resources.
+// Delete actions consult it, so resource can't be deleted while other resources depend on it.
+type GraphActionsImpl struct {
+	mongo     db.Storage
+	log       *cherrylog.LogrusAdapter
+	deleters  map[graph.Kind]deleteFunc
+	restorers map[graph.Kind]restoreFunc
+}
+
+func NewGraphActionsImpl(mongo db.Storage) *GraphActionsImpl {
+	return &GraphActionsImpl{
+		mongo:     mongo,
+		log:       cherrylog.NewLogrusAdapter(logrus.WithField("component", "graph_actions")),
+		deleters:  make(map[graph.Kind]deleteFunc),
+		restorers: make(map[graph.Kind]restoreFunc),
+	}
+}
+
+// SetDeleter sets function which deletes resources of kind on cascade delete
+func (ga *GraphActionsImpl) SetDeleter(kind graph.Kind, deleter deleteFunc) {
+	ga.deleters[kind] = deleter
+}
+
+// SetRestorer sets function which recreates resources of kind if cascade delete fails.
+// Only kinds which depend on other resources can be deleted by cascade, so only they need restorer.
+func (ga *GraphActionsImpl) SetRestorer(kind graph.Kind, restorer restoreFunc) {
+	ga.restorers[kind] = restorer
+}
+
+func (ga *GraphActionsImpl) GetGraph(ctx context.Context, nsID string) (*graph.Graph, error) {
+	userID := httputil.MustGetUserID(ctx)
+	ga.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+	}).Info("get resources graph")
+
+	ret, err := ga.build(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// CheckDelete must be called before resource is deleted.
+// If resource has dependents, they are deleted when cascade is true, otherwise error with list of dependents is returned.
+// On dry run dependents are not deleted, but resource existence is checked.
+func (ga *GraphActionsImpl) CheckDelete(ctx context.Context, nsID string, node graph.Node, cascade bool) error {
+	return ga.CheckDeleteAll(ctx, nsID, []graph.Node{node}, cascade)
+}
+
+// CheckDeleteAll is CheckDelete for resources deleted together. Resources from list don't block deletion of each other.
+// Cascade is all-or-nothing: it's checked that every dependent can be deleted and restored before anything is deleted,
+// and if some dependent can't be deleted, dependents deleted before it are restored.
+func (ga *GraphActionsImpl) CheckDeleteAll(ctx context.Context, nsID string, nodes []graph.Node, cascade bool) error {
+	resGraph, err := ga.build(nsID)
+	if err != nil {
+		return err
+	}
+
+	var deleted = make(map[graph.Node]bool, len(nodes))
+	for _, node := range nodes {
+		if server.IsDryRun(ctx) && !resGraph.Has(node) {
+			return rserrors.ErrResourceNotExists().AddDetails(node.String())
+		}
+		deleted[node] = true
+	}
+
+	var dependents []graph.Edge
+	for _, node := range nodes {
+		var nodeDependents []graph.Edge
+		for _, edge := range resGraph.Dependents(node) {
+			if !deleted[edge.From] {
+				deleted[edge.From] = true
+				nodeDependents = append(nodeDependents, edge)
+			}
+		}
+		if len(nodeDependents) > 0 && !cascade {
+			return dependentsError(node, nodeDependents)
+		}
+		dependents = append(dependents, nodeDependents...)
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	for _, edge := range dependents {
+		if ga.deleters[edge.From.Kind] == nil || ga.restorers[edge.From.Kind] == nil {
+			return rserrors.ErrInternal().AddDetailF("unable to delete %v", edge.From)
+		}
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	for i, edge := range dependents {
+		ga.log.WithFields(logrus.Fields{
+			"ns_id":     nsID,
+			"resource":  edge.To.String(),
+			"dependent": edge.From.String(),
+		}).Info("cascade delete")
+		if err := ga.delete(ctx, nsID, edge.From); err != nil {
+			ga.log.WithError(err).Debug("Unable to delete dependent resource! Restoring deleted resources.")
+			ga.restore(ctx, nsID, dependents[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckReferences checks that resources which will be added to namespace refer to existing resources or each other
+func (ga *GraphActionsImpl) CheckReferences(nsID string, added graph.Resources) error {
+	res, err := ga.resources(nsID)
+	if err != nil {
+		return err
+	}
+	res.Deployments = append(res.Deployments, added.Deployments...)
+	res.Services = append(res.Services, added.Services...)
+	res.Ingresses = append(res.Ingresses, added.Ingresses...)
+	res.ConfigMaps = append(res.ConfigMaps, added.ConfigMaps...)
+	res.Secrets = append(res.Secrets, added.Secrets...)
+
+	// secrets are not checked, image pull secrets may be not managed by resource-service
+	dangling := graph.Build(res).Dangling(graph.Deployment, graph.Service, graph.ConfigMap)
+	if len(dangling) == 0 {
+		return nil
+	}
+	ret := rserrors.ErrResourceNotExists()
+	for _, edge := range dangling {
+		ret.AddDetailF("%v used by %v not exists", edge.To, edge.From)
+	}
+	return ret
+}
+
+// delete deletes resource without dependency checks
+func (ga *GraphActionsImpl) delete(ctx context.Context, nsID string, node graph.Node) error {
+	deleter, ok := ga.deleters[node.Kind]
+	if !ok {
+		return rserrors.ErrInternal().AddDetailF("unable to delete %v", node)
+	}
+	return deleter(ctx, nsID, node.Name)
+}
+
+// restore recreates deleted dependents in reverse order, so resources are restored before resources which depend on them
+func (ga *GraphActionsImpl) restore(ctx context.Context, nsID string, deleted []graph.Edge) {
+	for i := len(deleted) - 1; i >= 0; i-- {
+		node := deleted[i].From
+		if err := ga.restorers[node.Kind](ctx, nsID, node.Name); err != nil {
+			ga.log.WithError(err).WithFields(logrus.Fields{
+				"ns_id":    nsID,
+				"resource": node.String(),
+			}).Error("unable to restore resource deleted by cascade")
+		}
+	}
+}
+
+func (ga *GraphActionsImpl) build(nsID string) (graph.Graph, error) {
+	res, err := ga.resources(nsID)
+	if err != nil {
+		return graph.Graph{}, err
+	}
+	return graph.Build(res), nil
+}
+
+func (ga *GraphActionsImpl) resources(nsID string) (graph.Resources, error) {
+	var res graph.Resources
+	var err error
+	if res.Deployments, _, err = ga.mongo.GetDeploymentList(nsID, nil); err != nil {
+		return res, err
+	}
+	if res.Services, _, err = ga.mongo.GetServiceList(nsID, nil); err != nil {
+		return res, err
+	}
+	if res.Ingresses, _, err = ga.mongo.GetIngressList(nsID, nil); err != nil {
+		return res, err
+	}
+	if res.ConfigMaps, _, err = ga.mongo.GetConfigMapList(nsID, nil); err != nil {
+		return res, err
+	}
+	if res.Secrets, _, err = ga.mongo.GetSecretList(nsID, nil); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// dependentsError returns error with dependents in details and fields, fields map dependent to resource it uses
+func dependentsError(node graph.Node, dependents []graph.Edge) *cherry.Err {
+	var err *cherry.Err
+	if node.Kind == graph.Service {
+		err = rserrors.ErrServiceHasIngresses()
+	} else {
+		err = rserrors.ErrResourceHasDependents()
+	}
+	var fields = make(cherry.Fields, len(dependents))
+	for _, edge := range dependents {
+		err.AddDetailF("%v is used by %v", edge.To, edge.From)
+		fields[edge.From.String()] = edge.To.String()
+	}
+	return err.WithFields(fields)
+}
@@ -0,0 +1,123 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCascadeDelete(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, da, deps, nil)
+	var sa = NewServiceActionsImpl(mongo, &permissions, &kube, ob, deps, nil, NewDomainPool(mongo, domain.Weighted, 0, 0))
+	NewIngressActionsImpl(mongo, &kube, ob, deps, nil, nil, "")
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := mongo.CreateConfigMap(configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{
+		Name:       "app",
+		Active:     true,
+		Containers: []kubtypes.Container{{Name: "app", ConfigMaps: []kubtypes.ContainerVolume{{Name: "cfg"}}}},
+	}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateService(service.FromKube("ns", "", service.Internal, kubtypes.Service{Name: "app-svc", Deploy: "app"}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateIngress(ingress.FromKube("ns", "", kubtypes.Ingress{
+		Name:  "app-ingr",
+		Rules: []kubtypes.Rule{{Host: "app.test", Path: []kubtypes.Path{{Path: "/", ServiceName: "app-svc", ServicePort: 80}}}},
+	}))
+	assert.NoError(t, err)
+
+	resGraph, err := deps.GetGraph(ctx, "ns")
+	assert.NoError(t, err)
+	assert.Len(t, resGraph.Nodes, 4)
+	assert.Equal(t, []graph.Edge{
+		{From: graph.Node{Kind: graph.Deployment, Name: "app"}, To: graph.Node{Kind: graph.ConfigMap, Name: "cfg"}},
+		{From: graph.Node{Kind: graph.Ingress, Name: "app-ingr"}, To: graph.Node{Kind: graph.Service, Name: "app-svc"}},
+		{From: graph.Node{Kind: graph.Service, Name: "app-svc"}, To: graph.Node{Kind: graph.Deployment, Name: "app"}},
+	}, resGraph.Edges)
+
+	err = sa.DeleteService(ctx, "ns", "app-svc", false)
+	assert.True(t, cherry.Equals(err, rserrors.ErrServiceHasIngresses()), "%v", err)
+
+	err = ca.DeleteConfigMap(ctx, "ns", "cfg", false)
+	if assert.True(t, cherry.Equals(err, rserrors.ErrResourceHasDependents()), "%v", err) {
+		assert.Equal(t, cherry.Fields{
+			"deployment/app":   "configmap/cfg",
+			"service/app-svc":  "deployment/app",
+			"ingress/app-ingr": "service/app-svc",
+		}, err.(*cherry.Err).Fields)
+	}
+
+	assert.NoError(t, ca.DeleteConfigMap(ctx, "ns", "cfg", true))
+	resGraph, err = deps.GetGraph(ctx, "ns")
+	assert.NoError(t, err)
+	assert.Empty(t, resGraph.Nodes)
+}
+
+// undeletableKube fails to delete deployments
+type undeletableKube struct {
+	clients.Kube
+}
+
+func (undeletableKube) DeleteDeployment(ctx context.Context, nsID, deplName string) error {
+	return rserrors.ErrInternal()
+}
+
+func TestCascadeDeleteRestore(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube clients.Kube = undeletableKube{Kube: clients.NewDummyKube()}
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, nil, 0)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, da, deps, nil)
+	NewServiceActionsImpl(mongo, &permissions, &kube, ob, deps, nil, NewDomainPool(mongo, domain.Weighted, 0, 0))
+	NewIngressActionsImpl(mongo, &kube, ob, deps, nil, nil, "")
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := mongo.CreateConfigMap(configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{
+		Name:       "app",
+		Active:     true,
+		Containers: []kubtypes.Container{{Name: "app", ConfigMaps: []kubtypes.ContainerVolume{{Name: "cfg"}}}},
+	}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateService(service.FromKube("ns", "", service.Internal, kubtypes.Service{Name: "app-svc", Deploy: "app"}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateIngress(ingress.FromKube("ns", "", kubtypes.Ingress{
+		Name:  "app-ingr",
+		Rules: []kubtypes.Rule{{Host: "app.test", Path: []kubtypes.Path{{Path: "/", ServiceName: "app-svc", ServicePort: 80}}}},
+	}))
+	assert.NoError(t, err)
+
+	before, err := deps.GetGraph(ctx, "ns")
+	assert.NoError(t, err)
+
+	err = ca.DeleteConfigMap(ctx, "ns", "cfg", true)
+	assert.True(t, cherry.Equals(err, rserrors.ErrInternal()), "%v", err)
+
+	after, err := deps.GetGraph(ctx, "ns")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, before.Nodes, after.Nodes, "dependents deleted before failure must be restored")
+}
@@ -0,0 +1,210 @@
+package impl
+
+import (
+	"context"
+	"strings"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHostReservations is the maximum number of hosts reserved by one user
+const maxHostReservations = 20
+
+type HostActionsImpl struct {
+	mongo  db.Storage
+	log    *cherrylog.LogrusAdapter
+	audit  *AuditImpl
+	suffix string
+}
+
+func NewHostActionsImpl(mongo db.Storage, audit *AuditImpl, ingressSuffix string) *HostActionsImpl {
+	return &HostActionsImpl{
+		mongo:  mongo,
+		log:    cherrylog.NewLogrusAdapter(logrus.WithField("component", "host_actions")),
+		audit:  audit,
+		suffix: ingressSuffix,
+	}
+}
+
+func (ha *HostActionsImpl) GetHostReservationsList(ctx context.Context) (*host.ReservationsResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	ha.log.WithField("user_id", userID).Info("get host reservations")
+
+	reservations, err := ha.mongo.GetHostReservations(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &host.ReservationsResponse{Reservations: reservations}, nil
+}
+
+func (ha *HostActionsImpl) GetAllHostReservationsList(ctx context.Context) (*host.ReservationsResponse, error) {
+	ha.log.Info("get all host reservations")
+
+	reservations, err := ha.mongo.GetHostReservations("")
+	if err != nil {
+		return nil, err
+	}
+	return &host.ReservationsResponse{Reservations: reservations}, nil
+}
+
+// ReserveHost reserves host or wildcard subtree for user.
+// Host can't be reserved if it's reserved or used by ingresses of another user.
+func (ha *HostActionsImpl) ReserveHost(ctx context.Context, req host.ReservationRequest) (ret *host.Reservation, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	ha.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"host":    req.Host,
+	}).Info("reserve host")
+
+	defer func() { ha.audit.Record(ctx, "", audit.HostReservation, req.Host, audit.Create, nil, ret, err) }()
+
+	res, err := ha.reservation(userID, req.Host)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = res.Host
+
+	if !server.IsAdmin(ctx) {
+		reserved, err := ha.mongo.GetHostReservations(userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(reserved) >= maxHostReservations {
+			return nil, rserrors.ErrTooManyHostReservations().AddDetailF("user can reserve at most %v hosts", maxHostReservations)
+		}
+	}
+
+	if err := ha.checkReservation(ctx, res); err != nil {
+		return nil, err
+	}
+
+	if server.IsDryRun(ctx) {
+		return &res, nil
+	}
+	if err := ha.mongo.ReserveHost(res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// reservation returns reservation of host converted like ingress hosts
+func (ha *HostActionsImpl) reservation(owner, hostName string) (host.Reservation, error) {
+	var res = host.Reservation{Owner: owner}
+	var prefix string
+	if strings.HasPrefix(hostName, host.WildcardPrefix) {
+		prefix, hostName = host.WildcardPrefix, strings.TrimPrefix(hostName, host.WildcardPrefix)
+	}
+	if hostName == "" || strings.Contains(hostName, "*") {
+		return res, rserrors.ErrValidation().AddDetailF("host must be domain name or wildcard %vdomain", host.WildcardPrefix)
+	}
+	hostName, err := normalizeHost(hostName, ha.suffix)
+	if err != nil {
+		return res, err
+	}
+	if prefix != "" && !ha.subdomain(hostName) {
+		return res, rserrors.ErrValidation().AddDetailF("wildcard must have at least one label before %q", ha.suffix)
+	}
+	res.Host = prefix + hostName
+	return res, nil
+}
+
+// subdomain returns true if domain has at least one label before ingress suffix.
+// Without suffix domain must have at least two labels, so top-level domains can't be reserved.
+func (ha *HostActionsImpl) subdomain(domain string) bool {
+	minLabels := 1
+	if strings.Trim(ha.suffix, ".") == "" {
+		minLabels = 2
+	}
+	labels := strings.Split(strings.TrimSuffix(domain, ha.suffix), ".")
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+	}
+	return len(labels) >= minLabels
+}
+
+// checkReservation checks that reserved hosts are not reserved or used by another user
+func (ha *HostActionsImpl) checkReservation(ctx context.Context, res host.Reservation) error {
+	covering, err := ha.mongo.GetCoveringReservations(res.Host)
+	if err != nil {
+		return err
+	}
+	var reservations = covering
+	var claims []host.Claim
+	if res.Wildcard() {
+		inSubtree, err := ha.mongo.GetReservationsInSubtree(res.Domain())
+		if err != nil {
+			return err
+		}
+		reservations = append(reservations, inSubtree...)
+		if claims, err = ha.mongo.GetHostClaims(res.Domain(), true); err != nil {
+			return err
+		}
+	} else if claims, err = ha.mongo.GetHostClaims(res.Host, false); err != nil {
+		return err
+	}
+
+	for _, existing := range reservations {
+		if existing.Owner == res.Owner {
+			continue
+		}
+		if server.IsAdmin(ctx) {
+			return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved by user %v", existing.Host, existing.Owner)
+		}
+		return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved by another user", existing.Host)
+	}
+	for _, claim := range claims {
+		if claim.Owner == res.Owner {
+			continue
+		}
+		if server.IsAdmin(ctx) {
+			return rserrors.ErrIngressHostConflict().AddDetailF("path '%v' is used by ingress '%v' in namespace %v", claim.Key(), claim.Ingress, claim.NamespaceID)
+		}
+		return rserrors.ErrIngressHostConflict().AddDetailF("path '%v' is used by another user", claim.Key())
+	}
+	return nil
+}
+
+// DeleteHostReservation deletes reservation of user
+func (ha *HostActionsImpl) DeleteHostReservation(ctx context.Context, hostName string) error {
+	userID := httputil.MustGetUserID(ctx)
+	ha.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"host":    hostName,
+	}).Info("delete host reservation")
+	return ha.deleteHostReservation(ctx, hostName, userID)
+}
+
+// DeleteAnyHostReservation deletes reservation of any user
+func (ha *HostActionsImpl) DeleteAnyHostReservation(ctx context.Context, hostName string) error {
+	ha.log.WithField("host", hostName).Info("delete any host reservation")
+	return ha.deleteHostReservation(ctx, hostName, "")
+}
+
+// deleteHostReservation deletes reservation if it's owned by owner. Empty owner matches any reservation.
+func (ha *HostActionsImpl) deleteHostReservation(ctx context.Context, hostName, owner string) (err error) {
+	var before interface{}
+	defer func() { ha.audit.Record(ctx, "", audit.HostReservation, hostName, audit.Delete, before, nil, err) }()
+
+	res, err := ha.mongo.GetHostReservation(hostName)
+	if err != nil {
+		return err
+	}
+	before = res
+	if owner != "" && res.Owner != owner {
+		return rserrors.ErrResourceNotExists().AddDetailF("host reservation %v", hostName)
+	}
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+	return ha.mongo.DeleteHostReservation(hostName)
+}
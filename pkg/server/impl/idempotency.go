@@ -0,0 +1,96 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// idempotencyLockTimeout is a time after which unfinished request is considered lost (e.g. on service restart) and key can be used again
+const idempotencyLockTimeout = 10 * time.Minute
+
+type IdempotencyImpl struct {
+	mongo  db.Storage
+	log    *cherrylog.LogrusAdapter
+	window time.Duration
+}
+
+// NewIdempotencyImpl returns idempotency keys storage, responses are replayed during window after first request
+func NewIdempotencyImpl(mongo db.Storage, window time.Duration) *IdempotencyImpl {
+	return &IdempotencyImpl{
+		mongo:  mongo,
+		log:    cherrylog.NewLogrusAdapter(logrus.WithField("component", "idempotency_impl")),
+		window: window,
+	}
+}
+
+func (ii *IdempotencyImpl) Begin(ctx context.Context, key, requestHash string) (*idempotency.Record, error) {
+	userID := httputil.MustGetUserID(ctx)
+	ii.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"key":     key,
+	}).Debug("begin idempotent request")
+
+	now := time.Now().UTC()
+	rec := idempotency.Record{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ii.window),
+	}
+	err := ii.mongo.CreateIdempotencyRecord(rec)
+	if err == nil {
+		return nil, nil
+	}
+	if !cherry.Equals(err, rserrors.ErrResourceAlreadyExists()) {
+		return nil, err
+	}
+
+	stored, err := ii.mongo.GetIdempotencyRecord(userID, key)
+	if err != nil {
+		if cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+			// record expired right now, client should just retry
+			return nil, rserrors.ErrIdempotentRequestInProgress().AddDetailF("idempotency key %v", key)
+		}
+		return nil, err
+	}
+	if stored.RequestHash != requestHash {
+		return nil, rserrors.ErrIdempotencyKeyReused().AddDetailF("idempotency key %v", key)
+	}
+	if !stored.Done {
+		if now.Sub(stored.CreatedAt) < idempotencyLockTimeout {
+			return nil, rserrors.ErrIdempotentRequestInProgress().AddDetailF("idempotency key %v", key)
+		}
+		ii.log.WithField("key", key).Warn("taking over lost idempotent request")
+		if err := ii.mongo.UpdateIdempotencyRecord(rec); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+func (ii *IdempotencyImpl) Finish(ctx context.Context, key string, status int, header map[string]string, body []byte) error {
+	userID := httputil.MustGetUserID(ctx)
+	stored, err := ii.mongo.GetIdempotencyRecord(userID, key)
+	if err != nil {
+		return err
+	}
+	stored.Done = true
+	stored.Status = status
+	stored.Header = header
+	stored.Body = body
+	return ii.mongo.UpdateIdempotencyRecord(stored)
+}
+
+func (ii *IdempotencyImpl) Abandon(ctx context.Context, key string) error {
+	return ii.mongo.DeleteIdempotencyRecord(httputil.MustGetUserID(ctx), key)
+}
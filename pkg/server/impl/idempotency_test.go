@@ -0,0 +1,63 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var ii = NewIdempotencyImpl(mongo, time.Hour)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+	var otherUserCtx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000002")
+	var hash = idempotency.RequestHash(http.MethodPost, "/namespaces/ns/deployments", []byte(`{"name":"app"}`))
+
+	stored, err := ii.Begin(ctx, "key", hash)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+
+	_, err = ii.Begin(ctx, "key", hash)
+	assert.True(t, cherry.Equals(err, rserrors.ErrIdempotentRequestInProgress()), "%v", err)
+
+	// keys of different users don't interfere
+	stored, err = ii.Begin(otherUserCtx, "key", hash)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+
+	assert.NoError(t, ii.Finish(ctx, "key", http.StatusCreated, map[string]string{"Content-Type": "application/json"}, []byte(`{"name":"app"}`)))
+
+	stored, err = ii.Begin(ctx, "key", hash)
+	assert.NoError(t, err)
+	if assert.NotNil(t, stored) {
+		assert.Equal(t, http.StatusCreated, stored.Status)
+		assert.Equal(t, "application/json", stored.Header["Content-Type"])
+		assert.Equal(t, `{"name":"app"}`, string(stored.Body))
+	}
+
+	_, err = ii.Begin(ctx, "key", idempotency.RequestHash(http.MethodPost, "/namespaces/ns/deployments", []byte(`{"name":"other"}`)))
+	assert.True(t, cherry.Equals(err, rserrors.ErrIdempotencyKeyReused()), "%v", err)
+
+	// abandoned request can be retried
+	assert.NoError(t, ii.Abandon(otherUserCtx, "key"))
+	stored, err = ii.Begin(otherUserCtx, "key", hash)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// key can be reused after replay window
+	var expired = NewIdempotencyImpl(mongo, 0)
+	stored, err = expired.Begin(ctx, "expired", hash)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	stored, err = expired.Begin(ctx, "expired", hash)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
@@ -2,13 +2,19 @@ package impl
 
 import (
 	"context"
+	"net/url"
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
 	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
+	"github.com/containerum/cherry"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
@@ -18,48 +24,67 @@ import (
 
 type IngressActionsImpl struct {
 	kube   clients.Kube
-	mongo  *db.MongoStorage
+	mongo  db.Storage
 	log    *cherrylog.LogrusAdapter
+	outbox *OutboxImpl
+	audit  *AuditImpl
+	certs  *CertificatesImpl
 	suffix string
 }
 
-func NewIngressActionsImpl(mongo *db.MongoStorage, kube *clients.Kube, ingressSuffix string) *IngressActionsImpl {
-	return &IngressActionsImpl{
+func NewIngressActionsImpl(mongo db.Storage, kube *clients.Kube, outbox *OutboxImpl, deps *GraphActionsImpl, audit *AuditImpl, certs *CertificatesImpl, ingressSuffix string) *IngressActionsImpl {
+	ia := &IngressActionsImpl{
 		kube:   *kube,
 		mongo:  mongo,
 		log:    cherrylog.NewLogrusAdapter(logrus.WithField("component", "ingress_actions")),
+		outbox: outbox,
+		audit:  audit,
+		certs:  certs,
 		suffix: ingressSuffix,
 	}
+	deps.SetDeleter(graph.Ingress, ia.deleteIngress)
+	deps.SetRestorer(graph.Ingress, ia.restoreIngress)
+	return ia
 }
 
-func (ia *IngressActionsImpl) GetIngressesList(ctx context.Context, nsID string) (*ingress.IngressesResponse, error) {
+func (ia *IngressActionsImpl) GetIngressesList(ctx context.Context, nsID string, params url.Values) (*ingress.IngressesResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id":   userID,
 		"namespace": nsID,
 	}).Info("get user ingresses")
 
-	ingresses, err := ia.mongo.GetIngressList(nsID)
+	query, err := db.ParseListQuery(params, db.IngressListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ingress.IngressesResponse{Ingresses: ingresses}, nil
+	ingresses, total, err := ia.mongo.GetIngressList(nsID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ingress.IngressesResponse{Ingresses: ingresses, Total: total}, nil
 }
 
-func (ia *IngressActionsImpl) GetSelectedIngressesList(ctx context.Context, namespaces []string) (*ingress.IngressesResponse, error) {
+func (ia *IngressActionsImpl) GetSelectedIngressesList(ctx context.Context, namespaces []string, params url.Values) (*ingress.IngressesResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id":    userID,
 		"namespaces": namespaces,
 	}).Info("get selected ingresses")
 
-	ingresses, err := ia.mongo.GetSelectedIngresses(namespaces)
+	query, err := db.ParseListQuery(params, db.IngressListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ingress.IngressesResponse{Ingresses: ingresses}, nil
+	ingresses, total, err := ia.mongo.GetSelectedIngresses(namespaces, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ingress.IngressesResponse{Ingresses: ingresses, Total: total}, nil
 }
 
 func (ia *IngressActionsImpl) GetIngress(ctx context.Context, nsID, ingressName string) (*ingress.ResourceIngress, error) {
@@ -70,7 +95,7 @@ func (ia *IngressActionsImpl) GetIngress(ctx context.Context, nsID, ingressName
 	return &resp, err
 }
 
-func (ia *IngressActionsImpl) CreateIngress(ctx context.Context, nsID string, req kubtypes.Ingress) (*ingress.ResourceIngress, error) {
+func (ia *IngressActionsImpl) CreateIngress(ctx context.Context, nsID string, req ingress.IngressRequest) (ret *ingress.ResourceIngress, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id": userID,
@@ -78,60 +103,390 @@ func (ia *IngressActionsImpl) CreateIngress(ctx context.Context, nsID string, re
 	}).Info("create ingress")
 	coblog.Std.Struct(req)
 
-	//Convert host to dns-label, validate it and append suffix
-	var err error
-	req.Rules[0].Host, err = idna.Lookup.ToASCII(req.Rules[0].Host)
-	if err != nil {
-		return nil, rserrors.ErrValidation().AddDetailsErr(err)
+	defer func() { ia.audit.Record(ctx, nsID, audit.Ingress, req.Name, audit.Create, nil, ret, err) }()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
+	if err := ia.prepareHost(&req.Ingress); err != nil {
+		return nil, err
 	}
 
-	req.Rules[0].Host = req.Rules[0].Host + ia.suffix
+	if err := ia.prepareTLS(&req.Ingress, req.TLS, ""); err != nil {
+		return nil, err
+	}
 
-	if req.Rules[0].Path[0].Path == "" {
-		req.Rules[0].Path[0].Path = "/"
+	if err := resolvePaths(&req.Ingress, ia.serviceLookup(nsID)); err != nil {
+		return nil, err
 	}
 
-	svc, err := ia.mongo.GetService(nsID, req.Rules[0].Path[0].ServiceName)
-	if err != nil {
-		ia.log.Error(err)
-		return nil, rserrors.ErrResourceNotExists().AddDetailF("service '%v' not exists", req.Rules[0].Path[0].ServiceName)
+	if err := ia.checkPathConflicts(nsID, req.Ingress); err != nil {
+		return nil, err
 	}
 
-	req.Rules[0].Path, err = server.IngressPaths(svc.Service, req.Rules[0].Path[0].Path, req.Rules[0].Path[0].ServicePort)
+	newIngress := ingress.FromKube(nsID, userID, req.Ingress)
+	newIngress.Metadata = req.Metadata.Copy()
+	newIngress.TLS = req.TLS
+	claims := host.Claims(nsID, newIngress.Ingress)
+
+	if newIngress.TLS == ingress.TLSAuto {
+		if err := ia.certs.Check(nsID, req.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if server.IsDryRun(ctx) {
+		_, err := ia.mongo.GetIngress(nsID, req.Name)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		if err := ia.checkHosts(ctx, claims, nil); err != nil {
+			return nil, err
+		}
+		return &newIngress, nil
+	}
+
+	op, err := ia.outbox.Enqueue(ctx, outbox.CreateIngress, nsID, req.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	createdIngress, err := ia.mongo.CreateIngress(ingress.FromKube(nsID, userID, req))
+	createdIngress, err := ia.mongo.CreateIngress(newIngress)
 	if err != nil {
+		ia.outbox.Cancel(op, err)
 		return nil, err
 	}
 
-	if err := ia.kube.CreateIngress(ctx, nsID, req); err != nil {
-		ia.log.Debug("Kube-API error! Deleting ingress from DB.")
+	// hosts are claimed after ingress is saved, so claims of existing ingress with the same name are not taken as ours
+	if err := ia.claimHosts(ctx, claims); err != nil {
 		if err := ia.mongo.DeleteIngress(nsID, req.Name); err != nil {
 			return nil, err
 		}
+		ia.outbox.Cancel(op, err)
 		return nil, err
 	}
 
+	if err := ia.outbox.Apply(ctx, op, func() error {
+		if err := ia.mongo.DeleteIngress(nsID, req.Name); err != nil {
+			return err
+		}
+		ia.releaseHosts(claims)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// certificate is issued after ingress is created, ingress controller uses default certificate until then
+	if newIngress.TLS == ingress.TLSAuto {
+		ia.requestCertificate(nsID, userID, req.Name, newIngress.Hosts())
+	}
+
 	return &createdIngress, nil
 }
 
-func (ia *IngressActionsImpl) ImportIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress) error {
+// prepareHost converts hosts of all rules to dns-labels, validates them and appends suffix
+func (ia *IngressActionsImpl) prepareHost(req *kubtypes.Ingress) error {
+	for i := range req.Rules {
+		host, err := normalizeHost(req.Rules[i].Host, ia.suffix)
+		if err != nil {
+			return err
+		}
+		req.Rules[i].Host = host
+
+		for j := range req.Rules[i].Path {
+			if req.Rules[i].Path[j].Path == "" {
+				req.Rules[i].Path[j].Path = "/"
+			}
+		}
+	}
+	return nil
+}
+
+// prepareTLS checks tls mode of ingress. In "auto" mode all rules use tls secret with issued certificate.
+// Rules of ingress switched from "auto" mode stop using that secret, because it's deleted.
+func (ia *IngressActionsImpl) prepareTLS(req *kubtypes.Ingress, mode, oldMode ingress.TLSMode) error {
+	secretName := tlsSecretName(req.Name)
+	switch mode {
+	case "", ingress.TLSManual:
+		if oldMode != ingress.TLSAuto {
+			return nil
+		}
+		for i, rule := range req.Rules {
+			if rule.TLSSecret != nil && *rule.TLSSecret == secretName {
+				req.Rules[i].TLSSecret = nil
+			}
+		}
+		return nil
+	case ingress.TLSAuto:
+	default:
+		return rserrors.ErrValidation().AddDetailF("unknown tls mode '%v'", mode)
+	}
+
+	if !ia.certs.Enabled() {
+		return rserrors.ErrValidation().AddDetailF("tls mode '%v' is not available, certificate issuer is not configured", mode)
+	}
+	for i, rule := range req.Rules {
+		// secret can be sent back with ingress received from api
+		if rule.TLSSecret != nil && *rule.TLSSecret != "" && *rule.TLSSecret != secretName {
+			return rserrors.ErrValidation().AddDetailF("Rules[%d].TLSSecret can't be set in tls mode '%v'", i, mode)
+		}
+		req.Rules[i].TLSSecret = &secretName
+	}
+	return nil
+}
+
+// requestCertificate requests certificate of ingress hosts, errors are only logged and request is repeated on next update
+func (ia *IngressActionsImpl) requestCertificate(nsID, owner, ingressName string, hosts []string) {
+	if err := ia.certs.Request(nsID, owner, ingressName, hosts); err != nil {
+		ia.log.WithError(err).WithField("ingress", ingressName).Error("unable to request certificate")
+	}
+}
+
+// deleteCertificate deletes issued certificate of ingress, errors are only logged
+func (ia *IngressActionsImpl) deleteCertificate(ctx context.Context, nsID, ingressName string) {
+	if ia.certs == nil {
+		return
+	}
+	if err := ia.certs.Delete(ctx, nsID, ingressName); err != nil {
+		ia.log.WithError(err).WithField("ingress", ingressName).Error("unable to delete certificate")
+	}
+}
+
+// normalizeHost converts host to dns-label and appends suffix
+func normalizeHost(host, suffix string) (string, error) {
+	host, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", rserrors.ErrValidation().AddDetailsErr(err)
+	}
+	return host + suffix, nil
+}
+
+// serviceLookup returns function which gets services of namespace from db
+func (ia *IngressActionsImpl) serviceLookup(nsID string) func(name string) (kubtypes.Service, error) {
+	return func(name string) (kubtypes.Service, error) {
+		svc, err := ia.mongo.GetService(nsID, name)
+		if err != nil {
+			ia.log.Error(err)
+			return kubtypes.Service{}, rserrors.ErrResourceNotExists().AddDetailF("service '%v' not exists", name)
+		}
+		return svc.Service, nil
+	}
+}
+
+// resolvePaths checks service and TCP port of every ingress path and replaces paths with generated ones
+func resolvePaths(req *kubtypes.Ingress, lookup func(name string) (kubtypes.Service, error)) error {
+	for i, rule := range req.Rules {
+		paths := make([]kubtypes.Path, 0, len(rule.Path))
+		for _, path := range rule.Path {
+			svc, err := lookup(path.ServiceName)
+			if err != nil {
+				return err
+			}
+			generated, err := server.IngressPaths(svc, path.Path, path.ServicePort)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, generated...)
+		}
+		req.Rules[i].Path = paths
+	}
+	return nil
+}
+
+// checkPathConflicts checks that host and path pairs of ingress are not used by other ingresses in namespace
+func (ia *IngressActionsImpl) checkPathConflicts(nsID string, req kubtypes.Ingress) error {
+	ingresses, _, err := ia.mongo.GetIngressList(nsID, nil)
+	if err != nil {
+		return err
+	}
+	return ingressPathConflicts(req, ingresses)
+}
+
+// ingressPathConflicts returns error if ingress uses same host and path twice or shares them with other ingress from list.
+// Ingress with same name in list is considered as old version of ingress and is skipped.
+func ingressPathConflicts(req kubtypes.Ingress, ingresses ingress.ListIngress) error {
+	var used = make(map[string]struct{})
+	for _, rule := range req.Rules {
+		for _, path := range rule.Path {
+			key := rule.Host + path.Path
+			if _, ok := used[key]; ok {
+				return rserrors.ErrIngressPathConflict().AddDetailF("path '%s' is used twice in ingress '%s'", key, req.Name)
+			}
+			used[key] = struct{}{}
+		}
+	}
+
+	for _, ingr := range ingresses {
+		if ingr.Name == req.Name {
+			continue
+		}
+		for _, rule := range ingr.Rules {
+			for _, path := range rule.Path {
+				key := rule.Host + path.Path
+				if _, ok := used[key]; ok {
+					return rserrors.ErrIngressPathConflict().AddDetailF("path '%s' is already used by ingress '%s'", key, ingr.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// claimHosts claims hosts and paths for ingress, all of them or none.
+// Hosts reserved by another user can't be claimed.
+func (ia *IngressActionsImpl) claimHosts(ctx context.Context, claims []host.Claim) error {
+	for i, claim := range claims {
+		if err := ia.checkHostReservation(ctx, claim); err != nil {
+			ia.releaseHosts(claims[:i])
+			return err
+		}
+		if err := ia.mongo.ClaimHost(claim); err != nil {
+			ia.releaseHosts(claims[:i])
+			if cherry.Equals(err, rserrors.ErrIngressHostConflict()) {
+				return ia.hostConflict(ctx, claim)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHosts checks that hosts and paths can be claimed without claiming them.
+// Planned claims are ones which would be claimed before, e.g. by other ingresses of bundle.
+func (ia *IngressActionsImpl) checkHosts(ctx context.Context, claims, planned []host.Claim) error {
+	for _, claim := range claims {
+		if err := ia.checkHostReservation(ctx, claim); err != nil {
+			return err
+		}
+		for _, other := range planned {
+			if other.SameIngress(claim) {
+				continue
+			}
+			if other.Key() == claim.Key() {
+				return rserrors.ErrIngressHostConflict().AddDetailF("path '%v' is used by ingress '%v'", claim.Key(), other.Ingress)
+			}
+			if other.Host == claim.Host && other.Owner != claim.Owner {
+				return rserrors.ErrIngressHostConflict().AddDetailF("host '%v' is used by ingress '%v' of another user", claim.Host, other.Ingress)
+			}
+		}
+		holders, err := ia.mongo.GetHostClaims(claim.Host, false)
+		if err != nil {
+			return err
+		}
+		for _, holder := range holders {
+			// paths of one host can't be split between users, otherwise user could intercept subpath of another user's site
+			if !holder.SameIngress(claim) && (holder.Path == claim.Path || holder.Owner != claim.Owner) {
+				return ia.hostConflict(ctx, claim)
+			}
+		}
+	}
+	return nil
+}
+
+func (ia *IngressActionsImpl) releaseHosts(claims []host.Claim) {
+	for _, claim := range claims {
+		if err := ia.mongo.ReleaseHost(claim); err != nil {
+			ia.log.WithError(err).WithField("host", claim.Key()).Error("unable to release host")
+		}
+	}
+}
+
+// checkHostReservation checks that claimed host is not reserved by another user
+func (ia *IngressActionsImpl) checkHostReservation(ctx context.Context, claim host.Claim) error {
+	reservations, err := ia.mongo.GetCoveringReservations(claim.Host)
+	if err != nil {
+		return err
+	}
+	for _, res := range reservations {
+		if res.Owner == claim.Owner {
+			continue
+		}
+		if server.IsAdmin(ctx) {
+			return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved as '%v' by user %v", claim.Host, res.Host, res.Owner)
+		}
+		return rserrors.ErrHostReserved().AddDetailF("host '%v' is reserved by another user", claim.Host)
+	}
+	return nil
+}
+
+// hostConflict returns error describing ingress which uses host and path.
+// Ingresses from other namespaces are named only for admins.
+func (ia *IngressActionsImpl) hostConflict(ctx context.Context, claim host.Claim) error {
+	var err = rserrors.ErrIngressHostConflict()
+	claims, getErr := ia.mongo.GetHostClaims(claim.Host, false)
+	if getErr != nil {
+		ia.log.WithError(getErr).Error("unable to get host claims")
+	}
+	var pathUsed bool
+	for _, holder := range claims {
+		if holder.Path != claim.Path {
+			continue
+		}
+		pathUsed = true
+		switch {
+		case server.IsAdmin(ctx):
+			return err.AddDetailF("path '%v' is used by ingress '%v' in namespace %v", claim.Key(), holder.Ingress, holder.NamespaceID)
+		case holder.NamespaceID == claim.NamespaceID:
+			return err.AddDetailF("path '%v' is used by ingress '%v'", claim.Key(), holder.Ingress)
+		}
+	}
+	if pathUsed {
+		return err.AddDetailF("path '%v' is used in another namespace", claim.Key())
+	}
+	for _, holder := range claims {
+		if holder.Owner == claim.Owner || holder.SameIngress(claim) {
+			continue
+		}
+		if server.IsAdmin(ctx) {
+			return err.AddDetailF("host '%v' is used by ingress '%v' in namespace %v of another user", claim.Host, holder.Ingress, holder.NamespaceID)
+		}
+		return err.AddDetailF("host '%v' is used by another user", claim.Host)
+	}
+	return err.AddDetailF("path '%v' is used in another namespace", claim.Key())
+}
+
+// claimIngressHosts claims hosts of ingress created outside of resource-service, e.g. imported one
+func claimIngressHosts(mongo db.Storage, claims []host.Claim) error {
+	for i, claim := range claims {
+		if err := mongo.ClaimHost(claim); err != nil {
+			for _, claimed := range claims[:i] {
+				mongo.ReleaseHost(claimed)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (ia *IngressActionsImpl) ImportIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress) (err error) {
 	ia.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("create ingress")
 	coblog.Std.Struct(ingr)
 
-	if _, err := ia.mongo.CreateIngress(ingress.FromKube(nsID, ingr.Owner, ingr)); err != nil {
+	defer func() { ia.audit.Record(ctx, nsID, audit.Ingress, ingr.Name, audit.Import, nil, ingr, err) }()
+
+	if server.IsDryRun(ctx) {
+		_, err := ia.mongo.GetIngress(nsID, ingr.Name)
+		return checkNotExists(err)
+	}
+
+	newIngress := ingress.FromKube(nsID, ingr.Owner, ingr)
+	if _, err := ia.mongo.CreateIngress(newIngress); err != nil {
+		return err
+	}
+	if err := claimIngressHosts(ia.mongo, host.Claims(nsID, newIngress.Ingress)); err != nil {
+		if err := ia.mongo.DeleteIngress(nsID, ingr.Name); err != nil {
+			return err
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (ia *IngressActionsImpl) UpdateIngress(ctx context.Context, nsID string, req kubtypes.Ingress) (*ingress.ResourceIngress, error) {
+func (ia *IngressActionsImpl) UpdateIngress(ctx context.Context, nsID string, req ingress.IngressRequest) (ret *ingress.ResourceIngress, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	ia.log.WithFields(logrus.Fields{
 		"user_id": userID,
@@ -139,42 +494,95 @@ func (ia *IngressActionsImpl) UpdateIngress(ctx context.Context, nsID string, re
 		"ingress": req,
 	}).Info("update ingress")
 
+	before := ia.auditState(nsID, req.Name)
+	defer func() { ia.audit.Record(ctx, nsID, audit.Ingress, req.Name, audit.Update, before, ret, err) }()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
 	oldIngress, err := ia.mongo.GetIngress(nsID, req.Name)
 	if err != nil {
 		return nil, err
 	}
+	if err := server.CheckIfMatch(ctx, oldIngress.ResourceVersion); err != nil {
+		return nil, err
+	}
 
-	req.Rules[0].Host = req.Rules[0].Host + ia.suffix
 	req.Name = oldIngress.Name
+	if req.TLS == "" {
+		req.TLS = oldIngress.TLS
+	}
 
-	if req.Rules[0].Path[0].Path == "" {
-		req.Rules[0].Path[0].Path = "/"
+	if err := ia.prepareHost(&req.Ingress); err != nil {
+		return nil, err
 	}
 
-	svc, err := ia.mongo.GetService(nsID, req.Rules[0].Path[0].ServiceName)
-	if err != nil {
-		ia.log.Error(err)
-		return nil, rserrors.ErrResourceNotExists().AddDetails("service '%v' not exists", req.Rules[0].Path[0].ServiceName)
+	if err := ia.prepareTLS(&req.Ingress, req.TLS, oldIngress.TLS); err != nil {
+		return nil, err
 	}
 
-	req.Rules[0].Path, err = server.IngressPaths(svc.Service, req.Rules[0].Path[0].Path, req.Rules[0].Path[0].ServicePort)
-	if err != nil {
+	if err := resolvePaths(&req.Ingress, ia.serviceLookup(nsID)); err != nil {
 		return nil, err
 	}
 
-	ingres, err := ia.mongo.UpdateIngress(ingress.FromKube(nsID, userID, req))
+	if err := ia.checkPathConflicts(nsID, req.Ingress); err != nil {
+		return nil, err
+	}
+
+	newIngress := ingress.FromKube(nsID, userID, req.Ingress)
+	newIngress.Metadata = req.Metadata.Merge(oldIngress.Metadata)
+	newIngress.TLS = req.TLS
+
+	// only hosts and paths which old ingress doesn't use are claimed, so they can be released if update fails
+	oldClaims := host.Claims(nsID, oldIngress.Ingress)
+	newClaims := host.Claims(nsID, newIngress.Ingress)
+	added := host.Diff(newClaims, oldClaims)
+
+	if newIngress.TLS == ingress.TLSAuto {
+		if err := ia.certs.Check(nsID, req.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if server.IsDryRun(ctx) {
+		if err := ia.checkHosts(ctx, added, nil); err != nil {
+			return nil, err
+		}
+		newIngress.ID = oldIngress.ID
+		return &newIngress, nil
+	}
+
+	if err := ia.claimHosts(ctx, added); err != nil {
+		return nil, err
+	}
+
+	newIngress.ResourceVersion = oldIngress.ResourceVersion
+	ingres, err := ia.mongo.UpdateIngress(newIngress)
 	if err != nil {
+		ia.releaseHosts(added)
 		return nil, err
 	}
 
-	if err := ia.kube.UpdateIngress(ctx, nsID, req); err != nil {
+	if err := ia.kube.UpdateIngress(ctx, nsID, req.Ingress, newIngress.Metadata); err != nil {
 		ia.log.Debug("Kube-API error! Reverting changes.")
+		ia.releaseHosts(added)
+		oldIngress.ResourceVersion = 0
 		if _, err := ia.mongo.UpdateIngress(oldIngress); err != nil {
 			return nil, err
 		}
 		return nil, err
 	}
 
+	ia.releaseHosts(host.Diff(oldClaims, newClaims))
+	// certificate is reissued asynchronously if hosts are changed
+	switch {
+	case newIngress.TLS == ingress.TLSAuto:
+		ia.requestCertificate(nsID, userID, req.Name, newIngress.Hosts())
+	case oldIngress.TLS == ingress.TLSAuto:
+		ia.deleteCertificate(ctx, nsID, req.Name)
+	}
+
 	return &ingres, nil
 }
 
@@ -186,6 +594,27 @@ func (ia *IngressActionsImpl) DeleteIngress(ctx context.Context, nsID, ingressNa
 		"domain":  ingressName,
 	}).Info("delete ingress")
 
+	if _, ok := server.IfMatch(ctx); ok || server.IsDryRun(ctx) {
+		ingr, err := ia.mongo.GetIngress(nsID, ingressName)
+		if err != nil {
+			return err
+		}
+		if err := server.CheckIfMatch(ctx, ingr.ResourceVersion); err != nil {
+			return err
+		}
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	// nothing depends on ingresses
+	return ia.deleteIngress(ctx, nsID, ingressName)
+}
+
+func (ia *IngressActionsImpl) deleteIngress(ctx context.Context, nsID, ingressName string) (err error) {
+	before := ia.auditState(nsID, ingressName)
+	defer func() { ia.audit.Record(ctx, nsID, audit.Ingress, ingressName, audit.Delete, before, nil, err) }()
+
 	if err := ia.mongo.DeleteIngress(nsID, ingressName); err != nil {
 		return err
 	}
@@ -198,17 +627,60 @@ func (ia *IngressActionsImpl) DeleteIngress(ctx context.Context, nsID, ingressNa
 		return err
 	}
 
+	ia.deleteCertificate(ctx, nsID, ingressName)
+
 	return nil
 }
 
-func (ia *IngressActionsImpl) DeleteAllIngresses(ctx context.Context, nsID string) error {
+// restoreIngress recreates ingress deleted by deleteIngress
+func (ia *IngressActionsImpl) restoreIngress(ctx context.Context, nsID, ingressName string) error {
+	if err := ia.mongo.RestoreIngress(nsID, ingressName); err != nil {
+		return err
+	}
+	ingr, err := ia.mongo.GetIngress(nsID, ingressName)
+	if err != nil {
+		return err
+	}
+	return ia.kube.CreateIngress(ctx, nsID, ingr.Ingress, ingr.Metadata)
+}
+
+func (ia *IngressActionsImpl) DeleteAllIngresses(ctx context.Context, nsID string) (err error) {
 	ia.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("delete all ingresses")
 
+	defer func() { ia.audit.Record(ctx, nsID, audit.Ingress, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	ingresses, _, err := ia.mongo.GetIngressList(nsID, nil)
+	if err != nil {
+		return err
+	}
+
 	if err := ia.mongo.DeleteAllIngressesInNamespace(nsID); err != nil {
 		return err
 	}
 
+	for _, ingr := range ingresses {
+		if ingr.TLS == ingress.TLSAuto {
+			ia.deleteCertificate(ctx, nsID, ingr.Name)
+		}
+	}
+
 	return nil
 }
+
+// auditState returns ingress for audit log, nil if ingress doesn't exist
+func (ia *IngressActionsImpl) auditState(nsID, ingressName string) interface{} {
+	if ia.audit == nil {
+		return nil
+	}
+	ingr, err := ia.mongo.GetIngress(nsID, ingressName)
+	if err != nil {
+		return nil
+	}
+	return ingr
+}
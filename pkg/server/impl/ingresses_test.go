@@ -0,0 +1,373 @@
+package impl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"git.containerum.net/ch/resource-service/pkg/util/certs"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiPathIngress(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var ia = NewIngressActionsImpl(mongo, &kube, ob, NewGraphActionsImpl(mongo), nil, nil, ".test")
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	var port = 80
+	for _, name := range []string{"api", "web"} {
+		_, err := mongo.CreateService(service.FromKube("ns", "", service.Internal, kubtypes.Service{
+			Name:   name,
+			Deploy: name,
+			Ports:  []kubtypes.ServicePort{{Name: "http", Port: &port, TargetPort: 80, Protocol: kubtypes.TCP}},
+		}))
+		assert.NoError(t, err)
+	}
+
+	var req = ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name: "app",
+		Rules: []kubtypes.Rule{
+			{Host: "app", Path: []kubtypes.Path{
+				{Path: "/api", ServiceName: "api", ServicePort: port},
+				{Path: "/web", ServiceName: "web", ServicePort: port},
+			}},
+			{Host: "web", Path: []kubtypes.Path{{ServiceName: "web", ServicePort: port}}},
+		},
+	}}
+	created, err := ia.CreateIngress(ctx, "ns", req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "app.test", created.Rules[0].Host)
+		assert.Equal(t, "web.test", created.Rules[1].Host)
+		assert.Len(t, created.Paths(), 3)
+		assert.Equal(t, "/", created.Rules[1].Path[0].Path)
+	}
+
+	// every path is checked
+	_, err = ia.CreateIngress(ctx, "ns", ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name: "other",
+		Rules: []kubtypes.Rule{{Host: "other", Path: []kubtypes.Path{
+			{Path: "/", ServiceName: "api", ServicePort: port},
+			{Path: "/admin", ServiceName: "api", ServicePort: 8080},
+		}}},
+	}})
+	assert.True(t, cherry.Equals(err, rserrors.ErrTCPPortNotFound()), "%v", err)
+
+	_, err = ia.CreateIngress(ctx, "ns", ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name:  "other",
+		Rules: []kubtypes.Rule{{Host: "app", Path: []kubtypes.Path{{Path: "/web", ServiceName: "api", ServicePort: port}}}},
+	}})
+	assert.True(t, cherry.Equals(err, rserrors.ErrIngressPathConflict()), "%v", err)
+
+	_, err = ia.CreateIngress(ctx, "ns", ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name: "other",
+		Rules: []kubtypes.Rule{
+			{Host: "other", Path: []kubtypes.Path{{Path: "/", ServiceName: "api", ServicePort: port}}},
+			{Host: "other", Path: []kubtypes.Path{{Path: "/", ServiceName: "web", ServicePort: port}}},
+		},
+	}})
+	assert.True(t, cherry.Equals(err, rserrors.ErrIngressPathConflict()), "%v", err)
+
+	// ingress doesn't conflict with its old version, CreateIngress changes rules of request
+	updated, err := ia.UpdateIngress(ctx, "ns", ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name: "app",
+		Rules: []kubtypes.Rule{{Host: "app", Path: []kubtypes.Path{
+			{Path: "/api", ServiceName: "api", ServicePort: port},
+			{Path: "/web", ServiceName: "web", ServicePort: port},
+		}}},
+	}})
+	if assert.NoError(t, err) {
+		assert.Len(t, updated.Rules, 1)
+		assert.Equal(t, "app.test", updated.Rules[0].Host)
+	}
+
+	_, err = ia.CreateIngress(ctx, "ns", ingress.IngressRequest{Ingress: kubtypes.Ingress{
+		Name:  "other",
+		Rules: []kubtypes.Rule{{Host: "web", Path: []kubtypes.Path{{Path: "/", ServiceName: "web", ServicePort: port}}}},
+	}})
+	assert.NoError(t, err)
+}
+
+func TestIngressHosts(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var ia = NewIngressActionsImpl(mongo, &kube, ob, NewGraphActionsImpl(mongo), nil, nil, ".test")
+	var ha = NewHostActionsImpl(mongo, nil, ".test")
+	var user1 = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+	var user2 = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000002")
+	var admin = context.WithValue(user2, httputil.UserRoleContextKey, server.RoleAdmin)
+
+	var port = 80
+	for _, nsID := range []string{"ns1", "ns2"} {
+		_, err := mongo.CreateService(service.FromKube(nsID, "", service.Internal, kubtypes.Service{
+			Name:   "app",
+			Deploy: "app",
+			Ports:  []kubtypes.ServicePort{{Name: "http", Port: &port, TargetPort: 80, Protocol: kubtypes.TCP}},
+		}))
+		assert.NoError(t, err)
+	}
+	var newIngress = func(name, host, path string) ingress.IngressRequest {
+		return ingress.IngressRequest{Ingress: kubtypes.Ingress{
+			Name:  name,
+			Rules: []kubtypes.Rule{{Host: host, Path: []kubtypes.Path{{Path: path, ServiceName: "app", ServicePort: port}}}},
+		}}
+	}
+
+	_, err := ia.CreateIngress(user1, "ns1", newIngress("app", "app", "/"))
+	assert.NoError(t, err)
+
+	_, err = ia.CreateIngress(user2, "ns2", newIngress("app2", "app", "/"))
+	if assert.True(t, cherry.Equals(err, rserrors.ErrIngressHostConflict()), "%v", err) {
+		assert.Contains(t, err.Error(), "another namespace", "namespace is not shown to users")
+	}
+	_, err = ia.CreateIngress(server.WithDryRun(admin), "ns2", newIngress("app2", "app", "/"))
+	if assert.True(t, cherry.Equals(err, rserrors.ErrIngressHostConflict()), "%v", err) {
+		assert.Contains(t, err.Error(), "ns1", "namespace is shown to admin")
+	}
+	// nested paths of host used by another user can't be claimed
+	_, err = ia.CreateIngress(server.WithDryRun(user2), "ns2", newIngress("api", "app", "/api"))
+	if assert.True(t, cherry.Equals(err, rserrors.ErrIngressHostConflict()), "%v", err) {
+		assert.Contains(t, err.Error(), "another user")
+	}
+	_, err = ia.CreateIngress(user2, "ns2", newIngress("admin", "app", "/admin"))
+	assert.True(t, cherry.Equals(err, rserrors.ErrIngressHostConflict()), "%v", err)
+	_, err = ia.CreateIngress(user1, "ns1", newIngress("api", "app", "/api"))
+	assert.NoError(t, err, "other paths of host can be used by the same user")
+	claims, err := mongo.GetHostClaims("app.test", false)
+	if assert.NoError(t, err) && assert.Len(t, claims, 2) {
+		for _, claim := range claims {
+			assert.Equal(t, "ns1", claim.NamespaceID)
+		}
+	}
+
+	// reserved host can't be used or reserved by other users
+	_, err = ha.ReserveHost(user2, host.ReservationRequest{Host: "*.apps"})
+	assert.NoError(t, err)
+	_, err = ha.ReserveHost(user1, host.ReservationRequest{Host: "x.apps"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrHostReserved()), "%v", err)
+	_, err = ia.CreateIngress(user1, "ns1", newIngress("x", "x.apps", "/"))
+	assert.True(t, cherry.Equals(err, rserrors.ErrHostReserved()), "%v", err)
+	_, err = ia.CreateIngress(user2, "ns2", newIngress("x", "x.apps", "/"))
+	assert.NoError(t, err)
+	_, err = ha.ReserveHost(user2, host.ReservationRequest{Host: "app"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrIngressHostConflict()), "host used by another user can't be reserved: %v", err)
+
+	reservations, err := ha.GetHostReservationsList(user2)
+	if assert.NoError(t, err) && assert.Len(t, reservations.Reservations, 1) {
+		assert.Equal(t, "*.apps.test", reservations.Reservations[0].Host)
+	}
+	assert.True(t, cherry.Equals(ha.DeleteHostReservation(user1, "*.apps.test"), rserrors.ErrResourceNotExists()))
+	assert.True(t, cherry.Equals(ha.DeleteHostReservation(context.WithValue(user1, httputil.UserRoleContextKey, server.RoleAdmin), "*.apps.test"), rserrors.ErrResourceNotExists()),
+		"admin deletes reservations of other users only with admin route")
+	assert.NoError(t, ha.DeleteAnyHostReservation(admin, "*.apps.test"))
+
+	// hosts are released on update and delete
+	_, err = ia.UpdateIngress(user1, "ns1", newIngress("app", "app", "/old"))
+	assert.NoError(t, err)
+	_, err = ia.CreateIngress(user1, "ns1", newIngress("web", "app", "/"))
+	assert.NoError(t, err)
+	for _, name := range []string{"app", "api", "web"} {
+		assert.NoError(t, ia.DeleteIngress(user1, "ns1", name))
+	}
+	_, err = ia.CreateIngress(user2, "ns2", newIngress("old", "app", "/old"))
+	assert.NoError(t, err)
+}
+
+func TestHostReservationLimits(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var ha = NewHostActionsImpl(mongo, nil, ".test")
+	var user = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := ha.ReserveHost(user, host.ReservationRequest{Host: "*..apps"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "%v", err)
+
+	noSuffix := NewHostActionsImpl(mongo, nil, "")
+	_, err = noSuffix.ReserveHost(user, host.ReservationRequest{Host: "*.com"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "top-level domain can't be reserved: %v", err)
+	_, err = noSuffix.ReserveHost(server.WithDryRun(user), host.ReservationRequest{Host: "*.example.com"})
+	assert.NoError(t, err)
+
+	for i := 0; i < maxHostReservations; i++ {
+		_, err = ha.ReserveHost(user, host.ReservationRequest{Host: fmt.Sprintf("*.app%v", i)})
+		assert.NoError(t, err)
+	}
+	_, err = ha.ReserveHost(user, host.ReservationRequest{Host: "one-more"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrTooManyHostReservations()), "%v", err)
+}
+
+func testCAIssuer(t *testing.T) *certs.CAIssuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	issuer, err := certs.NewCAIssuer(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		time.Hour)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return issuer
+}
+
+func TestIngressAutoTLS(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+	box, err := secretbox.New(testSecretKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var port = 80
+	_, err = mongo.CreateService(service.FromKube("ns", "", service.Internal, kubtypes.Service{
+		Name:   "web",
+		Deploy: "web",
+		Ports:  []kubtypes.ServicePort{{Name: "http", Port: &port, TargetPort: 80, Protocol: kubtypes.TCP}},
+	}))
+	assert.NoError(t, err)
+	var request = func(name string, tls ingress.TLSMode, hosts ...string) ingress.IngressRequest {
+		var req = ingress.IngressRequest{Ingress: kubtypes.Ingress{Name: name}, TLS: tls}
+		for _, h := range hosts {
+			req.Rules = append(req.Rules, kubtypes.Rule{Host: h, Path: []kubtypes.Path{{ServiceName: "web", ServicePort: port}}})
+		}
+		return req
+	}
+
+	// auto mode is not available without issuer
+	var ia = NewIngressActionsImpl(mongo, &kube, ob, NewGraphActionsImpl(mongo), nil, nil, ".test")
+	_, err = ia.CreateIngress(ctx, "ns", request("app", ingress.TLSAuto, "app"))
+	assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "%v", err)
+
+	var ci = NewCertificatesImpl(mongo, &kube, box, testCAIssuer(t), 10*time.Minute, nil)
+	ia = NewIngressActionsImpl(mongo, &kube, ob, NewGraphActionsImpl(mongo), nil, ci, ".test")
+
+	var secretName = "own"
+	var withSecret = request("app", ingress.TLSAuto, "app")
+	withSecret.Rules[0].TLSSecret = &secretName
+	_, err = ia.CreateIngress(ctx, "ns", withSecret)
+	assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "tls secret can't be set in auto mode: %v", err)
+
+	// secret created by user isn't overwritten by certificate
+	_, err = mongo.CreateSecret(secret.FromKube("ns", "", kubtypes.Secret{Name: "own-tls"}))
+	assert.NoError(t, err)
+	_, err = ia.CreateIngress(server.WithDryRun(ctx), "ns", request("own", ingress.TLSAuto, "own"))
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceAlreadyExists()), "%v", err)
+
+	created, err := ia.CreateIngress(ctx, "ns", request("app", ingress.TLSAuto, "app"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, ingress.TLSAuto, created.TLS)
+		if assert.NotNil(t, created.Rules[0].TLSSecret) {
+			assert.Equal(t, "app-tls", *created.Rules[0].TLSSecret)
+		}
+	}
+	_, err = mongo.GetSecret("ns", "app-tls")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "certificate is issued after ingress is created: %v", err)
+	assert.Len(t, ci.wake, 1, "certificate issue is started")
+	<-ci.wake
+	assert.NoError(t, ci.renew(ctx))
+	tlsSecret, err := mongo.GetSecret("ns", "app-tls")
+	if assert.NoError(t, err, "certificate is saved to secret") {
+		data, err := box.Open(tlsSecret.Data)
+		assert.NoError(t, err)
+		assert.Contains(t, data[tlsCertKey], "BEGIN CERTIFICATE")
+		assert.Contains(t, data[tlsKeyKey], "PRIVATE KEY")
+	}
+	cert, err := mongo.GetCertificate("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"app.test"}, cert.Hosts)
+		assert.Equal(t, "ca", cert.Issuer)
+	}
+
+	// certificate is reissued for new hosts, tls secret received from api is accepted
+	var update = request("app", "", "app", "www")
+	update.Rules[0].TLSSecret = created.Rules[0].TLSSecret
+	updated, err := ia.UpdateIngress(ctx, "ns", update)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ingress.TLSAuto, updated.TLS, "tls mode is kept on update")
+		assert.Equal(t, "app-tls", *updated.Rules[1].TLSSecret)
+	}
+	cert, err = mongo.GetCertificate("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"app.test"}, cert.Hosts, "certificate of old hosts is used until new one is issued")
+	}
+	assert.NoError(t, ci.renew(ctx))
+	cert, err = mongo.GetCertificate("ns", "app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"app.test", "www.test"}, cert.Hosts)
+	}
+
+	// certificate isn't requested again if it covers hosts
+	<-ci.wake
+	_, err = ia.UpdateIngress(ctx, "ns", request("app", "", "www", "app"))
+	assert.NoError(t, err)
+	assert.Len(t, ci.wake, 0)
+
+	// expiring certificate is renewed
+	cert.NotAfter = time.Now().Add(time.Minute)
+	assert.NoError(t, mongo.SetCertificate(cert))
+	assert.NoError(t, ci.renew(ctx))
+	cert, err = mongo.GetCertificate("ns", "app")
+	if assert.NoError(t, err) {
+		assert.True(t, cert.NotAfter.After(time.Now().Add(30*time.Minute)), "certificate is renewed: %v", cert.NotAfter)
+	}
+
+	// certificate is deleted when ingress switches to manual mode
+	updated, err = ia.UpdateIngress(ctx, "ns", ingress.IngressRequest{Ingress: updated.Ingress, TLS: ingress.TLSManual})
+	if assert.NoError(t, err) {
+		assert.Nil(t, updated.Rules[0].TLSSecret)
+	}
+	_, err = mongo.GetCertificate("ns", "app")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+	_, err = mongo.GetSecret("ns", "app-tls")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+
+	// and when ingress is deleted
+	_, err = ia.CreateIngress(ctx, "ns", request("web", ingress.TLSAuto, "web"))
+	assert.NoError(t, err)
+	assert.NoError(t, ia.DeleteIngress(ctx, "ns", "web"))
+	_, err = mongo.GetCertificate("ns", "web")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+	_, err = mongo.GetSecret("ns", "web-tls")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+}
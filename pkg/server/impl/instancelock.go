@@ -0,0 +1,92 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// instanceLockName -- name of lock held by running instance of resource-service
+const instanceLockName = "resource-service"
+
+// InstanceLockImpl allows only one instance of resource-service to run.
+// Watch history and rollout watchers live in process memory and background workers are not coordinated,
+// so second instance waits until lock of first one is released or expires.
+type InstanceLockImpl struct {
+	mongo  db.Storage
+	log    *cherrylog.LogrusAdapter
+	holder string
+	ttl    time.Duration
+}
+
+func NewInstanceLockImpl(mongo db.Storage, ttl time.Duration) *InstanceLockImpl {
+	return &InstanceLockImpl{
+		mongo:  mongo,
+		log:    cherrylog.NewLogrusAdapter(logrus.WithField("component", "instance_lock")),
+		holder: uuid.New().String(),
+		ttl:    ttl,
+	}
+}
+
+// Acquire waits until lock is taken or context is done
+func (l *InstanceLockImpl) Acquire(ctx context.Context) error {
+	retry := time.NewTicker(l.ttl / 3)
+	defer retry.Stop()
+	for {
+		err := l.mongo.AcquireLock(instanceLockName, l.holder, time.Now().Add(l.ttl))
+		if err == nil {
+			l.log.WithField("holder", l.holder).Info("instance lock acquired")
+			return nil
+		}
+		if !cherry.Equals(err, rserrors.ErrResourceAlreadyExists()) {
+			return err
+		}
+		l.log.Warn("another instance is running, waiting for its lock to be released")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-retry.C:
+		}
+	}
+}
+
+// Hold extends acquired lock until context is done. If lock can't be extended before it expires,
+// another instance may take it, so lost is called.
+func (l *InstanceLockImpl) Hold(ctx context.Context, lost func(err error)) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	extended := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		err := l.mongo.AcquireLock(instanceLockName, l.holder, time.Now().Add(l.ttl))
+		switch {
+		case err == nil:
+			extended = time.Now()
+		case cherry.Equals(err, rserrors.ErrResourceAlreadyExists()) || time.Since(extended) >= l.ttl:
+			lost(err)
+			return
+		default:
+			l.log.WithError(err).Warn("unable to extend instance lock, retrying")
+		}
+	}
+}
+
+// Release releases lock, so next instance can start without waiting for lock expiration
+func (l *InstanceLockImpl) Release() {
+	if err := l.mongo.ReleaseLock(instanceLockName, l.holder); err != nil {
+		l.log.WithError(err).Error("unable to release instance lock")
+	}
+}
@@ -0,0 +1,37 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceLock(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var first = NewInstanceLockImpl(mongo, 30*time.Millisecond)
+	var second = NewInstanceLockImpl(mongo, 30*time.Millisecond)
+
+	assert.NoError(t, first.Acquire(context.Background()))
+
+	// first instance holds lock, so second one waits until context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	holdCtx, stopHold := context.WithCancel(context.Background())
+	held := make(chan struct{})
+	go func() {
+		defer close(held)
+		first.Hold(holdCtx, func(err error) { t.Errorf("lock is lost: %v", err) })
+	}()
+	assert.Equal(t, context.DeadlineExceeded, second.Acquire(ctx))
+	cancel()
+	stopHold()
+	<-held
+
+	// released lock is taken without waiting for expiration
+	first.Release()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, second.Acquire(ctx))
+}
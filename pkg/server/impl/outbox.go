@@ -0,0 +1,241 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxLease is a time given to apply operation before worker picks it again
+	outboxLease      = time.Minute
+	outboxMinBackoff = 5 * time.Second
+	outboxMaxBackoff = 10 * time.Minute
+)
+
+// OutboxImpl records kube-api operations in db and applies them.
+// Operations which were not applied during request (e.g. if service was stopped) are applied by Run.
+type OutboxImpl struct {
+	kube  clients.Kube
+	mongo db.Storage
+	box   *secretbox.Box
+	watch *WatchImpl
+	log   *cherrylog.LogrusAdapter
+}
+
+// NewOutboxImpl creates outbox. Operations applied by worker are published to watch,
+// operations applied during request are published by audit log of request.
+func NewOutboxImpl(mongo db.Storage, kube *clients.Kube, box *secretbox.Box, watcher *WatchImpl) *OutboxImpl {
+	return &OutboxImpl{
+		kube:  *kube,
+		mongo: mongo,
+		box:   box,
+		watch: watcher,
+		log:   cherrylog.NewLogrusAdapter(logrus.WithField("component", "outbox")),
+	}
+}
+
+// Enqueue records operation for resource which is going to be written to db.
+// Operation is not picked by worker until lease passes, so it is applied by request which created it.
+func (o *OutboxImpl) Enqueue(ctx context.Context, kind outbox.Kind, nsID, name string) (outbox.Operation, error) {
+	op := outbox.NewOperation(kind, nsID, name, time.Now().Add(outboxLease))
+	op.UserID, op.UserRole = requestUser(ctx)
+	return o.mongo.CreateOperation(op)
+}
+
+// Apply performs operation in kube-api and marks it done.
+// If kube-api rejects operation, rollback removes resource from db, operation is cancelled and error is returned.
+// Other errors leave operation pending, so resource is created by worker later.
+func (o *OutboxImpl) Apply(ctx context.Context, op outbox.Operation, rollback func() error) error {
+	entry := o.log.WithFields(logrus.Fields{
+		"operation": op.ID,
+		"kind":      op.Kind,
+		"ns_id":     op.NamespaceID,
+		"name":      op.Name,
+	})
+
+	err := o.apply(ctx, op)
+	switch {
+	case err == nil:
+		op.Finish(outbox.Done, "")
+	case isClientError(err):
+		entry.WithError(err).Debug("Kube-API error! Deleting resource from DB.")
+		if err := rollback(); err != nil {
+			return err
+		}
+		op.Finish(outbox.Cancelled, err.Error())
+	default:
+		entry.WithError(err).Warn("unable to apply outbox operation, retrying")
+		op.Retry(time.Now().Add(outboxBackoff(op.Attempts+1)), err.Error())
+	}
+
+	if err := o.mongo.UpdateOperation(op); err != nil {
+		// pending operation is applied by worker once more
+		entry.WithError(err).Error("unable to update outbox operation")
+	}
+	if isClientError(err) {
+		return err
+	}
+	return nil
+}
+
+// Cancel marks operation cancelled, it is called when resource was not written to db
+func (o *OutboxImpl) Cancel(op outbox.Operation, reason error) {
+	op.Finish(outbox.Cancelled, reason.Error())
+	if err := o.mongo.UpdateOperation(op); err != nil {
+		o.log.WithError(err).WithField("operation", op.ID).Error("unable to cancel outbox operation")
+	}
+}
+
+// Run applies pending operations every period until context is done
+func (o *OutboxImpl) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		o.processPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *OutboxImpl) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		op, err := o.mongo.ClaimOperation(time.Now(), outboxLease)
+		if err != nil {
+			if !cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+				o.log.WithError(err).Error("unable to get pending outbox operation")
+			}
+			return
+		}
+		o.process(op)
+	}
+}
+
+func (o *OutboxImpl) process(op outbox.Operation) {
+	entry := o.log.WithFields(logrus.Fields{
+		"operation": op.ID,
+		"kind":      op.Kind,
+		"ns_id":     op.NamespaceID,
+		"name":      op.Name,
+		"attempt":   op.Attempts,
+	})
+
+	err := o.apply(userContext(op.UserID, op.UserRole), op)
+	switch {
+	case err == nil:
+		entry.Info("outbox operation applied")
+		op.Finish(outbox.Done, "")
+	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		entry.Info("resource was deleted, outbox operation cancelled")
+		op.Finish(outbox.Cancelled, err.Error())
+	case isClientError(err):
+		entry.WithError(err).Error("kube-api rejected outbox operation")
+		op.Finish(outbox.Failed, err.Error())
+	default:
+		entry.WithError(err).Warn("unable to apply outbox operation, retrying")
+		op.Retry(time.Now().Add(outboxBackoff(op.Attempts)), err.Error())
+	}
+
+	if err := o.mongo.UpdateOperation(op); err != nil {
+		entry.WithError(err).Error("unable to update outbox operation")
+	}
+	if kind, ok := outboxKinds[op.Kind]; ok && (op.Status == outbox.Done || op.Status == outbox.Failed) {
+		o.watch.notify(op.NamespaceID, watch.Kind(kind), op.Name, watch.Modified, nil)
+	}
+}
+
+// apply creates resource in kube-api from its db state. Existing resource is updated, so operation can be applied many times.
+func (o *OutboxImpl) apply(ctx context.Context, op outbox.Operation) error {
+	switch op.Kind {
+	case outbox.CreateDeployment:
+		depl, err := o.mongo.GetDeployment(op.NamespaceID, op.Name)
+		if err != nil {
+			return err
+		}
+		err = o.kube.CreateDeployment(ctx, op.NamespaceID, depl.Deployment, depl.Metadata, depl.Strategy, depl.ConfigHashes)
+		if isAlreadyExists(err) {
+			return o.kube.UpdateDeployment(ctx, op.NamespaceID, depl.Deployment, depl.Metadata, depl.Strategy, depl.ConfigHashes)
+		}
+		return err
+	case outbox.CreateService:
+		svc, err := o.mongo.GetService(op.NamespaceID, op.Name)
+		if err != nil {
+			return err
+		}
+		err = o.kube.CreateService(ctx, op.NamespaceID, svc.Service, svc.Metadata)
+		if isAlreadyExists(err) {
+			return o.kube.UpdateService(ctx, op.NamespaceID, svc.Service, svc.Metadata)
+		}
+		return err
+	case outbox.CreateIngress:
+		ingr, err := o.mongo.GetIngress(op.NamespaceID, op.Name)
+		if err != nil {
+			return err
+		}
+		err = o.kube.CreateIngress(ctx, op.NamespaceID, ingr.Ingress, ingr.Metadata)
+		if isAlreadyExists(err) {
+			return o.kube.UpdateIngress(ctx, op.NamespaceID, ingr.Ingress, ingr.Metadata)
+		}
+		return err
+	case outbox.CreateConfigMap:
+		cm, err := o.mongo.GetConfigMap(op.NamespaceID, op.Name)
+		if err != nil {
+			return err
+		}
+		// active version is applied, so configmap updated before operation is done is not rolled back
+		err = o.kube.CreateConfigMap(ctx, op.NamespaceID, cm.ConfigMap, cm.Metadata)
+		if isAlreadyExists(err) {
+			return o.kube.UpdateConfigMap(ctx, op.NamespaceID, cm.ConfigMap, cm.Metadata)
+		}
+		return err
+	case outbox.CreateSecret:
+		secret, err := o.mongo.GetSecret(op.NamespaceID, op.Name)
+		if err != nil {
+			return err
+		}
+		if secret.Data, err = o.box.Open(secret.Data); err != nil {
+			return rserrors.ErrInternal().Log(err, o.log)
+		}
+		err = o.kube.CreateSecret(ctx, op.NamespaceID, secret.Secret)
+		if isAlreadyExists(err) {
+			return o.kube.UpdateSecret(ctx, op.NamespaceID, secret.Secret)
+		}
+		return err
+	default:
+		return rserrors.ErrInternal().AddDetailF("unknown outbox operation %v", op.Kind)
+	}
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxMinBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
+
+func isAlreadyExists(err error) bool {
+	cherr, ok := err.(*cherry.Err)
+	return ok && cherr.StatusHTTP == http.StatusConflict
+}
+
+func isClientError(err error) bool {
+	cherr, ok := err.(*cherry.Err)
+	return ok && cherr.StatusHTTP >= http.StatusBadRequest && cherr.StatusHTTP < http.StatusInternalServerError
+}
@@ -0,0 +1,145 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/blang/semver"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyKube struct {
+	clients.Kube
+	failures   int
+	reject     bool
+	created    []string
+	configMaps []kubtypes.ConfigMap
+	// configmaps which already exist in cluster
+	existing map[string]bool
+}
+
+func (kube *flakyKube) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	if kube.reject {
+		return rserrors.ErrValidation()
+	}
+	if kube.failures > 0 {
+		kube.failures--
+		return rserrors.ErrInternal()
+	}
+	kube.created = append(kube.created, deploy.Name)
+	return nil
+}
+
+func (kube *flakyKube) CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
+	if kube.existing[cm.Name] {
+		return rserrors.ErrResourceAlreadyExists()
+	}
+	kube.configMaps = append(kube.configMaps, cm)
+	return nil
+}
+
+func (kube *flakyKube) UpdateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap, meta labels.Metadata) error {
+	kube.configMaps = append(kube.configMaps, cm)
+	return nil
+}
+
+func TestOutboxRetry(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube clients.Kube = &flakyKube{Kube: clients.NewDummyKube(), failures: 1}
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+
+	_, err := mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{Name: "app", Active: true}))
+	assert.NoError(t, err)
+	// operation left by stopped service
+	op, err := mongo.CreateOperation(outbox.NewOperation(outbox.CreateDeployment, "ns", "app", time.Now()))
+	assert.NoError(t, err)
+	_, err = mongo.CreateOperation(outbox.NewOperation(outbox.CreateDeployment, "ns", "deleted", time.Now()))
+	assert.NoError(t, err)
+
+	ob.processPending(context.Background())
+	assert.Empty(t, kube.(*flakyKube).created)
+
+	// nothing to claim until backoff passes
+	_, err = mongo.ClaimOperation(time.Now(), outboxLease)
+	assert.Error(t, err)
+
+	claimed, err := mongo.ClaimOperation(time.Now().Add(outboxBackoff(1)), outboxLease)
+	assert.NoError(t, err)
+	assert.Equal(t, op.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+	ob.process(claimed)
+	assert.Equal(t, []string{"app"}, kube.(*flakyKube).created)
+
+	_, err = mongo.ClaimOperation(time.Now().Add(time.Hour), outboxLease)
+	assert.Error(t, err, "all operations are finished")
+}
+
+func TestOutboxApply(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var flaky = &flakyKube{Kube: clients.NewDummyKube(), failures: 1}
+	var kube clients.Kube = flaky
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kube, ob, NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	// transient kube-api error keeps deployment, operation is retried by worker
+	_, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: kubtypes.Deployment{Name: "app", Replicas: 1}})
+	assert.NoError(t, err)
+	_, err = mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Empty(t, flaky.created)
+
+	op, err := mongo.ClaimOperation(time.Now().Add(outboxBackoff(1)), outboxLease)
+	assert.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", op.UserID)
+	ob.process(op)
+	assert.Equal(t, []string{"app"}, flaky.created)
+
+	// rejected deployment is removed from db
+	flaky.reject = true
+	_, err = da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{Deployment: kubtypes.Deployment{Name: "invalid", Replicas: 1}})
+	assert.Error(t, err)
+	_, err = mongo.GetDeployment("ns", "invalid")
+	assert.Error(t, err)
+	_, err = mongo.ClaimOperation(time.Now().Add(time.Hour), outboxLease)
+	assert.Error(t, err, "operation is cancelled")
+}
+
+func TestOutboxConfigMap(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var flaky = &flakyKube{Kube: clients.NewDummyKube(), existing: map[string]bool{"cm": true}}
+	var kube clients.Kube = flaky
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+
+	_, err := mongo.CreateConfigMap(configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "cm", Data: kubtypes.ConfigMapData{"key": "old"}}))
+	assert.NoError(t, err)
+	_, err = ob.Enqueue(context.Background(), outbox.CreateConfigMap, "ns", "cm")
+	assert.NoError(t, err)
+
+	// configmap is updated before operation is applied by worker
+	assert.NoError(t, mongo.DeactivateConfigMap("ns", "cm", 0))
+	updated := configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "cm", Data: kubtypes.ConfigMapData{"key": "new"}})
+	updated.Version = semver.MustParse("1.0.1")
+	_, err = mongo.CreateConfigMap(updated)
+	assert.NoError(t, err)
+
+	op, err := mongo.ClaimOperation(time.Now().Add(outboxLease), outboxLease)
+	assert.NoError(t, err)
+	ob.process(op)
+	if assert.Len(t, flaky.configMaps, 1, "existing configmap is updated") {
+		assert.Equal(t, "new", flaky.configMaps[0].Data["key"], "active version is applied")
+	}
+	_, err = mongo.ClaimOperation(time.Now().Add(time.Hour), outboxLease)
+	assert.Error(t, err, "operation is done")
+}
@@ -0,0 +1,74 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// portProtocols -- protocols of external service ports
+var portProtocols = []kubtypes.Protocol{kubtypes.TCP, kubtypes.UDP}
+
+type PortActionsImpl struct {
+	mongo   db.Storage
+	log     *cherrylog.LogrusAdapter
+	minPort uint
+	maxPort uint
+}
+
+func NewPortActionsImpl(mongo db.Storage, minPort, maxPort uint) *PortActionsImpl {
+	return &PortActionsImpl{
+		mongo:   mongo,
+		log:     cherrylog.NewLogrusAdapter(logrus.WithField("component", "port_actions")),
+		minPort: minPort,
+		maxPort: maxPort,
+	}
+}
+
+// GetPortsUsage returns usage of every domain and protocol, including ones without reserved ports and removed domains
+func (pa *PortActionsImpl) GetPortsUsage(ctx context.Context) (*port.UsageResponse, error) {
+	pa.log.WithField("user_id", httputil.MustGetUserID(ctx)).Info("get ports usage")
+
+	domains, err := pa.mongo.GetDomainsList(nil)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := pa.mongo.GetPortsUsage(int(pa.minPort), int(pa.maxPort))
+	if err != nil {
+		return nil, err
+	}
+
+	var used = make(map[port.Key]int, len(reserved))
+	for _, usage := range reserved {
+		used[usage.Key()] = usage.Used
+	}
+	var listed = make(map[string]bool, len(domains))
+	var resp = port.UsageResponse{
+		MinPort: int(pa.minPort),
+		MaxPort: int(pa.maxPort),
+		Usage:   make([]port.Usage, 0, len(domains)*len(portProtocols)),
+	}
+	for _, domain := range domains {
+		listed[domain.Domain] = true
+		for _, protocol := range portProtocols {
+			resp.Usage = append(resp.Usage, port.Usage{
+				Domain:   domain.Domain,
+				Protocol: protocol,
+				Used:     used[port.Key{Domain: domain.Domain, Protocol: protocol}],
+				Total:    int(pa.maxPort) - int(pa.minPort),
+			})
+		}
+	}
+	// ports of removed domains stay reserved until services are deleted
+	for _, usage := range reserved {
+		if !listed[usage.Domain] {
+			resp.Usage = append(resp.Usage, usage)
+		}
+	}
+	return &resp, nil
+}
@@ -0,0 +1,740 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/blang/semver"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// reconcilerHeaders are passed to kube-api by periodic reconciliation
+var reconcilerHeaders = map[string]string{
+	httputil.UserIDXHeader:   "00000000-0000-0000-0000-000000000000",
+	httputil.UserRoleXHeader: "admin",
+}
+
+// errNotManaged is returned when resource which was not created by resource-service should be deleted from cluster
+var errNotManaged = errors.New("resource is not managed by resource-service")
+
+// outboxKinds -- kinds of resources created by outbox operations
+var outboxKinds = map[outbox.Kind]reconcile.Kind{
+	outbox.CreateDeployment: reconcile.Deployment,
+	outbox.CreateService:    reconcile.Service,
+	outbox.CreateIngress:    reconcile.Ingress,
+	outbox.CreateConfigMap:  reconcile.ConfigMap,
+}
+
+// driftEvents -- watch events of drifts repaired in db direction
+var driftEvents = map[reconcile.DriftType]watch.EventType{
+	reconcile.MissingInDB:      watch.Added,
+	reconcile.MissingInCluster: watch.Deleted,
+	reconcile.Changed:          watch.Modified,
+}
+
+type ReconcileActionsImpl struct {
+	kube      clients.Kube
+	mongo     db.Storage
+	log       *cherrylog.LogrusAdapter
+	direction reconcile.Direction
+	watch     *WatchImpl
+
+	// running is held by reconciliation, so manual and periodic ones don't repair the same drift concurrently
+	running sync.Mutex
+
+	mu   sync.Mutex
+	last *reconcile.DriftResponse
+}
+
+// NewReconcileActionsImpl creates reconciler. If direction is not empty, drift is repaired in this direction by default.
+// Drifts which are repaired by deleting resources are repaired only by requests with prune flag.
+// Changes of db are published to watch.
+func NewReconcileActionsImpl(mongo db.Storage, kube *clients.Kube, direction reconcile.Direction, watcher *WatchImpl) *ReconcileActionsImpl {
+	return &ReconcileActionsImpl{
+		kube:      *kube,
+		mongo:     mongo,
+		log:       cherrylog.NewLogrusAdapter(logrus.WithField("component", "reconcile_actions")),
+		direction: direction,
+		watch:     watcher,
+	}
+}
+
+// driftItem -- detected drift and functions repairing it in each direction
+type driftItem struct {
+	reconcile.Drift
+	repair map[reconcile.Direction]func(ctx context.Context) error
+}
+
+func (ra *ReconcileActionsImpl) GetDrift(ctx context.Context) (*reconcile.DriftResponse, error) {
+	ra.log.Info("get drift")
+
+	ra.mu.Lock()
+	last := ra.last
+	ra.mu.Unlock()
+	if last != nil {
+		return last, nil
+	}
+
+	return ra.reconcile(ctx, reconcile.ReconcileRequest{}, "")
+}
+
+func (ra *ReconcileActionsImpl) Reconcile(ctx context.Context, req reconcile.ReconcileRequest) (*reconcile.DriftResponse, error) {
+	ra.log.WithFields(logrus.Fields{
+		"direction": req.Direction,
+		"namespace": req.Namespace,
+		"kind":      req.Kind,
+		"name":      req.Name,
+	}).Info("reconcile")
+
+	direction := req.Direction
+	if direction == "" {
+		direction = ra.direction
+	}
+
+	return ra.reconcile(ctx, req, direction)
+}
+
+// Run reconciles all namespaces every period until context is done
+func (ra *ReconcileActionsImpl) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		resp, err := ra.reconcile(headersContext(reconcilerHeaders), reconcile.ReconcileRequest{}, ra.direction)
+		if err != nil {
+			ra.log.WithError(err).Error("unable to reconcile")
+			continue
+		}
+		if len(resp.Drifts) > 0 || len(resp.Errors) > 0 {
+			ra.log.WithFields(logrus.Fields{
+				"drifts": len(resp.Drifts),
+				"errors": len(resp.Errors),
+			}).Warn("drift detected")
+		}
+	}
+}
+
+func (ra *ReconcileActionsImpl) reconcile(ctx context.Context, req reconcile.ReconcileRequest, direction reconcile.Direction) (*reconcile.DriftResponse, error) {
+	ra.running.Lock()
+	defer ra.running.Unlock()
+
+	namespaces := []string{req.Namespace}
+	if req.Namespace == "" {
+		var err error
+		if namespaces, err = ra.namespaces(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &reconcile.DriftResponse{
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+		Direction: direction,
+		Drifts:    make([]reconcile.Drift, 0),
+	}
+	for _, nsID := range namespaces {
+		items, err := ra.namespaceDrift(ctx, nsID)
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("%v: %v", nsID, err))
+			continue
+		}
+		for _, item := range items {
+			if !req.Match(item.Drift) {
+				continue
+			}
+			// dry run only reports drifts which would be repaired
+			if direction != "" && !server.IsDryRun(ctx) {
+				ra.repair(ctx, &item, direction, req.Prune)
+			}
+			resp.Drifts = append(resp.Drifts, item.Drift)
+		}
+	}
+
+	if req.Namespace == "" && req.Kind == "" && req.Name == "" && !server.IsDryRun(ctx) {
+		ra.mu.Lock()
+		ra.last = resp
+		ra.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// namespaces returns namespaces which have resources in db or exist in kube-api
+func (ra *ReconcileActionsImpl) namespaces(ctx context.Context) ([]string, error) {
+	dbNamespaces, err := ra.mongo.GetNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	kubeNamespaces, err := ra.kube.GetNamespaceList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set = make(map[string]bool, len(dbNamespaces)+len(kubeNamespaces))
+	var namespaces []string
+	for _, nsID := range dbNamespaces {
+		if !set[nsID] {
+			set[nsID] = true
+			namespaces = append(namespaces, nsID)
+		}
+	}
+	for _, ns := range kubeNamespaces {
+		if !set[ns.ID] {
+			set[ns.ID] = true
+			namespaces = append(namespaces, ns.ID)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (ra *ReconcileActionsImpl) repair(ctx context.Context, item *driftItem, direction reconcile.Direction, prune bool) {
+	entry := ra.log.WithFields(logrus.Fields{
+		"kind":      item.Kind,
+		"namespace": item.Namespace,
+		"name":      item.Name,
+		"drift":     item.Type,
+		"direction": direction,
+	})
+
+	if item.Destructive(direction) && !prune {
+		item.Error = fmt.Sprintf("%v drift is repaired in %v direction by deleting resource, prune is not requested", item.Type, direction)
+		entry.Debug(item.Error)
+		return
+	}
+
+	repair := item.repair[direction]
+	if repair == nil {
+		item.Error = fmt.Sprintf("%v drift can't be repaired in %v direction", item.Type, direction)
+		entry.Warn(item.Error)
+		return
+	}
+	if err := repair(ctx); err != nil {
+		item.Error = err.Error()
+		entry.WithError(err).Error("unable to repair drift")
+		return
+	}
+	item.Repaired = true
+	entry.Info("drift repaired")
+	if direction == reconcile.ToDB {
+		// reconcile kinds are named as watch kinds
+		ra.watch.notify(item.Namespace, watch.Kind(item.Kind), item.Name, driftEvents[item.Type], nil)
+	}
+}
+
+// namespaceDrift returns drifts of namespace. Resources which outbox operations are not applied yet are skipped,
+// they are going to be created in kube-api.
+func (ra *ReconcileActionsImpl) namespaceDrift(ctx context.Context, nsID string) ([]driftItem, error) {
+	ops, err := ra.mongo.GetPendingOperations(nsID)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[reconcile.Kind]map[string]bool)
+	for _, op := range ops {
+		kind, ok := outboxKinds[op.Kind]
+		if !ok {
+			continue
+		}
+		if pending[kind] == nil {
+			pending[kind] = make(map[string]bool)
+		}
+		pending[kind][op.Name] = true
+	}
+
+	var items []driftItem
+	for _, drift := range []func(ctx context.Context, nsID string) ([]driftItem, error){
+		ra.deploymentsDrift,
+		ra.servicesDrift,
+		ra.ingressesDrift,
+		ra.configMapsDrift,
+	} {
+		found, err := drift(ctx, nsID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range found {
+			if !pending[item.Kind][item.Name] {
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
+
+func (ra *ReconcileActionsImpl) deploymentsDrift(ctx context.Context, nsID string) ([]driftItem, error) {
+	dbList, _, err := ra.mongo.GetDeploymentList(nsID, nil)
+	if err != nil {
+		return nil, err
+	}
+	kubeList, err := ra.kube.GetDeploymentList(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	// canary versions run as separate kubernetes deployments
+	expected := make(map[string]deployment.ResourceDeploy)
+	var expectedNames []string
+	for _, depl := range dbList {
+		expected[depl.Name] = depl
+		expectedNames = append(expectedNames, depl.Name)
+		canary, err := ra.mongo.GetCanaryDeployment(nsID, depl.Name)
+		switch {
+		case err == nil:
+			expected[deployment.CanaryName(depl.Name)] = canary
+			expectedNames = append(expectedNames, deployment.CanaryName(depl.Name))
+		case !cherry.Equals(err, rserrors.ErrResourceNotExists()):
+			return nil, err
+		}
+	}
+	inCluster := make(map[string]kubtypes.Deployment)
+	managed := make(map[string]bool)
+	var clusterNames []string
+	for _, depl := range kubeList {
+		inCluster[depl.Name] = depl.Deployment
+		managed[depl.Name] = depl.IsManaged()
+		clusterNames = append(clusterNames, depl.Name)
+	}
+	sort.Strings(expectedNames)
+	sort.Strings(clusterNames)
+
+	var items []driftItem
+	for _, name := range expectedNames {
+		name := name
+		dbDepl := expected[name]
+		kubeDepl := dbDepl.Deployment
+		kubeDepl.Name = name
+		clusterDepl, ok := inCluster[name]
+		if !ok {
+			items = append(items, newDriftItem(reconcile.Deployment, nsID, name, reconcile.MissingInCluster, nil,
+				func(ctx context.Context) error {
+					return ra.kube.CreateDeployment(ctx, nsID, kubeDepl, dbDepl.Metadata, dbDepl.Strategy, dbDepl.ConfigHashes)
+				},
+				func(ctx context.Context) error {
+					if dbDepl.Canary {
+						dbDepl.Active = false
+						dbDepl.Canary = false
+						return ra.mongo.UpdateCanaryDeployment(dbDepl)
+					}
+					return ra.mongo.DeleteDeployment(nsID, name)
+				}))
+			continue
+		}
+		if fields := deploymentDiff(dbDepl.Deployment, clusterDepl); len(fields) > 0 {
+			items = append(items, newDriftItem(reconcile.Deployment, nsID, name, reconcile.Changed, fields,
+				func(ctx context.Context) error {
+					return ra.kube.UpdateDeployment(ctx, nsID, kubeDepl, dbDepl.Metadata, dbDepl.Strategy, dbDepl.ConfigHashes)
+				},
+				func(ctx context.Context) error {
+					upd := dbDepl.Copy()
+					upd.Replicas = clusterDepl.Replicas
+					upd.Containers = clusterDepl.Containers
+					server.CalculateDeployResources(&upd.Deployment)
+					if upd.Canary {
+						return ra.mongo.UpdateCanaryDeployment(upd)
+					}
+					return ra.mongo.UpdateActiveDeployment(upd)
+				}))
+		}
+	}
+	for _, name := range clusterNames {
+		name := name
+		if _, ok := expected[name]; ok {
+			continue
+		}
+		clusterDepl := inCluster[name]
+		items = append(items, newDriftItem(reconcile.Deployment, nsID, name, reconcile.MissingInDB, nil,
+			func(ctx context.Context) error {
+				if !managed[name] {
+					return errNotManaged
+				}
+				return ra.kube.DeleteDeployment(ctx, nsID, name)
+			},
+			func(ctx context.Context) error {
+				imported := clusterDepl
+				server.CalculateDeployResources(&imported)
+				imported.Version = semver.MustParse("1.0.0")
+				imported.Active = true
+				_, err := ra.mongo.CreateDeployment(deployment.FromKube(nsID, imported.Owner, imported))
+				return err
+			}))
+	}
+	return items, nil
+}
+
+func (ra *ReconcileActionsImpl) servicesDrift(ctx context.Context, nsID string) ([]driftItem, error) {
+	dbList, _, err := ra.mongo.GetServiceList(nsID, nil)
+	if err != nil {
+		return nil, err
+	}
+	kubeList, err := ra.kube.GetServiceList(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]service.ResourceService)
+	var expectedNames []string
+	for _, svc := range dbList {
+		expected[svc.Name] = svc
+		expectedNames = append(expectedNames, svc.Name)
+	}
+	inCluster := make(map[string]kubtypes.Service)
+	managed := make(map[string]bool)
+	var clusterNames []string
+	for _, svc := range kubeList {
+		inCluster[svc.Name] = svc.Service
+		managed[svc.Name] = svc.IsManaged()
+		clusterNames = append(clusterNames, svc.Name)
+	}
+	sort.Strings(expectedNames)
+	sort.Strings(clusterNames)
+
+	var items []driftItem
+	for _, name := range expectedNames {
+		name := name
+		dbSvc := expected[name]
+		clusterSvc, ok := inCluster[name]
+		if !ok {
+			items = append(items, newDriftItem(reconcile.Service, nsID, name, reconcile.MissingInCluster, nil,
+				func(ctx context.Context) error {
+					return ra.kube.CreateService(ctx, nsID, dbSvc.Service, dbSvc.Metadata)
+				},
+				func(ctx context.Context) error {
+					return ra.mongo.DeleteService(nsID, name)
+				}))
+			continue
+		}
+		if fields := serviceDiff(dbSvc.Service, clusterSvc); len(fields) > 0 {
+			items = append(items, newDriftItem(reconcile.Service, nsID, name, reconcile.Changed, fields,
+				func(ctx context.Context) error {
+					return ra.kube.UpdateService(ctx, nsID, dbSvc.Service, dbSvc.Metadata)
+				},
+				func(ctx context.Context) error {
+					upd := dbSvc.Copy()
+					upd.Ports = clusterSvc.Ports
+					if clusterSvc.Deploy != "" {
+						upd.Deploy = clusterSvc.Deploy
+					}
+					_, err := ra.mongo.UpdateService(upd)
+					return err
+				}))
+		}
+	}
+	for _, name := range clusterNames {
+		name := name
+		if _, ok := expected[name]; ok {
+			continue
+		}
+		clusterSvc := inCluster[name]
+		items = append(items, newDriftItem(reconcile.Service, nsID, name, reconcile.MissingInDB, nil,
+			func(ctx context.Context) error {
+				if !managed[name] {
+					return errNotManaged
+				}
+				return ra.kube.DeleteService(ctx, nsID, name)
+			},
+			func(ctx context.Context) error {
+				adopted := service.FromKube(nsID, clusterSvc.Owner, server.DetermineServiceType(clusterSvc), clusterSvc)
+				if err := claimServicePorts(ra.mongo, adopted); err != nil {
+					return err
+				}
+				if _, err := ra.mongo.CreateService(adopted); err != nil {
+					ra.mongo.ReleaseServicePorts(nsID, name)
+					return err
+				}
+				return nil
+			}))
+	}
+	return items, nil
+}
+
+func (ra *ReconcileActionsImpl) ingressesDrift(ctx context.Context, nsID string) ([]driftItem, error) {
+	dbList, _, err := ra.mongo.GetIngressList(nsID, nil)
+	if err != nil {
+		return nil, err
+	}
+	kubeList, err := ra.kube.GetIngressList(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]ingress.ResourceIngress)
+	var expectedNames []string
+	for _, ingr := range dbList {
+		expected[ingr.Name] = ingr
+		expectedNames = append(expectedNames, ingr.Name)
+	}
+	inCluster := make(map[string]kubtypes.Ingress)
+	managed := make(map[string]bool)
+	var clusterNames []string
+	for _, ingr := range kubeList {
+		inCluster[ingr.Name] = ingr.Ingress
+		managed[ingr.Name] = ingr.IsManaged()
+		clusterNames = append(clusterNames, ingr.Name)
+	}
+	sort.Strings(expectedNames)
+	sort.Strings(clusterNames)
+
+	var items []driftItem
+	for _, name := range expectedNames {
+		name := name
+		dbIngr := expected[name]
+		clusterIngr, ok := inCluster[name]
+		if !ok {
+			items = append(items, newDriftItem(reconcile.Ingress, nsID, name, reconcile.MissingInCluster, nil,
+				func(ctx context.Context) error {
+					return ra.kube.CreateIngress(ctx, nsID, dbIngr.Ingress, dbIngr.Metadata)
+				},
+				func(ctx context.Context) error {
+					return ra.mongo.DeleteIngress(nsID, name)
+				}))
+			continue
+		}
+		if fields := ingressDiff(dbIngr.Ingress, clusterIngr); len(fields) > 0 {
+			items = append(items, newDriftItem(reconcile.Ingress, nsID, name, reconcile.Changed, fields,
+				func(ctx context.Context) error {
+					return ra.kube.UpdateIngress(ctx, nsID, dbIngr.Ingress, dbIngr.Metadata)
+				},
+				func(ctx context.Context) error {
+					upd := dbIngr
+					upd.Rules = clusterIngr.Rules
+					oldClaims, newClaims := host.Claims(nsID, dbIngr.Ingress), host.Claims(nsID, upd.Ingress)
+					added := host.Diff(newClaims, oldClaims)
+					if err := claimIngressHosts(ra.mongo, added); err != nil {
+						return err
+					}
+					if _, err := ra.mongo.UpdateIngress(upd); err != nil {
+						for _, claim := range added {
+							ra.mongo.ReleaseHost(claim)
+						}
+						return err
+					}
+					for _, claim := range host.Diff(oldClaims, newClaims) {
+						ra.mongo.ReleaseHost(claim)
+					}
+					return nil
+				}))
+		}
+	}
+	for _, name := range clusterNames {
+		name := name
+		if _, ok := expected[name]; ok {
+			continue
+		}
+		clusterIngr := inCluster[name]
+		items = append(items, newDriftItem(reconcile.Ingress, nsID, name, reconcile.MissingInDB, nil,
+			func(ctx context.Context) error {
+				if !managed[name] {
+					return errNotManaged
+				}
+				return ra.kube.DeleteIngress(ctx, nsID, name)
+			},
+			func(ctx context.Context) error {
+				adopted := ingress.FromKube(nsID, clusterIngr.Owner, clusterIngr)
+				if err := claimIngressHosts(ra.mongo, host.Claims(nsID, adopted.Ingress)); err != nil {
+					return err
+				}
+				if _, err := ra.mongo.CreateIngress(adopted); err != nil {
+					ra.mongo.ReleaseIngressHosts(nsID, name)
+					return err
+				}
+				return nil
+			}))
+	}
+	return items, nil
+}
+
+// configMapsDrift compares data only for configmaps which data is stored in db
+func (ra *ReconcileActionsImpl) configMapsDrift(ctx context.Context, nsID string) ([]driftItem, error) {
+	dbList, _, err := ra.mongo.GetConfigMapList(nsID, nil)
+	if err != nil {
+		return nil, err
+	}
+	kubeList, err := ra.kube.GetConfigMapList(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]configmap.ResourceConfigMap)
+	var expectedNames []string
+	for _, cm := range dbList {
+		expected[cm.Name] = cm
+		expectedNames = append(expectedNames, cm.Name)
+	}
+	inCluster := make(map[string]kubtypes.ConfigMap)
+	managed := make(map[string]bool)
+	var clusterNames []string
+	for _, cm := range kubeList {
+		inCluster[cm.Name] = cm.ConfigMap
+		managed[cm.Name] = cm.IsManaged()
+		clusterNames = append(clusterNames, cm.Name)
+	}
+	sort.Strings(expectedNames)
+	sort.Strings(clusterNames)
+
+	var items []driftItem
+	for _, name := range expectedNames {
+		name := name
+		dbCM := expected[name]
+		// configmaps created before data was stored in db can't be pushed to cluster
+		var toCluster func(ctx context.Context) error
+		if dbCM.Data != nil {
+			toCluster = func(ctx context.Context) error {
+				return ra.kube.CreateConfigMap(ctx, nsID, dbCM.ConfigMap, dbCM.Metadata)
+			}
+		}
+		clusterCM, ok := inCluster[name]
+		if !ok {
+			items = append(items, newDriftItem(reconcile.ConfigMap, nsID, name, reconcile.MissingInCluster, nil,
+				toCluster,
+				func(ctx context.Context) error {
+					return ra.mongo.DeleteConfigMap(nsID, name)
+				}))
+			continue
+		}
+		if dbCM.Data != nil && !configMapDataEqual(dbCM.Data, clusterCM.Data) {
+			items = append(items, newDriftItem(reconcile.ConfigMap, nsID, name, reconcile.Changed, []string{"data"},
+				func(ctx context.Context) error {
+					return ra.kube.UpdateConfigMap(ctx, nsID, dbCM.ConfigMap, dbCM.Metadata)
+				},
+				nil))
+		}
+	}
+	for _, name := range clusterNames {
+		name := name
+		if _, ok := expected[name]; ok {
+			continue
+		}
+		clusterCM := inCluster[name]
+		items = append(items, newDriftItem(reconcile.ConfigMap, nsID, name, reconcile.MissingInDB, nil,
+			func(ctx context.Context) error {
+				if !managed[name] {
+					return errNotManaged
+				}
+				return ra.kube.DeleteConfigMap(ctx, nsID, name)
+			},
+			func(ctx context.Context) error {
+				_, err := ra.mongo.CreateConfigMap(configmap.FromKube(nsID, clusterCM.Owner, clusterCM))
+				return err
+			}))
+	}
+	return items, nil
+}
+
+func newDriftItem(kind reconcile.Kind, nsID, name string, driftType reconcile.DriftType, fields []string, toCluster, toDB func(ctx context.Context) error) driftItem {
+	return driftItem{
+		Drift: reconcile.Drift{
+			Kind:      kind,
+			Namespace: nsID,
+			Name:      name,
+			Type:      driftType,
+			Fields:    fields,
+		},
+		repair: map[reconcile.Direction]func(ctx context.Context) error{
+			reconcile.ToCluster: toCluster,
+			reconcile.ToDB:      toDB,
+		},
+	}
+}
+
+func deploymentDiff(dbDepl, clusterDepl kubtypes.Deployment) []string {
+	var fields []string
+	if dbDepl.Replicas != clusterDepl.Replicas {
+		fields = append(fields, "replicas")
+	}
+	// old deployments are stored without containers
+	if len(dbDepl.Containers) > 0 && dbDepl.Containers[0].Name != "" && !containersEqual(dbDepl.Containers, clusterDepl.Containers) {
+		fields = append(fields, "containers")
+	}
+	return fields
+}
+
+func containersEqual(a, b []kubtypes.Container) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Image != b[i].Image || a[i].Limits != b[i].Limits {
+			return false
+		}
+	}
+	return true
+}
+
+func serviceDiff(dbSvc, clusterSvc kubtypes.Service) []string {
+	var fields []string
+	if dbSvc.Deploy != "" && clusterSvc.Deploy != "" && dbSvc.Deploy != clusterSvc.Deploy {
+		fields = append(fields, "deploy")
+	}
+	if !servicePortsEqual(dbSvc.Ports, clusterSvc.Ports) {
+		fields = append(fields, "ports")
+	}
+	return fields
+}
+
+func servicePortsEqual(a, b []kubtypes.ServicePort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].TargetPort != b[i].TargetPort || a[i].Protocol != b[i].Protocol {
+			return false
+		}
+		if a[i].Port != nil && b[i].Port != nil && *a[i].Port != *b[i].Port {
+			return false
+		}
+	}
+	return true
+}
+
+func ingressDiff(dbIngr, clusterIngr kubtypes.Ingress) []string {
+	if len(dbIngr.Rules) != len(clusterIngr.Rules) {
+		return []string{"rules"}
+	}
+	for i := range dbIngr.Rules {
+		a, b := dbIngr.Rules[i], clusterIngr.Rules[i]
+		if a.Host != b.Host || len(a.Path) != len(b.Path) {
+			return []string{"rules"}
+		}
+		for j := range a.Path {
+			if a.Path[j] != b.Path[j] {
+				return []string{"rules"}
+			}
+		}
+	}
+	return nil
+}
+
+func configMapDataEqual(a, b kubtypes.ConfigMapData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type listKube struct {
+	clients.Kube
+	namespaces  []kubtypes.Namespace
+	deployments []clients.KubeDeployment
+}
+
+func (kube *listKube) GetNamespaceList(ctx context.Context) ([]kubtypes.Namespace, error) {
+	return kube.namespaces, nil
+}
+
+func (kube *listKube) GetDeploymentList(ctx context.Context, nsID string) ([]clients.KubeDeployment, error) {
+	var list []clients.KubeDeployment
+	for _, depl := range kube.deployments {
+		if depl.Namespace == nsID {
+			list = append(list, depl)
+		}
+	}
+	return list, nil
+}
+
+func (kube *listKube) CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment, meta labels.Metadata, strategy *deployment.Strategy, configHashes map[string]string) error {
+	deploy.Namespace = nsID
+	kube.deployments = append(kube.deployments, clients.KubeDeployment{Deployment: deploy, Metadata: meta.Managed()})
+	return nil
+}
+
+func (kube *listKube) DeleteDeployment(ctx context.Context, nsID, deplName string) error {
+	for i, depl := range kube.deployments {
+		if depl.Namespace == nsID && depl.Name == deplName {
+			kube.deployments = append(kube.deployments[:i], kube.deployments[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube clients.Kube = &listKube{
+		Kube: clients.NewDummyKube(),
+		deployments: []clients.KubeDeployment{
+			{Deployment: kubtypes.Deployment{Name: "orphan", Namespace: "ns", Replicas: 1}},
+		},
+	}
+	var ra = NewReconcileActionsImpl(mongo, &kube, "", nil)
+
+	_, err := mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{Name: "app", Active: true, Replicas: 1}))
+	assert.NoError(t, err)
+
+	drift, err := ra.GetDrift(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, drift.Drifts, 2) {
+		assert.Equal(t, reconcile.MissingInCluster, drift.Drifts[0].Type)
+		assert.Equal(t, "app", drift.Drifts[0].Name)
+		assert.False(t, drift.Drifts[0].Repaired)
+		assert.Equal(t, reconcile.MissingInDB, drift.Drifts[1].Type)
+		assert.Equal(t, "orphan", drift.Drifts[1].Name)
+	}
+
+	resp, err := ra.Reconcile(context.Background(), reconcile.ReconcileRequest{Direction: reconcile.ToCluster, Name: "app"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Drifts, 1) {
+		assert.True(t, resp.Drifts[0].Repaired)
+	}
+
+	// deployment created outside of resource-service is not deleted from cluster
+	resp, err = ra.Reconcile(context.Background(), reconcile.ReconcileRequest{Direction: reconcile.ToCluster, Name: "orphan", Prune: true})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Drifts, 1) {
+		assert.False(t, resp.Drifts[0].Repaired)
+		assert.Equal(t, errNotManaged.Error(), resp.Drifts[0].Error)
+	}
+
+	resp, err = ra.Reconcile(context.Background(), reconcile.ReconcileRequest{Direction: reconcile.ToDB, Name: "orphan"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Drifts, 1) {
+		assert.True(t, resp.Drifts[0].Repaired)
+	}
+	_, err = mongo.GetDeployment("ns", "orphan")
+	assert.NoError(t, err)
+}
+
+func TestReconcilePrune(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var cluster = &listKube{
+		Kube:       clients.NewDummyKube(),
+		namespaces: []kubtypes.Namespace{{ID: "empty"}},
+		deployments: []clients.KubeDeployment{
+			{Deployment: kubtypes.Deployment{Name: "removed", Namespace: "empty", Replicas: 1}, Metadata: labels.Metadata{}.Managed()},
+		},
+	}
+	var kube clients.Kube = cluster
+	var ra = NewReconcileActionsImpl(mongo, &kube, reconcile.ToCluster, nil)
+
+	// deployment which is being created is not a drift
+	_, err := mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{Name: "creating", Active: true, Replicas: 1}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateOperation(outbox.NewOperation(outbox.CreateDeployment, "ns", "creating", time.Now().Add(time.Minute)))
+	assert.NoError(t, err)
+
+	// namespace without resources in db is checked too, default direction doesn't delete
+	resp, err := ra.Reconcile(context.Background(), reconcile.ReconcileRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Drifts, 1) {
+		assert.Equal(t, "empty", resp.Drifts[0].Namespace)
+		assert.Equal(t, "removed", resp.Drifts[0].Name)
+		assert.False(t, resp.Drifts[0].Repaired)
+	}
+	assert.Len(t, cluster.deployments, 1)
+
+	resp, err = ra.Reconcile(context.Background(), reconcile.ReconcileRequest{Prune: true})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Drifts, 1) {
+		assert.True(t, resp.Drifts[0].Repaired)
+	}
+	assert.Empty(t, cluster.deployments)
+}
@@ -4,21 +4,25 @@ import (
 	"context"
 
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
 	"git.containerum.net/ch/resource-service/pkg/models/resources"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	"github.com/containerum/utils/httputil"
 	"github.com/sirupsen/logrus"
 )
 
 type ResourcesActionsImpl struct {
-	mongo *db.MongoStorage
+	mongo db.Storage
 	log   *cherrylog.LogrusAdapter
+	audit *AuditImpl
 }
 
-func NewResourcesActionsImpl(mongo *db.MongoStorage) *ResourcesActionsImpl {
+func NewResourcesActionsImpl(mongo db.Storage, audit *AuditImpl) *ResourcesActionsImpl {
 	return &ResourcesActionsImpl{
 		mongo: mongo,
+		audit: audit,
 		log:   cherrylog.NewLogrusAdapter(logrus.WithField("component", "resource_service")),
 	}
 }
@@ -52,6 +56,11 @@ func (rs *ResourcesActionsImpl) GetResourcesCount(ctx context.Context) (*resourc
 		rs.log.Debug(err)
 		return nil, rserrors.ErrUnableCountResources()
 	}
+	secrets, err := rs.mongo.CountSecrets(userID)
+	if err != nil {
+		rs.log.Debug(err)
+		return nil, rserrors.ErrUnableCountResources()
+	}
 
 	ret := resources.GetResourcesCountResponse{
 		Ingresses:   ingresses,
@@ -60,6 +69,7 @@ func (rs *ResourcesActionsImpl) GetResourcesCount(ctx context.Context) (*resourc
 		IntServices: services.Internal,
 		Pods:        pods,
 		ConfigMaps:  cms,
+		Secrets:     secrets,
 	}
 
 	return &ret, nil
@@ -91,6 +101,11 @@ func (rs *ResourcesActionsImpl) GetAllResourcesCount(ctx context.Context) (*reso
 		rs.log.Debug(err)
 		return nil, rserrors.ErrUnableCountResources()
 	}
+	secrets, err := rs.mongo.CountAllSecrets()
+	if err != nil {
+		rs.log.Debug(err)
+		return nil, rserrors.ErrUnableCountResources()
+	}
 
 	ret := resources.GetResourcesCountResponse{
 		Ingresses:   ingresses,
@@ -99,13 +114,20 @@ func (rs *ResourcesActionsImpl) GetAllResourcesCount(ctx context.Context) (*reso
 		IntServices: services.Internal,
 		Pods:        pods,
 		ConfigMaps:  cms,
+		Secrets:     secrets,
 	}
 
 	return &ret, nil
 }
 
-func (rs *ResourcesActionsImpl) DeleteAllResourcesInNamespace(ctx context.Context, nsID string) error {
+func (rs *ResourcesActionsImpl) DeleteAllResourcesInNamespace(ctx context.Context, nsID string) (err error) {
 	rs.log.WithField("namespace_id", nsID).Info("deleting all resources")
+
+	defer func() { rs.audit.Record(ctx, nsID, audit.Resources, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
 	if err := rs.mongo.DeleteAllIngressesInNamespace(nsID); err != nil {
 		return err
 	}
@@ -118,12 +140,21 @@ func (rs *ResourcesActionsImpl) DeleteAllResourcesInNamespace(ctx context.Contex
 	if err := rs.mongo.DeleteAllConfigMapsInNamespace(nsID); err != nil {
 		return err
 	}
+	if err := rs.mongo.DeleteAllSecretsInNamespace(nsID); err != nil {
+		return err
+	}
 	return nil
 }
 
-func (rs *ResourcesActionsImpl) DeleteAllUserResources(ctx context.Context) error {
+func (rs *ResourcesActionsImpl) DeleteAllUserResources(ctx context.Context) (err error) {
 	userID := httputil.MustGetUserID(ctx)
 	rs.log.WithField("user_id", userID).Info("deleting all user resources")
+
+	defer func() { rs.audit.Record(ctx, "", audit.Resources, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
 	if err := rs.mongo.DeleteAllIngressesByOwner(userID); err != nil {
 		return err
 	}
@@ -136,5 +167,8 @@ func (rs *ResourcesActionsImpl) DeleteAllUserResources(ctx context.Context) erro
 	if err := rs.mongo.DeleteAllConfigMapsByOwner(userID); err != nil {
 		return err
 	}
+	if err := rs.mongo.DeleteAllSecretsByOwner(userID); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceVersion(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var kubeClient = clients.NewDummyKube()
+	var da = NewDeployActionsImpl(mongo, &permissions, &kubeClient, NewOutboxImpl(mongo, &kubeClient, nil, nil), NewGraphActionsImpl(mongo), nil, 0)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	created, err := da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: kubtypes.Deployment{
+			Name:     "app",
+			Replicas: 1,
+			Containers: []kubtypes.Container{
+				{Name: "app", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, created.ResourceVersion)
+
+	updated, err := da.SetDeploymentReplicas(server.WithIfMatch(ctx, created.ResourceVersion), "ns", "app", kubtypes.UpdateReplicas{Replicas: 2})
+	assert.NoError(t, err)
+	assert.True(t, updated.ResourceVersion > created.ResourceVersion)
+
+	// request made with version read before previous update
+	_, err = da.SetDeploymentReplicas(server.WithIfMatch(ctx, created.ResourceVersion), "ns", "app", kubtypes.UpdateReplicas{Replicas: 3})
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+	err = da.DeleteDeployment(server.WithIfMatch(ctx, created.ResourceVersion), "ns", "app", false)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+
+	// concurrent write between read and update is detected by storage
+	stale, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	_, err = da.SetDeploymentReplicas(ctx, "ns", "app", kubtypes.UpdateReplicas{Replicas: 4})
+	assert.NoError(t, err)
+	stale.Replicas = 5
+	err = mongo.UpdateActiveDeployment(stale)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+
+	active, err := mongo.GetDeployment("ns", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, active.Replicas)
+	assert.NoError(t, da.DeleteDeployment(server.WithIfMatch(ctx, active.ResourceVersion), "ns", "app", false))
+
+	// new version created between read and deactivation of old version
+	_, err = da.CreateDeployment(ctx, "ns", deployment.DeploymentRequest{
+		Deployment: kubtypes.Deployment{
+			Name:     "web",
+			Replicas: 1,
+			Containers: []kubtypes.Container{
+				{Name: "web", Image: "nginx:1.0.0", Limits: kubtypes.Resource{CPU: 100, Memory: 100}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	stale, err = mongo.GetDeployment("ns", "web")
+	assert.NoError(t, err)
+	_, err = da.SetDeploymentContainerImage(ctx, "ns", "web", kubtypes.UpdateImage{Container: "web", Image: "nginx:1.1.0"})
+	assert.NoError(t, err)
+	err = mongo.DeactivateDeployment("ns", "web", stale.ResourceVersion)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+
+	// concurrent requests creating new versions fail with conflict and leave one active version
+	var wg sync.WaitGroup
+	var errs = make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = da.SetDeploymentContainerImage(ctx, "ns", "web", kubtypes.UpdateImage{Container: "web", Image: fmt.Sprintf("nginx:1.2.%v", i)})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			assert.True(t, cherry.Equals(err, rserrors.ErrResourceVersionConflict()), "%v", err)
+		}
+	}
+	versions, _, err := mongo.GetDeploymentVersionsList("ns", "web", nil)
+	assert.NoError(t, err)
+	var activeVersions int
+	for _, version := range versions {
+		if version.Active {
+			activeVersions++
+		}
+	}
+	assert.Equal(t, 1, activeVersions)
+}
@@ -0,0 +1,305 @@
+package impl
+
+import (
+	"context"
+	"net/url"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// SecretActionsImpl stores secret values encrypted, they are decrypted only when single secret is requested or sent to kube-api
+type SecretActionsImpl struct {
+	kube   clients.Kube
+	mongo  db.Storage
+	log    *cherrylog.LogrusAdapter
+	outbox *OutboxImpl
+	deps   *GraphActionsImpl
+	audit  *AuditImpl
+	box    *secretbox.Box
+}
+
+func NewSecretActionsImpl(mongo db.Storage, kube *clients.Kube, outbox *OutboxImpl, deps *GraphActionsImpl, audit *AuditImpl, box *secretbox.Box) *SecretActionsImpl {
+	sa := &SecretActionsImpl{
+		kube:   *kube,
+		mongo:  mongo,
+		log:    cherrylog.NewLogrusAdapter(logrus.WithField("component", "secret_actions")),
+		outbox: outbox,
+		deps:   deps,
+		audit:  audit,
+		box:    box,
+	}
+	deps.SetDeleter(graph.Secret, sa.deleteSecret)
+	return sa
+}
+
+func (sa *SecretActionsImpl) GetSecretsList(ctx context.Context, nsID string, params url.Values) (*secret.SecretsResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"namespace": nsID,
+	}).Info("get user secrets")
+
+	query, err := db.ParseListQuery(params, db.SecretListFields)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, total, err := sa.mongo.GetSecretList(nsID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret.SecretsResponse{Secrets: secrets.WithoutData(), Total: total}, nil
+}
+
+func (sa *SecretActionsImpl) GetSelectedSecretsList(ctx context.Context, namespaces []string, params url.Values) (*secret.SecretsResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"namespaces": namespaces,
+	}).Info("get selected secrets")
+
+	query, err := db.ParseListQuery(params, db.SecretListFields)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, total, err := sa.mongo.GetSelectedSecrets(namespaces, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret.SecretsResponse{Secrets: secrets.WithoutData(), Total: total}, nil
+}
+
+func (sa *SecretActionsImpl) GetSecret(ctx context.Context, nsID, secretName string) (*secret.ResourceSecret, error) {
+	sa.log.Info("get secret")
+
+	resp, err := sa.mongo.GetSecret(nsID, secretName)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data, err = sa.box.Open(resp.Data); err != nil {
+		return nil, rserrors.ErrInternal().Log(err, sa.log)
+	}
+
+	return &resp, nil
+}
+
+func (sa *SecretActionsImpl) CreateSecret(ctx context.Context, nsID string, req kubtypes.Secret) (ret *secret.ResourceSecret, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+		"secret":  req.Name,
+	}).Info("create secret")
+
+	defer func() { sa.audit.Record(ctx, nsID, audit.Secret, req.Name, audit.Create, nil, ret, err) }()
+
+	newSecret, err := sa.seal(secret.FromKube(nsID, userID, req))
+	if err != nil {
+		return nil, err
+	}
+
+	if server.IsDryRun(ctx) {
+		_, err := sa.mongo.GetSecret(nsID, req.Name)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		newSecret = newSecret.WithoutData()
+		return &newSecret, nil
+	}
+
+	op, err := sa.outbox.Enqueue(ctx, outbox.CreateSecret, nsID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	createdSecret, err := sa.mongo.CreateSecret(newSecret)
+	if err != nil {
+		sa.outbox.Cancel(op, err)
+		return nil, err
+	}
+
+	if err := sa.outbox.Apply(ctx, op, func() error {
+		return sa.mongo.DeleteSecret(nsID, req.Name)
+	}); err != nil {
+		return nil, err
+	}
+
+	createdSecret = createdSecret.WithoutData()
+	return &createdSecret, nil
+}
+
+func (sa *SecretActionsImpl) ImportSecret(ctx context.Context, nsID string, req kubtypes.Secret) (err error) {
+	sa.log.WithFields(logrus.Fields{
+		"ns_id":  nsID,
+		"secret": req.Name,
+	}).Info("import secret")
+
+	defer func() {
+		imported := req
+		imported.Data = nil
+		sa.audit.Record(ctx, nsID, audit.Secret, req.Name, audit.Import, nil, imported, err)
+	}()
+
+	newSecret, err := sa.seal(secret.FromKube(nsID, req.Owner, req))
+	if err != nil {
+		return err
+	}
+
+	if server.IsDryRun(ctx) {
+		_, err := sa.mongo.GetSecret(nsID, req.Name)
+		return checkNotExists(err)
+	}
+
+	_, err = sa.mongo.CreateSecret(newSecret)
+	return err
+}
+
+func (sa *SecretActionsImpl) UpdateSecret(ctx context.Context, nsID string, req kubtypes.Secret) (ret *secret.ResourceSecret, err error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+		"secret":  req.Name,
+	}).Info("update secret")
+
+	before := sa.auditState(nsID, req.Name)
+	defer func() { sa.audit.Record(ctx, nsID, audit.Secret, req.Name, audit.Update, before, ret, err) }()
+
+	oldSecret, err := sa.mongo.GetSecret(nsID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.CheckIfMatch(ctx, oldSecret.ResourceVersion); err != nil {
+		return nil, err
+	}
+
+	newSecret := oldSecret.Copy()
+	newSecret.Data = req.Data
+	if newSecret, err = sa.seal(newSecret); err != nil {
+		return nil, err
+	}
+
+	if server.IsDryRun(ctx) {
+		newSecret = newSecret.WithoutData()
+		return &newSecret, nil
+	}
+
+	updatedSecret, err := sa.mongo.UpdateSecret(newSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeSecret := updatedSecret.Secret
+	kubeSecret.Data = req.Data
+	if err := sa.kube.UpdateSecret(ctx, nsID, kubeSecret); err != nil {
+		sa.log.Debug("Kube-API error! Reverting changes.")
+		oldSecret.ResourceVersion = 0
+		if _, err := sa.mongo.UpdateSecret(oldSecret); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	updatedSecret = updatedSecret.WithoutData()
+	return &updatedSecret, nil
+}
+
+func (sa *SecretActionsImpl) DeleteSecret(ctx context.Context, nsID, secretName string, cascade bool) error {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+		"secret":  secretName,
+		"cascade": cascade,
+	}).Info("delete secret")
+
+	if _, ok := server.IfMatch(ctx); ok {
+		sec, err := sa.mongo.GetSecret(nsID, secretName)
+		if err != nil {
+			return err
+		}
+		if err := server.CheckIfMatch(ctx, sec.ResourceVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := sa.deps.CheckDelete(ctx, nsID, graph.Node{Kind: graph.Secret, Name: secretName}, cascade); err != nil {
+		sa.audit.Record(ctx, nsID, audit.Secret, secretName, audit.Delete, sa.auditState(nsID, secretName), nil, err)
+		return err
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	return sa.deleteSecret(ctx, nsID, secretName)
+}
+
+func (sa *SecretActionsImpl) deleteSecret(ctx context.Context, nsID, secretName string) (err error) {
+	before := sa.auditState(nsID, secretName)
+	defer func() { sa.audit.Record(ctx, nsID, audit.Secret, secretName, audit.Delete, before, nil, err) }()
+
+	if err := sa.mongo.DeleteSecret(nsID, secretName); err != nil {
+		return err
+	}
+
+	if err := sa.kube.DeleteSecret(ctx, nsID, secretName); err != nil {
+		sa.log.Debug("Kube-API error! Reverting changes.")
+		if err := sa.mongo.RestoreSecret(nsID, secretName); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (sa *SecretActionsImpl) DeleteAllSecrets(ctx context.Context, nsID string) (err error) {
+	sa.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+	}).Info("delete all secrets")
+
+	defer func() { sa.audit.Record(ctx, nsID, audit.Secret, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	return sa.mongo.DeleteAllSecretsInNamespace(nsID)
+}
+
+// seal encrypts secret values before they are written to db
+func (sa *SecretActionsImpl) seal(s secret.ResourceSecret) (secret.ResourceSecret, error) {
+	sealed, err := sa.box.Seal(s.Data)
+	if err != nil {
+		return s, rserrors.ErrInternal().Log(err, sa.log)
+	}
+	s.Data = sealed
+	return s, nil
+}
+
+// auditState returns secret without values for audit log, nil if secret doesn't exist
+func (sa *SecretActionsImpl) auditState(nsID, secretName string) interface{} {
+	if sa.audit == nil {
+		return nil
+	}
+	s, err := sa.mongo.GetSecret(nsID, secretName)
+	if err != nil {
+		return nil
+	}
+	return s.WithoutData()
+}
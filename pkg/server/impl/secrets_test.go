@@ -0,0 +1,55 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSecretKey -- base64 of 32 bytes key
+const testSecretKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestSecretsEncryption(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	box, err := secretbox.New(testSecretKey)
+	assert.NoError(t, err)
+	var sa = NewSecretActionsImpl(mongo, &kube, NewOutboxImpl(mongo, &kube, box, nil), NewGraphActionsImpl(mongo), nil, box)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err = sa.CreateSecret(ctx, "ns", kubtypes.Secret{Name: "creds", Data: map[string]string{"password": "qwerty"}})
+	assert.NoError(t, err)
+
+	stored, err := mongo.GetSecret("ns", "creds")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "qwerty", stored.Data["password"])
+
+	list, err := sa.GetSecretsList(ctx, "ns", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, list.Secrets, 1) {
+		assert.Nil(t, list.Secrets[0].Data)
+	}
+
+	got, err := sa.GetSecret(ctx, "ns", "creds")
+	assert.NoError(t, err)
+	assert.Equal(t, "qwerty", got.Data["password"])
+
+	_, err = sa.UpdateSecret(ctx, "ns", kubtypes.Secret{Name: "creds", Data: map[string]string{"password": "12345"}})
+	assert.NoError(t, err)
+	got, err = sa.GetSecret(ctx, "ns", "creds")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", got.Data["password"])
+
+	other, err := secretbox.New("YW5vdGhlciAzMiBieXRlcyBrZXkgZm9yIHRlc3RzISE=")
+	assert.NoError(t, err)
+	stored, err = mongo.GetSecret("ns", "creds")
+	assert.NoError(t, err)
+	_, err = other.Open(stored.Data)
+	assert.Error(t, err, "secret must not be opened with other key")
+}
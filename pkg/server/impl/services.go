@@ -2,54 +2,69 @@ package impl
 
 import (
 	"context"
+	"net/url"
 
 	"git.containerum.net/ch/resource-service/pkg/clients"
 	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
 	"git.containerum.net/ch/resource-service/pkg/models/service"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
 	"git.containerum.net/ch/resource-service/pkg/server"
 	"git.containerum.net/ch/resource-service/pkg/util/coblog"
-	"github.com/containerum/cherry"
 	"github.com/containerum/cherry/adaptors/cherrylog"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/containerum/utils/httputil"
-	"github.com/globalsign/mgo"
 	"github.com/sirupsen/logrus"
 )
 
 type ServiceActionsImpl struct {
 	kube        clients.Kube
 	permissions clients.Permissions
-	mongo       *db.MongoStorage
+	mongo       db.Storage
 	log         *cherrylog.LogrusAdapter
-	minPort     uint
-	maxPort     uint
+	outbox      *OutboxImpl
+	deps        *GraphActionsImpl
+	audit       *AuditImpl
+	pool        *DomainPool
 }
 
-func NewServiceActionsImpl(mongo *db.MongoStorage, permissions *clients.Permissions, kube *clients.Kube, minPort, maxPort uint) *ServiceActionsImpl {
-	return &ServiceActionsImpl{
+func NewServiceActionsImpl(mongo db.Storage, permissions *clients.Permissions, kube *clients.Kube, outbox *OutboxImpl, deps *GraphActionsImpl, audit *AuditImpl, pool *DomainPool) *ServiceActionsImpl {
+	sa := &ServiceActionsImpl{
 		mongo:       mongo,
 		kube:        *kube,
 		permissions: *permissions,
 		log:         cherrylog.NewLogrusAdapter(logrus.WithField("component", "service_actions")),
-		minPort:     minPort,
-		maxPort:     maxPort,
+		outbox:      outbox,
+		deps:        deps,
+		audit:       audit,
+		pool:        pool,
 	}
+	deps.SetDeleter(graph.Service, sa.deleteService)
+	deps.SetRestorer(graph.Service, sa.restoreService)
+	return sa
 }
 
-func (sa *ServiceActionsImpl) GetServicesList(ctx context.Context, nsID string) (*service.ServicesResponse, error) {
+func (sa *ServiceActionsImpl) GetServicesList(ctx context.Context, nsID string, params url.Values) (*service.ServicesResponse, error) {
 	userID := httputil.MustGetUserID(ctx)
 	sa.log.WithFields(logrus.Fields{
 		"user_id":   userID,
 		"namespace": nsID,
 	}).Info("get services")
 
-	services, err := sa.mongo.GetServiceList(nsID)
+	query, err := db.ParseListQuery(params, db.ServiceListFields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &service.ServicesResponse{Services: services}, nil
+	services, total, err := sa.mongo.GetServiceList(nsID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.ServicesResponse{Services: services, Total: total}, nil
 }
 
 func (sa *ServiceActionsImpl) GetService(ctx context.Context, nsID, serviceName string) (*service.ResourceService, error) {
@@ -65,7 +80,7 @@ func (sa *ServiceActionsImpl) GetService(ctx context.Context, nsID, serviceName
 	return &ret, err
 }
 
-func (sa *ServiceActionsImpl) CreateService(ctx context.Context, nsID string, req kubtypes.Service) (*service.ResourceService, error) {
+func (sa *ServiceActionsImpl) CreateService(ctx context.Context, nsID string, req service.ServiceRequest) (ret *service.ResourceService, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	sa.log.WithFields(logrus.Fields{
 		"user_id": userID,
@@ -73,33 +88,19 @@ func (sa *ServiceActionsImpl) CreateService(ctx context.Context, nsID string, re
 	}).Info("create service")
 	coblog.Std.Struct(req)
 
-	_, err := sa.mongo.GetDeployment(nsID, req.Deploy)
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, req.Name, audit.Create, nil, ret, err) }()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
+	_, err = sa.mongo.GetDeployment(nsID, req.Deploy)
 	if err != nil {
 		sa.log.Error(err)
 		return nil, rserrors.ErrResourceNotExists().AddDetailF("deployment '%s' not exists", req.Deploy)
 	}
 
-	serviceType := server.DetermineServiceType(req)
-
-	if serviceType == service.External {
-		domain, err := sa.mongo.GetRandomDomain()
-		if err != nil {
-			if err == mgo.ErrNotFound {
-				return nil, rserrors.ErrNoDomainsAvailable()
-			}
-			return nil, err
-		}
-
-		req.Domain = domain.Domain
-		req.IPs = domain.IP
-		for i, port := range req.Ports {
-			externalPort, err := sa.mongo.GetFreePort(domain.Domain, port.Protocol, int(sa.minPort), int(sa.maxPort))
-			if err != nil {
-				return nil, err
-			}
-			req.Ports[i].Port = &externalPort
-		}
-	}
+	serviceType := server.DetermineServiceType(req.Service)
 
 	nsLimits, err := sa.permissions.GetNamespaceLimits(ctx, nsID)
 	if err != nil {
@@ -115,38 +116,103 @@ func (sa *ServiceActionsImpl) CreateService(ctx context.Context, nsID string, re
 		return nil, err
 	}
 
-	createdService, err := sa.mongo.CreateService(service.FromKube(nsID, userID, serviceType, req))
+	if server.IsDryRun(ctx) {
+		_, err := sa.mongo.GetService(nsID, req.Name)
+		if err := checkNotExists(err); err != nil {
+			return nil, err
+		}
+		// domain is selected, but ports are not reserved, so dry run changes nothing
+		if serviceType == service.External {
+			if _, err := sa.pool.CheckPlace(nsID, userID, &req.Service, nil); err != nil {
+				return nil, err
+			}
+		}
+		newService := service.FromKube(nsID, userID, serviceType, req.Service)
+		newService.Metadata = req.Metadata.Copy()
+		return &newService, nil
+	}
+
+	var reserved []port.Allocation
+	if serviceType == service.External {
+		if reserved, err = sa.pool.Place(nsID, userID, &req.Service); err != nil {
+			return nil, err
+		}
+	}
+
+	op, err := sa.outbox.Enqueue(ctx, outbox.CreateService, nsID, req.Name)
+	if err != nil {
+		sa.pool.Release(reserved)
+		return nil, err
+	}
+
+	newService := service.FromKube(nsID, userID, serviceType, req.Service)
+	newService.Metadata = req.Metadata.Copy()
+	createdService, err := sa.mongo.CreateService(newService)
 	if err != nil {
+		sa.pool.Release(reserved)
+		sa.outbox.Cancel(op, err)
 		return nil, err
 	}
 
-	if err := sa.kube.CreateService(ctx, nsID, req); err != nil {
-		sa.log.Debug("Kube-API error! Deleting service from DB.")
+	if err := sa.outbox.Apply(ctx, op, func() error {
 		if err := sa.mongo.DeleteService(nsID, req.Name); err != nil {
-			return nil, err
+			return err
 		}
+		sa.pool.Release(reserved)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 
 	return &createdService, nil
 }
 
-func (sa *ServiceActionsImpl) ImportService(ctx context.Context, nsID string, svc kubtypes.Service) error {
+// claimServicePorts reserves ports of service created outside of resource-service, e.g. imported one
+func claimServicePorts(mongo db.Storage, svc service.ResourceService) error {
+	var allocs = svc.PortAllocations()
+	for i, alloc := range allocs {
+		if err := mongo.ClaimPort(alloc); err != nil {
+			for _, claimed := range allocs[:i] {
+				mongo.ReleasePort(claimed)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (sa *ServiceActionsImpl) ImportService(ctx context.Context, nsID string, svc kubtypes.Service) (err error) {
 	sa.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("importing service")
 	coblog.Std.Struct(svc)
 
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, svc.Name, audit.Import, nil, svc, err) }()
+
 	serviceType := server.DetermineServiceType(svc)
 
-	if _, err := sa.mongo.CreateService(service.FromKube(nsID, svc.Owner, serviceType, svc)); err != nil {
+	// ports of existing service must not be released if import fails
+	_, err = sa.mongo.GetService(nsID, svc.Name)
+	if err := checkNotExists(err); err != nil {
+		return err
+	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	newService := service.FromKube(nsID, svc.Owner, serviceType, svc)
+	if err := claimServicePorts(sa.mongo, newService); err != nil {
+		return err
+	}
+	if _, err := sa.mongo.CreateService(newService); err != nil {
+		sa.pool.Release(newService.PortAllocations())
 		return err
 	}
 
 	return nil
 }
 
-func (sa *ServiceActionsImpl) UpdateService(ctx context.Context, nsID string, req kubtypes.Service) (*service.ResourceService, error) {
+func (sa *ServiceActionsImpl) UpdateService(ctx context.Context, nsID string, req service.ServiceRequest) (ret *service.ResourceService, err error) {
 	userID := httputil.MustGetUserID(ctx)
 	sa.log.WithFields(logrus.Fields{
 		"user_id":      userID,
@@ -154,10 +220,20 @@ func (sa *ServiceActionsImpl) UpdateService(ctx context.Context, nsID string, re
 		"service_name": req.Name,
 	}).Info("update service")
 
+	before := sa.auditState(nsID, req.Name)
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, req.Name, audit.Update, before, ret, err) }()
+
+	if errs := req.Metadata.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+
 	oldService, err := sa.mongo.GetService(nsID, req.Name)
 	if err != nil {
 		return nil, err
 	}
+	if err := server.CheckIfMatch(ctx, oldService.ResourceVersion); err != nil {
+		return nil, err
+	}
 
 	if len(oldService.Ports) == 0 {
 		kubeSvc, err := sa.kube.GetService(ctx, nsID, oldService.Name)
@@ -169,69 +245,187 @@ func (sa *ServiceActionsImpl) UpdateService(ctx context.Context, nsID string, re
 		oldService.IPs = kubeSvc.IPs
 	}
 
-	serviceType := server.DetermineServiceType(req)
+	serviceType := server.DetermineServiceType(req.Service)
 
+	var reserved []port.Allocation
 	if serviceType == service.External {
-		domain, err := sa.mongo.GetRandomDomain()
-		if err != nil {
-			if err == mgo.ErrNotFound {
-				return nil, rserrors.ErrNoDomainsAvailable()
-			}
+		if reserved, err = sa.updateExternalPorts(nsID, userID, oldService, &req.Service, server.IsDryRun(ctx)); err != nil {
 			return nil, err
 		}
+	}
 
-		req.Domain = domain.Domain
-		req.IPs = domain.IP
-		for i, port := range req.Ports {
-			externalPort := 0
-			for _, oldport := range oldService.Ports {
-				if port.Name == oldport.Name {
-					externalPort = *oldport.Port
-				}
-			}
-			if externalPort == 0 {
-				externalPort, err = sa.mongo.GetFreePort(domain.Domain, port.Protocol, int(sa.minPort), int(sa.maxPort))
-				if err != nil {
-					return nil, err
-				}
-			}
-			req.Ports[i].Port = &externalPort
-		}
+	newService := service.FromKube(nsID, userID, serviceType, req.Service)
+	newService.Metadata = req.Metadata.Merge(oldService.Metadata)
+
+	if server.IsDryRun(ctx) {
+		newService.ID = oldService.ID
+		return &newService, nil
 	}
 
-	createdService, err := sa.mongo.UpdateService(service.FromKube(nsID, userID, serviceType, req))
+	newService.ResourceVersion = oldService.ResourceVersion
+	createdService, err := sa.mongo.UpdateService(newService)
 	if err != nil {
+		sa.pool.Release(reserved)
 		return nil, err
 	}
 
-	if err := sa.kube.UpdateService(ctx, nsID, req); err != nil {
+	if err := sa.kube.UpdateService(ctx, nsID, req.Service, newService.Metadata); err != nil {
 		sa.log.Debug("Kube-API error! Reverting changes.")
+		oldService.ResourceVersion = 0
 		if _, err := sa.mongo.UpdateService(oldService); err != nil {
 			return nil, err
 		}
+		sa.pool.Release(reserved)
 		return nil, err
 	}
 
+	sa.pool.Release(unusedPorts(oldService.PortAllocations(), newService.PortAllocations()))
+
 	return &createdService, nil
 }
 
-func (sa *ServiceActionsImpl) DeleteService(ctx context.Context, nsID, serviceName string) error {
+// moveService changes domain placement of external service: place sets domain, addresses or ports of service
+// and returns newly reserved ports. Change is pushed to kube-api, ports which are not used anymore are released.
+func (sa *ServiceActionsImpl) moveService(ctx context.Context, oldService service.ResourceService, place func(svc *kubtypes.Service) ([]port.Allocation, error)) (ret *service.ResourceService, err error) {
+	sa.log.WithFields(logrus.Fields{
+		"ns_id":        oldService.NamespaceID,
+		"service_name": oldService.Name,
+		"domain":       oldService.Domain,
+	}).Info("move service")
+
+	before := oldService
+	defer func() {
+		sa.audit.Record(ctx, before.NamespaceID, audit.Service, before.Name, audit.Update, before, ret, err)
+	}()
+
+	newService := oldService.Copy()
+	reserved, err := place(&newService.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedService, err := sa.mongo.UpdateService(newService)
+	if err != nil {
+		sa.pool.Release(reserved)
+		return nil, err
+	}
+
+	if err := sa.kube.UpdateService(ctx, oldService.NamespaceID, newService.Service, newService.Metadata); err != nil {
+		sa.log.Debug("Kube-API error! Reverting changes.")
+		oldService.ResourceVersion = 0
+		if _, err := sa.mongo.UpdateService(oldService); err != nil {
+			return nil, err
+		}
+		sa.pool.Release(reserved)
+		return nil, err
+	}
+
+	sa.pool.Release(unusedPorts(oldService.PortAllocations(), updatedService.PortAllocations()))
+
+	return &updatedService, nil
+}
+
+// updateExternalPorts keeps domain and external ports of existing service ports with same name and protocol
+// and reserves ports for new ones. Service which was internal is placed on domain of pool.
+// On dry run ports are only checked to be available, new ports are left unset.
+func (sa *ServiceActionsImpl) updateExternalPorts(nsID, owner string, oldService service.ResourceService, req *kubtypes.Service, dryRun bool) ([]port.Allocation, error) {
+	if oldService.Type != service.External || oldService.Domain == "" {
+		if dryRun {
+			_, err := sa.pool.CheckPlace(nsID, owner, req, nil)
+			return nil, err
+		}
+		return sa.pool.Place(nsID, owner, req)
+	}
+
+	req.Domain = oldService.Domain
+	req.IPs = oldService.IPs
+	var reserved, planned []port.Allocation
+	for i, svcPort := range req.Ports {
+		var externalPort *int
+		for _, oldPort := range oldService.Ports {
+			if svcPort.Name == oldPort.Name && svcPort.Protocol == oldPort.Protocol && oldPort.Port != nil {
+				externalPort = oldPort.Port
+			}
+		}
+		if externalPort == nil {
+			alloc := port.Allocation{
+				Domain:      req.Domain,
+				Protocol:    svcPort.Protocol,
+				NamespaceID: nsID,
+				Service:     req.Name,
+				Owner:       owner,
+			}
+			if dryRun {
+				planned = append(planned, alloc)
+				continue
+			}
+			alloc, err := sa.pool.ReservePort(alloc)
+			if err != nil {
+				sa.pool.Release(reserved)
+				return nil, err
+			}
+			reserved = append(reserved, alloc)
+			externalPort = &alloc.Port
+		}
+		req.Ports[i].Port = externalPort
+	}
+	if dryRun {
+		return nil, sa.pool.CheckFree(planned)
+	}
+	return reserved, nil
+}
+
+// unusedPorts returns old allocations which are not present in new ones
+func unusedPorts(oldAllocs, newAllocs []port.Allocation) []port.Allocation {
+	var unused []port.Allocation
+	for _, oldAlloc := range oldAllocs {
+		var used bool
+		for _, newAlloc := range newAllocs {
+			if oldAlloc.Domain == newAlloc.Domain && oldAlloc.Protocol == newAlloc.Protocol && oldAlloc.Port == newAlloc.Port {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, oldAlloc)
+		}
+	}
+	return unused
+}
+
+func (sa *ServiceActionsImpl) DeleteService(ctx context.Context, nsID, serviceName string, cascade bool) error {
 	userID := httputil.MustGetUserID(ctx)
 	sa.log.WithFields(logrus.Fields{
 		"user_id":      userID,
 		"ns_id":        nsID,
 		"service_name": serviceName,
+		"cascade":      cascade,
 	}).Info("delete service")
 
-	_, err := sa.mongo.GetIngressByService(nsID, serviceName)
-	switch {
-	case err == nil:
-		return rserrors.ErrServiceHasIngresses()
-	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
-		// pass
-	default:
+	if _, ok := server.IfMatch(ctx); ok {
+		svc, err := sa.mongo.GetService(nsID, serviceName)
+		if err != nil {
+			return err
+		}
+		if err := server.CheckIfMatch(ctx, svc.ResourceVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := sa.deps.CheckDelete(ctx, nsID, graph.Node{Kind: graph.Service, Name: serviceName}, cascade); err != nil {
+		sa.audit.Record(ctx, nsID, audit.Service, serviceName, audit.Delete, sa.auditState(nsID, serviceName), nil, err)
 		return err
 	}
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	return sa.deleteService(ctx, nsID, serviceName)
+}
+
+func (sa *ServiceActionsImpl) deleteService(ctx context.Context, nsID, serviceName string) (err error) {
+	before := sa.auditState(nsID, serviceName)
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, serviceName, audit.Delete, before, nil, err) }()
 
 	if err := sa.mongo.DeleteService(nsID, serviceName); err != nil {
 		return err
@@ -248,11 +442,29 @@ func (sa *ServiceActionsImpl) DeleteService(ctx context.Context, nsID, serviceNa
 	return nil
 }
 
-func (sa *ServiceActionsImpl) DeleteAllServices(ctx context.Context, nsID string) error {
+// restoreService recreates service deleted by deleteService
+func (sa *ServiceActionsImpl) restoreService(ctx context.Context, nsID, serviceName string) error {
+	if err := sa.mongo.RestoreService(nsID, serviceName); err != nil {
+		return err
+	}
+	svc, err := sa.mongo.GetService(nsID, serviceName)
+	if err != nil {
+		return err
+	}
+	return sa.kube.CreateService(ctx, nsID, svc.Service, svc.Metadata)
+}
+
+func (sa *ServiceActionsImpl) DeleteAllServices(ctx context.Context, nsID string) (err error) {
 	sa.log.WithFields(logrus.Fields{
 		"ns_id": nsID,
 	}).Info("delete all services")
 
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, "", audit.Delete, nil, nil, err) }()
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
 	if err := sa.mongo.DeleteAllServicesInNamespace(nsID); err != nil {
 		return err
 	}
@@ -260,12 +472,33 @@ func (sa *ServiceActionsImpl) DeleteAllServices(ctx context.Context, nsID string
 	return nil
 }
 
-func (sa *ServiceActionsImpl) DeleteAllSolutionServices(ctx context.Context, nsID, solutionName string) error {
+func (sa *ServiceActionsImpl) DeleteAllSolutionServices(ctx context.Context, nsID, solutionName string, cascade bool) (err error) {
 	sa.log.WithFields(logrus.Fields{
 		"ns_id":    nsID,
 		"solution": solutionName,
+		"cascade":  cascade,
 	}).Info("delete all solution services")
 
+	defer func() { sa.audit.Record(ctx, nsID, audit.Service, "", audit.Delete, nil, nil, err) }()
+
+	services, _, err := sa.mongo.GetServiceList(nsID, nil)
+	if err != nil {
+		return err
+	}
+	var nodes []graph.Node
+	for _, svc := range services {
+		if svc.SolutionID == solutionName {
+			nodes = append(nodes, graph.Node{Kind: graph.Service, Name: svc.Name})
+		}
+	}
+	if err := sa.deps.CheckDeleteAll(ctx, nsID, nodes, cascade); err != nil {
+		return err
+	}
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
 	if err := sa.kube.DeleteSolutionServices(ctx, nsID, solutionName); err != nil {
 		return err
 	}
@@ -275,3 +508,15 @@ func (sa *ServiceActionsImpl) DeleteAllSolutionServices(ctx context.Context, nsI
 	}
 	return nil
 }
+
+// auditState returns service for audit log, nil if service doesn't exist
+func (sa *ServiceActionsImpl) auditState(nsID, serviceName string) interface{} {
+	if sa.audit == nil {
+		return nil
+	}
+	svc, err := sa.mongo.GetService(nsID, serviceName)
+	if err != nil {
+		return nil
+	}
+	return svc
+}
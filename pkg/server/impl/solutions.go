@@ -0,0 +1,234 @@
+package impl
+
+import (
+	"context"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/solution"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+// SolutionActionsImpl groups namespace resources by solution.
+// Solution is deleted all-or-nothing: if some resource can't be deleted, resources deleted before it are restored.
+type SolutionActionsImpl struct {
+	kube  clients.Kube
+	mongo db.Storage
+	log   *cherrylog.LogrusAdapter
+	deps  *GraphActionsImpl
+}
+
+func NewSolutionActionsImpl(mongo db.Storage, kube *clients.Kube, deps *GraphActionsImpl) *SolutionActionsImpl {
+	return &SolutionActionsImpl{
+		kube:  *kube,
+		mongo: mongo,
+		log:   cherrylog.NewLogrusAdapter(logrus.WithField("component", "solution_actions")),
+		deps:  deps,
+	}
+}
+
+// solutionStep -- solution resource and function which recreates it after delete
+type solutionStep struct {
+	node    graph.Node
+	restore func(ctx context.Context) error
+}
+
+func (sa *SolutionActionsImpl) GetSolutionsList(ctx context.Context, nsID string) (*solution.SolutionsResponse, error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id": userID,
+		"ns_id":   nsID,
+	}).Info("get solutions")
+
+	solutions, err := sa.solutions(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &solution.SolutionsResponse{Solutions: solutions}, nil
+}
+
+func (sa *SolutionActionsImpl) GetSolution(ctx context.Context, nsID, solutionName string) (*solution.Solution, error) {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"ns_id":    nsID,
+		"solution": solutionName,
+	}).Info("get solution")
+
+	sol, err := sa.solution(ctx, nsID, solutionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sol, nil
+}
+
+func (sa *SolutionActionsImpl) DeleteSolution(ctx context.Context, nsID, solutionName string) error {
+	userID := httputil.MustGetUserID(ctx)
+	sa.log.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"ns_id":    nsID,
+		"solution": solutionName,
+	}).Info("delete solution")
+
+	sol, err := sa.solution(ctx, nsID, solutionName)
+	if err != nil {
+		return err
+	}
+
+	steps := sa.steps(nsID, sol)
+	if err := sa.checkNoDependents(nsID, steps); err != nil {
+		return err
+	}
+
+	if server.IsDryRun(ctx) {
+		return nil
+	}
+
+	for i, step := range steps {
+		if err := sa.deps.delete(ctx, nsID, step.node); err != nil {
+			sa.log.WithError(err).Debug("Unable to delete solution resource! Restoring deleted resources.")
+			sa.restore(ctx, nsID, steps[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// steps returns solution resources in deletion order: ingresses, services, deployments, configmaps
+func (sa *SolutionActionsImpl) steps(nsID string, sol solution.Solution) []solutionStep {
+	var steps []solutionStep
+	for _, ingr := range sol.Ingresses {
+		ingr := ingr
+		steps = append(steps, solutionStep{
+			node: graph.Node{Kind: graph.Ingress, Name: ingr.Name},
+			restore: func(ctx context.Context) error {
+				if err := sa.mongo.RestoreIngress(nsID, ingr.Name); err != nil {
+					return err
+				}
+				return sa.kube.CreateIngress(ctx, nsID, ingr.Ingress, ingr.Metadata)
+			},
+		})
+	}
+	for _, svc := range sol.Services {
+		svc := svc
+		steps = append(steps, solutionStep{
+			node: graph.Node{Kind: graph.Service, Name: svc.Name},
+			restore: func(ctx context.Context) error {
+				if err := sa.mongo.RestoreService(nsID, svc.Name); err != nil {
+					return err
+				}
+				return sa.kube.CreateService(ctx, nsID, svc.Service, svc.Metadata)
+			},
+		})
+	}
+	for _, depl := range sol.Deployments {
+		depl := depl
+		steps = append(steps, solutionStep{
+			node: graph.Node{Kind: graph.Deployment, Name: depl.Name},
+			restore: func(ctx context.Context) error {
+				if err := sa.mongo.RestoreDeployment(nsID, depl.Name); err != nil {
+					return err
+				}
+				return sa.kube.CreateDeployment(ctx, nsID, depl.Deployment, depl.Metadata, depl.Strategy, depl.ConfigHashes)
+			},
+		})
+	}
+	for _, cm := range sol.ConfigMaps {
+		cm := cm
+		steps = append(steps, solutionStep{
+			node: graph.Node{Kind: graph.ConfigMap, Name: cm.Name},
+			restore: func(ctx context.Context) error {
+				if err := sa.mongo.RestoreConfigMap(nsID, cm.Name); err != nil {
+					return err
+				}
+				return sa.kube.CreateConfigMap(ctx, nsID, cm.ConfigMap, cm.Metadata)
+			},
+		})
+	}
+	return steps
+}
+
+// checkNoDependents returns error if resources outside of solution depend on solution resources
+func (sa *SolutionActionsImpl) checkNoDependents(nsID string, steps []solutionStep) error {
+	resGraph, err := sa.deps.build(nsID)
+	if err != nil {
+		return err
+	}
+
+	var inSolution = make(map[graph.Node]bool, len(steps))
+	for _, step := range steps {
+		inSolution[step.node] = true
+	}
+
+	for _, step := range steps {
+		var outside []graph.Edge
+		for _, edge := range resGraph.Dependents(step.node) {
+			if !inSolution[edge.From] {
+				outside = append(outside, edge)
+			}
+		}
+		if len(outside) > 0 {
+			return dependentsError(step.node, outside)
+		}
+	}
+	return nil
+}
+
+// restore recreates deleted resources in reverse order, so resources are restored before resources which depend on them
+func (sa *SolutionActionsImpl) restore(ctx context.Context, nsID string, deleted []solutionStep) {
+	for i := len(deleted) - 1; i >= 0; i-- {
+		if err := deleted[i].restore(ctx); err != nil {
+			sa.log.WithError(err).WithFields(logrus.Fields{
+				"ns_id":    nsID,
+				"resource": deleted[i].node.String(),
+			}).Error("unable to restore deleted solution resource")
+		}
+	}
+}
+
+func (sa *SolutionActionsImpl) solution(ctx context.Context, nsID, solutionName string) (solution.Solution, error) {
+	solutions, err := sa.solutions(ctx, nsID)
+	if err != nil {
+		return solution.Solution{}, err
+	}
+	for _, sol := range solutions {
+		if sol.Name == solutionName {
+			return sol, nil
+		}
+	}
+	return solution.Solution{}, rserrors.ErrResourceNotExists().AddDetailF("solution %v", solutionName)
+}
+
+func (sa *SolutionActionsImpl) solutions(ctx context.Context, nsID string) ([]solution.Solution, error) {
+	res, err := sa.deps.resources(nsID)
+	if err != nil {
+		return nil, err
+	}
+
+	solutions := solution.Group(res)
+	if len(solutions) == 0 {
+		return solutions, nil
+	}
+
+	kubeDeploys, err := sa.kube.GetDeploymentList(ctx, nsID)
+	if err != nil {
+		return nil, err
+	}
+	var deploys = make([]kubtypes.Deployment, 0, len(kubeDeploys))
+	for _, depl := range kubeDeploys {
+		deploys = append(deploys, depl.Deployment)
+	}
+	for i := range solutions {
+		solutions[i].SetStatus(deploys)
+	}
+	return solutions, nil
+}
@@ -0,0 +1,120 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/labels"
+	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/solution"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type solutionKube struct {
+	clients.Kube
+	failDeploymentDelete bool
+	restored             []string
+}
+
+func (kube *solutionKube) GetDeploymentList(ctx context.Context, nsID string) ([]clients.KubeDeployment, error) {
+	return []clients.KubeDeployment{{Deployment: kubtypes.Deployment{Name: "app", Status: &kubtypes.DeploymentStatus{ReadyReplicas: 1}}}}, nil
+}
+
+func (kube *solutionKube) DeleteDeployment(ctx context.Context, nsID, deployName string) error {
+	if kube.failDeploymentDelete {
+		return rserrors.ErrInternal()
+	}
+	return nil
+}
+
+func (kube *solutionKube) CreateIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress, meta labels.Metadata) error {
+	kube.restored = append(kube.restored, "ingress/"+ingr.Name)
+	return nil
+}
+
+func (kube *solutionKube) CreateService(ctx context.Context, nsID string, svc kubtypes.Service, meta labels.Metadata) error {
+	kube.restored = append(kube.restored, "service/"+svc.Name)
+	return nil
+}
+
+func TestSolutions(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = &solutionKube{Kube: clients.NewDummyKube(), failDeploymentDelete: true}
+	var kubeClient clients.Kube = kube
+	var ob = NewOutboxImpl(mongo, &kubeClient, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var da = NewDeployActionsImpl(mongo, &permissions, &kubeClient, ob, deps, nil, 0)
+	NewConfigMapsActionsImpl(mongo, &kubeClient, ob, da, deps, nil)
+	NewServiceActionsImpl(mongo, &permissions, &kubeClient, ob, deps, nil, NewDomainPool(mongo, domain.Weighted, 0, 0))
+	NewIngressActionsImpl(mongo, &kubeClient, ob, deps, nil, nil, "")
+	var sa = NewSolutionActionsImpl(mongo, &kubeClient, deps)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err := mongo.CreateConfigMap(configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateConfigMap(configmap.FromKube("ns", "", kubtypes.ConfigMap{Name: "shared", Data: kubtypes.ConfigMapData{"a": "1"}}))
+	assert.NoError(t, err)
+	for _, depl := range []kubtypes.Deployment{
+		{Name: "app", SolutionID: "sol", Replicas: 1, Containers: []kubtypes.Container{{Name: "app", ConfigMaps: []kubtypes.ContainerVolume{{Name: "cfg"}, {Name: "shared"}}}}},
+		{Name: "db", SolutionID: "sol", Replicas: 1},
+		{Name: "other", Replicas: 1, Containers: []kubtypes.Container{{Name: "other", ConfigMaps: []kubtypes.ContainerVolume{{Name: "shared"}}}}},
+	} {
+		depl.Active = true
+		_, err = mongo.CreateDeployment(deployment.FromKube("ns", "", depl))
+		assert.NoError(t, err)
+	}
+	_, err = mongo.CreateService(service.FromKube("ns", "", service.Internal, kubtypes.Service{Name: "app-svc", Deploy: "app", SolutionID: "sol"}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateIngress(ingress.FromKube("ns", "", kubtypes.Ingress{
+		Name:  "app-ingr",
+		Rules: []kubtypes.Rule{{Host: "app.test", Path: []kubtypes.Path{{Path: "/", ServiceName: "app-svc", ServicePort: 80}}}},
+	}))
+	assert.NoError(t, err)
+
+	list, err := sa.GetSolutionsList(ctx, "ns")
+	assert.NoError(t, err)
+	if assert.Len(t, list.Solutions, 1) {
+		sol := list.Solutions[0]
+		assert.Equal(t, "sol", sol.Name)
+		assert.Len(t, sol.Deployments, 2)
+		assert.Len(t, sol.Services, 1)
+		assert.Len(t, sol.Ingresses, 1)
+		// "shared" is mounted by deployment which is not in solution
+		if assert.Len(t, sol.ConfigMaps, 1) {
+			assert.Equal(t, "cfg", sol.ConfigMaps[0].Name)
+		}
+		assert.Equal(t, solution.Status{Phase: solution.Pending, Deployments: 2, ReadyDeployments: 1, Replicas: 2, ReadyReplicas: 1}, sol.Status)
+	}
+
+	_, err = sa.GetSolution(ctx, "ns", "missing")
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()), "%v", err)
+
+	assert.Error(t, sa.DeleteSolution(ctx, "ns", "sol"))
+	assert.Equal(t, []string{"service/app-svc", "ingress/app-ingr"}, kube.restored)
+	restored, err := sa.GetSolution(ctx, "ns", "sol")
+	assert.NoError(t, err)
+	assert.Len(t, restored.Ingresses, 1)
+	assert.Len(t, restored.Services, 1)
+	assert.Len(t, restored.Deployments, 2)
+
+	kube.failDeploymentDelete = false
+	assert.NoError(t, sa.DeleteSolution(ctx, "ns", "sol"))
+	list, err = sa.GetSolutionsList(ctx, "ns")
+	assert.NoError(t, err)
+	assert.Empty(t, list.Solutions)
+	_, err = mongo.GetDeployment("ns", "other")
+	assert.NoError(t, err)
+	_, err = mongo.GetConfigMap("ns", "shared")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,209 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// watchHistorySize is a number of last events kept to resume watches
+	watchHistorySize = 1000
+	// watchBufferSize is a number of events which are kept for slow subscriber before it is dropped
+	watchBufferSize = 100
+)
+
+// WatchImpl streams changes of namespace resources made by this instance.
+// Events are published by audit log and by background workers: reconciler, automatic rollback, outbox and certificates renewal.
+// History is kept in process memory, so service must run as single instance (see InstanceLockImpl);
+// changes made directly in kubernetes are streamed only after they are imported by reconciler.
+// Epoch is generated on start, so watches can't be resumed after restart.
+// Nil *WatchImpl doesn't publish anything.
+type WatchImpl struct {
+	log   *cherrylog.LogrusAdapter
+	epoch string
+
+	mu          sync.Mutex
+	seq         uint64
+	history     []watch.Event
+	subscribers map[*watchSubscriber]struct{}
+}
+
+type watchSubscriber struct {
+	nsID   string
+	events chan watch.Event
+}
+
+func NewWatchImpl() *WatchImpl {
+	return &WatchImpl{
+		log:         cherrylog.NewLogrusAdapter(logrus.WithField("component", "watch")),
+		epoch:       uuid.New().String(),
+		subscribers: make(map[*watchSubscriber]struct{}),
+	}
+}
+
+// Watch subscribes to events of namespace. If since is not nil, events after since are sent first.
+// If since is from another epoch or some of these events are not kept anymore, ErrWatchExpired is returned.
+func (w *WatchImpl) Watch(ctx context.Context, nsID string, since *watch.Position) (*watch.Subscription, error) {
+	w.log.WithFields(logrus.Fields{
+		"user_id": httputil.MustGetUserID(ctx),
+		"ns_id":   nsID,
+	}).Info("watch namespace")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var replay []watch.Event
+	if since != nil {
+		// position from other instance or previous run
+		if since.Epoch != w.epoch || since.Seq > w.seq {
+			return nil, rserrors.ErrWatchExpired().AddDetailF("resume token %v is stale", since)
+		}
+		// events which are not kept
+		if len(w.history) > 0 && w.history[0].Seq > since.Seq+1 {
+			return nil, rserrors.ErrWatchExpired().AddDetailF("seq %d, current seq %d", since.Seq, w.seq)
+		}
+		for _, event := range w.history {
+			if event.Seq > since.Seq && event.NamespaceID == nsID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &watchSubscriber{
+		nsID:   nsID,
+		events: make(chan watch.Event, len(replay)+watchBufferSize),
+	}
+	for _, event := range replay {
+		sub.events <- event
+	}
+	w.subscribers[sub] = struct{}{}
+
+	return &watch.Subscription{
+		Events: sub.events,
+		Stop: func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.unsubscribe(sub)
+		},
+	}, nil
+}
+
+// publish converts successful mutation to watch events
+func (w *WatchImpl) publish(entry audit.Entry) {
+	if w == nil || entry.Outcome != audit.Success {
+		return
+	}
+
+	var eventType watch.EventType
+	var object json.RawMessage
+	switch entry.Action {
+	case audit.Create, audit.Import:
+		eventType, object = watch.Added, entry.After
+	case audit.Update, audit.Activate, audit.Rename:
+		eventType, object = watch.Modified, entry.After
+	case audit.Delete:
+		eventType, object = watch.Deleted, entry.Before
+	default:
+		return
+	}
+	// changes of inactive versions affect only version history
+	hasSnapshot := len(entry.Before) > 0 || len(entry.After) > 0
+	if hasSnapshot && !isActiveSnapshot(entry.Before) && !isActiveSnapshot(entry.After) {
+		return
+	}
+
+	switch entry.Kind {
+	case audit.Deployment:
+		w.send(entry.NamespaceID, watch.Deployment, entry.Name, eventType, object)
+	case audit.Service:
+		w.send(entry.NamespaceID, watch.Service, entry.Name, eventType, object)
+	case audit.Ingress:
+		w.send(entry.NamespaceID, watch.Ingress, entry.Name, eventType, object)
+	case audit.ConfigMap:
+		w.send(entry.NamespaceID, watch.ConfigMap, entry.Name, eventType, object)
+	case audit.Resources:
+		if entry.NamespaceID == "" {
+			// resources of user in all namespaces
+			return
+		}
+		for _, kind := range watch.Kinds {
+			w.send(entry.NamespaceID, kind, "", eventType, nil)
+		}
+	}
+}
+
+// notify publishes change made by background worker which is not recorded by audit log.
+// Object is resource state after change, if it's nil watchers should get resource themselves.
+func (w *WatchImpl) notify(nsID string, kind watch.Kind, name string, eventType watch.EventType, object interface{}) {
+	if w == nil {
+		return
+	}
+	snapshot, err := audit.Snapshot(object)
+	if err != nil {
+		w.log.WithError(err).Error("unable to encode resource state")
+	}
+	w.send(nsID, kind, name, eventType, snapshot)
+}
+
+func (w *WatchImpl) send(nsID string, kind watch.Kind, name string, eventType watch.EventType, object json.RawMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seq++
+	event := watch.Event{
+		Epoch:       w.epoch,
+		Seq:         w.seq,
+		ResumeToken: watch.Position{Epoch: w.epoch, Seq: w.seq}.String(),
+		Type:        eventType,
+		Kind:        kind,
+		NamespaceID: nsID,
+		Name:        name,
+		Object:      object,
+	}
+	w.history = append(w.history, event)
+	if len(w.history) > 2*watchHistorySize {
+		w.history = append([]watch.Event(nil), w.history[len(w.history)-watchHistorySize:]...)
+	}
+
+	for sub := range w.subscribers {
+		if sub.nsID != nsID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			w.log.WithField("ns_id", nsID).Warn("watch subscriber is too slow, dropping it")
+			w.unsubscribe(sub)
+		}
+	}
+}
+
+func (w *WatchImpl) unsubscribe(sub *watchSubscriber) {
+	if _, ok := w.subscribers[sub]; ok {
+		delete(w.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// isActiveSnapshot returns false only for snapshot of inactive resource version
+func isActiveSnapshot(snapshot json.RawMessage) bool {
+	if len(snapshot) == 0 {
+		return false
+	}
+	var versioned struct {
+		Active *bool `json:"active"`
+	}
+	if err := json.Unmarshal(snapshot, &versioned); err != nil {
+		return true
+	}
+	return versioned.Active == nil || *versioned.Active
+}
@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
+	"git.containerum.net/ch/resource-service/pkg/models/outbox"
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveEvents(sub *watch.Subscription) []watch.Event {
+	var events []watch.Event
+	for {
+		select {
+		case event, ok := <-sub.Events:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	var ob = NewOutboxImpl(mongo, &kube, nil, nil)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var deps = NewGraphActionsImpl(mongo)
+	var wa = NewWatchImpl()
+	var al = NewAuditImpl(mongo, wa, nil)
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, al, 0), deps, al)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	sub, err := wa.Watch(ctx, "ns", nil)
+	assert.NoError(t, err)
+	defer sub.Stop()
+
+	_, err = ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+	_, err = ca.CreateConfigMap(ctx, "other", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "other-cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+	_, err = ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.Error(t, err, "failed mutations must not be published")
+	_, err = ca.UpdateConfigMap(server.WithDryRun(ctx), "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "2"}}})
+	assert.NoError(t, err)
+	_, err = ca.UpdateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "2"}}})
+	assert.NoError(t, err)
+	assert.NoError(t, ca.DeleteConfigMap(ctx, "ns", "cfg", false))
+
+	events := receiveEvents(sub)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, watch.Added, events[0].Type)
+		assert.Equal(t, watch.ConfigMap, events[0].Kind)
+		assert.Equal(t, "cfg", events[0].Name)
+		assert.NotEmpty(t, events[0].Object)
+		assert.Equal(t, watch.Modified, events[1].Type)
+		assert.Equal(t, watch.Deleted, events[2].Type)
+		assert.NotEmpty(t, events[2].Object)
+		assert.True(t, events[0].Seq < events[1].Seq && events[1].Seq < events[2].Seq)
+
+		since, err := watch.ParsePosition(events[0].ResumeToken)
+		assert.NoError(t, err)
+		assert.Equal(t, events[0].Position(), since)
+		resumed, err := wa.Watch(ctx, "ns", &since)
+		assert.NoError(t, err)
+		assert.Equal(t, events[1:], receiveEvents(resumed))
+		resumed.Stop()
+		_, ok := <-resumed.Events
+		assert.False(t, ok, "stopped subscription must be closed")
+	}
+
+	var future = watch.Position{Epoch: wa.epoch, Seq: 100}
+	_, err = wa.Watch(ctx, "ns", &future)
+	assert.True(t, cherry.Equals(err, rserrors.ErrWatchExpired()), "%v", err)
+
+	// token of previous run is stale even if its seq is known
+	var restarted = NewWatchImpl()
+	restarted.send("ns", watch.Service, "svc", watch.Modified, nil)
+	var stale = watch.Position{Epoch: wa.epoch, Seq: 1}
+	_, err = restarted.Watch(ctx, "ns", &stale)
+	assert.True(t, cherry.Equals(err, rserrors.ErrWatchExpired()), "%v", err)
+}
+
+func TestWatchSlowSubscriber(t *testing.T) {
+	var wa = NewWatchImpl()
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	sub, err := wa.Watch(ctx, "ns", nil)
+	assert.NoError(t, err)
+	for i := 0; i <= watchBufferSize; i++ {
+		wa.send("ns", watch.Service, "svc", watch.Modified, nil)
+	}
+	assert.Len(t, receiveEvents(sub), watchBufferSize)
+	_, ok := <-sub.Events
+	assert.False(t, ok, "slow subscriber must be dropped")
+	sub.Stop()
+
+	for i := 0; i < 2*watchHistorySize; i++ {
+		wa.send("ns", watch.Service, "svc", watch.Modified, nil)
+	}
+	var first = watch.Position{Epoch: wa.epoch, Seq: 1}
+	_, err = wa.Watch(ctx, "ns", &first)
+	assert.True(t, cherry.Equals(err, rserrors.ErrWatchExpired()), "%v", err)
+}
+
+func TestWatchWorkers(t *testing.T) {
+	var mongo = db.NewMemory(nil)
+	var kube clients.Kube = &listKube{
+		Kube: clients.NewDummyKube(),
+		deployments: []clients.KubeDeployment{
+			{Deployment: kubtypes.Deployment{Name: "orphan", Namespace: "ns", Replicas: 1}},
+		},
+	}
+	var wa = NewWatchImpl()
+	var ra = NewReconcileActionsImpl(mongo, &kube, "", wa)
+	var ob = NewOutboxImpl(mongo, &kube, nil, wa)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	sub, err := wa.Watch(ctx, "ns", nil)
+	assert.NoError(t, err)
+	defer sub.Stop()
+
+	// deployment left for worker by stopped service
+	_, err = mongo.CreateDeployment(deployment.FromKube("ns", "", kubtypes.Deployment{Name: "app", Active: true, Replicas: 1}))
+	assert.NoError(t, err)
+	_, err = mongo.CreateOperation(outbox.NewOperation(outbox.CreateDeployment, "ns", "app", time.Now()))
+	assert.NoError(t, err)
+
+	// deployment created in kubernetes is streamed when it's imported
+	_, err = ra.Reconcile(ctx, reconcile.ReconcileRequest{Direction: reconcile.ToDB, Name: "orphan"})
+	assert.NoError(t, err)
+	ob.processPending(context.Background())
+
+	events := receiveEvents(sub)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, watch.Added, events[0].Type)
+		assert.Equal(t, watch.Deployment, events[0].Kind)
+		assert.Equal(t, "orphan", events[0].Name)
+		assert.Equal(t, watch.Modified, events[1].Type)
+		assert.Equal(t, "app", events[1].Name)
+	}
+}
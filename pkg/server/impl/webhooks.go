@@ -0,0 +1,469 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/server"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry"
+	"github.com/containerum/cherry/adaptors/cherrylog"
+	"github.com/containerum/utils/httputil"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// webhookLease is a time given to send delivery before worker picks it again
+	webhookLease       = time.Minute
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 8
+
+	// namespaceEventKind -- kind of events sent when all resources of namespace are deleted
+	namespaceEventKind = "namespace"
+
+	webhookSecretKey = "secret"
+)
+
+// webhookBlockedNetworks -- networks webhooks can't be sent to: loopback, private, shared, link-local and unspecified addresses.
+// Otherwise users could reach internal services and cloud metadata with requests of resource-service.
+var webhookBlockedNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks = make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// webhookKinds -- resource kinds which produce webhook events
+var webhookKinds = map[audit.Kind]string{
+	audit.Deployment: string(audit.Deployment),
+	audit.Service:    string(audit.Service),
+	audit.Ingress:    string(audit.Ingress),
+	audit.ConfigMap:  string(audit.ConfigMap),
+	audit.Resources:  namespaceEventKind,
+}
+
+// WebhookImpl manages namespace webhooks and sends them events of resource changes.
+// Events are recorded as pending deliveries and sent by Run, so slow webhooks don't affect requests.
+// Nil *WebhookImpl doesn't send anything.
+type WebhookImpl struct {
+	mongo  db.Storage
+	box    *secretbox.Box
+	client *http.Client
+	log    *cherrylog.LogrusAdapter
+	// blocked -- networks webhook hosts can't resolve to
+	blocked []*net.IPNet
+}
+
+// NewWebhookImpl creates webhooks which can't be sent to webhookBlockedNetworks and cluster networks, e.g. pod and service CIDRs.
+// Addresses are checked when webhook is registered and every time connection is made, so changed DNS records don't bypass the check.
+func NewWebhookImpl(mongo db.Storage, box *secretbox.Box, clusterNetworks []*net.IPNet) *WebhookImpl {
+	w := &WebhookImpl{
+		mongo:   mongo,
+		box:     box,
+		log:     cherrylog.NewLogrusAdapter(logrus.WithField("component", "webhooks")),
+		blocked: append(append([]*net.IPNet{}, webhookBlockedNetworks...), clusterNetworks...),
+	}
+	w.client = &http.Client{
+		Timeout: webhookTimeout,
+		// proxy is not used, it would connect to unchecked address
+		Transport: &http.Transport{DialContext: w.dial},
+		// redirect target could be blocked address, so redirects are not followed and fail delivery
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return w
+}
+
+func (w *WebhookImpl) GetWebhooksList(ctx context.Context, nsID string) (*webhook.WebhooksResponse, error) {
+	w.log.WithField("ns_id", nsID).Info("get webhooks")
+
+	hooks, err := w.mongo.GetWebhookList(nsID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i] = hooks[i].WithoutSecret()
+	}
+	return &webhook.WebhooksResponse{Webhooks: hooks}, nil
+}
+
+func (w *WebhookImpl) GetWebhook(ctx context.Context, nsID, name string) (*webhook.Webhook, error) {
+	w.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"name":  name,
+	}).Info("get webhook")
+
+	hook, err := w.mongo.GetWebhook(nsID, name)
+	if err != nil {
+		return nil, err
+	}
+	hook = hook.WithoutSecret()
+	return &hook, nil
+}
+
+func (w *WebhookImpl) CreateWebhook(ctx context.Context, nsID string, req webhook.Webhook) (*webhook.Webhook, error) {
+	w.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"name":  req.Name,
+	}).Info("create webhook")
+
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+	if err := w.checkURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	if req.Secret == "" {
+		return nil, rserrors.ErrValidation().AddDetails("secret is required")
+	}
+	req.ID = ""
+	req.NamespaceID = nsID
+	req.Owner = httputil.MustGetUserID(ctx)
+	req.CreatedAt = time.Now().UTC()
+
+	if server.IsDryRun(ctx) {
+		if _, err := w.mongo.GetWebhook(nsID, req.Name); err == nil {
+			return nil, rserrors.ErrResourceAlreadyExists().AddDetailF("webhook %v", req.Name)
+		}
+		req = req.WithoutSecret()
+		return &req, nil
+	}
+
+	var err error
+	if req.Secret, err = w.sealSecret(req.Secret); err != nil {
+		return nil, err
+	}
+	created, err := w.mongo.CreateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+	created = created.WithoutSecret()
+	return &created, nil
+}
+
+// UpdateWebhook replaces URL and event filters of webhook. Secret is kept if it's not provided.
+func (w *WebhookImpl) UpdateWebhook(ctx context.Context, nsID string, req webhook.Webhook) (*webhook.Webhook, error) {
+	w.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"name":  req.Name,
+	}).Info("update webhook")
+
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, rserrors.ErrValidation().AddDetails(errs...)
+	}
+	if err := w.checkURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	old, err := w.mongo.GetWebhook(nsID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	old.URL = req.URL
+	old.Events = req.Events
+
+	if server.IsDryRun(ctx) {
+		old = old.WithoutSecret()
+		return &old, nil
+	}
+
+	if req.Secret != "" {
+		if old.Secret, err = w.sealSecret(req.Secret); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := w.mongo.UpdateWebhook(old)
+	if err != nil {
+		return nil, err
+	}
+	updated = updated.WithoutSecret()
+	return &updated, nil
+}
+
+// DeleteWebhook removes webhook, its pending deliveries fail on next attempt
+func (w *WebhookImpl) DeleteWebhook(ctx context.Context, nsID, name string) error {
+	w.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"name":  name,
+	}).Info("delete webhook")
+
+	if server.IsDryRun(ctx) {
+		_, err := w.mongo.GetWebhook(nsID, name)
+		return err
+	}
+	return w.mongo.DeleteWebhook(nsID, name)
+}
+
+func (w *WebhookImpl) GetWebhookDeliveries(ctx context.Context, nsID, name string) (*webhook.DeliveriesResponse, error) {
+	w.log.WithFields(logrus.Fields{
+		"ns_id": nsID,
+		"name":  name,
+	}).Info("get webhook deliveries")
+
+	hook, err := w.mongo.GetWebhook(nsID, name)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := w.mongo.GetDeliveryList(nsID, hook.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook.DeliveriesResponse{Deliveries: deliveries}, nil
+}
+
+// notify records deliveries of successful mutation to all matching webhooks of namespace
+func (w *WebhookImpl) notify(entry audit.Entry) {
+	kind, ok := webhookKinds[entry.Kind]
+	if w == nil || !ok || entry.Outcome != audit.Success || entry.NamespaceID == "" {
+		return
+	}
+	log := w.log.WithFields(logrus.Fields{
+		"ns_id":  entry.NamespaceID,
+		"kind":   entry.Kind,
+		"name":   entry.Name,
+		"action": entry.Action,
+	})
+
+	hooks, err := w.mongo.GetWebhookList(entry.NamespaceID)
+	if err != nil {
+		log.WithError(err).Error("unable to get webhooks")
+		return
+	}
+	var payload = webhook.Payload{
+		Event:       webhook.EventType(kind, string(entry.Action)),
+		Time:        entry.Time,
+		NamespaceID: entry.NamespaceID,
+		Kind:        kind,
+		Name:        entry.Name,
+		UserID:      entry.UserID,
+		Object:      entry.After,
+	}
+	if entry.Action == audit.Delete {
+		payload.Object = entry.Before
+	}
+	for _, hook := range hooks {
+		if !hook.Matches(payload.Event) {
+			continue
+		}
+		if err := w.mongo.CreateDelivery(webhook.NewDelivery(hook, payload)); err != nil {
+			log.WithError(err).WithField("webhook", hook.Name).Error("unable to create webhook delivery")
+		}
+	}
+}
+
+// Run sends pending deliveries every period until context is done
+func (w *WebhookImpl) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		w.processPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WebhookImpl) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := w.mongo.ClaimDelivery(time.Now(), webhookLease)
+		if err != nil {
+			if !cherry.Equals(err, rserrors.ErrResourceNotExists()) {
+				w.log.WithError(err).Error("unable to get pending webhook delivery")
+			}
+			return
+		}
+		w.process(ctx, delivery)
+	}
+}
+
+func (w *WebhookImpl) process(ctx context.Context, delivery webhook.Delivery) {
+	entry := w.log.WithFields(logrus.Fields{
+		"delivery": delivery.ID,
+		"webhook":  delivery.WebhookName,
+		"ns_id":    delivery.NamespaceID,
+		"event":    delivery.Payload.Event,
+		"attempt":  delivery.Attempts,
+	})
+
+	var attempt = webhook.Attempt{Time: time.Now().UTC()}
+	hook, err := w.mongo.GetWebhook(delivery.NamespaceID, delivery.WebhookName)
+	switch {
+	case err == nil && hook.ID != delivery.WebhookID:
+		err = rserrors.ErrResourceNotExists().AddDetailF("webhook %v was recreated", delivery.WebhookName)
+		fallthrough
+	case cherry.Equals(err, rserrors.ErrResourceNotExists()):
+		entry.Info("webhook was deleted, delivery failed")
+		attempt.Error = err.Error()
+		delivery.Finish(webhook.Failed, attempt)
+	case err != nil:
+		entry.WithError(err).Warn("unable to get webhook, retrying")
+		attempt.Error = err.Error()
+		delivery.Retry(time.Now().Add(outboxBackoff(delivery.Attempts)), attempt)
+	default:
+		attempt.StatusCode, err = w.send(ctx, hook, delivery.Payload)
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		switch {
+		case err == nil:
+			entry.WithField("status", attempt.StatusCode).Info("webhook delivered")
+			delivery.Finish(webhook.Delivered, attempt)
+		case delivery.Attempts >= webhookMaxAttempts:
+			entry.WithError(err).Error("webhook delivery failed")
+			delivery.Finish(webhook.Failed, attempt)
+		default:
+			entry.WithError(err).Warn("unable to deliver webhook, retrying")
+			delivery.Retry(time.Now().Add(outboxBackoff(delivery.Attempts)), attempt)
+		}
+	}
+
+	if err := w.mongo.UpdateDelivery(delivery); err != nil {
+		entry.WithError(err).Error("unable to update webhook delivery")
+	}
+}
+
+// send posts signed payload to webhook and returns response status, non-2xx status is an error
+func (w *WebhookImpl) send(ctx context.Context, hook webhook.Webhook, payload webhook.Payload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	secret, err := w.openSecret(hook.Secret)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", payload.Event)
+	req.Header.Set("X-Webhook-Delivery", payload.DeliveryID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %v", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns hex encoded HMAC-SHA256 of webhook request timestamp and body joined with ".".
+// Timestamp is signed, so receivers can reject old requests and captured deliveries can't be replayed.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkURL checks that webhook host doesn't resolve to blocked address
+func (w *WebhookImpl) checkURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rserrors.ErrValidation().AddDetailF("invalid url: %v", err)
+	}
+	if _, err := w.resolve(ctx, u.Hostname()); err != nil {
+		return rserrors.ErrValidation().AddDetails(err.Error())
+	}
+	return nil
+}
+
+// resolve returns addresses of host, error if any of them is in blocked network
+func (w *WebhookImpl) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve webhook host %v: %v", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsMulticast() {
+			return nil, fmt.Errorf("webhook host %v resolves to not allowed address %v", host, ip)
+		}
+		for _, network := range w.blocked {
+			if network.Contains(ip) {
+				return nil, fmt.Errorf("webhook host %v resolves to not allowed address %v", host, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// dial connects to checked address of host, so host can't be resolved to another address after check
+func (w *WebhookImpl) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := w.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialer = net.Dialer{Timeout: webhookTimeout}
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func (w *WebhookImpl) sealSecret(secret string) (string, error) {
+	sealed, err := w.box.Seal(map[string]string{webhookSecretKey: secret})
+	if err != nil {
+		return "", err
+	}
+	return sealed[webhookSecretKey], nil
+}
+
+func (w *WebhookImpl) openSecret(sealed string) (string, error) {
+	opened, err := w.box.Open(map[string]string{webhookSecretKey: sealed})
+	if err != nil {
+		return "", err
+	}
+	return opened[webhookSecretKey], nil
+}
@@ -0,0 +1,126 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.containerum.net/ch/resource-service/pkg/clients"
+	"git.containerum.net/ch/resource-service/pkg/db"
+	"git.containerum.net/ch/resource-service/pkg/models/configmap"
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
+	"git.containerum.net/ch/resource-service/pkg/rserrors"
+	"git.containerum.net/ch/resource-service/pkg/util/secretbox"
+	"github.com/containerum/cherry"
+	kubtypes "github.com/containerum/kube-client/pkg/model"
+	"github.com/containerum/utils/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks(t *testing.T) {
+	var requests []*http.Request
+	var bodies [][]byte
+	var status = http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if status == http.StatusFound {
+			w.Header().Set("Location", "/redirected")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	var mongo = db.NewMemory(nil)
+	var kube = clients.NewDummyKube()
+	box, err := secretbox.New(testSecretKey)
+	assert.NoError(t, err)
+	var ob = NewOutboxImpl(mongo, &kube, box, nil)
+	var wh = NewWebhookImpl(mongo, box, nil)
+	var al = NewAuditImpl(mongo, nil, wh)
+	var deps = NewGraphActionsImpl(mongo)
+	var permissions clients.Permissions = unlimitedPermissions{}
+	var ca = NewConfigMapsActionsImpl(mongo, &kube, ob, NewDeployActionsImpl(mongo, &permissions, &kube, ob, deps, al, 0), deps, al)
+	var ctx = context.WithValue(context.Background(), httputil.UserIDContextKey, "00000000-0000-0000-0000-000000000001")
+
+	_, err = wh.CreateWebhook(ctx, "ns", webhook.Webhook{Name: "ci", URL: "ftp://example.com"})
+	assert.True(t, cherry.Equals(err, rserrors.ErrValidation()))
+	for _, blocked := range []string{srv.URL, "http://10.1.2.3/hook", "http://169.254.169.254/latest", "http://[::1]:8080"} {
+		_, err = wh.CreateWebhook(ctx, "ns", webhook.Webhook{Name: "ci", URL: blocked, Secret: "s3cret"})
+		assert.True(t, cherry.Equals(err, rserrors.ErrValidation()), "%v: %v", blocked, err)
+	}
+	// test server listens on loopback
+	wh.blocked = nil
+	hook, err := wh.CreateWebhook(ctx, "ns", webhook.Webhook{Name: "ci", URL: srv.URL, Secret: "s3cret", Events: []string{"configmap.create"}})
+	assert.NoError(t, err)
+	assert.Empty(t, hook.Secret, "secret must not be returned")
+	stored, err := mongo.GetWebhook("ns", "ci")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "s3cret", stored.Secret, "secret must be stored encrypted")
+
+	_, err = ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+	_, err = ca.UpdateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg", Data: kubtypes.ConfigMapData{"a": "2"}}})
+	assert.NoError(t, err, "filtered out event must not be delivered")
+	_, err = ca.CreateConfigMap(ctx, "other", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "other-cfg", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err, "events of other namespaces must not be delivered")
+
+	// first attempt fails and is retried
+	wh.processPending(context.Background())
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "configmap.create", requests[0].Header.Get("X-Webhook-Event"))
+		timestamp := requests[0].Header.Get("X-Webhook-Timestamp")
+		assert.NotEmpty(t, timestamp)
+		assert.Equal(t, "sha256="+Sign("s3cret", timestamp, bodies[0]), requests[0].Header.Get("X-Webhook-Signature"))
+		var payload webhook.Payload
+		assert.NoError(t, json.Unmarshal(bodies[0], &payload))
+		assert.Equal(t, "cfg", payload.Name)
+		assert.Equal(t, "ns", payload.NamespaceID)
+		assert.Equal(t, requests[0].Header.Get("X-Webhook-Delivery"), payload.DeliveryID)
+		assert.NotEmpty(t, payload.Object)
+	}
+
+	status = http.StatusOK
+	delivery, err := mongo.ClaimDelivery(time.Now().Add(time.Hour), webhookLease)
+	assert.NoError(t, err)
+	wh.process(context.Background(), delivery)
+	assert.Len(t, requests, 2)
+
+	deliveries, err := wh.GetWebhookDeliveries(ctx, "ns", "ci")
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries.Deliveries, 1) {
+		var d = deliveries.Deliveries[0]
+		assert.Equal(t, webhook.Delivered, d.Status)
+		if assert.Len(t, d.Log, 2) {
+			assert.Equal(t, http.StatusInternalServerError, d.Log[0].StatusCode)
+			assert.NotEmpty(t, d.Log[0].Error)
+			assert.Equal(t, http.StatusOK, d.Log[1].StatusCode)
+		}
+	}
+
+	// deliveries of deleted webhook fail without requests
+	_, err = ca.CreateConfigMap(ctx, "ns", configmap.ConfigMapRequest{ConfigMap: kubtypes.ConfigMap{Name: "cfg2", Data: kubtypes.ConfigMapData{"a": "1"}}})
+	assert.NoError(t, err)
+	assert.NoError(t, wh.DeleteWebhook(ctx, "ns", "ci"))
+	wh.processPending(context.Background())
+	assert.Len(t, requests, 2)
+	_, err = mongo.ClaimDelivery(time.Now().Add(time.Hour), webhookLease)
+	assert.True(t, cherry.Equals(err, rserrors.ErrResourceNotExists()))
+
+	// redirects are not followed
+	status = http.StatusFound
+	code, err := wh.send(context.Background(), stored, webhook.Payload{})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusFound, code)
+	assert.Len(t, requests, 3)
+
+	// address is checked on connect too, e.g. if DNS record is changed after registration
+	_, err = NewWebhookImpl(mongo, box, nil).send(context.Background(), stored, webhook.Payload{})
+	assert.Error(t, err)
+	assert.Len(t, requests, 3)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
 	"git.containerum.net/ch/resource-service/pkg/models/service"
 	"git.containerum.net/ch/resource-service/pkg/models/stats"
 	"git.containerum.net/ch/resource-service/pkg/rserrors"
@@ -59,18 +60,16 @@ func CheckDeploymentCreateQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resourc
 	return nil
 }
 
-func CheckDeploymentReplaceQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resource, oldDeploy, newDeploy kubtypes.Deployment) error {
+// CheckDeploymentReplaceQuotas checks if namespace has enough resources to replace oldDeploy with newDeploy.
+// Resources used by deployment during rollout (surge replicas, canary replicas) are also taken into account.
+func CheckDeploymentReplaceQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resource, oldDeploy, newDeploy kubtypes.Deployment, strategy *deployment.Strategy) error {
 	CalculateDeployResources(&oldDeploy)
 
 	var oldDeployCPU, oldDeployRAM int
 	oldDeployCPU = int(oldDeploy.TotalCPU)
 	oldDeployRAM = int(oldDeploy.TotalMemory)
 
-	CalculateDeployResources(&newDeploy)
-
-	var newDeployCPU, newDeployRAM int
-	newDeployCPU = int(newDeploy.TotalCPU)
-	newDeployRAM = int(newDeploy.TotalMemory)
+	newDeployCPU, newDeployRAM := rolloutResources(oldDeploy, newDeploy, strategy)
 
 	if exceededCPU := int(ns.Resources.Hard.CPU) - int(nsUsage.CPU) - newDeployCPU + oldDeployCPU; exceededCPU < 0 {
 		return rserrors.ErrQuotaExceeded().AddDetailF("Exceeded %d CPU", -exceededCPU)
@@ -83,6 +82,24 @@ func CheckDeploymentReplaceQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resour
 	return nil
 }
 
+// CheckCanaryQuotas checks if namespace has enough resources to run canaryReplicas replicas of canary version
+// instead of replicas of stable version
+func CheckCanaryQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resource, stable, canary kubtypes.Deployment, canaryReplicas int) error {
+	stableCPU, stableRAM := replicaResources(stable)
+	canaryCPU, canaryRAM := replicaResources(canary)
+	var delta = canaryReplicas - canary.Replicas
+
+	if exceededCPU := int(ns.Resources.Hard.CPU) - int(nsUsage.CPU) - delta*(canaryCPU-stableCPU); exceededCPU < 0 {
+		return rserrors.ErrQuotaExceeded().AddDetailF("Exceeded %d CPU", -exceededCPU)
+	}
+
+	if exceededRAM := int(ns.Resources.Hard.Memory) - int(nsUsage.Memory) - delta*(canaryRAM-stableRAM); exceededRAM < 0 {
+		return rserrors.ErrQuotaExceeded().AddDetailF("Exceeded %d memory", -exceededRAM)
+	}
+
+	return nil
+}
+
 func CheckDeploymentReplicasChangeQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resource, deploy kubtypes.Deployment, newReplicas int) error {
 	CalculateDeployResources(&deploy)
 	var deployCPU, deployRAM int
@@ -116,6 +133,39 @@ func CheckServiceCreateQuotas(ns kubtypes.Namespace, nsUsage stats.Service, serv
 	return nil
 }
 
+// CheckBundleCreateQuotas checks if namespace has enough resources to create all deployments and services at once.
+// Like in CheckDeploymentReplaceQuotas, resources of extra replicas started during rollout are taken into account.
+func CheckBundleCreateQuotas(ns kubtypes.Namespace, nsUsage kubtypes.Resource, svcUsage stats.Service, deploys []deployment.DeploymentRequest, serviceTypes []service.Type) error {
+	var bundleCPU, bundleRAM int
+	for _, deploy := range deploys {
+		cpu, ram := rolloutResources(deploy.Deployment, deploy.Deployment, deploy.Strategy)
+		bundleCPU += cpu
+		bundleRAM += ram
+	}
+
+	if exceededCPU := int(ns.Resources.Hard.CPU) - bundleCPU - int(nsUsage.CPU); exceededCPU < 0 {
+		return rserrors.ErrQuotaExceeded().AddDetailF("Exceeded %d CPU", -exceededCPU)
+	}
+
+	if exceededRAM := int(ns.Resources.Hard.Memory) - bundleRAM - int(nsUsage.Memory); exceededRAM < 0 {
+		return rserrors.ErrQuotaExceeded().AddDetailF("Exceeded %d memory", -exceededRAM)
+	}
+
+	for _, serviceType := range serviceTypes {
+		if err := CheckServiceCreateQuotas(ns, svcUsage, serviceType); err != nil {
+			return err
+		}
+		switch serviceType {
+		case service.External:
+			svcUsage.External++
+		case service.Internal:
+			svcUsage.Internal++
+		}
+	}
+
+	return nil
+}
+
 func CalculateDeployResources(deploy *kubtypes.Deployment) {
 	var mCPU, mbRAM int64
 	for _, container := range deploy.Containers {
@@ -127,3 +177,42 @@ func CalculateDeployResources(deploy *kubtypes.Deployment) {
 	deploy.TotalCPU = uint(mCPU)
 	deploy.TotalMemory = uint(mbRAM)
 }
+
+// replicaResources returns resources used by one deployment replica
+func replicaResources(deploy kubtypes.Deployment) (cpu, ram int) {
+	for _, container := range deploy.Containers {
+		cpu += int(container.Limits.CPU)
+		ram += int(container.Limits.Memory)
+	}
+	return cpu, ram
+}
+
+// rolloutResources returns max resources used by deployment while rolling out newDeploy
+func rolloutResources(oldDeploy, newDeploy kubtypes.Deployment, strategy *deployment.Strategy) (cpu, ram int) {
+	CalculateDeployResources(&newDeploy)
+	cpu, ram = int(newDeploy.TotalCPU), int(newDeploy.TotalMemory)
+
+	oldCPU, oldRAM := replicaResources(oldDeploy)
+	newCPU, newRAM := replicaResources(newDeploy)
+	switch {
+	case strategy.IsCanary():
+		var canary = deployment.CanaryReplicas(newDeploy.Replicas, strategy.CanaryPercent)
+		var stable = newDeploy.Replicas - canary
+		if canaryCPU := stable*oldCPU + canary*newCPU; canaryCPU > cpu {
+			cpu = canaryCPU
+		}
+		if canaryRAM := stable*oldRAM + canary*newRAM; canaryRAM > ram {
+			ram = canaryRAM
+		}
+	case strategy.Surge() > 0:
+		if oldCPU > newCPU {
+			newCPU = oldCPU
+		}
+		if oldRAM > newRAM {
+			newRAM = oldRAM
+		}
+		cpu += strategy.Surge() * newCPU
+		ram += strategy.Surge() * newRAM
+	}
+	return cpu, ram
+}
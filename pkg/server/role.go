@@ -0,0 +1,16 @@
+package server
+
+import (
+	"context"
+
+	"github.com/containerum/utils/httputil"
+)
+
+// RoleAdmin -- value of user role header for admins
+const RoleAdmin = "admin"
+
+// IsAdmin returns true if request is made by admin
+func IsAdmin(ctx context.Context) bool {
+	role, _ := ctx.Value(httputil.UserRoleContextKey).(string)
+	return role == RoleAdmin
+}
@@ -2,34 +2,49 @@ package server
 
 import (
 	"context"
+	"net/url"
 
+	"git.containerum.net/ch/resource-service/pkg/models/audit"
+	"git.containerum.net/ch/resource-service/pkg/models/bundle"
 	"git.containerum.net/ch/resource-service/pkg/models/configmap"
 	"git.containerum.net/ch/resource-service/pkg/models/deployment"
 	"git.containerum.net/ch/resource-service/pkg/models/domain"
+	"git.containerum.net/ch/resource-service/pkg/models/graph"
+	"git.containerum.net/ch/resource-service/pkg/models/host"
+	"git.containerum.net/ch/resource-service/pkg/models/idempotency"
 	"git.containerum.net/ch/resource-service/pkg/models/ingress"
+	"git.containerum.net/ch/resource-service/pkg/models/port"
+	"git.containerum.net/ch/resource-service/pkg/models/reconcile"
 	"git.containerum.net/ch/resource-service/pkg/models/resources"
+	"git.containerum.net/ch/resource-service/pkg/models/secret"
 	"git.containerum.net/ch/resource-service/pkg/models/service"
+	"git.containerum.net/ch/resource-service/pkg/models/solution"
+	"git.containerum.net/ch/resource-service/pkg/models/watch"
+	"git.containerum.net/ch/resource-service/pkg/models/webhook"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 )
 
 type DeployActions interface {
-	GetDeploymentsList(ctx context.Context, nsID string) (*deployment.DeploymentsResponse, error)
+	GetDeploymentsList(ctx context.Context, nsID string, params url.Values) (*deployment.DeploymentsResponse, error)
 	GetDeployment(ctx context.Context, nsID, deplName string) (*deployment.ResourceDeploy, error)
-	GetDeploymentVersionsList(ctx context.Context, nsID, deployName string) (*deployment.DeploymentsResponse, error)
+	GetDeploymentVersionsList(ctx context.Context, nsID, deployName string, params url.Values) (*deployment.DeploymentsResponse, error)
 	GetDeploymentVersion(ctx context.Context, nsID, deplName, version string) (*deployment.ResourceDeploy, error)
 	DiffDeployments(ctx context.Context, nsID, deplName, version1, version2 string) (*kubtypes.DeploymentDiff, error)
 	DiffDeploymentsPrevious(ctx context.Context, nsID, deplName, version string) (*kubtypes.DeploymentDiff, error)
-	CreateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) (*deployment.ResourceDeploy, error)
+	CreateDeployment(ctx context.Context, nsID string, req deployment.DeploymentRequest) (*deployment.ResourceDeploy, error)
 	ImportDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) error
 	ChangeActiveDeployment(ctx context.Context, nsID, deplName, version string) (*deployment.ResourceDeploy, error)
-	UpdateDeployment(ctx context.Context, nsID string, deploy kubtypes.Deployment) (*deployment.ResourceDeploy, error)
+	UpdateDeployment(ctx context.Context, nsID string, req deployment.DeploymentRequest) (*deployment.ResourceDeploy, error)
 	SetDeploymentReplicas(ctx context.Context, nsID, deplName string, req kubtypes.UpdateReplicas) (*deployment.ResourceDeploy, error)
 	SetDeploymentContainerImage(ctx context.Context, nsID, deplName string, req kubtypes.UpdateImage) (*deployment.ResourceDeploy, error)
 	RenameDeploymentVersion(ctx context.Context, nsID, deplName, oldversion, newversion string) (*deployment.ResourceDeploy, error)
-	DeleteDeployment(ctx context.Context, nsID, deplName string) error
+	PromoteDeployment(ctx context.Context, nsID, deplName, version string) (*deployment.ResourceDeploy, error)
+	AbortDeployment(ctx context.Context, nsID, deplName, version string) (*deployment.ResourceDeploy, error)
+	RollbackDeployment(ctx context.Context, nsID, deplName string) (*deployment.ResourceDeploy, error)
+	DeleteDeployment(ctx context.Context, nsID, deplName string, cascade bool) error
 	DeleteDeploymentVersion(ctx context.Context, nsID, deplName, version string) error
 	DeleteAllDeployments(ctx context.Context, nsID string) error
-	DeleteAllSolutionDeployments(ctx context.Context, nsID, solutionName string) error
+	DeleteAllSolutionDeployments(ctx context.Context, nsID, solutionName string, cascade bool) error
 }
 
 type DomainActions interface {
@@ -37,43 +52,124 @@ type DomainActions interface {
 	GetDomain(ctx context.Context, domain string) (*domain.Domain, error)
 	AddDomain(ctx context.Context, req domain.Domain) (*domain.Domain, error)
 	DeleteDomain(ctx context.Context, domain string) error
+	UpdateDomain(ctx context.Context, domain string, req domain.UpdateRequest) (*domain.Domain, error)
+	DrainDomain(ctx context.Context, domain string) (*domain.DrainResponse, error)
+	SetDomainPoolSettings(ctx context.Context, domain string, settings domain.PoolSettings) (*domain.Domain, error)
+	GetDomainPinsList(ctx context.Context) (*domain.PinsResponse, error)
+	SetDomainPin(ctx context.Context, pin domain.Pin) (*domain.Pin, error)
+	DeleteDomainPin(ctx context.Context, kind domain.PinKind, id string) error
 }
 
 type IngressActions interface {
-	GetIngressesList(ctx context.Context, nsID string) (*ingress.IngressesResponse, error)
-	GetSelectedIngressesList(ctx context.Context, namespaces []string) (*ingress.IngressesResponse, error)
+	GetIngressesList(ctx context.Context, nsID string, params url.Values) (*ingress.IngressesResponse, error)
+	GetSelectedIngressesList(ctx context.Context, namespaces []string, params url.Values) (*ingress.IngressesResponse, error)
 	GetIngress(ctx context.Context, nsID, ingressName string) (*ingress.ResourceIngress, error)
-	CreateIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress) (*ingress.ResourceIngress, error)
+	CreateIngress(ctx context.Context, nsID string, ingr ingress.IngressRequest) (*ingress.ResourceIngress, error)
 	ImportIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress) error
-	UpdateIngress(ctx context.Context, nsID string, ingr kubtypes.Ingress) (*ingress.ResourceIngress, error)
+	UpdateIngress(ctx context.Context, nsID string, ingr ingress.IngressRequest) (*ingress.ResourceIngress, error)
 	DeleteIngress(ctx context.Context, nsID, ingressName string) error
 	DeleteAllIngresses(ctx context.Context, nsID string) error
 }
 
 type ServiceActions interface {
-	GetServicesList(ctx context.Context, nsID string) (*service.ServicesResponse, error)
+	GetServicesList(ctx context.Context, nsID string, params url.Values) (*service.ServicesResponse, error)
 	GetService(ctx context.Context, nsID, serviceName string) (*service.ResourceService, error)
-	CreateService(ctx context.Context, nsID string, svc kubtypes.Service) (*service.ResourceService, error)
+	CreateService(ctx context.Context, nsID string, svc service.ServiceRequest) (*service.ResourceService, error)
 	ImportService(ctx context.Context, nsID string, svc kubtypes.Service) error
-	UpdateService(ctx context.Context, nsID string, svc kubtypes.Service) (*service.ResourceService, error)
-	DeleteService(ctx context.Context, nsID, serviceName string) error
+	UpdateService(ctx context.Context, nsID string, svc service.ServiceRequest) (*service.ResourceService, error)
+	DeleteService(ctx context.Context, nsID, serviceName string, cascade bool) error
 	DeleteAllServices(ctx context.Context, nsID string) error
-	DeleteAllSolutionServices(ctx context.Context, nsID, solutionName string) error
+	DeleteAllSolutionServices(ctx context.Context, nsID, solutionName string, cascade bool) error
 }
 
 type ConfigMapActions interface {
-	GetConfigMapsList(ctx context.Context, nsID string) (*configmap.ConfigMapsResponse, error)
-	GetSelectedConfigMapsList(ctx context.Context, namespaces []string) (*configmap.ConfigMapsResponse, error)
+	GetConfigMapsList(ctx context.Context, nsID string, params url.Values) (*configmap.ConfigMapsResponse, error)
+	GetSelectedConfigMapsList(ctx context.Context, namespaces []string, params url.Values) (*configmap.ConfigMapsResponse, error)
 	GetConfigMap(ctx context.Context, nsID, ingressName string) (*configmap.ResourceConfigMap, error)
-	CreateConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) (*configmap.ResourceConfigMap, error)
+	GetConfigMapVersionsList(ctx context.Context, nsID, cmName string, params url.Values) (*configmap.ConfigMapsResponse, error)
+	GetConfigMapVersion(ctx context.Context, nsID, cmName, version string) (*configmap.ResourceConfigMap, error)
+	DiffConfigMaps(ctx context.Context, nsID, cmName, version1, version2 string) (*configmap.ConfigMapDiff, error)
+	DiffConfigMapsPrevious(ctx context.Context, nsID, cmName, version string) (*configmap.ConfigMapDiff, error)
+	CreateConfigMap(ctx context.Context, nsID string, cm configmap.ConfigMapRequest) (*configmap.ResourceConfigMap, error)
 	ImportConfigMap(ctx context.Context, nsID string, cm kubtypes.ConfigMap) error
-	DeleteConfigMap(ctx context.Context, nsID, cmName string) error
+	UpdateConfigMap(ctx context.Context, nsID string, cm configmap.ConfigMapRequest) (*configmap.ResourceConfigMap, error)
+	PatchConfigMap(ctx context.Context, nsID, cmName string, patch configmap.ConfigMapPatch) (*configmap.ResourceConfigMap, error)
+	DeleteConfigMap(ctx context.Context, nsID, cmName string, cascade bool) error
 	DeleteAllConfigMaps(ctx context.Context, nsID string) error
 }
 
+type SecretActions interface {
+	GetSecretsList(ctx context.Context, nsID string, params url.Values) (*secret.SecretsResponse, error)
+	GetSelectedSecretsList(ctx context.Context, namespaces []string, params url.Values) (*secret.SecretsResponse, error)
+	GetSecret(ctx context.Context, nsID, secretName string) (*secret.ResourceSecret, error)
+	CreateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) (*secret.ResourceSecret, error)
+	ImportSecret(ctx context.Context, nsID string, secret kubtypes.Secret) error
+	UpdateSecret(ctx context.Context, nsID string, secret kubtypes.Secret) (*secret.ResourceSecret, error)
+	DeleteSecret(ctx context.Context, nsID, secretName string, cascade bool) error
+	DeleteAllSecrets(ctx context.Context, nsID string) error
+}
+
 type ResourcesActions interface {
 	GetResourcesCount(ctx context.Context) (*resources.GetResourcesCountResponse, error)
 	GetAllResourcesCount(ctx context.Context) (*resources.GetResourcesCountResponse, error)
 	DeleteAllResourcesInNamespace(ctx context.Context, nsID string) error
 	DeleteAllUserResources(ctx context.Context) error
 }
+
+type ReconcileActions interface {
+	GetDrift(ctx context.Context) (*reconcile.DriftResponse, error)
+	Reconcile(ctx context.Context, req reconcile.ReconcileRequest) (*reconcile.DriftResponse, error)
+}
+
+type GraphActions interface {
+	GetGraph(ctx context.Context, nsID string) (*graph.Graph, error)
+}
+
+type BundleActions interface {
+	ApplyBundle(ctx context.Context, nsID string, req bundle.Bundle) (*bundle.BundleResponse, error)
+}
+
+type SolutionActions interface {
+	GetSolutionsList(ctx context.Context, nsID string) (*solution.SolutionsResponse, error)
+	GetSolution(ctx context.Context, nsID, solutionName string) (*solution.Solution, error)
+	DeleteSolution(ctx context.Context, nsID, solutionName string) error
+}
+
+type AuditActions interface {
+	GetAuditLog(ctx context.Context, query audit.Query, params url.Values) (*audit.EntriesResponse, error)
+	GetNamespaceAuditLog(ctx context.Context, nsID string, query audit.Query, params url.Values) (*audit.EntriesResponse, error)
+}
+
+type WatchActions interface {
+	Watch(ctx context.Context, nsID string, since *watch.Position) (*watch.Subscription, error)
+}
+
+type WebhookActions interface {
+	GetWebhooksList(ctx context.Context, nsID string) (*webhook.WebhooksResponse, error)
+	GetWebhook(ctx context.Context, nsID, name string) (*webhook.Webhook, error)
+	CreateWebhook(ctx context.Context, nsID string, req webhook.Webhook) (*webhook.Webhook, error)
+	UpdateWebhook(ctx context.Context, nsID string, req webhook.Webhook) (*webhook.Webhook, error)
+	DeleteWebhook(ctx context.Context, nsID, name string) error
+	GetWebhookDeliveries(ctx context.Context, nsID, name string) (*webhook.DeliveriesResponse, error)
+}
+
+type PortActions interface {
+	GetPortsUsage(ctx context.Context) (*port.UsageResponse, error)
+}
+
+type HostActions interface {
+	GetHostReservationsList(ctx context.Context) (*host.ReservationsResponse, error)
+	GetAllHostReservationsList(ctx context.Context) (*host.ReservationsResponse, error)
+	ReserveHost(ctx context.Context, req host.ReservationRequest) (*host.Reservation, error)
+	DeleteHostReservation(ctx context.Context, hostName string) error
+	DeleteAnyHostReservation(ctx context.Context, hostName string) error
+}
+
+type IdempotencyActions interface {
+	// Begin returns stored response of previous request with the same key, or nil if request should be processed
+	Begin(ctx context.Context, key, requestHash string) (*idempotency.Record, error)
+	// Finish stores response of processed request
+	Finish(ctx context.Context, key string, status int, header map[string]string, body []byte) error
+	// Abandon forgets the key, so request can be retried
+	Abandon(ctx context.Context, key string) error
+}
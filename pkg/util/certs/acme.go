@@ -0,0 +1,217 @@
+package certs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// ChallengePath -- path prefix of http-01 challenge responses.
+// ACME server requests it on port 80 of every ingress host, so ingress controller must route it to resource-service.
+const ChallengePath = "/.well-known/acme-challenge/"
+
+// ACMEConfig -- settings of ACME client
+type ACMEConfig struct {
+	// DirectoryURL -- URL of ACME directory, e.g. https://acme-v02.api.letsencrypt.org/directory
+	DirectoryURL string
+	// Email -- account contact, optional
+	Email string
+	// AccountKeyPEM -- RSA or ECDSA key of account, required, so the same account is used after restarts
+	AccountKeyPEM []byte
+	// HTTPClient is used for requests to ACME server, e.g. client trusting CA of local test server
+	HTTPClient *http.Client
+	// Timeout -- max duration of issuing one certificate
+	Timeout time.Duration
+}
+
+// ACMEIssuer issues certificates through ACME (RFC 8555) server using http-01 challenges
+type ACMEIssuer struct {
+	client     *acme.Client
+	email      string
+	timeout    time.Duration
+	challenges *challengeStore
+
+	// account is registered on first issue
+	mu         sync.Mutex
+	registered bool
+}
+
+func NewACMEIssuer(cfg ACMEConfig) (*ACMEIssuer, error) {
+	if cfg.DirectoryURL == "" {
+		return nil, errors.New("ACME directory URL is not set")
+	}
+	if len(cfg.AccountKeyPEM) == 0 {
+		return nil, errors.New("ACME account key is not set")
+	}
+	key, err := parsePrivateKey(cfg.AccountKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	return &ACMEIssuer{
+		client: &acme.Client{
+			Key:          key,
+			HTTPClient:   cfg.HTTPClient,
+			DirectoryURL: cfg.DirectoryURL,
+			UserAgent:    "resource-service",
+		},
+		email:      cfg.Email,
+		timeout:    cfg.Timeout,
+		challenges: &challengeStore{tokens: make(map[string]string)},
+	}, nil
+}
+
+func (ai *ACMEIssuer) Name() string {
+	return "acme"
+}
+
+// ChallengeHandler serves http-01 challenge responses at ChallengePath
+func (ai *ACMEIssuer) ChallengeHandler() http.Handler {
+	return ai.challenges
+}
+
+func (ai *ACMEIssuer) Issue(ctx context.Context, hosts []string) (*Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("no hosts to issue certificate for")
+	}
+	ctx, cancel := context.WithTimeout(ctx, ai.timeout)
+	defer cancel()
+
+	if err := ai.register(ctx); err != nil {
+		return nil, err
+	}
+
+	order, err := ai.client.AuthorizeOrder(ctx, acme.DomainIDs(hosts...))
+	if err != nil {
+		return nil, err
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := ai.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := ai.client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, err
+	}
+
+	key, keyPEM, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := ai.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, err
+	}
+	var chainPEM []byte
+	for _, der := range chain {
+		chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	notAfter, err := leafNotAfter(chainPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{CertPEM: chainPEM, KeyPEM: keyPEM, NotAfter: notAfter}, nil
+}
+
+// register registers account if it's not done yet, account of the same key is reused
+func (ai *ACMEIssuer) register(ctx context.Context) error {
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+	if ai.registered {
+		return nil
+	}
+	var account = &acme.Account{}
+	if ai.email != "" {
+		account.Contact = []string{"mailto:" + ai.email}
+	}
+	if _, err := ai.client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+	ai.registered = true
+	return nil
+}
+
+// authorize completes http-01 challenge of authorization if it's not valid yet
+func (ai *ACMEIssuer) authorize(ctx context.Context, authzURL string) error {
+	authz, err := ai.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var challenge *acme.Challenge
+	for _, ch := range authz.Challenges {
+		if ch.Type == "http-01" {
+			challenge = ch
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("acme: no http-01 challenge for %v", authz.Identifier.Value)
+	}
+
+	keyAuth, err := ai.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+	ai.challenges.set(challenge.Token, keyAuth)
+	defer ai.challenges.delete(challenge.Token)
+	if _, err := ai.client.Accept(ctx, challenge); err != nil {
+		return err
+	}
+	_, err = ai.client.WaitAuthorization(ctx, authz.URI)
+	return err
+}
+
+// challengeStore keeps key authorizations of pending challenges
+type challengeStore struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+func (store *challengeStore) set(token, keyAuth string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens[token] = keyAuth
+}
+
+func (store *challengeStore) delete(token string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.tokens, token)
+}
+
+func (store *challengeStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	store.mu.RLock()
+	keyAuth, ok := store.tokens[strings.TrimPrefix(r.URL.Path, ChallengePath)]
+	store.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
@@ -0,0 +1,83 @@
+package certs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"time"
+)
+
+// CAIssuer signs certificates with internal CA key pair
+type CAIssuer struct {
+	cert     *x509.Certificate
+	key      crypto.Signer
+	validity time.Duration
+}
+
+// NewCAIssuer creates issuer from PEM encoded CA certificate and private key.
+// Certificates are valid for validity period, but not longer than CA certificate.
+func NewCAIssuer(certPEM, keyPEM []byte, validity time.Duration) (*CAIssuer, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("CA certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not CA certificate")
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if validity <= 0 {
+		return nil, errors.New("certificate validity must be positive")
+	}
+	return &CAIssuer{cert: cert, key: key, validity: validity}, nil
+}
+
+func (ca *CAIssuer) Name() string {
+	return "ca"
+}
+
+func (ca *CAIssuer) Issue(ctx context.Context, hosts []string) (*Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("no hosts to issue certificate for")
+	}
+	key, keyPEM, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	notAfter := now.Add(ca.validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		// tolerate clock skew of clients
+		NotBefore:   now.Add(-5 * time.Minute),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})...)
+	return &Certificate{CertPEM: chain, KeyPEM: keyPEM, NotAfter: notAfter}, nil
+}
@@ -0,0 +1,277 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCA(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func verify(t *testing.T, caPEM []byte, cert *Certificate, host string) {
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	block, _ := pem.Decode(cert.CertPEM)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+		t.Fatalf("certificate is not valid for %v: %v", host, err)
+	}
+	if !leaf.NotAfter.Equal(cert.NotAfter) {
+		t.Fatalf("expected NotAfter %v, got %v", leaf.NotAfter, cert.NotAfter)
+	}
+	if _, err := parsePrivateKey(cert.KeyPEM); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCAIssuer(t *testing.T) {
+	caPEM, caKeyPEM := testCA(t)
+	if _, err := NewCAIssuer(caPEM, caKeyPEM, 0); err == nil {
+		t.Fatalf("expected error on zero validity")
+	}
+	issuer, err := NewCAIssuer(caPEM, caKeyPEM, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := issuer.Issue(context.Background(), []string{"app.test.io", "www.test.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(t, caPEM, cert, "www.test.io")
+	// validity is capped by CA certificate
+	if cert.NotAfter.After(time.Now().Add(25 * time.Hour)) {
+		t.Fatalf("certificate outlives CA: %v", cert.NotAfter)
+	}
+}
+
+// fakeACME -- minimal ACME server which validates http-01 challenges through challenge handler
+type fakeACME struct {
+	t          *testing.T
+	url        string
+	ca         *CAIssuer
+	challenges http.Handler
+	jwk        json.RawMessage
+	hosts      []string
+	validated  bool
+}
+
+type fakeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type fakeOrder struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate,omitempty"`
+}
+
+type fakeAuthorization struct {
+	Status     string          `json:"status"`
+	Identifier fakeIdentifier  `json:"identifier"`
+	Challenges []fakeChallenge `json:"challenges"`
+}
+
+type fakeChallenge struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Status string `json:"status"`
+}
+
+func (acme *fakeACME) payload(r *http.Request) []byte {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &jws); err != nil {
+		acme.t.Fatal(err)
+	}
+	protectedJSON, _ := base64.RawURLEncoding.DecodeString(jws.Protected)
+	var protected struct {
+		JWK json.RawMessage `json:"jwk"`
+		KID string          `json:"kid"`
+	}
+	json.Unmarshal(protectedJSON, &protected)
+	if protected.JWK != nil {
+		acme.jwk = protected.JWK
+	} else if protected.KID != acme.url+"/account/1" {
+		acme.t.Fatalf("unexpected kid %q", protected.KID)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return payload
+}
+
+func (acme *fakeACME) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", "nonce")
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   acme.url + "/nonce",
+			"newAccount": acme.url + "/account",
+			"newOrder":   acme.url + "/order",
+		})
+		return
+	}
+	if r.URL.Path == "/nonce" {
+		return
+	}
+	payload := acme.payload(r)
+	order := fakeOrder{
+		Status:         "pending",
+		Authorizations: []string{acme.url + "/authz/1"},
+		Finalize:       acme.url + "/finalize",
+	}
+	if acme.validated {
+		order.Status = "ready"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/account":
+		w.Header().Set("Location", acme.url+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": "valid"})
+	case "/order":
+		var req struct {
+			Identifiers []fakeIdentifier `json:"identifiers"`
+		}
+		json.Unmarshal(payload, &req)
+		for _, id := range req.Identifiers {
+			acme.hosts = append(acme.hosts, id.Value)
+		}
+		w.Header().Set("Location", acme.url+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	case "/authz/1":
+		authz := fakeAuthorization{
+			Status:     "pending",
+			Identifier: fakeIdentifier{Type: "dns", Value: acme.hosts[0]},
+			Challenges: []fakeChallenge{{Type: "http-01", URL: acme.url + "/challenge/1", Token: "token1", Status: "pending"}},
+		}
+		if acme.validated {
+			authz.Status = "valid"
+		}
+		json.NewEncoder(w).Encode(authz)
+	case "/challenge/1":
+		rec := httptest.NewRecorder()
+		acme.challenges.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ChallengePath+"token1", nil))
+		sum := sha256.Sum256(acme.jwk)
+		if expected := "token1." + base64.RawURLEncoding.EncodeToString(sum[:]); rec.Body.String() != expected {
+			acme.t.Fatalf("expected key authorization %q, got %q", expected, rec.Body.String())
+		}
+		acme.validated = true
+		json.NewEncoder(w).Encode(fakeChallenge{Type: "http-01", URL: acme.url + "/challenge/1", Token: "token1", Status: "processing"})
+	case "/finalize":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			acme.t.Fatal(err)
+		}
+		if strings.Join(csr.DNSNames, ",") != strings.Join(acme.hosts, ",") {
+			acme.t.Fatalf("unexpected CSR hosts %v", csr.DNSNames)
+		}
+		order.Status = "valid"
+		order.Certificate = acme.url + "/cert/1"
+		w.Header().Set("Location", acme.url+"/order/1")
+		json.NewEncoder(w).Encode(order)
+	case "/order/1":
+		json.NewEncoder(w).Encode(order)
+	case "/cert/1":
+		// certificate key doesn't matter for client, sign new one for the same hosts
+		cert, err := acme.ca.Issue(r.Context(), acme.hosts)
+		if err != nil {
+			acme.t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(cert.CertPEM)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestACMEIssuer(t *testing.T) {
+	caPEM, caKeyPEM := testCA(t)
+	ca, err := NewCAIssuer(caPEM, caKeyPEM, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme := &fakeACME{t: t, ca: ca}
+	srv := httptest.NewServer(acme)
+	defer srv.Close()
+	acme.url = srv.URL
+
+	if _, err := NewACMEIssuer(ACMEConfig{DirectoryURL: srv.URL + "/directory"}); err == nil {
+		t.Fatalf("expected error without account key")
+	}
+	_, accountKeyPEM := testCA(t)
+	issuer, err := NewACMEIssuer(ACMEConfig{
+		DirectoryURL:  srv.URL + "/directory",
+		AccountKeyPEM: accountKeyPEM,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme.challenges = issuer.ChallengeHandler()
+
+	cert, err := issuer.Issue(context.Background(), []string{"app.test.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acme.validated {
+		t.Fatalf("challenge was not validated")
+	}
+	verify(t, caPEM, cert, "app.test.io")
+
+	// challenge response is removed after validation
+	rec := httptest.NewRecorder()
+	issuer.ChallengeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ChallengePath+"token1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected removed challenge, got %v", rec.Code)
+	}
+}
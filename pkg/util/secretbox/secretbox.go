@@ -0,0 +1,108 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySize -- size of encryption key in bytes
+const KeySize = 32
+
+const (
+	// sealedPrefix marks sealed values
+	sealedPrefix = "sb1:"
+	// plainPrefix marks values written in plaintext mode.
+	// Values without prefix were written before encryption was added and are returned as is.
+	plainPrefix = "pt1:"
+)
+
+// Box encrypts secret values with AES-256-GCM.
+// Nil box works in plaintext mode, it's used only in tests: values are stored with plaintext marker.
+// Values stored in plaintext mode are still opened after key is configured and are sealed when they are written next time.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates box with base64-encoded random key of KeySize bytes, e.g. generated with `openssl rand -base64 32`
+func New(key string) (*Box, error) {
+	if key == "" {
+		return nil, errors.New("secret encryption key is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("secret encryption key is not base64-encoded: %v", err)
+	}
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("secret encryption key must have %d bytes, got %d", KeySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts every value of data. Key of value is authenticated, so values can't be swapped between keys.
+func (box *Box) Seal(data map[string]string) (map[string]string, error) {
+	if data == nil {
+		return nil, nil
+	}
+	sealed := make(map[string]string, len(data))
+	for k, v := range data {
+		if box == nil {
+			sealed[k] = plainPrefix + v
+			continue
+		}
+		nonce := make([]byte, box.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		sealed[k] = sealedPrefix + base64.StdEncoding.EncodeToString(box.aead.Seal(nonce, nonce, []byte(v), []byte(k)))
+	}
+	return sealed, nil
+}
+
+// Open decrypts values encrypted by Seal. Values written in plaintext mode are returned without marker.
+func (box *Box) Open(data map[string]string) (map[string]string, error) {
+	if data == nil {
+		return nil, nil
+	}
+	opened := make(map[string]string, len(data))
+	for k, v := range data {
+		if strings.HasPrefix(v, plainPrefix) {
+			opened[k] = strings.TrimPrefix(v, plainPrefix)
+			continue
+		}
+		if !strings.HasPrefix(v, sealedPrefix) {
+			opened[k] = v
+			continue
+		}
+		if box == nil {
+			return nil, fmt.Errorf("%v: value is sealed, but secret encryption key is not set", k)
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, sealedPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", k, err)
+		}
+		if len(raw) < box.aead.NonceSize() {
+			return nil, fmt.Errorf("%v: ciphertext is too short", k)
+		}
+		nonce, ciphertext := raw[:box.aead.NonceSize()], raw[box.aead.NonceSize():]
+		plain, err := box.aead.Open(nil, nonce, ciphertext, []byte(k))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", k, err)
+		}
+		opened[k] = string(plain)
+	}
+	return opened, nil
+}
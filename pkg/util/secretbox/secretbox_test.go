@@ -0,0 +1,61 @@
+package secretbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestBox(t *testing.T) {
+	_, err := New("passphrase")
+	assert.Error(t, err, "key is not base64")
+	_, err = New("c2hvcnQ=")
+	assert.Error(t, err, "key is too short")
+
+	box, err := New(testKey)
+	assert.NoError(t, err)
+
+	sealed, err := box.Seal(map[string]string{"password": "qwerty"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, "qwerty", sealed["password"])
+	opened, err := box.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "qwerty", opened["password"])
+
+	// values can't be swapped between keys
+	_, err = box.Open(map[string]string{"token": sealed["password"]})
+	assert.Error(t, err)
+}
+
+func TestPlaintextBox(t *testing.T) {
+	var plain *Box
+	stored, err := plain.Seal(map[string]string{"password": "qwerty", "token": "sb1:plain"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, "qwerty", stored["password"], "plaintext values are marked")
+	opened, err := plain.Open(stored)
+	assert.NoError(t, err)
+	assert.Equal(t, "sb1:plain", opened["token"], "plaintext value isn't taken as sealed one")
+
+	// values stored before key was configured are still readable
+	box, err := New(testKey)
+	assert.NoError(t, err)
+	opened, err = box.Open(stored)
+	assert.NoError(t, err)
+	assert.Equal(t, "qwerty", opened["password"])
+
+	sealed, err := box.Seal(opened)
+	assert.NoError(t, err)
+	_, err = plain.Open(sealed)
+	assert.Error(t, err, "key is required to open sealed values")
+}
+
+func TestUnmarkedValues(t *testing.T) {
+	// values written before encryption was added have no prefix
+	box, err := New(testKey)
+	assert.NoError(t, err)
+	opened, err := box.Open(map[string]string{"password": "qwerty"})
+	assert.NoError(t, err)
+	assert.Equal(t, "qwerty", opened["password"])
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"fmt"
 
+	"git.containerum.net/ch/resource-service/pkg/models/deployment"
 	kubtypes "github.com/containerum/kube-client/pkg/model"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/en_US"
@@ -28,6 +29,7 @@ func StandardResourceValidator(uni *ut.UniversalTranslator) (ret *validator.Vali
 	ret.RegisterStructValidation(ingressValidate, kubtypes.Ingress{})
 	ret.RegisterStructValidation(serviceValidate, kubtypes.Service{})
 	ret.RegisterStructValidation(deploymentValidate, kubtypes.Deployment{})
+	ret.RegisterStructValidation(strategyValidate, deployment.Strategy{})
 	ret.RegisterStructValidation(containerVolumeValidate, kubtypes.ContainerVolume{})
 	ret.RegisterStructValidation(containerPortValidate, kubtypes.ContainerPort{})
 	ret.RegisterStructValidation(updateReplicasValidate, kubtypes.UpdateReplicas{})
@@ -41,30 +43,34 @@ func ingressValidate(structLevel validator.StructLevel) {
 
 	v := structLevel.Validator()
 
-	if err := v.Var(req.Rules, "required,len=1"); err != nil {
+	if err := v.Var(req.Rules, "required,min=1"); err != nil {
 		structLevel.ReportValidationErrors("Rules", "", err.(validator.ValidationErrors))
 		return
 	}
 
-	if err := v.Var(req.Rules[0].TLSSecret, "omitempty,dns"); err != nil {
-		structLevel.ReportValidationErrors("Rules[0].TLSSecret", "", err.(validator.ValidationErrors))
-	}
+	for i, rule := range req.Rules {
+		if err := v.Var(rule.TLSSecret, "omitempty,dns"); err != nil {
+			structLevel.ReportValidationErrors(fmt.Sprintf("Rules[%d].TLSSecret", i), "", err.(validator.ValidationErrors))
+		}
 
-	if err := v.Var(req.Rules[0].Host, "required"); err != nil {
-		structLevel.ReportValidationErrors("Rules[0].Host", "", err.(validator.ValidationErrors))
-	}
+		if err := v.Var(rule.Host, "required"); err != nil {
+			structLevel.ReportValidationErrors(fmt.Sprintf("Rules[%d].Host", i), "", err.(validator.ValidationErrors))
+		}
 
-	if err := v.Var(req.Rules[0].Path, "required,len=1"); err != nil {
-		structLevel.ReportValidationErrors("Rules[0].Path", "", err.(validator.ValidationErrors))
-		return
-	}
+		if err := v.Var(rule.Path, "required,min=1"); err != nil {
+			structLevel.ReportValidationErrors(fmt.Sprintf("Rules[%d].Path", i), "", err.(validator.ValidationErrors))
+			continue
+		}
 
-	if err := v.Var(req.Rules[0].Path[0].ServiceName, "dns"); err != nil {
-		structLevel.ReportValidationErrors("Rules[0].Path[0].ServiceName", "", err.(validator.ValidationErrors))
-	}
+		for j, path := range rule.Path {
+			if err := v.Var(path.ServiceName, "dns"); err != nil {
+				structLevel.ReportValidationErrors(fmt.Sprintf("Rules[%d].Path[%d].ServiceName", i, j), "", err.(validator.ValidationErrors))
+			}
 
-	if err := v.Var(req.Rules[0].Path[0].ServicePort, "min=1,max=65535"); err != nil {
-		structLevel.ReportValidationErrors("Rules[0].Path[0].ServicePort", "", err.(validator.ValidationErrors))
+			if err := v.Var(path.ServicePort, "min=1,max=65535"); err != nil {
+				structLevel.ReportValidationErrors(fmt.Sprintf("Rules[%d].Path[%d].ServicePort", i, j), "", err.(validator.ValidationErrors))
+			}
+		}
 	}
 }
 
@@ -98,6 +104,18 @@ func serviceValidate(structLevel validator.StructLevel) {
 	}
 }
 
+func strategyValidate(structLevel validator.StructLevel) {
+	req := structLevel.Current().Interface().(deployment.Strategy)
+
+	v := structLevel.Validator()
+
+	if req.Type == deployment.StrategyCanary {
+		if err := v.Var(req.CanaryPercent, "required"); err != nil {
+			structLevel.ReportValidationErrors("CanaryPercent", "", err.(validator.ValidationErrors))
+		}
+	}
+}
+
 func deploymentValidate(structLevel validator.StructLevel) {
 	req := structLevel.Current().Interface().(kubtypes.Deployment)
 